@@ -11,6 +11,7 @@ import (
 	"github.com/beatlabs/patron/correlation"
 	"github.com/beatlabs/patron/encoding"
 	patronErrors "github.com/beatlabs/patron/errors"
+	"github.com/beatlabs/patron/info"
 	"github.com/beatlabs/patron/log"
 	"github.com/beatlabs/patron/trace"
 	"github.com/google/uuid"
@@ -128,6 +129,7 @@ func (f *Factory) Create() (async.Consumer, error) {
 		}
 	}
 
+	info.AddDependency(info.Dependency{Type: "amqp", Name: f.queue, Direction: info.Inbound})
 	return c, nil
 }
 
@@ -275,5 +277,5 @@ func getCorrelationID(hh amqp.Table) string {
 			break
 		}
 	}
-	return uuid.New().String()
+	return correlation.NewID()
 }
@@ -0,0 +1,30 @@
+package async
+
+import (
+	"time"
+)
+
+// BackoffFunc definition of a function which returns the time to wait before the next attempt,
+// given the number of the failed attempt, starting from 1.
+type BackoffFunc func(attempt int) time.Duration
+
+// ConstantBackoff returns a backoff which waits the same time before every attempt.
+func ConstantBackoff(wait time.Duration) BackoffFunc {
+	return func(int) time.Duration {
+		return wait
+	}
+}
+
+// ExponentialBackoff returns a backoff which doubles the wait, starting from initial, up to max.
+func ExponentialBackoff(initial, max time.Duration) BackoffFunc {
+	return func(attempt int) time.Duration {
+		wait := initial
+		for i := 1; i < attempt && wait < max; i++ {
+			wait *= 2
+		}
+		if wait > max {
+			return max
+		}
+		return wait
+	}
+}
@@ -0,0 +1,118 @@
+package async
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConstantBackoff(t *testing.T) {
+	b := ConstantBackoff(time.Second)
+	assert.Equal(t, time.Second, b(1))
+	assert.Equal(t, time.Second, b(10))
+}
+
+func TestExponentialBackoff(t *testing.T) {
+	b := ExponentialBackoff(10*time.Millisecond, 50*time.Millisecond)
+	assert.Equal(t, 10*time.Millisecond, b(1))
+	assert.Equal(t, 20*time.Millisecond, b(2))
+	assert.Equal(t, 40*time.Millisecond, b(3))
+	assert.Equal(t, 50*time.Millisecond, b(4))
+	assert.Equal(t, 50*time.Millisecond, b(100))
+}
+
+func TestBuilder_WithRetryBudget(t *testing.T) {
+	proc := mockProcessor{}
+	_, err := New("name", &mockConsumerFactory{}, proc.Process).WithRetryBudget(0, ConstantBackoff(time.Millisecond)).Create()
+	assert.EqualError(t, err, "retry budget must be positive\n")
+	_, err = New("name", &mockConsumerFactory{}, proc.Process).WithRetryBudget(time.Second, nil).Create()
+	assert.EqualError(t, err, "nil backoff func provided\n")
+}
+
+type nackCountingMessage struct {
+	mockMessage
+	nacks int32
+	acks  int32
+}
+
+func (m *nackCountingMessage) Ack() error {
+	atomic.AddInt32(&m.acks, 1)
+	return nil
+}
+
+func (m *nackCountingMessage) Nack() error {
+	atomic.AddInt32(&m.nacks, 1)
+	return nil
+}
+
+func TestRun_RetryBudget_Exhausted(t *testing.T) {
+	cnr := mockConsumer{chMsg: make(chan Message, 1), chErr: make(chan error)}
+	msg := &nackCountingMessage{mockMessage: mockMessage{ctx: context.Background()}}
+	cnr.chMsg <- msg
+	var attempts int32
+	proc := func(Message) error {
+		atomic.AddInt32(&attempts, 1)
+		return errProcess
+	}
+	cmp, err := New("name", &mockConsumerFactory{c: &cnr}, proc).WithRetryBudget(50*time.Millisecond, ConstantBackoff(20*time.Millisecond)).Create()
+	require.NoError(t, err)
+
+	start := time.Now()
+	assert.Equal(t, errProcess, cmp.Run(context.Background()))
+	// the attempts start at 0ms, 20ms and 40ms, while a fourth attempt would start after the budget
+	assert.Equal(t, int32(3), atomic.LoadInt32(&attempts))
+	assert.True(t, time.Since(start) < 50*time.Millisecond)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&msg.nacks))
+}
+
+func TestRun_RetryBudget_Success(t *testing.T) {
+	cnr := mockConsumer{chMsg: make(chan Message, 1), chErr: make(chan error)}
+	msg := &nackCountingMessage{mockMessage: mockMessage{ctx: context.Background()}}
+	cnr.chMsg <- msg
+	var attempts int32
+	done := make(chan struct{})
+	proc := func(Message) error {
+		if atomic.AddInt32(&attempts, 1) < 3 {
+			return errProcess
+		}
+		close(done)
+		return nil
+	}
+	cmp, err := New("name", &mockConsumerFactory{c: &cnr}, proc).WithRetryBudget(time.Second, ConstantBackoff(time.Millisecond)).Create()
+	require.NoError(t, err)
+	ctx, cnl := context.WithCancel(context.Background())
+	chDone := make(chan error)
+	go func() { chDone <- cmp.Run(ctx) }()
+	<-done
+	cnl()
+	assert.NoError(t, <-chDone)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&msg.acks))
+	assert.Equal(t, int32(0), atomic.LoadInt32(&msg.nacks))
+}
+
+func TestRun_RetryBudget_Canceled(t *testing.T) {
+	cnr := mockConsumer{chMsg: make(chan Message, 1), chErr: make(chan error)}
+	msg := &nackCountingMessage{mockMessage: mockMessage{ctx: context.Background()}}
+	cnr.chMsg <- msg
+	failed := make(chan struct{}, 1)
+	proc := func(Message) error {
+		failed <- struct{}{}
+		return errProcess
+	}
+	cmp, err := New("name", &mockConsumerFactory{c: &cnr}, proc).WithFailureStrategy(NackStrategy).
+		WithRetryBudget(time.Minute, ConstantBackoff(time.Minute)).Create()
+	require.NoError(t, err)
+	ctx, cnl := context.WithCancel(context.Background())
+	chDone := make(chan error)
+	go func() { chDone <- cmp.Run(ctx) }()
+	<-failed
+	start := time.Now()
+	cnl()
+	assert.NoError(t, <-chDone)
+	assert.True(t, time.Since(start) < time.Second)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&msg.nacks))
+}
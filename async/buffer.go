@@ -0,0 +1,69 @@
+package async
+
+import (
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// BufferStats is the usage of the buffer of a component, i.e. the messages consumed but not yet taken for processing,
+// which reveals the back-pressure of a stalled pipeline.
+type BufferStats struct {
+	// Length is the number of messages in the message channel of the consumer.
+	Length int `json:"length"`
+	// Capacity is the capacity of the message channel of the consumer.
+	Capacity int `json:"capacity"`
+	// Lanes is the number of messages queued on the ordered lanes of the component, if any.
+	Lanes int `json:"lanes"`
+}
+
+var (
+	buffersMu sync.Mutex
+	buffers   = make(map[string]func() BufferStats)
+)
+
+// Buffers returns the usage of the buffers of the running components built with buffer inspection, by component name.
+func Buffers() map[string]BufferStats {
+	buffersMu.Lock()
+	defer buffersMu.Unlock()
+	bb := make(map[string]BufferStats, len(buffers))
+	for name, stats := range buffers {
+		bb[name] = stats()
+	}
+	return bb
+}
+
+func registerBuffer(name string, stats func() BufferStats) {
+	buffersMu.Lock()
+	defer buffersMu.Unlock()
+	buffers[name] = stats
+}
+
+func unregisterBuffer(name string) {
+	buffersMu.Lock()
+	defer buffersMu.Unlock()
+	delete(buffers, name)
+}
+
+// bufferCollector reports the usage of the buffers when the metrics are collected,
+// so the usage is current even when the component stalls and takes no messages.
+type bufferCollector struct {
+	desc *prometheus.Desc
+}
+
+func (bc bufferCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- bc.desc
+}
+
+func (bc bufferCollector) Collect(ch chan<- prometheus.Metric) {
+	for name, stats := range Buffers() {
+		ch <- prometheus.MustNewConstMetric(bc.desc, prometheus.GaugeValue, float64(stats.Length+stats.Lanes), name)
+	}
+}
+
+func init() {
+	prometheus.MustRegister(bufferCollector{
+		desc: prometheus.NewDesc(prometheus.BuildFQName("component", "async", "buffer_usage"),
+			"Messages consumed but not yet taken for processing, classified by name", []string{"name"}, nil),
+	})
+}
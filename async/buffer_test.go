@@ -0,0 +1,80 @@
+package async
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// bufferUsage returns the value of the buffer usage metric of the component, and false if it is not reported.
+func bufferUsage(t *testing.T, name string) (float64, bool) {
+	mfs, err := prometheus.DefaultGatherer.Gather()
+	require.NoError(t, err)
+	for _, mf := range mfs {
+		if mf.GetName() != "component_async_buffer_usage" {
+			continue
+		}
+		for _, m := range mf.GetMetric() {
+			if m.GetLabel()[0].GetValue() == name {
+				return m.GetGauge().GetValue(), true
+			}
+		}
+	}
+	return 0, false
+}
+
+func TestRun_BufferInspection(t *testing.T) {
+	cnr := mockConsumer{chMsg: make(chan Message, 10), chErr: make(chan error)}
+	for i := 0; i < 4; i++ {
+		cnr.chMsg <- &mockMessage{ctx: context.Background()}
+	}
+	processing := make(chan struct{})
+	release := make(chan struct{})
+	proc := func(Message) error {
+		processing <- struct{}{}
+		<-release
+		return nil
+	}
+	cmp, err := New("buffered", &mockConsumerFactory{c: &cnr}, proc).WithBufferInspection().Create()
+	require.NoError(t, err)
+	_, ok := bufferUsage(t, "buffered")
+	assert.False(t, ok)
+
+	ctx, cnl := context.WithCancel(context.Background())
+	chDone := make(chan error)
+	go func() { chDone <- cmp.Run(ctx) }()
+
+	// the messages enqueued but not taken for processing are reported while the processor is stuck
+	<-processing
+	usage, ok := bufferUsage(t, "buffered")
+	assert.True(t, ok)
+	assert.Equal(t, float64(3), usage)
+	assert.Equal(t, BufferStats{Length: 3, Capacity: 10}, Buffers()["buffered"])
+
+	release <- struct{}{}
+	<-processing
+	usage, _ = bufferUsage(t, "buffered")
+	assert.Equal(t, float64(2), usage)
+	for i := 0; i < 2; i++ {
+		release <- struct{}{}
+		<-processing
+	}
+	usage, _ = bufferUsage(t, "buffered")
+	assert.Equal(t, float64(0), usage)
+	release <- struct{}{}
+
+	cnl()
+	select {
+	case err = <-chDone:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("component not stopped")
+	}
+	_, ok = bufferUsage(t, "buffered")
+	assert.False(t, ok)
+	assert.NotContains(t, Buffers(), "buffered")
+}
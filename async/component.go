@@ -4,10 +4,16 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"runtime/debug"
+	"strconv"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	patronErrors "github.com/beatlabs/patron/errors"
 	"github.com/beatlabs/patron/log"
+	"github.com/beatlabs/patron/reliability/budget"
+	"github.com/beatlabs/patron/reliability/errorrate"
 	"github.com/prometheus/client_golang/prometheus"
 )
 
@@ -32,25 +38,93 @@ func consumerErrorsInc(name string) {
 	consumerErrors.WithLabelValues(name).Inc()
 }
 
+// ErrorHandlerFunc definition of a function which handles errors received from the consumer's error channel,
+// as well as the PanicError of a processor panic, in which case it may be called concurrently by the processing goroutines.
+// It returns true if the error is fatal and the component should stop, or false if the error
+// should be logged and the consumption should continue.
+type ErrorHandlerFunc func(error) bool
+
+// DefaultErrorHandler treats every consumer error as recoverable, so the error is logged and the consumption continues.
+// The component stops anyway when the consumer closes its channels.
+var DefaultErrorHandler ErrorHandlerFunc = func(error) bool { return false }
+
+var (
+	errConsumerChannelClosed        = errors.New("consumer error channel closed")
+	errConsumerMessageChannelClosed = errors.New("consumer message channel closed")
+)
+
 // Component implementation of a async component.
 type Component struct {
-	name         string
-	proc         ProcessorFunc
-	failStrategy FailStrategy
-	cf           ConsumerFactory
-	retries      int
-	retryWait    time.Duration
+	name             string
+	proc             ProcessorFunc
+	failStrategy     FailStrategy
+	cf               ConsumerFactory
+	retries          int
+	retryWait        time.Duration
+	errHandler       ErrorHandlerFunc
+	errRate          *errorrate.Tracker
+	lanes            int
+	laneKey          KeyFunc
+	ctxFunc          ContextFunc
+	retryBudget      time.Duration
+	backoff          BackoffFunc
+	bufferInspection bool
+	processed        uint64
+	// started is closed once the consumer of the component consumes for the first time.
+	started   chan struct{}
+	startOnce sync.Once
+}
+
+// Started returns a channel which is closed once the component consumes, i.e. its consumer is created and consuming,
+// which the service waits for before reporting that it has started.
+func (c *Component) Started() <-chan struct{} {
+	return c.started
+}
+
+// Info returns information about the component, including the information of the consumer factory, if it provides any.
+func (c *Component) Info() map[string]interface{} {
+	in := map[string]interface{}{
+		"name":         c.name,
+		"failStrategy": failStrategyName(c.failStrategy),
+		"retries":      c.retries,
+		"retryWait":    c.retryWait.String(),
+	}
+	if inf, ok := c.cf.(interface{ Info() map[string]interface{} }); ok {
+		in["consumer"] = inf.Info()
+	}
+	return in
+}
+
+func failStrategyName(fs FailStrategy) string {
+	switch fs {
+	case NackExitStrategy:
+		return "nack-exit"
+	case NackStrategy:
+		return "nack"
+	case AckStrategy:
+		return "ack"
+	default:
+		return strconv.Itoa(int(fs))
+	}
 }
 
 // Builder gathers all required properties in order to construct a component
 type Builder struct {
-	errors       []error
-	name         string
-	proc         ProcessorFunc
-	failStrategy FailStrategy
-	cf           ConsumerFactory
-	retries      uint
-	retryWait    time.Duration
+	errors           []error
+	name             string
+	proc             ProcessorFunc
+	failStrategy     FailStrategy
+	cf               ConsumerFactory
+	retries          uint
+	retryWait        time.Duration
+	errHandler       ErrorHandlerFunc
+	errRate          *errorrate.Tracker
+	lanes            int
+	laneKey          KeyFunc
+	ctxFunc          ContextFunc
+	retryBudget      time.Duration
+	backoff          BackoffFunc
+	bufferInspection bool
 }
 
 // New initializes a new builder for a component with the given name
@@ -67,10 +141,11 @@ func New(name string, cf ConsumerFactory, proc ProcessorFunc) *Builder {
 		errs = append(errs, errors.New("work processor is required"))
 	}
 	return &Builder{
-		name:   name,
-		cf:     cf,
-		proc:   proc,
-		errors: errs,
+		name:       name,
+		cf:         cf,
+		proc:       proc,
+		errHandler: DefaultErrorHandler,
+		errors:     errs,
 	}
 }
 
@@ -108,6 +183,94 @@ func (cb *Builder) WithRetryWait(retryWait time.Duration) *Builder {
 	return cb
 }
 
+// WithErrorHandler specifies the handler which decides if an error received from the consumer
+// stops the component or is logged and ignored
+// default value is DefaultErrorHandler, which logs every error and continues, while the closing of the channels stops the component
+// it will append an error to the builder if the handler is nil.
+func (cb *Builder) WithErrorHandler(eh ErrorHandlerFunc) *Builder {
+	if eh == nil {
+		cb.errors = append(cb.errors, errors.New("nil error handler provided"))
+	} else {
+		log.Infof(propSetMSG, "error handler", cb.name)
+		cb.errHandler = eh
+	}
+	return cb
+}
+
+// WithErrorRateTracker specifies a tracker which records the outcome of the processing of every message,
+// which can be used to report a degraded status when the error rate exceeds a threshold
+// it will append an error to the builder if the tracker is nil.
+func (cb *Builder) WithErrorRateTracker(t *errorrate.Tracker) *Builder {
+	if t == nil {
+		cb.errors = append(cb.errors, errors.New("nil error rate tracker provided"))
+	} else {
+		log.Infof(propSetMSG, "error rate tracker", cb.name)
+		cb.errRate = t
+	}
+	return cb
+}
+
+// WithOrderedLanes specifies that the messages are processed concurrently on the given number of lanes,
+// while the messages with the same key, as returned by the key function, are processed in order on the same lane
+// default is to process the messages sequentially
+// it will append an error to the builder if the number of lanes is not positive or the key function is nil.
+func (cb *Builder) WithOrderedLanes(lanes int, key KeyFunc) *Builder {
+	if lanes <= 0 {
+		cb.errors = append(cb.errors, errors.New("lanes must be positive"))
+	}
+	if key == nil {
+		cb.errors = append(cb.errors, errors.New("nil key func provided"))
+	}
+	if lanes > 0 && key != nil {
+		log.Infof(propSetMSG, "ordered lanes", cb.name)
+		cb.lanes = lanes
+		cb.laneKey = key
+	}
+	return cb
+}
+
+// WithContextFunc specifies a function which enriches the context of every message before it is processed,
+// e.g. with shared dependencies or a scoped logger, which are then available to the processor via msg.Context()
+// it will append an error to the builder if the function is nil.
+func (cb *Builder) WithContextFunc(cf ContextFunc) *Builder {
+	if cf == nil {
+		cb.errors = append(cb.errors, errors.New("nil context func provided"))
+	} else {
+		log.Infof(propSetMSG, "context func", cb.name)
+		cb.ctxFunc = cf
+	}
+	return cb
+}
+
+// WithRetryBudget specifies that a message failing to be processed is processed again, waiting between the attempts
+// as returned by the backoff, until it succeeds or the total time elapsed would exceed the budget,
+// after which the failure strategy is executed with the last error
+// default is to process a message once
+// it will append an error to the builder if the budget is not positive or the backoff is nil.
+func (cb *Builder) WithRetryBudget(total time.Duration, backoff BackoffFunc) *Builder {
+	if total <= 0 {
+		cb.errors = append(cb.errors, errors.New("retry budget must be positive"))
+	}
+	if backoff == nil {
+		cb.errors = append(cb.errors, errors.New("nil backoff func provided"))
+	}
+	if total > 0 && backoff != nil {
+		log.Infof(propSetMSG, "retry budget", cb.name)
+		cb.retryBudget = total
+		cb.backoff = backoff
+	}
+	return cb
+}
+
+// WithBufferInspection specifies that the usage of the buffer of the messages consumed but not yet taken for processing
+// is exposed by component name, in the component_async_buffer_usage metric and by async.Buffers, e.g. in the /debug/buffers route of the HTTP component
+// default is to not expose the usage of the buffer.
+func (cb *Builder) WithBufferInspection() *Builder {
+	log.Infof(propSetMSG, "buffer inspection", cb.name)
+	cb.bufferInspection = true
+	return cb
+}
+
 // Create constructs the Component applying
 func (cb *Builder) Create() (*Component, error) {
 
@@ -116,12 +279,21 @@ func (cb *Builder) Create() (*Component, error) {
 	}
 
 	c := &Component{
-		name:         cb.name,
-		proc:         cb.proc,
-		cf:           cb.cf,
-		failStrategy: cb.failStrategy,
-		retries:      int(cb.retries),
-		retryWait:    cb.retryWait,
+		name:             cb.name,
+		proc:             cb.proc,
+		cf:               cb.cf,
+		failStrategy:     cb.failStrategy,
+		retries:          int(cb.retries),
+		retryWait:        cb.retryWait,
+		errHandler:       cb.errHandler,
+		errRate:          cb.errRate,
+		lanes:            cb.lanes,
+		laneKey:          cb.laneKey,
+		ctxFunc:          cb.ctxFunc,
+		retryBudget:      cb.retryBudget,
+		backoff:          cb.backoff,
+		bufferInspection: cb.bufferInspection,
+		started:          make(chan struct{}),
 	}
 
 	return c, nil
@@ -167,39 +339,172 @@ func (c *Component) processing(ctx context.Context) error {
 	if err != nil {
 		return fmt.Errorf("failed to get consumer channels: %w", err)
 	}
+	c.startOnce.Do(func() { close(c.started) })
 
 	failCh := make(chan error)
 
+	var l *lanes
+	if c.lanes > 0 {
+		l = newLanes(c.lanes, c.laneKey, func(msg Message) error { return c.processMessage(ctx, msg) })
+	}
+	if c.bufferInspection {
+		registerBuffer(c.name, func() BufferStats {
+			return BufferStats{Length: len(chMsg), Capacity: cap(chMsg), Lanes: l.queued()}
+		})
+		defer unregisterBuffer(c.name)
+	}
+
 	go func() {
 		for {
 			select {
 			case <-ctx.Done():
 				log.Info("closing consumer")
+				l.stop()
 				failCh <- cns.Close()
-			case msg := <-chMsg:
+				return
+			case msg, ok := <-chMsg:
+				if !ok {
+					l.stop()
+					if ctx.Err() != nil {
+						log.Info("closing consumer")
+						failCh <- cns.Close()
+					} else {
+						failCh <- errConsumerMessageChannelClosed
+					}
+					return
+				}
 				log.Debug("New message from consumer arrived")
-				c.processMessage(msg, failCh)
-			case errMsg := <-chErr:
-				failCh <- fmt.Errorf("an error occurred during message consumption: %w", errMsg)
+				if l == nil {
+					if err := c.processMessage(ctx, msg); err != nil {
+						failCh <- err
+						return
+					}
+					continue
+				}
+				if err := l.dispatch(ctx, msg); err != nil {
+					l.stop()
+					failCh <- err
+					return
+				}
+			case err := <-l.failures():
+				l.stop()
+				failCh <- err
 				return
+			case errMsg, ok := <-chErr:
+				if !ok {
+					l.stop()
+					if ctx.Err() != nil {
+						log.Info("closing consumer")
+						failCh <- cns.Close()
+					} else {
+						failCh <- errConsumerChannelClosed
+					}
+					return
+				}
+				if c.errHandler(errMsg) {
+					l.stop()
+					failCh <- fmt.Errorf("an error occurred during message consumption: %w", errMsg)
+					return
+				}
+				consumerErrorsInc(c.name)
+				log.Warnf("recoverable error occurred during message consumption: %v", errMsg)
 			}
 		}
 	}()
 	return <-failCh
 }
 
-func (c *Component) processMessage(msg Message, ch chan error) {
-	err := c.proc(msg)
-	if err != nil {
-		err := c.executeFailureStrategy(msg, err)
+// ShutdownStats returns the name of the component and the number of messages it processed.
+func (c *Component) ShutdownStats() map[string]interface{} {
+	return map[string]interface{}{
+		"name":              c.name,
+		"processedMessages": atomic.LoadUint64(&c.processed),
+	}
+}
+
+// Restart prepares the component to be run again after a restart of the service's components.
+// Nothing needs to be reset, since the consumer is re-created from the factory on every run,
+// which allows factories to pick up a changed configuration.
+func (c *Component) Restart() error {
+	return nil
+}
+
+// PanicError is the error a panic of the processor is converted to, which is handled like an error returned
+// by the processor according to the failure strategy, so a message causing a panic does not stop the consumption.
+// It is also passed to the error handler, which stops the component, leaving the message unacknowledged, if it returns true.
+type PanicError struct {
+	Value interface{}
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("processor panicked: %v\n%s", e.Value, e.Stack)
+}
+
+// process executes the processor, converting a panic to a PanicError.
+func (c *Component) process(msg Message) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = &PanicError{Value: r, Stack: debug.Stack()}
+		}
+	}()
+	if c.ctxFunc != nil {
+		msg = &contextMessage{Message: msg, ctx: c.ctxFunc(msg.Context())}
+	}
+	return c.proc(msg)
+}
+
+// processWithRetries processes the message, retrying the failures within the retry budget, if any,
+// until the context is done.
+func (c *Component) processWithRetries(ctx context.Context, msg Message) error {
+	start := time.Now()
+	for attempt := 1; ; attempt++ {
+		err := c.process(msg)
+		if err == nil || c.retryBudget <= 0 {
+			return err
+		}
+		wait := c.backoff(attempt)
+		if time.Since(start)+wait > c.retryBudget {
+			log.FromContext(msg.Context()).Warnf("retry budget of %v exhausted after %d attempts: %v", c.retryBudget, attempt, err)
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(wait):
+		}
+	}
+}
+
+func (c *Component) processMessage(ctx context.Context, msg Message) error {
+	// the goroutine budget of the service is shared with the other components,
+	// so the message waits for it and is left unacknowledged if the component is closing
+	b := budget.FromContext(ctx)
+	if err := b.Acquire(ctx); err != nil {
+		return nil
+	}
+	defer b.Release()
+	defer atomic.AddUint64(&c.processed, 1)
+	err := c.processWithRetries(ctx, msg)
+	if c.errRate != nil {
 		if err != nil {
-			ch <- err
+			c.errRate.Failure()
+		} else {
+			c.errRate.Success()
 		}
-		return
 	}
-	if err := msg.Ack(); err != nil {
-		ch <- err
+	if err != nil {
+		var pe *PanicError
+		if errors.As(err, &pe) && c.errHandler(pe) {
+			log.FromContext(msg.Context()).Errorf("failed to process message, stopping on the panic: %v", pe)
+			if nackErr := msg.Nack(); nackErr != nil {
+				return patronErrors.Aggregate(err, fmt.Errorf("failed to NACK message: %w", nackErr))
+			}
+			return fmt.Errorf("an error occurred during message processing: %w", pe)
+		}
+		return c.executeFailureStrategy(msg, err)
 	}
+	return msg.Ack()
 }
 
 var errInvalidFS = errors.New("invalid failure strategy")
@@ -3,10 +3,14 @@ package async
 import (
 	"context"
 	"errors"
+	"fmt"
 	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/beatlabs/patron/reliability/budget"
+	"github.com/beatlabs/patron/reliability/errorrate"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -74,6 +78,47 @@ func TestNew(t *testing.T) {
 	}
 }
 
+func TestRun_GoroutineBudget(t *testing.T) {
+	b, err := budget.New(1)
+	assert.NoError(t, err)
+
+	var active, maxActive int32
+	processed := make(chan struct{}, 4)
+	proc := func(Message) error {
+		n := atomic.AddInt32(&active, 1)
+		for {
+			m := atomic.LoadInt32(&maxActive)
+			if n <= m || atomic.CompareAndSwapInt32(&maxActive, m, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		atomic.AddInt32(&active, -1)
+		processed <- struct{}{}
+		return nil
+	}
+
+	ctx, cnl := context.WithCancel(budget.WithContext(context.Background(), b))
+	chErr := make(chan error, 2)
+	for i := 0; i < 2; i++ {
+		cnr := &mockConsumer{chMsg: make(chan Message, 2), chErr: make(chan error)}
+		cnr.chMsg <- &mockMessage{ctx: ctx}
+		cnr.chMsg <- &mockMessage{ctx: ctx}
+		cmp, err := New(fmt.Sprintf("test-%d", i), &mockConsumerFactory{c: cnr}, proc).Create()
+		assert.NoError(t, err)
+		go func() { chErr <- cmp.Run(ctx) }()
+	}
+
+	for i := 0; i < 4; i++ {
+		<-processed
+	}
+	cnl()
+	assert.NoError(t, <-chErr)
+	assert.NoError(t, <-chErr)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&maxActive))
+	assert.Equal(t, 0, b.InUse())
+}
+
 type proxyBuilder struct {
 	proc      mockProcessor
 	cnr       mockConsumer
@@ -81,6 +126,7 @@ type proxyBuilder struct {
 	fs        FailStrategy
 	retries   int
 	retryWait time.Duration
+	eh        ErrorHandlerFunc
 }
 
 func run(ctx context.Context, t *testing.T, builder *proxyBuilder) error {
@@ -89,11 +135,14 @@ func run(ctx context.Context, t *testing.T, builder *proxyBuilder) error {
 		builder.cf = &mockConsumerFactory{c: &builder.cnr}
 	}
 
-	cmp, err := New("test", builder.cf, builder.proc.Process).
+	b := New("test", builder.cf, builder.proc.Process).
 		WithFailureStrategy(builder.fs).
 		WithRetries(uint(builder.retries)).
-		WithRetryWait(builder.retryWait).
-		Create()
+		WithRetryWait(builder.retryWait)
+	if builder.eh != nil {
+		b.WithErrorHandler(builder.eh)
+	}
+	cmp, err := b.Create()
 	assert.NoError(t, err)
 	return cmp.Run(ctx)
 }
@@ -300,9 +349,8 @@ func TestRun_MessageAckError(t *testing.T) {
 
 }
 
-// TestRun_ConsumeError will break the component execution,
-// when an error is injected into the consumers error channel
-// while using the default NackExit Failure Strategy
+// TestRun_ConsumeError will log an error injected into the consumers error channel and continue,
+// while using the default error handler, until the consumer closes its message channel
 func TestRun_ConsumeError(t *testing.T) {
 
 	builder := proxyBuilder{
@@ -314,12 +362,130 @@ func TestRun_ConsumeError(t *testing.T) {
 
 	ctx := context.Background()
 	builder.cnr.chErr <- errConsumer
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		builder.cnr.chMsg <- &mockMessage{ctx: ctx}
+		time.Sleep(10 * time.Millisecond)
+		close(builder.cnr.chMsg)
+	}()
 	err := run(ctx, t, &builder)
 
+	assert.True(t, errors.Is(err, errConsumerMessageChannelClosed))
+	assert.Equal(t, 1, builder.proc.execs)
+
+}
+
+func TestBuilder_WithErrorHandler(t *testing.T) {
+	proc := mockProcessor{}
+	got, err := New("name", &mockConsumerFactory{}, proc.Process).WithErrorHandler(nil).Create()
 	assert.Error(t, err)
-	assert.True(t, strings.Contains(err.Error(), errConsumer.Error()))
+	assert.Nil(t, got)
+	got, err = New("name", &mockConsumerFactory{}, proc.Process).WithErrorHandler(func(error) bool { return false }).Create()
+	assert.NoError(t, err)
+	assert.NotNil(t, got)
+}
+
+// TestRun_ConsumeError_ErrorHandlerStop will break the component execution,
+// when the error handler decides that the error is fatal
+func TestRun_ConsumeError_ErrorHandlerStop(t *testing.T) {
+	var handled error
+	builder := proxyBuilder{
+		cnr: mockConsumer{
+			chMsg: make(chan Message, 10),
+			chErr: make(chan error, 10),
+		},
+		eh: func(err error) bool {
+			handled = err
+			return true
+		},
+	}
+
+	builder.cnr.chErr <- errConsumer
+	err := run(context.Background(), t, &builder)
+
+	assert.Error(t, err)
+	assert.True(t, errors.Is(err, errConsumer))
+	assert.Equal(t, errConsumer, handled)
 	assert.Equal(t, 0, builder.proc.execs)
+}
 
+// TestRun_ConsumeError_ErrorHandlerContinue will continue processing messages,
+// when the error handler decides that the error is recoverable
+func TestRun_ConsumeError_ErrorHandlerContinue(t *testing.T) {
+	builder := proxyBuilder{
+		cnr: mockConsumer{
+			chMsg: make(chan Message, 10),
+			chErr: make(chan error, 10),
+		},
+		eh: func(err error) bool { return false },
+	}
+
+	builder.cnr.chErr <- errConsumer
+	ctx, cnl := context.WithCancel(context.Background())
+	ch := make(chan error)
+	go func() {
+		ch <- run(ctx, t, &builder)
+	}()
+	time.Sleep(10 * time.Millisecond)
+	builder.cnr.chMsg <- &mockMessage{ctx: ctx}
+	time.Sleep(10 * time.Millisecond)
+	cnl()
+
+	assert.NoError(t, <-ch)
+	assert.Equal(t, 1, builder.proc.execs)
+}
+
+// TestRun_ConsumeError_ChannelClosed will break the component execution,
+// when the consumer closes its error channel
+func TestRun_ConsumeError_ChannelClosed(t *testing.T) {
+	builder := proxyBuilder{
+		cnr: mockConsumer{
+			chMsg: make(chan Message, 10),
+			chErr: make(chan error, 10),
+		},
+		eh: func(err error) bool { return false },
+	}
+
+	close(builder.cnr.chErr)
+	err := run(context.Background(), t, &builder)
+
+	assert.Equal(t, errConsumerChannelClosed, err)
+}
+
+// TestRun_MessageChannelClosed will break the component execution,
+// when the consumer closes its message channel while the context is still active
+func TestRun_MessageChannelClosed(t *testing.T) {
+	builder := proxyBuilder{
+		cnr: mockConsumer{
+			chMsg: make(chan Message, 10),
+			chErr: make(chan error, 10),
+		},
+	}
+
+	close(builder.cnr.chMsg)
+	err := run(context.Background(), t, &builder)
+
+	assert.Equal(t, errConsumerMessageChannelClosed, err)
+	assert.Equal(t, 0, builder.proc.execs)
+}
+
+// TestRun_MessageChannelClosed_Shutdown verifies the component shuts down cleanly,
+// when the consumer closes its message channel due to a context cancellation
+func TestRun_MessageChannelClosed_Shutdown(t *testing.T) {
+	builder := proxyBuilder{
+		cnr: mockConsumer{
+			chMsg: make(chan Message, 10),
+			chErr: make(chan error, 10),
+		},
+	}
+
+	close(builder.cnr.chMsg)
+	ctx, cnl := context.WithCancel(context.Background())
+	cnl()
+	err := run(ctx, t, &builder)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 0, builder.proc.execs)
 }
 
 // TestRun_ConsumeError_WithRetry will retry the specified amount of times
@@ -391,6 +557,188 @@ func TestRun_Process_Shutdown(t *testing.T) {
 
 }
 
+func TestComponent_ShutdownStats(t *testing.T) {
+	builder := proxyBuilder{
+		cnr: mockConsumer{
+			chMsg: make(chan Message, 10),
+			chErr: make(chan error, 10),
+		},
+	}
+	cmp, err := New("test", &mockConsumerFactory{c: &builder.cnr}, builder.proc.Process).Create()
+	assert.NoError(t, err)
+
+	builder.cnr.chMsg <- &mockMessage{ctx: context.Background()}
+	builder.cnr.chMsg <- &mockMessage{ctx: context.Background()}
+	ctx, cnl := context.WithCancel(context.Background())
+	ch := make(chan error)
+	go func() {
+		ch <- cmp.Run(ctx)
+	}()
+	time.Sleep(10 * time.Millisecond)
+	cnl()
+	assert.NoError(t, <-ch)
+
+	stats := cmp.ShutdownStats()
+	assert.Equal(t, "test", stats["name"])
+	assert.Equal(t, uint64(2), stats["processedMessages"])
+}
+
+func TestComponent_Restart(t *testing.T) {
+	builder := proxyBuilder{
+		cnr: mockConsumer{
+			chMsg: make(chan Message, 10),
+			chErr: make(chan error, 10),
+		},
+	}
+	cmp, err := New("test", &mockConsumerFactory{c: &builder.cnr}, builder.proc.Process).Create()
+	assert.NoError(t, err)
+
+	for i := 0; i < 2; i++ {
+		builder.cnr.chMsg <- &mockMessage{ctx: context.Background()}
+		ctx, cnl := context.WithCancel(context.Background())
+		ch := make(chan error)
+		go func() {
+			ch <- cmp.Run(ctx)
+		}()
+		time.Sleep(10 * time.Millisecond)
+		cnl()
+		assert.NoError(t, <-ch)
+		assert.NoError(t, cmp.Restart())
+	}
+	assert.Equal(t, uint64(2), cmp.ShutdownStats()["processedMessages"])
+}
+
+func TestComponent_ErrorRateTracker(t *testing.T) {
+	_, err := New("test", &mockConsumerFactory{}, (&mockProcessor{}).Process).WithErrorRateTracker(nil).Create()
+	assert.Error(t, err)
+
+	tr, err := errorrate.New(errorrate.Setting{Window: time.Minute, Threshold: 0.5})
+	assert.NoError(t, err)
+	cnr := mockConsumer{
+		chMsg: make(chan Message, 10),
+		chErr: make(chan error, 10),
+	}
+	proc := mockProcessor{errReturn: true}
+	cmp, err := New("test", &mockConsumerFactory{c: &cnr}, proc.Process).
+		WithFailureStrategy(AckStrategy).
+		WithErrorRateTracker(tr).
+		Create()
+	assert.NoError(t, err)
+
+	cnr.chMsg <- &mockMessage{ctx: context.Background()}
+	cnr.chMsg <- &mockMessage{ctx: context.Background()}
+	ctx, cnl := context.WithCancel(context.Background())
+	ch := make(chan error)
+	go func() {
+		ch <- cmp.Run(ctx)
+	}()
+	time.Sleep(10 * time.Millisecond)
+	cnl()
+	assert.NoError(t, <-ch)
+	assert.Equal(t, 1.0, tr.Rate())
+	assert.True(t, tr.Exceeded())
+}
+
+func TestRun_Process_Panic_NackStrategy(t *testing.T) {
+	cnr := mockConsumer{
+		chMsg: make(chan Message, 10),
+		chErr: make(chan error, 10),
+	}
+	var execs int32
+	proc := func(msg Message) error {
+		if atomic.AddInt32(&execs, 1) == 1 {
+			panic("poison message")
+		}
+		return nil
+	}
+	cmp, err := New("test", &mockConsumerFactory{c: &cnr}, proc).
+		WithFailureStrategy(NackStrategy).
+		Create()
+	assert.NoError(t, err)
+
+	cnr.chMsg <- &mockMessage{ctx: context.Background()}
+	cnr.chMsg <- &mockMessage{ctx: context.Background()}
+	ctx, cnl := context.WithCancel(context.Background())
+	ch := make(chan error)
+	go func() {
+		ch <- cmp.Run(ctx)
+	}()
+	time.Sleep(10 * time.Millisecond)
+	cnl()
+	assert.NoError(t, <-ch)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&execs))
+	assert.Equal(t, uint64(2), cmp.ShutdownStats()["processedMessages"])
+}
+
+func TestRun_Process_Panic_NackExitStrategy(t *testing.T) {
+	cnr := mockConsumer{
+		chMsg: make(chan Message, 10),
+		chErr: make(chan error, 10),
+	}
+	cmp, err := New("test", &mockConsumerFactory{c: &cnr}, func(Message) error { panic("poison message") }).Create()
+	assert.NoError(t, err)
+
+	cnr.chMsg <- &mockMessage{ctx: context.Background()}
+	err = cmp.Run(context.Background())
+	var pe *PanicError
+	assert.True(t, errors.As(err, &pe))
+	assert.Equal(t, "poison message", pe.Value)
+	assert.Contains(t, string(pe.Stack), "async.(*Component).process")
+}
+
+func TestRun_Process_Panic_ErrorHandler(t *testing.T) {
+	tests := map[string]struct {
+		fatal   bool
+		wantErr bool
+	}{
+		"recoverable": {},
+		"fatal":       {fatal: true, wantErr: true},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			cnr := mockConsumer{
+				chMsg: make(chan Message, 10),
+				chErr: make(chan error, 10),
+			}
+			handled := make(chan error, 1)
+			cmp, err := New("test", &mockConsumerFactory{c: &cnr}, func(Message) error { panic("poison message") }).
+				WithFailureStrategy(NackStrategy).
+				WithErrorHandler(func(err error) bool {
+					handled <- err
+					return tt.fatal
+				}).
+				Create()
+			assert.NoError(t, err)
+
+			cnr.chMsg <- &mockMessage{ctx: context.Background()}
+			ctx, cnl := context.WithCancel(context.Background())
+			defer cnl()
+			ch := make(chan error)
+			go func() {
+				ch <- cmp.Run(ctx)
+			}()
+
+			var pe *PanicError
+			select {
+			case err := <-handled:
+				assert.True(t, errors.As(err, &pe))
+			case <-time.After(time.Second):
+				t.Fatal("panic not passed to the error handler")
+			}
+			assert.Equal(t, "poison message", pe.Value)
+			assert.Contains(t, string(pe.Stack), "async.(*Component).process")
+
+			if !tt.wantErr {
+				cnl()
+				assert.NoError(t, <-ch)
+				return
+			}
+			err = <-ch
+			assert.True(t, errors.As(err, &pe))
+		})
+	}
+}
+
 // TestRun_Process_Error_InvalidStrategy expects a invalid failure strategy error
 // NOTE : we injected the failure strategy after the construction,
 // in order to avoid the failure strategy check
@@ -477,6 +825,31 @@ func (mcf *mockConsumerFactory) Create() (Consumer, error) {
 	return mcf.c, nil
 }
 
+type informingConsumerFactory struct {
+	mockConsumerFactory
+}
+
+func (icf *informingConsumerFactory) Info() map[string]interface{} {
+	return map[string]interface{}{"topic": "orders"}
+}
+
+func TestComponent_Info(t *testing.T) {
+	proc := mockProcessor{}
+	cmp, err := New("name", &informingConsumerFactory{}, proc.Process).WithFailureStrategy(NackStrategy).WithRetries(3).Create()
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"name":         "name",
+		"failStrategy": "nack",
+		"retries":      3,
+		"retryWait":    "0s",
+		"consumer":     map[string]interface{}{"topic": "orders"},
+	}, cmp.Info())
+
+	cmp, err = New("name", &mockConsumerFactory{}, proc.Process).Create()
+	assert.NoError(t, err)
+	assert.NotContains(t, cmp.Info(), "consumer")
+}
+
 type mockConsumer struct {
 	consumeError bool
 	clsError     bool
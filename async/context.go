@@ -0,0 +1,27 @@
+package async
+
+import (
+	"context"
+)
+
+// ContextFunc definition of a function which enriches the context of a message before it is processed.
+// The context provided is the context of the message, which holds the span extracted from the message headers,
+// the correlation id and the logger, so the values set by the function take precedence over them.
+// The function should therefore derive the returned context from the provided one, without replacing the span.
+type ContextFunc func(context.Context) context.Context
+
+// contextMessage is a message whose context is enriched by a ContextFunc.
+type contextMessage struct {
+	Message
+	ctx context.Context
+}
+
+// Context returns the enriched context of the message.
+func (m *contextMessage) Context() context.Context {
+	return m.ctx
+}
+
+// Unwrap returns the wrapped message.
+func (m *contextMessage) Unwrap() Message {
+	return m.Message
+}
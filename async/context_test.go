@@ -0,0 +1,48 @@
+package async
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type ctxKey string
+
+func TestBuilder_WithContextFunc(t *testing.T) {
+	proc := mockProcessor{}
+	_, err := New("name", &mockConsumerFactory{}, proc.Process).WithContextFunc(nil).Create()
+	assert.EqualError(t, err, "nil context func provided\n")
+}
+
+func TestRun_ContextFunc(t *testing.T) {
+	cnr := mockConsumer{chMsg: make(chan Message, 1), chErr: make(chan error)}
+	msgCtx := context.WithValue(context.Background(), ctxKey("span"), "message-span")
+	msgCtx = context.WithValue(msgCtx, ctxKey("db"), "message-db")
+	cnr.chMsg <- &mockMessage{ctx: msgCtx}
+
+	type values struct{ span, db, config interface{} }
+	chValues := make(chan values, 1)
+	proc := func(msg Message) error {
+		ctx := msg.Context()
+		chValues <- values{span: ctx.Value(ctxKey("span")), db: ctx.Value(ctxKey("db")), config: ctx.Value(ctxKey("config"))}
+		return nil
+	}
+	enrich := func(ctx context.Context) context.Context {
+		ctx = context.WithValue(ctx, ctxKey("db"), "pool")
+		return context.WithValue(ctx, ctxKey("config"), "config")
+	}
+
+	cmp, err := New("name", &mockConsumerFactory{c: &cnr}, proc).WithContextFunc(enrich).Create()
+	require.NoError(t, err)
+	ctx, cnl := context.WithCancel(context.Background())
+	chDone := make(chan error)
+	go func() { chDone <- cmp.Run(ctx) }()
+
+	got := <-chValues
+	cnl()
+	assert.NoError(t, <-chDone)
+	// the values of the message context are kept, unless they are overridden by the function
+	assert.Equal(t, values{span: "message-span", db: "pool", config: "config"}, got)
+}
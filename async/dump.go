@@ -0,0 +1,44 @@
+package async
+
+import "sync"
+
+// DumpFunc definition of a function which returns the state of a consumer, e.g. of its goroutines and the positions of its partitions,
+// which is marshalled to JSON in order to diagnose a stalled consumer.
+type DumpFunc func() interface{}
+
+var (
+	dumpsMu sync.Mutex
+	dumps   = make(map[string]DumpFunc)
+)
+
+// RegisterDump registers the dump of a consumer by name, replacing any dump registered with the same name.
+// The consumers register their dump when they start consuming and unregister it when closed.
+func RegisterDump(name string, df DumpFunc) {
+	dumpsMu.Lock()
+	defer dumpsMu.Unlock()
+	dumps[name] = df
+}
+
+// UnregisterDump unregisters the dump of a consumer.
+func UnregisterDump(name string) {
+	dumpsMu.Lock()
+	defer dumpsMu.Unlock()
+	delete(dumps, name)
+}
+
+// Dumps returns the current state of the registered consumers by name.
+func Dumps() map[string]interface{} {
+	dumpsMu.Lock()
+	dd := make(map[string]DumpFunc, len(dumps))
+	for name, df := range dumps {
+		dd[name] = df
+	}
+	dumpsMu.Unlock()
+
+	// the dumps are taken without holding the lock, since they lock the state of the consumers
+	states := make(map[string]interface{}, len(dd))
+	for name, df := range dd {
+		states[name] = df()
+	}
+	return states
+}
@@ -0,0 +1,20 @@
+package async
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDumps(t *testing.T) {
+	RegisterDump("first", func() interface{} { return 1 })
+	RegisterDump("second", func() interface{} { return "state" })
+	assert.Equal(t, map[string]interface{}{"first": 1, "second": "state"}, Dumps())
+
+	RegisterDump("first", func() interface{} { return 2 })
+	UnregisterDump("second")
+	UnregisterDump("missing")
+	assert.Equal(t, map[string]interface{}{"first": 2}, Dumps())
+	UnregisterDump("first")
+	assert.Empty(t, Dumps())
+}
@@ -0,0 +1,139 @@
+package kafka
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/beatlabs/patron/encoding"
+	"github.com/beatlabs/patron/encoding/avro"
+	"github.com/beatlabs/patron/log"
+)
+
+const (
+	avroMagicByte  byte = 0
+	avroHeaderSize      = 5
+	registryTries       = 3
+)
+
+var registryRetryWait = 500 * time.Millisecond
+
+// AvroDecoder creates a decoder for messages in the Confluent Schema Registry wire format,
+// which consists of a zero magic byte, the 4-byte big endian schema ID and the Avro binary encoded data.
+// The schema is fetched by ID from the registry and cached.
+// Messages are decoded into a generic map, which is assigned directly when decoding into a *map[string]interface{}
+// or *interface{} and mapped onto any other Go type via its JSON representation.
+func AvroDecoder(registryURL string) encoding.DecodeRawFunc {
+	r := &schemaRegistry{
+		url:     strings.TrimSuffix(registryURL, "/"),
+		client:  &http.Client{Timeout: 5 * time.Second},
+		schemas: make(map[uint32]*avro.Schema),
+	}
+	return r.decode
+}
+
+type schemaRegistry struct {
+	url    string
+	client *http.Client
+	sync.RWMutex
+	schemas map[uint32]*avro.Schema
+}
+
+func (r *schemaRegistry) decode(data []byte, v interface{}) error {
+	if len(data) < avroHeaderSize || data[0] != avroMagicByte {
+		return errors.New("message is not in the schema registry wire format")
+	}
+	id := binary.BigEndian.Uint32(data[1:avroHeaderSize])
+	s, err := r.schema(id)
+	if err != nil {
+		return err
+	}
+	val, err := s.Decode(data[avroHeaderSize:])
+	if err != nil {
+		return fmt.Errorf("failed to decode avro message with schema %d: %w", id, err)
+	}
+
+	switch t := v.(type) {
+	case *interface{}:
+		*t = val
+		return nil
+	case *map[string]interface{}:
+		m, ok := val.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("avro value of schema %d is not a record", id)
+		}
+		*t = m
+		return nil
+	}
+	b, err := json.Marshal(val)
+	if err != nil {
+		return fmt.Errorf("failed to map avro value of schema %d: %w", id, err)
+	}
+	return json.Unmarshal(b, v)
+}
+
+// schema returns the cached schema or fetches it from the registry. The registry is called without holding the lock,
+// so that the messages of cached schemas are decoded while a schema is fetched.
+func (r *schemaRegistry) schema(id uint32) (*avro.Schema, error) {
+	r.RLock()
+	s, ok := r.schemas[id]
+	r.RUnlock()
+	if ok {
+		return s, nil
+	}
+
+	var err error
+	for i := 0; i < registryTries; i++ {
+		if i > 0 {
+			log.Warnf("failed to fetch schema %d from registry, retry %d/%d: %v", id, i, registryTries-1, err)
+			time.Sleep(registryRetryWait)
+		}
+		s, err = r.fetch(id)
+		if err == nil {
+			r.Lock()
+			r.schemas[id] = s
+			r.Unlock()
+			return s, nil
+		}
+		if errors.Is(err, errSchemaInvalid) {
+			break
+		}
+	}
+	return nil, fmt.Errorf("schema registry %s unavailable for schema %d: %w", r.url, id, err)
+}
+
+var errSchemaInvalid = errors.New("invalid schema")
+
+func (r *schemaRegistry) fetch(id uint32) (*avro.Schema, error) {
+	rsp, err := r.client.Get(fmt.Sprintf("%s/schemas/ids/%d", r.url, id))
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := rsp.Body.Close(); err != nil {
+			log.Warnf("failed to close schema registry response body: %v", err)
+		}
+	}()
+	if rsp.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("%w: schema not found", errSchemaInvalid)
+	}
+	if rsp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code %d", rsp.StatusCode)
+	}
+	var payload struct {
+		Schema string `json:"schema"`
+	}
+	if err := json.NewDecoder(rsp.Body).Decode(&payload); err != nil {
+		return nil, fmt.Errorf("%w: %v", errSchemaInvalid, err)
+	}
+	s, err := avro.ParseSchema(payload.Schema)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errSchemaInvalid, err)
+	}
+	return s, nil
+}
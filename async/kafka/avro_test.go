@@ -0,0 +1,141 @@
+package kafka
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const avroUserSchema = `{"type":"record","name":"User","fields":[{"name":"name","type":"string"},{"name":"age","type":"long"}]}`
+
+func avroMessage(id uint32, name string, age int64) []byte {
+	b := []byte{avroMagicByte, 0, 0, 0, 0}
+	binary.BigEndian.PutUint32(b[1:], id)
+	v := make([]byte, binary.MaxVarintLen64)
+	n := binary.PutVarint(v, int64(len(name)))
+	b = append(b, v[:n]...)
+	b = append(b, name...)
+	n = binary.PutVarint(v, age)
+	return append(b, v[:n]...)
+}
+
+func newMockRegistry(t *testing.T, failures int32) (*httptest.Server, *int32) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c := atomic.AddInt32(&calls, 1)
+		if c <= failures {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		switch r.URL.Path {
+		case "/schemas/ids/1":
+			assert.NoError(t, json.NewEncoder(w).Encode(map[string]string{"schema": avroUserSchema}))
+		case "/schemas/ids/2":
+			assert.NoError(t, json.NewEncoder(w).Encode(map[string]string{"schema": `"foo"`}))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	return srv, &calls
+}
+
+func TestAvroDecoder(t *testing.T) {
+	srv, calls := newMockRegistry(t, 0)
+	defer srv.Close()
+	dec := AvroDecoder(srv.URL + "/")
+
+	var m map[string]interface{}
+	assert.NoError(t, dec(avroMessage(1, "John", 42), &m))
+	assert.Equal(t, map[string]interface{}{"name": "John", "age": int64(42)}, m)
+
+	var i interface{}
+	assert.NoError(t, dec(avroMessage(1, "Jane", 7), &i))
+	assert.Equal(t, map[string]interface{}{"name": "Jane", "age": int64(7)}, i)
+
+	var u struct {
+		Name string `json:"name"`
+		Age  int    `json:"age"`
+	}
+	assert.NoError(t, dec(avroMessage(1, "Jim", 3), &u))
+	assert.Equal(t, "Jim", u.Name)
+	assert.Equal(t, 3, u.Age)
+
+	// the schema is cached after the first fetch
+	assert.Equal(t, int32(1), atomic.LoadInt32(calls))
+}
+
+func TestAvroDecoder_Errors(t *testing.T) {
+	srv, calls := newMockRegistry(t, 0)
+	defer srv.Close()
+	dec := AvroDecoder(srv.URL)
+	var m map[string]interface{}
+
+	assert.Error(t, dec([]byte{1, 0, 0, 0, 1}, &m))
+	assert.Error(t, dec([]byte{0, 0}, &m))
+	assert.Error(t, dec(avroMessage(1, "John", 42)[:8], &m))
+
+	// invalid schemas and unknown IDs are not retried
+	err := dec(avroMessage(2, "John", 42), &m)
+	assert.Error(t, err)
+	err = dec(avroMessage(3, "John", 42), &m)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "schema not found")
+	assert.Equal(t, int32(3), atomic.LoadInt32(calls))
+}
+
+func TestAvroDecoder_RegistryRetries(t *testing.T) {
+	defer func(w time.Duration) { registryRetryWait = w }(registryRetryWait)
+	registryRetryWait = time.Millisecond
+
+	srv, calls := newMockRegistry(t, registryTries-1)
+	defer srv.Close()
+	var m map[string]interface{}
+	assert.NoError(t, AvroDecoder(srv.URL)(avroMessage(1, "John", 42), &m))
+	assert.Equal(t, int32(registryTries), atomic.LoadInt32(calls))
+
+	srv2, _ := newMockRegistry(t, registryTries)
+	defer srv2.Close()
+	err := AvroDecoder(srv2.URL)(avroMessage(1, "John", 42), &m)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "unavailable")
+}
+
+func TestAvroDecoder_FetchDoesNotBlockCachedSchemas(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/schemas/ids/3" {
+			<-release
+		}
+		assert.NoError(t, json.NewEncoder(w).Encode(map[string]string{"schema": avroUserSchema}))
+	}))
+	defer srv.Close()
+	defer close(release)
+	dec := AvroDecoder(srv.URL)
+	var m map[string]interface{}
+	assert.NoError(t, dec(avroMessage(1, "John", 42), &m))
+
+	go func() {
+		var m map[string]interface{}
+		_ = dec(avroMessage(3, "Jane", 7), &m)
+	}()
+	time.Sleep(10 * time.Millisecond)
+
+	// the schema being fetched does not block the messages of the cached schema
+	done := make(chan error)
+	go func() {
+		var m map[string]interface{}
+		done <- dec(avroMessage(1, "Jim", 3), &m)
+	}()
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(time.Second):
+		assert.Fail(t, "decoding blocked by the fetch of another schema")
+	}
+}
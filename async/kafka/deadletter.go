@@ -0,0 +1,118 @@
+package kafka
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/beatlabs/patron/async"
+	"github.com/beatlabs/patron/log"
+)
+
+const (
+	// RetryCountHeader is the header of the number of times a message has been requeued after failing to be processed.
+	RetryCountHeader = "x-retry-count"
+	// FailureHeader is the header of a failure to process a message, holding its time and error, which is repeated per failure.
+	FailureHeader = "x-failure"
+	// OriginalTopicHeader is the header of the topic a dead-lettered message was consumed from.
+	OriginalTopicHeader = "x-original-topic"
+	// OriginalPartitionHeader is the header of the partition a dead-lettered message was consumed from.
+	OriginalPartitionHeader = "x-original-partition"
+	// OriginalOffsetHeader is the header of the offset a dead-lettered message was consumed from.
+	OriginalOffsetHeader = "x-original-offset"
+)
+
+// DeadLetter requeues the messages failing to be processed to their topic, up to a maximum number of retries,
+// after which they are sent to a dead-letter topic. The retries of a message are tracked in its RetryCountHeader,
+// while every failure is appended to its FailureHeader headers, so dead-lettered messages carry their failure history.
+type DeadLetter struct {
+	producer   sarama.SyncProducer
+	topic      string
+	maxRetries int
+}
+
+// NewDeadLetter creates a dead letter, which produces the requeued and the dead-lettered messages with the provided producer,
+// in order for a message to be acked only after it is produced.
+func NewDeadLetter(producer sarama.SyncProducer, topic string, maxRetries int) (*DeadLetter, error) {
+	if producer == nil {
+		return nil, errors.New("producer is required")
+	}
+	if topic == "" {
+		return nil, errors.New("dead-letter topic is required")
+	}
+	if maxRetries < 0 {
+		return nil, errors.New("max retries must be zero or positive")
+	}
+	return &DeadLetter{producer: producer, topic: topic, maxRetries: maxRetries}, nil
+}
+
+// Processor wraps the processor of a component consuming from Kafka, in order to requeue or dead-letter the messages
+// it fails to process. A message which is requeued or dead-lettered is processed successfully, so it is acked,
+// while a failure to produce it is returned, which executes the failure strategy of the component.
+// Requeued messages are appended to their partition, so they are processed after the messages consumed in the meantime,
+// including the later messages of the same key, which is not suitable for processing depending on the ordering of the messages.
+func (dl *DeadLetter) Processor(proc async.ProcessorFunc) async.ProcessorFunc {
+	return func(msg async.Message) error {
+		err := proc(msg)
+		if err == nil {
+			return nil
+		}
+		m := unwrap(msg, func(m async.Message) bool {
+			_, ok := m.(*message)
+			return ok
+		})
+		if m == nil {
+			return err
+		}
+		return dl.handle(msg, m.(*message).msg, err, time.Now())
+	}
+}
+
+func (dl *DeadLetter) handle(msg async.Message, cm *sarama.ConsumerMessage, procErr error, now time.Time) error {
+	retries := retryCount(cm.Headers)
+	headers := make([]sarama.RecordHeader, 0, len(cm.Headers)+5)
+	for _, h := range cm.Headers {
+		if string(h.Key) != RetryCountHeader {
+			headers = append(headers, *h)
+		}
+	}
+	headers = append(headers, sarama.RecordHeader{Key: []byte(FailureHeader), Value: []byte(now.UTC().Format(time.RFC3339) + " " + procErr.Error())})
+
+	pm := &sarama.ProducerMessage{Value: sarama.ByteEncoder(cm.Value)}
+	if cm.Key != nil {
+		pm.Key = sarama.ByteEncoder(cm.Key)
+	}
+	if retries < dl.maxRetries {
+		pm.Topic = cm.Topic
+		pm.Headers = append(headers, sarama.RecordHeader{Key: []byte(RetryCountHeader), Value: []byte(strconv.Itoa(retries + 1))})
+		log.FromContext(msg.Context()).Warnf("requeuing message of topic %s, retry %d/%d: %v", cm.Topic, retries+1, dl.maxRetries, procErr)
+	} else {
+		pm.Topic = dl.topic
+		pm.Headers = append(headers,
+			sarama.RecordHeader{Key: []byte(RetryCountHeader), Value: []byte(strconv.Itoa(retries))},
+			sarama.RecordHeader{Key: []byte(OriginalTopicHeader), Value: []byte(cm.Topic)},
+			sarama.RecordHeader{Key: []byte(OriginalPartitionHeader), Value: []byte(strconv.Itoa(int(cm.Partition)))},
+			sarama.RecordHeader{Key: []byte(OriginalOffsetHeader), Value: []byte(strconv.FormatInt(cm.Offset, 10))})
+		log.FromContext(msg.Context()).Errorf("sending message of topic %s to dead-letter topic %s after %d retries: %v", cm.Topic, dl.topic, retries, procErr)
+	}
+	if _, _, err := dl.producer.SendMessage(pm); err != nil {
+		return fmt.Errorf("failed to produce message to topic %s: %v: %w", pm.Topic, err, procErr)
+	}
+	return nil
+}
+
+// retryCount returns the retry count of the headers, which is zero for messages which have not been requeued.
+func retryCount(hh []*sarama.RecordHeader) int {
+	for _, h := range hh {
+		if string(h.Key) == RetryCountHeader {
+			n, err := strconv.Atoi(string(h.Value))
+			if err != nil || n < 0 {
+				return 0
+			}
+			return n
+		}
+	}
+	return 0
+}
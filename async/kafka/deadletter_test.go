@@ -0,0 +1,144 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/Shopify/sarama"
+	"github.com/beatlabs/patron/async"
+	"github.com/beatlabs/patron/encoding/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type syncProducer struct {
+	sent []*sarama.ProducerMessage
+	err  error
+}
+
+func (p *syncProducer) SendMessage(msg *sarama.ProducerMessage) (int32, int64, error) {
+	if p.err != nil {
+		return 0, 0, p.err
+	}
+	p.sent = append(p.sent, msg)
+	return 0, int64(len(p.sent)), nil
+}
+
+func (p *syncProducer) SendMessages(msgs []*sarama.ProducerMessage) error {
+	for _, msg := range msgs {
+		if _, _, err := p.SendMessage(msg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *syncProducer) Close() error {
+	return nil
+}
+
+func TestNewDeadLetter(t *testing.T) {
+	_, err := NewDeadLetter(nil, "dlq", 3)
+	assert.EqualError(t, err, "producer is required")
+	_, err = NewDeadLetter(&syncProducer{}, "", 3)
+	assert.EqualError(t, err, "dead-letter topic is required")
+	_, err = NewDeadLetter(&syncProducer{}, "dlq", -1)
+	assert.EqualError(t, err, "max retries must be zero or positive")
+	dl, err := NewDeadLetter(&syncProducer{}, "dlq", 0)
+	assert.NoError(t, err)
+	assert.NotNil(t, dl)
+}
+
+// consumed returns the message which is consumed after a message is produced.
+func consumed(pm *sarama.ProducerMessage, offset int64) *sarama.ConsumerMessage {
+	cm := &sarama.ConsumerMessage{Topic: pm.Topic, Offset: offset}
+	cm.Key, _ = pm.Key.Encode()
+	cm.Value, _ = pm.Value.Encode()
+	for i := range pm.Headers {
+		cm.Headers = append(cm.Headers, &pm.Headers[i])
+	}
+	return cm
+}
+
+func headerValues(hh []sarama.RecordHeader, key string) []string {
+	var vv []string
+	for _, h := range hh {
+		if string(h.Key) == key {
+			vv = append(vv, string(h.Value))
+		}
+	}
+	return vv
+}
+
+func TestDeadLetter_Processor(t *testing.T) {
+	const maxRetries = 3
+	producer := &syncProducer{}
+	dl, err := NewDeadLetter(producer, "dlq", maxRetries)
+	require.NoError(t, err)
+	execs := 0
+	proc := dl.Processor(func(async.Message) error {
+		execs++
+		return errors.New("failed to process")
+	})
+
+	cm := &sarama.ConsumerMessage{Topic: "topic", Key: []byte("key"), Value: []byte(`"value"`), Offset: 10,
+		Headers: []*sarama.RecordHeader{{Key: []byte("custom"), Value: []byte("header")}}}
+	for i := 0; i <= maxRetries; i++ {
+		msg, err := ClaimMessage(context.Background(), cm, json.DecodeRaw, nil, ClaimOptions{Group: "group"})
+		require.NoError(t, err)
+		// the message is acked, since it is requeued or dead-lettered
+		require.NoError(t, proc(msg))
+		require.Len(t, producer.sent, i+1)
+		cm = consumed(producer.sent[i], cm.Offset+1)
+	}
+	assert.Equal(t, maxRetries+1, execs)
+
+	for i, pm := range producer.sent[:maxRetries] {
+		assert.Equal(t, "topic", pm.Topic)
+		assert.Equal(t, []string{strconv.Itoa(i + 1)}, headerValues(pm.Headers, RetryCountHeader))
+		assert.Len(t, headerValues(pm.Headers, FailureHeader), i+1)
+	}
+	pm := producer.sent[maxRetries]
+	assert.Equal(t, "dlq", pm.Topic)
+	key, _ := pm.Key.Encode()
+	assert.Equal(t, "key", string(key))
+	value, _ := pm.Value.Encode()
+	assert.Equal(t, `"value"`, string(value))
+	assert.Equal(t, []string{"header"}, headerValues(pm.Headers, "custom"))
+	assert.Equal(t, []string{"3"}, headerValues(pm.Headers, RetryCountHeader))
+	assert.Equal(t, []string{"topic"}, headerValues(pm.Headers, OriginalTopicHeader))
+	assert.Equal(t, []string{"0"}, headerValues(pm.Headers, OriginalPartitionHeader))
+	assert.Equal(t, []string{"13"}, headerValues(pm.Headers, OriginalOffsetHeader))
+	failures := headerValues(pm.Headers, FailureHeader)
+	assert.Len(t, failures, maxRetries+1)
+	for _, f := range failures {
+		assert.True(t, strings.HasSuffix(f, " failed to process"), f)
+	}
+}
+
+func TestDeadLetter_Processor_Errors(t *testing.T) {
+	producer := &syncProducer{err: errors.New("broker down")}
+	dl, err := NewDeadLetter(producer, "dlq", 1)
+	require.NoError(t, err)
+	procErr := errors.New("failed to process")
+	proc := dl.Processor(func(async.Message) error { return procErr })
+
+	// a failure to produce is returned in order to execute the failure strategy
+	cm := &sarama.ConsumerMessage{Topic: "topic", Value: []byte(`"value"`)}
+	msg, err := ClaimMessage(context.Background(), cm, json.DecodeRaw, nil, ClaimOptions{Group: "group"})
+	require.NoError(t, err)
+	err = proc(msg)
+	assert.EqualError(t, err, "failed to produce message to topic topic: broker down: failed to process")
+	assert.True(t, errors.Is(err, procErr))
+
+	// messages which are not consumed from Kafka are not requeued
+	assert.Equal(t, procErr, proc(nil))
+
+	// successfully processed messages are not produced
+	proc = dl.Processor(func(async.Message) error { return nil })
+	assert.NoError(t, proc(msg))
+	assert.Empty(t, producer.sent)
+}
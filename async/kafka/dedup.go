@@ -0,0 +1,79 @@
+package kafka
+
+import (
+	"sync"
+	"time"
+
+	"github.com/beatlabs/patron/log"
+)
+
+// dedupWarnf logs the repetitions of the errors, which tests replace.
+var dedupWarnf = log.Warnf
+
+// ErrorDedup collapses the identical errors of a consumer within a window, e.g. during an outage, in order not to flood
+// the error channel and the logs. The first error is reported, while its repetitions within the window are counted
+// and logged as a single line when the window ends or a different error occurs. A nil deduplication reports every error.
+type ErrorDedup struct {
+	name     string
+	window   time.Duration
+	mu       sync.Mutex
+	last     string
+	since    time.Time
+	repeated int
+	// timer logs the repetitions when the window ends, if no other error occurs until then.
+	timer *time.Timer
+}
+
+// NewErrorDedup creates the error deduplication of a consumer.
+// It returns nil if the window is not positive, i.e. when the kafka.ErrorDedupWindow option is not provided.
+func NewErrorDedup(name string, window time.Duration) *ErrorDedup {
+	if window <= 0 {
+		return nil
+	}
+	return &ErrorDedup{name: name, window: window}
+}
+
+// Allow reports whether an error should be reported, i.e. it differs from the last reported error
+// or the window of the last reported error has elapsed. Otherwise the error is counted as a repetition.
+func (d *ErrorDedup) Allow(err error) bool {
+	if d == nil {
+		return true
+	}
+	msg := err.Error()
+	now := time.Now()
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if msg == d.last && now.Sub(d.since) < d.window {
+		d.repeated++
+		if d.timer == nil {
+			d.timer = time.AfterFunc(d.window-now.Sub(d.since), d.Flush)
+		}
+		return false
+	}
+	d.flush()
+	d.last = msg
+	d.since = now
+	return true
+}
+
+// Flush logs the repetitions of the last reported error, if any, e.g. when the consumer closes.
+func (d *ErrorDedup) Flush() {
+	if d == nil {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.flush()
+}
+
+func (d *ErrorDedup) flush() {
+	if d.timer != nil {
+		d.timer.Stop()
+		d.timer = nil
+	}
+	if d.repeated == 0 {
+		return
+	}
+	dedupWarnf("consumer %s: error '%s' repeated %d times", d.name, d.last, d.repeated)
+	d.repeated = 0
+}
@@ -0,0 +1,78 @@
+package kafka
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewErrorDedup(t *testing.T) {
+	assert.Nil(t, NewErrorDedup("name", 0))
+	assert.NotNil(t, NewErrorDedup("name", time.Second))
+}
+
+func TestErrorDedup(t *testing.T) {
+	var logs []string
+	defer func(f func(string, ...interface{})) { dedupWarnf = f }(dedupWarnf)
+	dedupWarnf = func(msg string, args ...interface{}) { logs = append(logs, fmt.Sprintf(msg, args...)) }
+
+	d := NewErrorDedup("orders", time.Minute)
+	errBroker := errors.New("broker unreachable")
+	assert.True(t, d.Allow(errBroker))
+	for i := 0; i < 5; i++ {
+		assert.False(t, d.Allow(errors.New("broker unreachable")))
+	}
+	assert.Empty(t, logs)
+
+	// a distinct error passes through, logging the repetitions of the previous one
+	assert.True(t, d.Allow(errors.New("decoding failed")))
+	assert.Equal(t, []string{"consumer orders: error 'broker unreachable' repeated 5 times"}, logs)
+	assert.True(t, d.Allow(errBroker))
+	assert.Len(t, logs, 1)
+
+	assert.False(t, d.Allow(errBroker))
+	d.Flush()
+	d.Flush()
+	assert.Equal(t, "consumer orders: error 'broker unreachable' repeated 1 times", logs[1])
+	assert.Len(t, logs, 2)
+}
+
+func TestErrorDedup_Window(t *testing.T) {
+	d := NewErrorDedup("orders", 10*time.Millisecond)
+	errBroker := errors.New("broker unreachable")
+	assert.True(t, d.Allow(errBroker))
+	assert.False(t, d.Allow(errBroker))
+	time.Sleep(20 * time.Millisecond)
+	assert.True(t, d.Allow(errBroker))
+}
+
+func TestErrorDedup_WindowEnd(t *testing.T) {
+	logs := make(chan string, 2)
+	defer func(f func(string, ...interface{})) { dedupWarnf = f }(dedupWarnf)
+	dedupWarnf = func(msg string, args ...interface{}) { logs <- fmt.Sprintf(msg, args...) }
+
+	d := NewErrorDedup("orders", 20*time.Millisecond)
+	errBroker := errors.New("broker unreachable")
+	assert.True(t, d.Allow(errBroker))
+	assert.False(t, d.Allow(errBroker))
+	assert.False(t, d.Allow(errBroker))
+	// the repetitions are logged when the window ends, without waiting for another error
+	select {
+	case l := <-logs:
+		assert.Equal(t, "consumer orders: error 'broker unreachable' repeated 2 times", l)
+	case <-time.After(time.Second):
+		t.Fatal("repetitions not logged at the end of the window")
+	}
+	d.Flush()
+	assert.Len(t, logs, 0)
+}
+
+func TestErrorDedup_Nil(t *testing.T) {
+	var d *ErrorDedup
+	assert.True(t, d.Allow(errors.New("broker unreachable")))
+	assert.True(t, d.Allow(errors.New("broker unreachable")))
+	d.Flush()
+}
@@ -0,0 +1,44 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type srvResolver interface {
+	LookupSRV(ctx context.Context, service, proto, name string) (string, []*net.SRV, error)
+}
+
+var (
+	resolver          srvResolver = net.DefaultResolver
+	dnsResolveTimeout             = 10 * time.Second
+)
+
+// BrokersFromDNS resolves the DNS SRV record, e.g. _kafka._tcp.example.com, to a list of brokers,
+// which can be used in place of a static list of brokers for consumers and producers.
+// The record is resolved once, since after connecting, the brokers of the cluster are discovered via the metadata
+// of the bootstrap brokers.
+func BrokersFromDNS(srvName string) ([]string, error) {
+	if srvName == "" {
+		return nil, errors.New("SRV record name is required")
+	}
+	ctx, cnl := context.WithTimeout(context.Background(), dnsResolveTimeout)
+	defer cnl()
+	_, addrs, err := resolver.LookupSRV(ctx, "", "", srvName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve SRV record %s: %w", srvName, err)
+	}
+	brokers := make([]string, 0, len(addrs))
+	for _, addr := range addrs {
+		brokers = append(brokers, net.JoinHostPort(strings.TrimSuffix(addr.Target, "."), strconv.Itoa(int(addr.Port))))
+	}
+	if len(brokers) == 0 {
+		return nil, fmt.Errorf("SRV record %s resolved to no brokers", srvName)
+	}
+	return brokers, nil
+}
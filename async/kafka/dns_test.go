@@ -0,0 +1,56 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type mockResolver struct {
+	addrs []*net.SRV
+	err   error
+	name  string
+}
+
+func (m *mockResolver) LookupSRV(_ context.Context, service, proto, name string) (string, []*net.SRV, error) {
+	m.name = name
+	return "", m.addrs, m.err
+}
+
+func TestBrokersFromDNS(t *testing.T) {
+	defer func(r srvResolver) { resolver = r }(resolver)
+
+	tests := map[string]struct {
+		srvName string
+		addrs   []*net.SRV
+		err     error
+		want    []string
+		wantErr bool
+	}{
+		"success": {srvName: "_kafka._tcp.example.com", addrs: []*net.SRV{
+			{Target: "broker-1.example.com.", Port: 9092},
+			{Target: "broker-2.example.com.", Port: 9093},
+		}, want: []string{"broker-1.example.com:9092", "broker-2.example.com:9093"}},
+		"missing name":       {srvName: "", wantErr: true},
+		"resolution failure": {srvName: "_kafka._tcp.example.com", err: errors.New("no such host"), wantErr: true},
+		"no records":         {srvName: "_kafka._tcp.example.com", addrs: []*net.SRV{}, wantErr: true},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			r := &mockResolver{addrs: tt.addrs, err: tt.err}
+			resolver = r
+			got, err := BrokersFromDNS(tt.srvName)
+			if tt.wantErr {
+				assert.Error(t, err)
+				assert.Nil(t, got)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.want, got)
+				assert.Equal(t, tt.srvName, r.name)
+			}
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package group
+
+import (
+	"sync"
+	"time"
+
+	"github.com/beatlabs/patron/async"
+	"github.com/beatlabs/patron/log"
+)
+
+// drainer tracks the messages which are delivered but not yet acked or nacked, in order for a session
+// to wait for them before its claims are released on a rebalance, since the offsets of the messages acked
+// after the release are not committed and the messages are redelivered to the new owner of the partition.
+type drainer struct {
+	mu       sync.Mutex
+	inFlight int
+	// idle is closed while no message is in flight
+	idle chan struct{}
+}
+
+func newDrainer() *drainer {
+	d := &drainer{idle: make(chan struct{})}
+	close(d.idle)
+	return d
+}
+
+// track returns a message which is in flight until its first Ack or Nack.
+func (d *drainer) track(msg async.Message) async.Message {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.inFlight == 0 {
+		d.idle = make(chan struct{})
+	}
+	d.inFlight++
+	return &drainedMessage{Message: msg, done: d.done}
+}
+
+func (d *drainer) done() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.inFlight--
+	if d.inFlight == 0 {
+		close(d.idle)
+	}
+}
+
+// wait waits up to the timeout for the messages in flight to be acked or nacked.
+func (d *drainer) wait(timeout time.Duration) bool {
+	d.mu.Lock()
+	idle, inFlight := d.idle, d.inFlight
+	d.mu.Unlock()
+	if inFlight == 0 {
+		return true
+	}
+	log.Infof("waiting up to %v for %d messages in flight before releasing the claims", timeout, inFlight)
+	select {
+	case <-idle:
+		return true
+	case <-time.After(timeout):
+		log.Warnf("%d messages still in flight after %v, their offsets will not be committed", d.pending(), timeout)
+		return false
+	}
+}
+
+func (d *drainer) pending() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.inFlight
+}
+
+type drainedMessage struct {
+	async.Message
+	once sync.Once
+	done func()
+}
+
+// Ack acknowledges the message, marking its offset, and stops tracking it.
+func (m *drainedMessage) Ack() error {
+	defer m.once.Do(m.done)
+	return m.Message.Ack()
+}
+
+// Unwrap returns the tracked message.
+func (m *drainedMessage) Unwrap() async.Message {
+	return m.Message
+}
+
+// Nack signals the failure of the message and stops tracking it.
+func (m *drainedMessage) Nack() error {
+	defer m.once.Do(m.done)
+	return m.Message.Nack()
+}
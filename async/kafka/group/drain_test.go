@@ -0,0 +1,119 @@
+package group
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/beatlabs/patron/async"
+	"github.com/beatlabs/patron/async/kafka"
+	"github.com/beatlabs/patron/encoding/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type markingSession struct {
+	mockConsumerSession
+	mu     sync.Mutex
+	marked int
+}
+
+func (m *markingSession) MarkMessage(*sarama.ConsumerMessage, string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.marked++
+}
+
+func (m *markingSession) markedMessages() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.marked
+}
+
+func TestHandler_Cleanup_DrainsMessagesInFlight(t *testing.T) {
+	chMsg := make(chan async.Message, 2)
+	h := handler{
+		messages: chMsg,
+		consumer: &consumer{config: kafka.ConsumerConfig{RebalanceDrainTimeout: time.Second}},
+		drainer:  newDrainer(),
+	}
+	msgs := append(saramaConsumerMessages(json.Type), saramaConsumerMessages(json.Type)...)
+	sess := &markingSession{}
+	require.NoError(t, h.ConsumeClaim(sess, &mockConsumerClaim{msgs}))
+
+	// the partitions are revoked while the messages are being processed
+	go func() {
+		for i := 0; i < 2; i++ {
+			m := <-chMsg
+			time.Sleep(50 * time.Millisecond)
+			assert.NoError(t, m.Ack())
+		}
+	}()
+	assert.NoError(t, h.Cleanup(sess))
+	assert.Equal(t, 2, sess.markedMessages())
+	assert.Equal(t, 0, h.drainer.pending())
+}
+
+func TestHandler_Cleanup_DrainTimeout(t *testing.T) {
+	chMsg := make(chan async.Message, 2)
+	h := handler{
+		messages: chMsg,
+		consumer: &consumer{config: kafka.ConsumerConfig{RebalanceDrainTimeout: 50 * time.Millisecond}},
+		drainer:  newDrainer(),
+	}
+	msgs := append(saramaConsumerMessages(json.Type), saramaConsumerMessages(json.Type)...)
+	sess := &markingSession{}
+	require.NoError(t, h.ConsumeClaim(sess, &mockConsumerClaim{msgs}))
+
+	assert.NoError(t, (<-chMsg).Nack())
+	assert.NoError(t, h.Cleanup(sess))
+	assert.Equal(t, 1, h.drainer.pending())
+
+	// acking twice releases the message once
+	m := <-chMsg
+	assert.NoError(t, m.Ack())
+	assert.NoError(t, m.Ack())
+	assert.Equal(t, 0, h.drainer.pending())
+	assert.True(t, h.drainer.wait(time.Millisecond))
+}
+
+func TestHandler_Cleanup_ShutdownTimeout(t *testing.T) {
+	ctx, cnl := context.WithCancel(context.Background())
+	cnl()
+	h := handler{ctx: ctx, consumer: &consumer{config: kafka.ConsumerConfig{ShutdownTimeout: 10 * time.Millisecond}}, drainer: newDrainer()}
+	h.drainer.track(nil)
+	start := time.Now()
+	assert.NoError(t, h.Cleanup(&mockConsumerSession{}))
+	assert.True(t, time.Since(start) >= 10*time.Millisecond)
+	assert.Equal(t, 1, h.drainer.pending())
+}
+
+func TestHandler_Cleanup_NacksUndeliveredMessages(t *testing.T) {
+	ctx, cnl := context.WithCancel(context.Background())
+	chMsg := make(chan async.Message, 2)
+	h := handler{
+		ctx:      ctx,
+		messages: chMsg,
+		consumer: &consumer{config: kafka.ConsumerConfig{ShutdownTimeout: 10 * time.Second}},
+		drainer:  newDrainer(),
+	}
+	msgs := append(saramaConsumerMessages(json.Type), saramaConsumerMessages(json.Type)...)
+	sess := &markingSession{}
+	require.NoError(t, h.ConsumeClaim(sess, &mockConsumerClaim{msgs}))
+
+	// the processor received a message when the consumption is canceled, while the other is still buffered
+	m := <-chMsg
+	cnl()
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		assert.NoError(t, m.Ack())
+	}()
+	start := time.Now()
+	assert.NoError(t, h.Cleanup(sess))
+	assert.True(t, time.Since(start) < time.Second)
+	assert.Equal(t, 0, h.drainer.pending())
+	assert.Equal(t, 1, sess.markedMessages())
+	assert.Empty(t, chMsg)
+}
@@ -4,21 +4,41 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"math"
+	"strings"
+	"time"
 
 	"github.com/Shopify/sarama"
 	"github.com/beatlabs/patron/async"
 	"github.com/beatlabs/patron/async/kafka"
+	"github.com/beatlabs/patron/encoding"
+	"github.com/beatlabs/patron/info"
 	"github.com/beatlabs/patron/log"
-	"github.com/opentracing/opentracing-go"
+	"github.com/beatlabs/patron/reliability/retry"
+)
+
+const (
+	// defaultRetries retries the transient errors of the sessions indefinitely, without the kafka.Retries option.
+	defaultRetries = math.MaxUint32
+	// defaultRetryWait is the wait before the first retry.
+	defaultRetryWait = time.Second
+	// defaultMaxRetryWait caps the backoff of the retries, without the kafka.MaxRetryWait option.
+	defaultMaxRetryWait = time.Minute
+	// defaultRetryJitter randomizes the backoff of the retries, without the kafka.RetryJitter option.
+	defaultRetryJitter = 0.2
+	// defaultShutdownTimeout is the wait for the messages in flight on shutdown, without the kafka.ShutdownTimeout option.
+	defaultShutdownTimeout = 10 * time.Second
 )
 
 // Factory definition of a consumer factory.
 type Factory struct {
 	name    string
 	group   string
-	topic   string
+	topics  []string
 	brokers []string
 	oo      []kafka.OptionFunc
+	// readiness is shared by the consumers of the factory.
+	readiness *readiness
 }
 
 // New constructor.
@@ -40,56 +60,161 @@ func New(name, group, topic string, brokers []string, oo ...kafka.OptionFunc) (*
 		return nil, errors.New("topic is required")
 	}
 
-	return &Factory{name: name, group: group, topic: topic, brokers: brokers, oo: oo}, nil
+	return &Factory{name: name, group: group, topics: []string{topic}, brokers: brokers, oo: oo, readiness: &readiness{}}, nil
 }
 
-// Create a new consumer.
-func (f *Factory) Create() (async.Consumer, error) {
-
-	config, err := kafka.DefaultSaramaConfig(f.name)
+// NewWithTopics constructor of a consumer of multiple topics.
+// Kafka does not guarantee the ordering of messages across topics, so messages of different topics
+// are delivered in no specific order, which can be weighted with the kafka.TopicWeights option.
+func NewWithTopics(name, group string, topics []string, brokers []string, oo ...kafka.OptionFunc) (*Factory, error) {
+	if len(topics) == 0 {
+		return nil, errors.New("provide at least one topic")
+	}
 
+	f, err := New(name, group, topics[0], brokers, oo...)
 	if err != nil {
 		return nil, err
 	}
 
-	cc := kafka.ConsumerConfig{
-		Brokers:      f.brokers,
-		Buffer:       0,
-		SaramaConfig: config,
+	for _, topic := range topics {
+		if topic == "" {
+			return nil, errors.New("topic is required")
+		}
+	}
+	f.topics = topics
+	return f, nil
+}
+
+// Create a new consumer.
+func (f *Factory) Create() (async.Consumer, error) {
+	cc, err := f.config()
+	if err != nil {
+		return nil, err
 	}
 
 	c := &consumer{
-		topic:    f.topic,
-		group:    f.group,
-		traceTag: opentracing.Tag{Key: "group", Value: f.group},
-		config:   cc,
+		name:      f.name,
+		topics:    f.topics,
+		group:     f.group,
+		config:    cc,
+		status:    kafka.NewConsumerStatus("kafka-group"),
+		readiness: f.readiness,
 	}
 
-	for _, o := range f.oo {
-		err = o(&c.config)
-		if err != nil {
-			return nil, fmt.Errorf("could not apply OptionFunc to consumer : %w", err)
+	for topic := range c.config.TopicWeights {
+		if !contains(c.topics, topic) {
+			return nil, fmt.Errorf("weight provided for topic '%s' which is not consumed", topic)
 		}
 	}
 
+	if len(c.config.TopicDecoders) > 0 {
+		for topic := range c.config.TopicDecoders {
+			if !contains(c.topics, topic) {
+				return nil, fmt.Errorf("decoder provided for topic '%s' which is not consumed", topic)
+			}
+		}
+		// when decoders are provided per topic, a topic without one is most likely a misconfiguration,
+		// unless a decoder for all topics is provided as well
+		if c.config.DecoderFunc == nil {
+			for _, topic := range c.topics {
+				if _, ok := c.config.TopicDecoders[topic]; !ok {
+					return nil, fmt.Errorf("no decoder provided for topic '%s' and no fallback decoder", topic)
+				}
+			}
+		}
+	}
+
+	for _, topic := range c.topics {
+		info.AddDependency(info.Dependency{Type: "kafka", Name: topic, Direction: info.Inbound})
+	}
 	return c, nil
 }
 
+// Ready returns true if a consumer of the factory has joined the group and was assigned partitions,
+// which can be surfaced on the readiness check of the service with http.ConsumersReadyCheck.
+// A consumer is not ready before the first assignment, between the rebalances of the group,
+// and while the group has more members than the partitions of the topics.
+func (f *Factory) Ready() bool {
+	return f.readiness.ready()
+}
+
+// Info returns the group, the topics and a summary of the effective sarama config of the consumers, without credentials.
+func (f *Factory) Info() map[string]interface{} {
+	in := map[string]interface{}{
+		"type":    "kafka-group",
+		"group":   f.group,
+		"topics":  f.topics,
+		"brokers": f.brokers,
+	}
+	cc, err := f.config()
+	if err != nil {
+		in["error"] = err.Error()
+		return in
+	}
+	in["sarama"] = kafka.SaramaConfigInfo(cc.SaramaConfig)
+	return in
+}
+
+// config returns the config of the consumers, with the options applied.
+func (f *Factory) config() (kafka.ConsumerConfig, error) {
+	config, err := kafka.DefaultSaramaConfig(f.name)
+	if err != nil {
+		return kafka.ConsumerConfig{}, err
+	}
+
+	cc := kafka.ConsumerConfig{
+		Brokers:         f.brokers,
+		Buffer:          0,
+		SaramaConfig:    config,
+		MessageTags:     kafka.DefaultMessageTags,
+		Retries:         defaultRetries,
+		RetryWait:       defaultRetryWait,
+		MaxRetryWait:    defaultMaxRetryWait,
+		RetryJitter:     defaultRetryJitter,
+		ShutdownTimeout: defaultShutdownTimeout,
+		TraceSampleRate: 1,
+	}
+
+	cc, err = kafka.ApplyOptions(cc, f.oo...)
+	if err != nil {
+		return kafka.ConsumerConfig{}, fmt.Errorf("could not apply OptionFunc to consumer : %w", err)
+	}
+	return cc, nil
+}
+
 // consumer members can be injected or overwritten with the usage of OptionFunc arguments.
 type consumer struct {
-	topic    string
-	group    string
-	traceTag opentracing.Tag
-	cnl      context.CancelFunc
-	cg       sarama.ConsumerGroup
-	config   kafka.ConsumerConfig
+	name   string
+	topics []string
+	group  string
+	cnl    context.CancelFunc
+	cg     sarama.ConsumerGroup
+	config kafka.ConsumerConfig
+	// status tracks the claimed partitions, which is dumped by name while consuming.
+	status *kafka.ConsumerStatus
+	// readiness tracks the assignment of partitions to the consumer.
+	readiness *readiness
+	// heartbeat logs periodically that the consumer is alive, when the kafka.HeartbeatLog option is provided.
+	heartbeat *kafka.Heartbeat
+	// dedup collapses the identical errors of the retries, when the kafka.ErrorDedupWindow option is provided.
+	dedup *kafka.ErrorDedup
 }
 
-// Close handles closing consumer.
+// Close handles closing consumer gracefully, in order to minimize the redelivered messages and the rebalance delay:
+// canceling the consumption stops the delivery of messages, the session waits, up to the shutdown timeout,
+// for the messages in flight to be acked or nacked and commits their offsets when its claims are released,
+// after which the consumer leaves the group, instead of the group waiting for the session timeout.
 func (c *consumer) Close() error {
 	if c.cnl != nil {
 		c.cnl()
 	}
+	async.UnregisterDump(c.name)
+	c.readiness.cleanup()
+	c.dedup.Flush()
+	if c.cg == nil {
+		// the consumer was never started
+		return nil
+	}
 
 	err := c.cg.Close()
 	if err != nil {
@@ -104,12 +229,19 @@ func (c *consumer) Consume(ctx context.Context) (<-chan async.Message, <-chan er
 	ctx, cnl := context.WithCancel(ctx)
 	c.cnl = cnl
 
-	cg, err := sarama.NewConsumerGroup(c.config.Brokers, c.group, c.config.SaramaConfig)
+	var cg sarama.ConsumerGroup
+	var err error
+	if c.config.Client != nil {
+		cg, err = sarama.NewConsumerGroupFromClient(c.group, c.config.Client)
+	} else {
+		cg, err = sarama.NewConsumerGroup(c.config.Brokers, c.group, c.config.SaramaConfig)
+	}
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to create consumer: %w", err)
 	}
 	c.cg = cg
-	log.Infof("consuming messages from topic '%s' using group '%s'", c.topic, c.group)
+	async.RegisterDump(c.name, func() interface{} { return c.status.Dump() })
+	log.Infof("consuming messages from topics '%s' using group '%s'", strings.Join(c.topics, ","), c.group)
 
 	chMsg := make(chan async.Message, c.config.Buffer)
 	chErr := make(chan error, c.config.Buffer)
@@ -123,24 +255,107 @@ func (c *consumer) Consume(ctx context.Context) (<-chan async.Message, <-chan er
 				return
 			case consumerError := <-c.cg.Errors():
 				closeConsumer(c.cg)
-				chErr <- consumerError
+				// the closed consumer group stops the sessions, which always report their error
+				if c.dedup.Allow(consumerError) {
+					chErr <- consumerError
+				}
 				return
 			}
 		}
 	}()
 
-	// Iterate over consumer sessions.
-	go func() {
-		hnd := handler{consumer: c, messages: chMsg}
-		for {
-			err := c.cg.Consume(ctx, []string{c.topic}, hnd)
-			if err != nil {
-				chErr <- err
+	c.heartbeat = kafka.NewHeartbeat(c.name, c.config.HeartbeatInterval, c.status)
+	go c.heartbeat.Run(ctx)
+	c.dedup = kafka.NewErrorDedup(c.name, c.config.ErrorDedupWindow)
+	go kafka.NewStuckDetector(c.name, c.config.StuckPartitionTimeout, c.status).Run(ctx)
+	go c.consumeSessions(ctx, chMsg, chErr)
+
+	return chMsg, chErr, nil
+}
+
+// consumeSessions iterates over consumer sessions until the context is canceled or a session fails without being retried,
+// after which the message channel is closed.
+func (c *consumer) consumeSessions(ctx context.Context, chMsg chan async.Message, chErr chan<- error) {
+	// the messages in flight are always tracked, in order to be waited for on shutdown
+	hnd := handler{ctx: ctx, consumer: c, messages: chMsg, drainer: newDrainer()}
+	if c.config.MaxInFlight > 0 {
+		// the limit is validated by the option
+		hnd.limiter, _ = kafka.NewInFlightLimiter(c.config.MaxInFlight)
+	}
+	var ws *weightedSelector
+	if len(c.config.TopicWeights) > 0 {
+		ws = newWeightedSelector(c.topics, c.config.TopicWeights, c.config.Buffer)
+		hnd.topicMessages = ws.inputs
+		go ws.run(ctx, chMsg)
+	}
+	var retries uint
+	for {
+		err := c.cg.Consume(ctx, c.topics, hnd)
+		if ctx.Err() == nil && err != nil && !fatalConsumeError(err) && retries < c.config.Retries {
+			wait := retry.Backoff(c.config.RetryWait, c.config.MaxRetryWait, retries, c.config.RetryJitter)
+			retries++
+			if c.dedup.Allow(err) {
+				log.Warnf("failed to consume from topics '%s' using group '%s', retry %s in %v: %v",
+					strings.Join(c.topics, ","), c.group, retryCount(retries, c.config.Retries), wait, err)
+			}
+			select {
+			case <-ctx.Done():
+			case <-time.After(wait):
+				continue
 			}
 		}
-	}()
+		if ctx.Err() == nil && err == nil {
+			// a session ended without an error, e.g. on a rebalance
+			retries = 0
+			continue
+		}
+		if ctx.Err() != nil {
+			log.Infof("stopped consuming messages from topics '%s' using group '%s'", strings.Join(c.topics, ","), c.group)
+		} else {
+			if c.dedup.Allow(err) {
+				log.Errorf("stopped consuming messages from topics '%s' using group '%s': %v", strings.Join(c.topics, ","), c.group, err)
+			}
+			// the error which stops the consumer is always reported, even if it repeats the retried error
+			chErr <- err
+		}
+		// the message channel is closed in both cases, in order for the component not to wait for messages
+		// when its error handler continues on the error
+		if ws != nil {
+			// the selector closes the message channel after draining the topic channels
+			ws.close()
+		} else {
+			close(chMsg)
+		}
+		return
+	}
+}
 
-	return chMsg, chErr, nil
+// retryCount formats the count of a retry, along with the maximum retries unless the retries are not bounded.
+func retryCount(retries, max uint) string {
+	if max == defaultRetries {
+		return fmt.Sprintf("%d", retries)
+	}
+	return fmt.Sprintf("%d/%d", retries, max)
+}
+
+// fatalConsumeError reports whether the error of a session is not resolved by retrying, e.g. a misconfiguration
+// or a failed authorization, in which case the consumer fails without retrying.
+func fatalConsumeError(err error) bool {
+	var ce sarama.ConfigurationError
+	if errors.As(err, &ce) || errors.Is(err, sarama.ErrClosedConsumerGroup) {
+		return true
+	}
+	var ke sarama.KError
+	if !errors.As(err, &ke) {
+		return false
+	}
+	switch ke {
+	case sarama.ErrSASLAuthenticationFailed, sarama.ErrUnsupportedSASLMechanism, sarama.ErrTopicAuthorizationFailed,
+		sarama.ErrGroupAuthorizationFailed, sarama.ErrClusterAuthorizationFailed:
+		return true
+	default:
+		return false
+	}
 }
 
 func closeConsumer(cns sarama.ConsumerGroup) {
@@ -154,21 +369,128 @@ func closeConsumer(cns sarama.ConsumerGroup) {
 }
 
 type handler struct {
-	consumer *consumer
-	messages chan async.Message
+	// ctx is the context of the consumption, which is canceled on shutdown.
+	ctx           context.Context
+	consumer      *consumer
+	messages      chan async.Message
+	topicMessages map[string]chan async.Message
+	limiter       *kafka.InFlightLimiter
+	drainer       *drainer
+}
+
+// Setup marks the consumer as ready, if partitions were assigned to it.
+func (h handler) Setup(sess sarama.ConsumerGroupSession) error {
+	h.consumer.readiness.setup(sess)
+	return nil
+}
+
+// Cleanup marks the consumer as not ready, since its claims are released, and waits for the messages in flight to be acked or nacked,
+// in order for their offsets to be committed before the claims are released. On shutdown it nacks the buffered messages, which are
+// not received by the processor, and waits up to the shutdown timeout for the rest, while on a rebalance it waits up to the
+// rebalance drain timeout, if it is set.
+func (h handler) Cleanup(_ sarama.ConsumerGroupSession) error {
+	h.consumer.readiness.cleanup()
+	if h.drainer == nil {
+		return nil
+	}
+	if h.ctx != nil && h.ctx.Err() != nil {
+		h.nackUndelivered()
+		h.drainer.wait(h.consumer.config.ShutdownTimeout)
+	} else if h.consumer.config.RebalanceDrainTimeout > 0 {
+		h.drainer.wait(h.consumer.config.RebalanceDrainTimeout)
+	}
+	return nil
+}
+
+// nackUndelivered nacks the messages buffered in the message channels, which are not processed after the consumption is canceled,
+// in order to stop tracking them. Their offsets are not marked, so they are redelivered.
+func (h handler) nackUndelivered() {
+	nack := func(ch chan async.Message) {
+		for {
+			select {
+			case m, ok := <-ch:
+				if !ok {
+					return
+				}
+				_ = m.Nack()
+			default:
+				return
+			}
+		}
+	}
+	nack(h.messages)
+	for _, ch := range h.topicMessages {
+		nack(ch)
+	}
 }
 
-func (h handler) Setup(_ sarama.ConsumerGroupSession) error   { return nil }
-func (h handler) Cleanup(_ sarama.ConsumerGroupSession) error { return nil }
 func (h handler) ConsumeClaim(sess sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) error {
 	ctx := sess.Context()
+	// a claim is a single partition, whose offsets are marked in order
+	ordered := newOrderedSession(sess)
+	status := h.consumer.status
+	status.SetPartition(claim.Topic(), claim.Partition(), kafka.PartitionFetching, -1, claim.HighWaterMarkOffset())
+	defer status.RemovePartition(claim.Topic(), claim.Partition())
 	for msg := range claim.Messages() {
+		if ctx.Err() != nil {
+			// the session ended, e.g. on shutdown, so no more messages are delivered and the unmarked ones are redelivered
+			return nil
+		}
 		kafka.TopicPartitionOffsetDiffGaugeSet(h.consumer.group, msg.Topic, msg.Partition, claim.HighWaterMarkOffset(), msg.Offset)
-		m, err := kafka.ClaimMessage(ctx, msg, h.consumer.config.DecoderFunc, sess)
+		if h.limiter != nil {
+			status.SetPartition(msg.Topic, msg.Partition, kafka.PartitionThrottled, msg.Offset, claim.HighWaterMarkOffset())
+			if h.limiter.Acquire(ctx) != nil {
+				// the session ended while waiting for a message in flight to be acked
+				return nil
+			}
+		}
+		status.SetPartition(msg.Topic, msg.Partition, kafka.PartitionDelivering, msg.Offset, claim.HighWaterMarkOffset())
+		ordered.add(msg)
+		m, err := kafka.ClaimMessage(ctx, msg, h.consumer.decoder(msg.Topic), ordered,
+			h.consumer.config.ClaimOptions(h.consumer.group))
 		if err != nil {
+			if h.limiter != nil {
+				h.limiter.Release()
+			}
 			return err
 		}
-		h.messages <- m
+		m = ordered.track(m, msg)
+		if h.limiter != nil {
+			m = h.limiter.Track(m)
+		}
+		if h.drainer != nil {
+			m = h.drainer.track(m)
+		}
+		ch, ok := h.topicMessages[msg.Topic]
+		if !ok {
+			ch = h.messages
+		}
+		select {
+		case ch <- m:
+		case <-ctx.Done():
+			// the message is not delivered, so it is nacked in order to stop tracking it, without marking its offset
+			_ = m.Nack()
+			return nil
+		}
+		h.consumer.heartbeat.Processed()
+		status.SetPartitionState(msg.Topic, msg.Partition, kafka.PartitionFetching)
 	}
 	return nil
 }
+
+// decoder returns the decoder of the topic, falling back to the decoder of all topics.
+func (c *consumer) decoder(topic string) encoding.DecodeRawFunc {
+	if dec, ok := c.config.TopicDecoders[topic]; ok {
+		return dec
+	}
+	return c.config.DecoderFunc
+}
+
+func contains(ss []string, s string) bool {
+	for _, v := range ss {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
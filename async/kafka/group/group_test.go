@@ -2,7 +2,10 @@ package group
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -71,7 +74,7 @@ func TestNew(t *testing.T) {
 func TestFactory_Create(t *testing.T) {
 	type fields struct {
 		clientName string
-		topic      string
+		topics     []string
 		brokers    []string
 		oo         []kafka.OptionFunc
 	}
@@ -82,7 +85,7 @@ func TestFactory_Create(t *testing.T) {
 		"success": {
 			fields: fields{
 				clientName: "clientA",
-				topic:      "topicA",
+				topics:     []string{"topicA"},
 				brokers:    []string{"192.168.1.1"},
 			},
 			wantErr: false,
@@ -90,7 +93,7 @@ func TestFactory_Create(t *testing.T) {
 		"failed with invalid option": {
 			fields: fields{
 				clientName: "clientB",
-				topic:      "topicA",
+				topics:     []string{"topicA"},
 				brokers:    []string{"192.168.1.1"},
 				oo:         []kafka.OptionFunc{kafka.Buffer(-100)},
 			},
@@ -101,7 +104,7 @@ func TestFactory_Create(t *testing.T) {
 		t.Run(testName, func(t *testing.T) {
 			f := &Factory{
 				name:    tt.fields.clientName,
-				topic:   tt.fields.topic,
+				topics:  tt.fields.topics,
 				brokers: tt.fields.brokers,
 				oo:      tt.fields.oo,
 			}
@@ -115,7 +118,7 @@ func TestFactory_Create(t *testing.T) {
 				consumer, ok := got.(*consumer)
 				assert.True(t, ok, "consumer is not of type group.consumer")
 				assert.Equal(t, tt.fields.brokers, consumer.config.Brokers)
-				assert.Equal(t, tt.fields.topic, consumer.topic)
+				assert.Equal(t, tt.fields.topics, consumer.topics)
 				assert.True(t, strings.HasSuffix(consumer.config.SaramaConfig.ClientID, tt.fields.clientName))
 			}
 		})
@@ -149,7 +152,7 @@ func (m *mockConsumerSession) MarkOffset(topic string, partition int32, offset i
 func (m *mockConsumerSession) ResetOffset(topic string, partition int32, offset int64, metadata string) {
 }
 func (m *mockConsumerSession) MarkMessage(msg *sarama.ConsumerMessage, metadata string) {}
-func (m *mockConsumerSession) Context() context.Context                                 { return nil }
+func (m *mockConsumerSession) Context() context.Context                                 { return context.Background() }
 
 func TestHandler_ConsumeClaim(t *testing.T) {
 
@@ -182,6 +185,63 @@ func TestHandler_ConsumeClaim(t *testing.T) {
 	}
 }
 
+func TestHandler_ConsumeClaim_TopicDecoders(t *testing.T) {
+	decoder := func(name string) encoding.DecodeRawFunc {
+		return func(data []byte, v interface{}) error {
+			*v.(*string) = name + ":" + string(data)
+			return nil
+		}
+	}
+	f, err := NewWithTopics("name", "group", []string{"commands", "telemetry"}, []string{"192.168.1.1"},
+		kafka.TopicDecoders(map[string]encoding.DecodeRawFunc{"commands": decoder("commands"), "telemetry": decoder("telemetry")}))
+	assert.NoError(t, err)
+	c, err := f.Create()
+	assert.NoError(t, err)
+
+	commands := saramaConsumerMessage("start", &sarama.RecordHeader{})
+	commands.Topic = "commands"
+	telemetry := saramaConsumerMessage("42", &sarama.RecordHeader{})
+	telemetry.Topic = "telemetry"
+
+	chMsg := make(chan async.Message, 2)
+	h := handler{messages: chMsg, consumer: c.(*consumer)}
+	err = h.ConsumeClaim(&mockConsumerSession{}, &mockConsumerClaim{[]*sarama.ConsumerMessage{commands, telemetry}})
+	assert.NoError(t, err)
+
+	var got string
+	assert.NoError(t, (<-chMsg).Decode(&got))
+	assert.Equal(t, "commands:start", got)
+	assert.NoError(t, (<-chMsg).Decode(&got))
+	assert.Equal(t, "telemetry:42", got)
+}
+
+func TestFactory_Create_FailingOption(t *testing.T) {
+	f, err := New("name", "group", "topic", []string{"192.168.1.1"}, kafka.Buffer(10), kafka.Retries(3), kafka.Buffer(-1))
+	assert.NoError(t, err)
+	_, err = f.Create()
+	assert.EqualError(t, err, "could not apply OptionFunc to consumer : failed to apply option 2 (kafka.Buffer): buffer must greater or equal than 0")
+}
+
+func TestFactory_Create_TopicDecoders(t *testing.T) {
+	brokers := []string{"192.168.1.1"}
+	decoders := map[string]encoding.DecodeRawFunc{"commands": json.DecodeRaw}
+
+	f, err := NewWithTopics("name", "group", []string{"commands", "telemetry"}, brokers, kafka.TopicDecoders(decoders))
+	assert.NoError(t, err)
+	_, err = f.Create()
+	assert.EqualError(t, err, "no decoder provided for topic 'telemetry' and no fallback decoder")
+
+	f, err = NewWithTopics("name", "group", []string{"commands", "telemetry"}, brokers, kafka.TopicDecoders(decoders), kafka.DecoderJSON())
+	assert.NoError(t, err)
+	_, err = f.Create()
+	assert.NoError(t, err)
+
+	f, err = New("name", "group", "telemetry", brokers, kafka.TopicDecoders(decoders))
+	assert.NoError(t, err)
+	_, err = f.Create()
+	assert.EqualError(t, err, "decoder provided for topic 'commands' which is not consumed")
+}
+
 func saramaConsumerMessages(ct string) []*sarama.ConsumerMessage {
 	return []*sarama.ConsumerMessage{
 		saramaConsumerMessage("value", &sarama.RecordHeader{
@@ -226,6 +286,42 @@ func TestConsumer_ConsumeFailedBroker(t *testing.T) {
 	assert.Error(t, err)
 }
 
+func TestConsumer_CloseNotStarted(t *testing.T) {
+	f, err := New("name", "group", "topic", []string{"1", "2"})
+	assert.NoError(t, err)
+	c, err := f.Create()
+	assert.NoError(t, err)
+	assert.NoError(t, c.Close())
+}
+
+type failingConsumer struct{ closed bool }
+
+func (c *failingConsumer) Consume(context.Context) (<-chan async.Message, <-chan error, error) {
+	return nil, nil, errors.New("consume failed")
+}
+
+func (c *failingConsumer) Close() error {
+	c.closed = true
+	return nil
+}
+
+func TestMultiConsumer_ConsumeFailureWithGroupConsumer(t *testing.T) {
+	f, err := New("name", "group", "topic", []string{"1", "2"})
+	assert.NoError(t, err)
+	c, err := f.Create()
+	assert.NoError(t, err)
+	fc := &failingConsumer{}
+	mc := async.MultiConsumer(fc, c)
+
+	_, _, err = mc.Consume(context.Background())
+	assert.Error(t, err)
+	// the consumers which did not start are not closed by the failed consume
+	assert.False(t, fc.closed)
+	// the component closes the multi consumer, including the group consumer which was never started
+	assert.NoError(t, mc.Close())
+	assert.True(t, fc.closed)
+}
+
 func TestConsumer_ConsumeWithGroup(t *testing.T) {
 	broker := sarama.NewMockBroker(t, 0)
 	broker.SetHandlerByMap(map[string]sarama.MockResponse{
@@ -256,3 +352,336 @@ func TestConsumer_ConsumeWithGroup(t *testing.T) {
 
 	ctx.Done()
 }
+
+type mockConsumerGroup struct {
+	sarama.ConsumerGroup
+	consumes int
+}
+
+func (m *mockConsumerGroup) Consume(ctx context.Context, topics []string, handler sarama.ConsumerGroupHandler) error {
+	m.consumes++
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func TestConsumer_ConsumeSessions_ContextCanceled(t *testing.T) {
+	cg := &mockConsumerGroup{}
+	c := &consumer{topics: []string{"TOPIC"}, group: "group", cg: cg}
+	chMsg := make(chan async.Message)
+	chErr := make(chan error, 1)
+	ctx, cnl := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		c.consumeSessions(ctx, chMsg, chErr)
+		close(done)
+	}()
+	cnl()
+
+	select {
+	case _, ok := <-chMsg:
+		assert.False(t, ok)
+	case <-time.After(time.Second):
+		t.Fatal("message channel not closed")
+	}
+	<-done
+	assert.Empty(t, chErr)
+	assert.Equal(t, 1, cg.consumes)
+}
+
+// failingConsumerGroup returns the errors of the consume calls in order, and blocks afterwards until the context is done.
+type failingConsumerGroup struct {
+	sarama.ConsumerGroup
+	errs     []error
+	consumes int
+}
+
+func (m *failingConsumerGroup) Consume(ctx context.Context, topics []string, handler sarama.ConsumerGroupHandler) error {
+	m.consumes++
+	if m.consumes <= len(m.errs) {
+		return m.errs[m.consumes-1]
+	}
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func TestConsumer_ConsumeSessions_RetriesReset(t *testing.T) {
+	errConsume := errors.New("brokers unreachable")
+	// the successful session in between resets the retries
+	cg := &failingConsumerGroup{errs: []error{errConsume, errConsume, nil, errConsume, errConsume}}
+	c := &consumer{topics: []string{"TOPIC"}, group: "group", cg: cg, config: kafka.ConsumerConfig{Retries: 2, RetryWait: time.Millisecond}}
+	chMsg := make(chan async.Message)
+	chErr := make(chan error, 1)
+	ctx, cnl := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		c.consumeSessions(ctx, chMsg, chErr)
+		close(done)
+	}()
+
+	select {
+	case err := <-chErr:
+		t.Fatalf("unexpected error: %v", err)
+	case <-time.After(200 * time.Millisecond):
+	}
+	cnl()
+	<-done
+	assert.Empty(t, chErr)
+	assert.Equal(t, 6, cg.consumes)
+}
+
+func TestConsumer_ConsumeSessions_RetriesByDefault(t *testing.T) {
+	f, err := New("name", "group", "TOPIC", []string{"1"}, kafka.RetryWait(time.Millisecond), kafka.MaxRetryWait(time.Millisecond))
+	assert.NoError(t, err)
+	cns, err := f.Create()
+	assert.NoError(t, err)
+	c := cns.(*consumer)
+	assert.Equal(t, uint(defaultRetries), c.config.Retries)
+
+	errConsume := errors.New("brokers unreachable")
+	cg := &failingConsumerGroup{errs: []error{errConsume, errConsume, errConsume, errConsume, errConsume}}
+	c.cg = cg
+	chMsg := make(chan async.Message)
+	chErr := make(chan error, 1)
+	ctx, cnl := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		c.consumeSessions(ctx, chMsg, chErr)
+		close(done)
+	}()
+
+	// the transient errors are retried without the kafka.Retries option
+	select {
+	case err := <-chErr:
+		t.Fatalf("unexpected error: %v", err)
+	case <-time.After(200 * time.Millisecond):
+	}
+	cnl()
+	<-done
+	assert.Empty(t, chErr)
+	assert.Equal(t, 6, cg.consumes)
+}
+
+func TestRetryCount(t *testing.T) {
+	assert.Equal(t, "3", retryCount(3, defaultRetries))
+	assert.Equal(t, "3/5", retryCount(3, 5))
+}
+
+func TestConsumer_ConsumeSessions_RetriesExhausted(t *testing.T) {
+	errConsume := errors.New("brokers unreachable")
+	cg := &failingConsumerGroup{errs: []error{errConsume, errConsume, errConsume}}
+	c := &consumer{topics: []string{"TOPIC"}, group: "group", cg: cg, config: kafka.ConsumerConfig{Retries: 2, RetryWait: time.Millisecond}}
+	chMsg := make(chan async.Message)
+	chErr := make(chan error, 1)
+	c.consumeSessions(context.Background(), chMsg, chErr)
+	assert.Equal(t, errConsume, <-chErr)
+	assert.Equal(t, 3, cg.consumes)
+	// the message channel is closed, so the component stops even if its error handler continues
+	_, ok := <-chMsg
+	assert.False(t, ok)
+}
+
+func TestConsumer_ConsumeSessions_RetriesExhaustedWithErrorDedup(t *testing.T) {
+	errConsume := errors.New("brokers unreachable")
+	cg := &failingConsumerGroup{errs: []error{errConsume, errConsume, errConsume}}
+	c := &consumer{topics: []string{"TOPIC"}, group: "group", cg: cg, dedup: kafka.NewErrorDedup("name", time.Minute),
+		config: kafka.ConsumerConfig{Retries: 2, RetryWait: time.Millisecond}}
+	chMsg := make(chan async.Message)
+	chErr := make(chan error, 1)
+	c.consumeSessions(context.Background(), chMsg, chErr)
+	// the error which stops the consumer is reported, although it repeats the retried error
+	assert.Equal(t, errConsume, <-chErr)
+	c.dedup.Flush()
+}
+
+func TestConsumer_ConsumeSessions_FailedWithTopicWeights(t *testing.T) {
+	cg := &failingConsumerGroup{errs: []error{sarama.ErrClosedConsumerGroup}}
+	c := &consumer{topics: []string{"TOPIC1", "TOPIC2"}, group: "group", cg: cg,
+		config: kafka.ConsumerConfig{TopicWeights: map[string]int{"TOPIC1": 2}}}
+	chMsg := make(chan async.Message)
+	chErr := make(chan error, 1)
+	go c.consumeSessions(context.Background(), chMsg, chErr)
+	assert.Equal(t, sarama.ErrClosedConsumerGroup, <-chErr)
+	select {
+	case _, ok := <-chMsg:
+		assert.False(t, ok)
+	case <-time.After(time.Second):
+		t.Fatal("message channel not closed")
+	}
+}
+
+// gracefulConsumerGroup releases a session like sarama: the claim is consumed until the context is canceled,
+// after which the cleanup is called and the offsets are committed, while closing the group waits for the session to be released.
+type gracefulConsumerGroup struct {
+	sarama.ConsumerGroup
+	// mu is held by the session, like the lock of the sarama consumer group
+	mu     sync.Mutex
+	once   sync.Once
+	claim  chan *sarama.ConsumerMessage
+	events *events
+}
+
+func (m *gracefulConsumerGroup) Consume(ctx context.Context, topics []string, handler sarama.ConsumerGroupHandler) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	sess := &eventSession{ctx: ctx, events: m.events}
+	if err := handler.Setup(sess); err != nil {
+		return err
+	}
+	done := make(chan error)
+	go func() { done <- handler.ConsumeClaim(sess, &channelClaim{ch: m.claim}) }()
+	<-ctx.Done()
+	close(m.claim)
+	err := <-done
+	if err := handler.Cleanup(sess); err != nil {
+		return err
+	}
+	m.events.add("commit")
+	return err
+}
+
+func (m *gracefulConsumerGroup) Close() error {
+	m.once.Do(func() {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		m.events.add("leave")
+	})
+	return nil
+}
+
+type channelClaim struct {
+	mockConsumerClaim
+	ch chan *sarama.ConsumerMessage
+}
+
+func (c *channelClaim) Messages() <-chan *sarama.ConsumerMessage { return c.ch }
+
+type eventSession struct {
+	mockConsumerSession
+	ctx    context.Context
+	events *events
+}
+
+func (s *eventSession) Context() context.Context { return s.ctx }
+
+func (s *eventSession) MarkMessage(msg *sarama.ConsumerMessage, _ string) {
+	s.events.add(fmt.Sprintf("mark %d", msg.Offset))
+}
+
+type events struct {
+	mu     sync.Mutex
+	events []string
+}
+
+func (e *events) add(event string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.events = append(e.events, event)
+}
+
+func (e *events) get() []string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]string(nil), e.events...)
+}
+
+func TestConsumer_Close_Graceful(t *testing.T) {
+	claim := make(chan *sarama.ConsumerMessage, 2)
+	for i := int64(0); i < 2; i++ {
+		msg := saramaConsumerMessages(json.Type)[0]
+		msg.Offset = i
+		claim <- msg
+	}
+	ee := &events{}
+	cg := &gracefulConsumerGroup{claim: claim, events: ee}
+	c := &consumer{topics: []string{"TOPIC"}, group: "group", cg: cg, config: kafka.ConsumerConfig{ShutdownTimeout: time.Second}}
+	ctx, cnl := context.WithCancel(context.Background())
+	c.cnl = cnl
+	chMsg := make(chan async.Message)
+	go c.consumeSessions(ctx, chMsg, make(chan error, 1))
+
+	// the first message is in flight while the second one waits to be delivered
+	msg := <-chMsg
+	closed := make(chan error)
+	go func() { closed <- c.Close() }()
+
+	time.Sleep(50 * time.Millisecond)
+	// the delivery stopped, and the offsets are not committed while the message is in flight
+	select {
+	case m, ok := <-chMsg:
+		assert.False(t, ok && m != nil, "message delivered after closing")
+	default:
+	}
+	assert.Empty(t, ee.get())
+
+	assert.NoError(t, msg.Ack())
+	assert.NoError(t, <-closed)
+	// the second message is not marked, so it is redelivered after the rebalance
+	assert.Equal(t, []string{"mark 0", "commit", "leave"}, ee.get())
+	_, ok := <-chMsg
+	assert.False(t, ok)
+}
+
+func TestConsumer_ConsumeSessions_Backoff(t *testing.T) {
+	errConsume := errors.New("brokers unreachable")
+	cg := &failingConsumerGroup{errs: []error{errConsume, errConsume, errConsume, errConsume}}
+	c := &consumer{topics: []string{"TOPIC"}, group: "group", cg: cg,
+		config: kafka.ConsumerConfig{Retries: 3, RetryWait: 20 * time.Millisecond, MaxRetryWait: 40 * time.Millisecond, RetryJitter: 0.5}}
+	chErr := make(chan error, 1)
+	start := time.Now()
+	c.consumeSessions(context.Background(), make(chan async.Message), chErr)
+
+	// the retries wait for at least half of 20ms, 40ms and 40ms, instead of spinning
+	assert.True(t, time.Since(start) >= 50*time.Millisecond)
+	assert.Equal(t, errConsume, <-chErr)
+	assert.Equal(t, 4, cg.consumes)
+}
+
+func TestConsumer_ConsumeSessions_FatalError(t *testing.T) {
+	tests := map[string]error{
+		"authentication": sarama.ErrSASLAuthenticationFailed,
+		"authorization":  fmt.Errorf("failed to join: %w", sarama.ErrGroupAuthorizationFailed),
+		"configuration":  sarama.ConfigurationError("invalid"),
+		"closed":         sarama.ErrClosedConsumerGroup,
+	}
+	for name, errConsume := range tests {
+		t.Run(name, func(t *testing.T) {
+			cg := &failingConsumerGroup{errs: []error{errConsume}}
+			c := &consumer{topics: []string{"TOPIC"}, group: "group", cg: cg, config: kafka.ConsumerConfig{Retries: 3, RetryWait: time.Millisecond}}
+			chErr := make(chan error, 1)
+			c.consumeSessions(context.Background(), make(chan async.Message), chErr)
+			assert.Equal(t, errConsume, <-chErr)
+			assert.Equal(t, 1, cg.consumes)
+		})
+	}
+}
+
+func TestNewWithTopics(t *testing.T) {
+	brokers := []string{"192.168.1.1"}
+	_, err := NewWithTopics("name", "group", nil, brokers)
+	assert.Error(t, err)
+	_, err = NewWithTopics("name", "group", []string{"commands", ""}, brokers)
+	assert.Error(t, err)
+
+	f, err := NewWithTopics("name", "group", []string{"commands", "telemetry"}, brokers,
+		kafka.TopicWeights(map[string]int{"commands": 3}))
+	assert.NoError(t, err)
+	c, err := f.Create()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"commands", "telemetry"}, c.(*consumer).topics)
+
+	f, err = NewWithTopics("name", "group", []string{"commands", "telemetry"}, brokers,
+		kafka.TopicWeights(map[string]int{"events": 3}))
+	assert.NoError(t, err)
+	_, err = f.Create()
+	assert.EqualError(t, err, "weight provided for topic 'events' which is not consumed")
+}
+
+func TestFactory_Info(t *testing.T) {
+	f, err := NewWithTopics("name", "group", []string{"commands", "telemetry"}, []string{"192.168.1.1"}, kafka.Version(sarama.V2_1_0_0.String()))
+	assert.NoError(t, err)
+	in := f.Info()
+	assert.Equal(t, "kafka-group", in["type"])
+	assert.Equal(t, "group", in["group"])
+	assert.Equal(t, []string{"commands", "telemetry"}, in["topics"])
+	assert.Equal(t, sarama.V2_1_0_0.String(), in["sarama"].(map[string]interface{})["version"])
+}
@@ -0,0 +1,85 @@
+package group
+
+import (
+	"sync"
+
+	"github.com/Shopify/sarama"
+	"github.com/beatlabs/patron/async"
+)
+
+// orderedSession marks the offsets of a claimed partition in the order the messages were consumed,
+// since a marked offset commits all the offsets before it, while messages which are processed concurrently,
+// e.g. on the ordered lanes of the async component, are acked out of order.
+// The offset of an acked message is marked only after all the messages before it are acked or nacked.
+type orderedSession struct {
+	sarama.ConsumerGroupSession
+	mu      sync.Mutex
+	pending []*pendingOffset
+}
+
+type pendingOffset struct {
+	msg   *sarama.ConsumerMessage
+	done  bool
+	acked bool
+}
+
+func newOrderedSession(sess sarama.ConsumerGroupSession) *orderedSession {
+	return &orderedSession{ConsumerGroupSession: sess}
+}
+
+// add tracks a consumed message until it is acked or nacked.
+func (s *orderedSession) add(msg *sarama.ConsumerMessage) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pending = append(s.pending, &pendingOffset{msg: msg})
+}
+
+// MarkMessage marks the offset of the message, once all the messages before it are acked or nacked.
+func (s *orderedSession) MarkMessage(msg *sarama.ConsumerMessage, _ string) {
+	s.complete(msg, true)
+}
+
+func (s *orderedSession) complete(msg *sarama.ConsumerMessage, acked bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, p := range s.pending {
+		if p.msg.Offset == msg.Offset {
+			p.done = true
+			p.acked = p.acked || acked
+			break
+		}
+	}
+	var last *sarama.ConsumerMessage
+	for len(s.pending) > 0 && s.pending[0].done {
+		// a nacked message is committed by the next acked message, as when the messages are processed sequentially
+		if s.pending[0].acked {
+			last = s.pending[0].msg
+		}
+		s.pending = s.pending[1:]
+	}
+	if last != nil {
+		s.ConsumerGroupSession.MarkMessage(last, "")
+	}
+}
+
+// track returns a message which completes its offset when nacked, since nacked messages are not marked.
+func (s *orderedSession) track(m async.Message, msg *sarama.ConsumerMessage) async.Message {
+	return &orderedMessage{Message: m, msg: msg, sess: s}
+}
+
+type orderedMessage struct {
+	async.Message
+	msg  *sarama.ConsumerMessage
+	sess *orderedSession
+}
+
+// Nack nacks the message and completes its offset.
+func (m *orderedMessage) Nack() error {
+	defer m.sess.complete(m.msg, false)
+	return m.Message.Nack()
+}
+
+// Unwrap returns the wrapped message.
+func (m *orderedMessage) Unwrap() async.Message {
+	return m.Message
+}
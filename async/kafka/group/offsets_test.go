@@ -0,0 +1,77 @@
+package group
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/Shopify/sarama"
+	"github.com/beatlabs/patron/async/kafka"
+	"github.com/beatlabs/patron/encoding/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type offsetSession struct {
+	mockConsumerSession
+	mu     sync.Mutex
+	marked []int64
+}
+
+func (o *offsetSession) MarkMessage(msg *sarama.ConsumerMessage, _ string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.marked = append(o.marked, msg.Offset)
+}
+
+func TestOrderedSession_MarksInConsumedOrder(t *testing.T) {
+	sess := &offsetSession{}
+	ordered := newOrderedSession(sess)
+	var mm []*sarama.ConsumerMessage
+	for i := int64(0); i < 4; i++ {
+		msg := &sarama.ConsumerMessage{Topic: "topic", Offset: i}
+		ordered.add(msg)
+		mm = append(mm, msg)
+	}
+
+	ordered.MarkMessage(mm[2], "")
+	ordered.MarkMessage(mm[1], "")
+	assert.Empty(t, sess.marked)
+	ordered.MarkMessage(mm[0], "")
+	assert.Equal(t, []int64{2}, sess.marked)
+	ordered.MarkMessage(mm[3], "")
+	assert.Equal(t, []int64{2, 3}, sess.marked)
+	assert.Empty(t, ordered.pending)
+}
+
+func TestOrderedSession_Nack(t *testing.T) {
+	sess := &offsetSession{}
+	ordered := newOrderedSession(sess)
+	var mm []*sarama.ConsumerMessage
+	for i := int64(0); i < 3; i++ {
+		msg := &sarama.ConsumerMessage{Topic: "topic", Offset: i, Value: []byte(`"value"`)}
+		ordered.add(msg)
+		mm = append(mm, msg)
+	}
+	for _, msg := range mm {
+		m, err := kafka.ClaimMessage(context.Background(), msg, json.DecodeRaw, ordered, kafka.ClaimOptions{Group: "group"})
+		require.NoError(t, err)
+		m = ordered.track(m, msg)
+		if msg.Offset == 1 {
+			require.NoError(t, m.Nack())
+			continue
+		}
+		require.NoError(t, m.Ack())
+	}
+	// the nacked message is committed by the next acked message
+	assert.Equal(t, []int64{0, 2}, sess.marked)
+
+	// a nacked message alone is not marked
+	msg := &sarama.ConsumerMessage{Topic: "topic", Offset: 3, Value: []byte(`"value"`)}
+	ordered.add(msg)
+	m, err := kafka.ClaimMessage(context.Background(), msg, json.DecodeRaw, ordered, kafka.ClaimOptions{Group: "group"})
+	require.NoError(t, err)
+	require.NoError(t, ordered.track(m, msg).Nack())
+	assert.Equal(t, []int64{0, 2}, sess.marked)
+	assert.Empty(t, ordered.pending)
+}
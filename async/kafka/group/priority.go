@@ -0,0 +1,104 @@
+package group
+
+import (
+	"context"
+	"reflect"
+	"sort"
+
+	"github.com/beatlabs/patron/async"
+)
+
+// weightedSelector forwards the messages of per topic channels to a single channel. When messages of multiple
+// topics are available, up to weight messages of each topic are forwarded in turn, starting with the highest weight,
+// so higher priority topics are favored without starving the lower priority ones.
+type weightedSelector struct {
+	inputs   map[string]chan async.Message
+	schedule []chan async.Message
+}
+
+func newWeightedSelector(topics []string, weights map[string]int, buffer int) *weightedSelector {
+	weight := func(topic string) int {
+		if w, ok := weights[topic]; ok {
+			return w
+		}
+		return 1
+	}
+	sorted := append([]string(nil), topics...)
+	sort.SliceStable(sorted, func(i, j int) bool { return weight(sorted[i]) > weight(sorted[j]) })
+
+	ws := &weightedSelector{inputs: make(map[string]chan async.Message, len(topics))}
+	for _, topic := range sorted {
+		ch := make(chan async.Message, buffer)
+		ws.inputs[topic] = ch
+		for i := 0; i < weight(topic); i++ {
+			ws.schedule = append(ws.schedule, ch)
+		}
+	}
+	return ws
+}
+
+// run forwards the messages until all topic channels are closed, after which the output channel is closed.
+// After the context is done the messages are drained and nacked, in order to stop tracking them, and are redelivered.
+func (ws *weightedSelector) run(ctx context.Context, out chan<- async.Message) {
+	defer close(out)
+	closed := make(map[chan async.Message]bool, len(ws.inputs))
+	for len(closed) < len(ws.inputs) {
+		forwarded := false
+		for _, ch := range ws.schedule {
+			if closed[ch] {
+				continue
+			}
+			select {
+			case m, ok := <-ch:
+				if !ok {
+					closed[ch] = true
+					continue
+				}
+				forward(ctx, out, m)
+				forwarded = true
+			default:
+			}
+		}
+		if forwarded {
+			continue
+		}
+
+		// no topic has a message available, wait for the first one
+		var cases []reflect.SelectCase
+		var chans []chan async.Message
+		for _, ch := range ws.inputs {
+			if !closed[ch] {
+				cases = append(cases, reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(ch)})
+				chans = append(chans, ch)
+			}
+		}
+		if len(cases) == 0 {
+			return
+		}
+		i, v, ok := reflect.Select(cases)
+		if !ok {
+			closed[chans[i]] = true
+			continue
+		}
+		forward(ctx, out, v.Interface().(async.Message))
+	}
+}
+
+func forward(ctx context.Context, out chan<- async.Message, m async.Message) {
+	if ctx.Err() != nil {
+		_ = m.Nack()
+		return
+	}
+	select {
+	case out <- m:
+	case <-ctx.Done():
+		_ = m.Nack()
+	}
+}
+
+// close closes the topic channels, which must happen after all messages have been sent to them.
+func (ws *weightedSelector) close() {
+	for _, ch := range ws.inputs {
+		close(ch)
+	}
+}
@@ -0,0 +1,67 @@
+package group
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/beatlabs/patron/async"
+	"github.com/stretchr/testify/assert"
+)
+
+type topicMessage struct {
+	async.Message
+	topic string
+}
+
+func (m topicMessage) Nack() error { return nil }
+
+func TestWeightedSelector_MixedLoad(t *testing.T) {
+	ws := newWeightedSelector([]string{"telemetry", "commands"}, map[string]int{"commands": 3}, 100)
+	for i := 0; i < 40; i++ {
+		ws.inputs["commands"] <- topicMessage{topic: "commands"}
+		ws.inputs["telemetry"] <- topicMessage{topic: "telemetry"}
+	}
+	out := make(chan async.Message)
+	go ws.run(context.Background(), out)
+
+	counts := map[string]int{}
+	for i := 0; i < 40; i++ {
+		counts[(<-out).(topicMessage).topic]++
+	}
+	assert.Equal(t, map[string]int{"commands": 30, "telemetry": 10}, counts)
+
+	// with the higher priority topic exhausted, the lower priority topic takes over
+	for i := 0; i < 40; i++ {
+		counts[(<-out).(topicMessage).topic]++
+	}
+	assert.Equal(t, map[string]int{"commands": 40, "telemetry": 40}, counts)
+
+	ws.close()
+	_, ok := <-out
+	assert.False(t, ok)
+}
+
+func TestWeightedSelector_WaitsForMessages(t *testing.T) {
+	ws := newWeightedSelector([]string{"commands", "telemetry"}, map[string]int{"commands": 3}, 0)
+	out := make(chan async.Message)
+	ctx, cnl := context.WithCancel(context.Background())
+	go ws.run(ctx, out)
+
+	go func() { ws.inputs["telemetry"] <- topicMessage{topic: "telemetry"} }()
+	select {
+	case m := <-out:
+		assert.Equal(t, "telemetry", m.(topicMessage).topic)
+	case <-time.After(time.Second):
+		t.Fatal("message not forwarded")
+	}
+
+	// messages are drained and nacked after the context is done, until the topic channels are closed
+	cnl()
+	d := newDrainer()
+	ws.inputs["commands"] <- d.track(topicMessage{topic: "commands"})
+	ws.close()
+	_, ok := <-out
+	assert.False(t, ok)
+	assert.Equal(t, 0, d.pending())
+}
@@ -0,0 +1,37 @@
+package group
+
+import (
+	"sync/atomic"
+
+	"github.com/Shopify/sarama"
+)
+
+// readiness tracks whether the consumers of a factory have joined the group and were assigned partitions,
+// which is the case between the setup and the cleanup of a session with claims.
+type readiness struct {
+	claims int32
+}
+
+// setup records the claims of a new session.
+func (r *readiness) setup(sess sarama.ConsumerGroupSession) {
+	if r == nil {
+		return
+	}
+	n := 0
+	for _, pp := range sess.Claims() {
+		n += len(pp)
+	}
+	atomic.StoreInt32(&r.claims, int32(n))
+}
+
+// cleanup records the release of the claims at the end of a session, e.g. on a rebalance.
+func (r *readiness) cleanup() {
+	if r == nil {
+		return
+	}
+	atomic.StoreInt32(&r.claims, 0)
+}
+
+func (r *readiness) ready() bool {
+	return r != nil && atomic.LoadInt32(&r.claims) > 0
+}
@@ -0,0 +1,38 @@
+package group
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type claimsSession struct {
+	mockConsumerSession
+	claims map[string][]int32
+}
+
+func (s *claimsSession) Claims() map[string][]int32 { return s.claims }
+
+func TestHandler_Readiness(t *testing.T) {
+	f, err := New("name", "group", "topic", []string{"192.168.1.1"})
+	require.NoError(t, err)
+	cnr, err := f.Create()
+	require.NoError(t, err)
+	h := handler{consumer: cnr.(*consumer)}
+	assert.False(t, f.Ready())
+
+	// a member of a group with more members than partitions is not assigned any
+	assert.NoError(t, h.Setup(&claimsSession{}))
+	assert.False(t, f.Ready())
+
+	assert.NoError(t, h.Setup(&claimsSession{claims: map[string][]int32{"topic": {0, 1}}}))
+	assert.True(t, f.Ready())
+
+	// the claims are released on a rebalance
+	assert.NoError(t, h.Cleanup(&claimsSession{}))
+	assert.False(t, f.Ready())
+
+	assert.NoError(t, h.Setup(&claimsSession{claims: map[string][]int32{"topic": {1}}}))
+	assert.True(t, f.Ready())
+}
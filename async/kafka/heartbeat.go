@@ -0,0 +1,72 @@
+package kafka
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/beatlabs/patron/log"
+)
+
+// heartbeatInfof logs the heartbeats, which tests replace.
+var heartbeatInfof = log.Infof
+
+// Heartbeat periodically logs that a consumer is alive, along with the messages delivered since the last heartbeat
+// and the lag of its partitions, in order to tell an idle consumer apart from a stalled one. A nil heartbeat logs nothing.
+type Heartbeat struct {
+	name      string
+	interval  time.Duration
+	status    *ConsumerStatus
+	processed int64
+}
+
+// NewHeartbeat creates the heartbeat of a consumer, whose lag is calculated from the positions of its status.
+// It returns nil if the interval is not positive, i.e. when the kafka.HeartbeatLog option is not provided.
+func NewHeartbeat(name string, interval time.Duration, status *ConsumerStatus) *Heartbeat {
+	if interval <= 0 {
+		return nil
+	}
+	return &Heartbeat{name: name, interval: interval, status: status}
+}
+
+// Processed counts a message delivered to the processing component.
+func (h *Heartbeat) Processed() {
+	if h == nil {
+		return
+	}
+	atomic.AddInt64(&h.processed, 1)
+}
+
+// Run logs a heartbeat on every interval, until the context is canceled when the consumer shuts down.
+func (h *Heartbeat) Run(ctx context.Context) {
+	if h == nil {
+		return
+	}
+	ticker := time.NewTicker(h.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			h.beat()
+		}
+	}
+}
+
+func (h *Heartbeat) beat() {
+	heartbeatInfof("consumer %s alive, %d messages processed, current lag %d", h.name, atomic.SwapInt64(&h.processed, 0), h.lag())
+}
+
+// lag returns the messages between the last message read and the high-water mark of every partition,
+// skipping the partitions which have not been read yet, since their starting position is unknown.
+func (h *Heartbeat) lag() int64 {
+	if h.status == nil {
+		return 0
+	}
+	var lag int64
+	for _, p := range h.status.Dump().Partitions {
+		lag += p.lag()
+	}
+	return lag
+}
@@ -0,0 +1,64 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewHeartbeat(t *testing.T) {
+	assert.Nil(t, NewHeartbeat("name", 0, nil))
+	var h *Heartbeat
+	h.Processed()
+	h.Run(context.Background())
+}
+
+func TestHeartbeat_Run(t *testing.T) {
+	var mu sync.Mutex
+	var logs []string
+	defer func(f func(string, ...interface{})) { heartbeatInfof = f }(heartbeatInfof)
+	heartbeatInfof = func(msg string, args ...interface{}) {
+		mu.Lock()
+		defer mu.Unlock()
+		logs = append(logs, fmt.Sprintf(msg, args...))
+	}
+	logged := func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), logs...)
+	}
+
+	status := NewConsumerStatus("kafka-simple")
+	status.SetPartition("topic", 0, PartitionFetching, 4, 10)
+	status.SetPartition("topic", 1, PartitionFetching, -1, 3)
+	h := NewHeartbeat("name", 20*time.Millisecond, status)
+	h.Processed()
+	h.Processed()
+	h.Processed()
+
+	ctx, cnl := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		h.Run(ctx)
+		close(done)
+	}()
+
+	for i := 0; i < 100 && len(logged()) < 2; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	cnl()
+	<-done
+	ll := logged()
+	require.True(t, len(ll) >= 2)
+	assert.Equal(t, "consumer name alive, 3 messages processed, current lag 5", ll[0])
+	assert.Equal(t, "consumer name alive, 0 messages processed, current lag 5", ll[1])
+
+	// no heartbeat is logged after shutting down
+	time.Sleep(50 * time.Millisecond)
+	assert.Len(t, logged(), len(ll))
+}
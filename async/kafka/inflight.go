@@ -0,0 +1,73 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/beatlabs/patron/async"
+)
+
+// InFlightLimiter limits the messages of a consumer which are delivered but not yet acknowledged.
+// A credit is acquired before a message is delivered and released when the message is acked or nacked.
+// While no credits are available the consumer stops reading from the partitions, which
+// in turn stops fetching from the brokers once the internal buffers of sarama are full.
+type InFlightLimiter struct {
+	credits chan struct{}
+}
+
+// NewInFlightLimiter creates a limiter allowing at most n messages in flight.
+func NewInFlightLimiter(n int) (*InFlightLimiter, error) {
+	if n <= 0 {
+		return nil, errors.New("max in flight messages must be positive")
+	}
+	return &InFlightLimiter{credits: make(chan struct{}, n)}, nil
+}
+
+// Acquire blocks until a credit is available or the context is done.
+func (l *InFlightLimiter) Acquire(ctx context.Context) error {
+	select {
+	case l.credits <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Release returns a credit.
+func (l *InFlightLimiter) Release() {
+	<-l.credits
+}
+
+// InFlight returns the number of messages in flight.
+func (l *InFlightLimiter) InFlight() int {
+	return len(l.credits)
+}
+
+// Track returns a message which releases the acquired credit on its first Ack or Nack.
+func (l *InFlightLimiter) Track(msg async.Message) async.Message {
+	return &trackedMessage{Message: msg, release: l.Release}
+}
+
+type trackedMessage struct {
+	async.Message
+	once    sync.Once
+	release func()
+}
+
+// Ack acknowledges the message and releases its credit.
+func (m *trackedMessage) Ack() error {
+	defer m.once.Do(m.release)
+	return m.Message.Ack()
+}
+
+// Unwrap returns the tracked message.
+func (m *trackedMessage) Unwrap() async.Message {
+	return m.Message
+}
+
+// Nack signals the failure of the message and releases its credit.
+func (m *trackedMessage) Nack() error {
+	defer m.once.Do(m.release)
+	return m.Message.Nack()
+}
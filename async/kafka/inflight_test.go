@@ -0,0 +1,71 @@
+package kafka
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type mockMessage struct {
+	acks  int
+	nacks int
+}
+
+func (m *mockMessage) Context() context.Context   { return context.Background() }
+func (m *mockMessage) Decode(v interface{}) error { return nil }
+func (m *mockMessage) Ack() error                 { m.acks++; return nil }
+func (m *mockMessage) Nack() error                { m.nacks++; return nil }
+
+func TestNewInFlightLimiter(t *testing.T) {
+	l, err := NewInFlightLimiter(0)
+	assert.Error(t, err)
+	assert.Nil(t, l)
+	l, err = NewInFlightLimiter(1)
+	assert.NoError(t, err)
+	assert.NotNil(t, l)
+}
+
+func TestInFlightLimiter(t *testing.T) {
+	l, err := NewInFlightLimiter(2)
+	assert.NoError(t, err)
+	ctx := context.Background()
+
+	assert.NoError(t, l.Acquire(ctx))
+	m1 := &mockMessage{}
+	msg1 := l.Track(m1)
+	assert.NoError(t, l.Acquire(ctx))
+	m2 := &mockMessage{}
+	msg2 := l.Track(m2)
+	assert.Equal(t, 2, l.InFlight())
+
+	// the limit is reached
+	acquired := make(chan struct{})
+	go func() {
+		assert.NoError(t, l.Acquire(ctx))
+		close(acquired)
+	}()
+	select {
+	case <-acquired:
+		t.Fatal("credit acquired over the limit")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// acking releases the credit only once
+	assert.NoError(t, msg1.Ack())
+	assert.NoError(t, msg1.Ack())
+	<-acquired
+	assert.Equal(t, 2, m1.acks)
+	assert.Equal(t, 2, l.InFlight())
+
+	assert.NoError(t, msg2.Nack())
+	assert.Equal(t, 1, m2.nacks)
+	assert.Equal(t, 1, l.InFlight())
+
+	// acquiring is canceled with the context
+	assert.NoError(t, l.Acquire(ctx))
+	ctx, cnl := context.WithCancel(ctx)
+	cnl()
+	assert.Equal(t, context.Canceled, l.Acquire(ctx))
+}
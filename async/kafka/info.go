@@ -0,0 +1,56 @@
+package kafka
+
+import (
+	"strconv"
+
+	"github.com/Shopify/sarama"
+)
+
+// SaramaConfigInfo returns a summary of the effective sarama config, e.g. the version, the initial offset,
+// the group settings and the fetch sizes, in order to be exposed in the /info endpoint.
+// Authentication is summarized by whether it is enabled, while credentials are never included.
+func SaramaConfigInfo(cfg *sarama.Config) map[string]interface{} {
+	if cfg == nil {
+		return nil
+	}
+	in := map[string]interface{}{
+		"clientID": cfg.ClientID,
+		"version":  cfg.Version.String(),
+		"consumer": map[string]interface{}{
+			"offsetsInitial":        offsetName(cfg.Consumer.Offsets.Initial),
+			"offsetsCommitInterval": cfg.Consumer.Offsets.CommitInterval.String(),
+			"fetchMin":              cfg.Consumer.Fetch.Min,
+			"fetchDefault":          cfg.Consumer.Fetch.Default,
+			"fetchMax":              cfg.Consumer.Fetch.Max,
+			"maxWaitTime":           cfg.Consumer.MaxWaitTime.String(),
+			"maxProcessingTime":     cfg.Consumer.MaxProcessingTime.String(),
+		},
+		"group": map[string]interface{}{
+			"sessionTimeout":    cfg.Consumer.Group.Session.Timeout.String(),
+			"heartbeatInterval": cfg.Consumer.Group.Heartbeat.Interval.String(),
+			"rebalanceTimeout":  cfg.Consumer.Group.Rebalance.Timeout.String(),
+		},
+		"net": map[string]interface{}{
+			"dialTimeout":           cfg.Net.DialTimeout.String(),
+			"tlsEnabled":            cfg.Net.TLS.Enable,
+			"tlsInsecureSkipVerify": cfg.Net.TLS.Enable && cfg.Net.TLS.Config != nil && cfg.Net.TLS.Config.InsecureSkipVerify,
+			"saslEnabled":           cfg.Net.SASL.Enable,
+			"saslMechanism":         string(cfg.Net.SASL.Mechanism),
+		},
+	}
+	if cfg.Consumer.Group.Rebalance.Strategy != nil {
+		in["group"].(map[string]interface{})["rebalanceStrategy"] = cfg.Consumer.Group.Rebalance.Strategy.Name()
+	}
+	return in
+}
+
+func offsetName(offset int64) string {
+	switch offset {
+	case sarama.OffsetNewest:
+		return "newest"
+	case sarama.OffsetOldest:
+		return "oldest"
+	default:
+		return strconv.FormatInt(offset, 10)
+	}
+}
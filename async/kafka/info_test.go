@@ -0,0 +1,44 @@
+package kafka
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSaramaConfigInfo(t *testing.T) {
+	assert.Nil(t, SaramaConfigInfo(nil))
+
+	cfg, err := DefaultSaramaConfig("info")
+	require.NoError(t, err)
+	cfg.Consumer.Offsets.Initial = sarama.OffsetOldest
+	cfg.Consumer.Group.Session.Timeout = 20 * time.Second
+	cfg.Net.SASL.Enable = true
+	cfg.Net.SASL.Mechanism = sarama.SASLTypePlaintext
+	cfg.Net.SASL.User = "secret-user"
+	cfg.Net.SASL.Password = "secret-password"
+
+	in := SaramaConfigInfo(cfg)
+	assert.Equal(t, cfg.ClientID, in["clientID"])
+	assert.Equal(t, sarama.V0_11_0_0.String(), in["version"])
+	assert.Equal(t, "oldest", in["consumer"].(map[string]interface{})["offsetsInitial"])
+	assert.Equal(t, "20s", in["group"].(map[string]interface{})["sessionTimeout"])
+	assert.Equal(t, "range", in["group"].(map[string]interface{})["rebalanceStrategy"])
+	assert.Equal(t, true, in["net"].(map[string]interface{})["saslEnabled"])
+	assert.Equal(t, sarama.SASLTypePlaintext, in["net"].(map[string]interface{})["saslMechanism"])
+	assert.Equal(t, false, in["net"].(map[string]interface{})["tlsEnabled"])
+
+	b, err := json.Marshal(in)
+	require.NoError(t, err)
+	assert.NotContains(t, string(b), "secret")
+}
+
+func TestOffsetName(t *testing.T) {
+	assert.Equal(t, "newest", offsetName(sarama.OffsetNewest))
+	assert.Equal(t, "oldest", offsetName(sarama.OffsetOldest))
+	assert.Equal(t, "42", offsetName(42))
+}
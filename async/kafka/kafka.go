@@ -4,16 +4,21 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"math/rand"
 	"os"
 	"strconv"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/Shopify/sarama"
 	"github.com/beatlabs/patron/async"
 	"github.com/beatlabs/patron/correlation"
 	"github.com/beatlabs/patron/encoding"
+	"github.com/beatlabs/patron/encoding/compression"
+	"github.com/beatlabs/patron/encoding/json"
 	"github.com/beatlabs/patron/log"
 	"github.com/beatlabs/patron/trace"
-	"github.com/google/uuid"
 	"github.com/opentracing/opentracing-go"
 	"github.com/prometheus/client_golang/prometheus"
 )
@@ -38,12 +43,82 @@ func init() {
 	prometheus.MustRegister(topicPartitionOffsetDiff)
 }
 
+// ErrTopicNotFound is returned when the topic to consume does not exist in the cluster,
+// allowing callers to tell a missing topic apart from other failures, e.g. in order to create it.
+var ErrTopicNotFound = errors.New("topic not found")
+
+var (
+	defaultDecoderMu sync.RWMutex
+	defaultDecoder   encoding.DecodeRawFunc = json.DecodeRaw
+)
+
+// SetDefaultDecoder sets the decoder of the consumers created without a decoder, unless they decode the messages based
+// on their content type or per topic. The default decoder is resolved when a consumer is created, so it has to be set before.
+// The default decoder is JSON.
+func SetDefaultDecoder(dec encoding.DecodeRawFunc) error {
+	if dec == nil {
+		return errors.New("default decoder is nil")
+	}
+	defaultDecoderMu.Lock()
+	defer defaultDecoderMu.Unlock()
+	defaultDecoder = dec
+	return nil
+}
+
+// DefaultDecoder returns the decoder of the consumers created without a decoder.
+func DefaultDecoder() encoding.DecodeRawFunc {
+	defaultDecoderMu.RLock()
+	defer defaultDecoderMu.RUnlock()
+	return defaultDecoder
+}
+
+// MessageTag definition of a message attribute which can be added as a tag to the consumer span.
+type MessageTag string
+
+const (
+	// TopicTag tags the span with the topic of the message.
+	TopicTag MessageTag = "topic"
+	// PartitionTag tags the span with the partition of the message.
+	PartitionTag MessageTag = "partition"
+	// OffsetTag tags the span with the offset of the message.
+	OffsetTag MessageTag = "offset"
+	// KeyTag tags the span with the key of the message.
+	KeyTag MessageTag = "key"
+	// TimestampTag tags the span with the timestamp of the message.
+	TimestampTag MessageTag = "timestamp"
+)
+
+// DefaultMessageTags are the message attributes added by default to the consumer span.
+var DefaultMessageTags = []MessageTag{TopicTag, PartitionTag, OffsetTag}
+
 // ConsumerConfig is the common configuration of patron kafka consumers.
 type ConsumerConfig struct {
-	Brokers      []string
-	Buffer       int
-	DecoderFunc  encoding.DecodeRawFunc
-	SaramaConfig *sarama.Config
+	Brokers               []string
+	Buffer                int
+	DecoderFunc           encoding.DecodeRawFunc
+	DecodeByContentType   bool
+	SaramaConfig          *sarama.Config
+	LeaderWaitTimeout     time.Duration
+	MessageTags           []MessageTag
+	Client                sarama.Client
+	BaggagePrefix         string
+	MaxInFlight           int
+	TopicWeights          map[string]int
+	TopicDecoders         map[string]encoding.DecodeRawFunc
+	TimestampType         TimestampType
+	Workers               int
+	RebalanceDrainTimeout time.Duration
+	OnCaughtUp            func()
+	Retries               uint
+	RetryWait             time.Duration
+	MaxRetryWait          time.Duration
+	RetryJitter           float64
+	HeartbeatInterval     time.Duration
+	ErrorDedupWindow      time.Duration
+	StuckPartitionTimeout time.Duration
+	TraceSampleRate       float64
+	ShutdownTimeout       time.Duration
+	StartTimestamp        time.Time
 }
 
 type message struct {
@@ -52,6 +127,7 @@ type message struct {
 	sess sarama.ConsumerGroupSession
 	msg  *sarama.ConsumerMessage
 	dec  encoding.DecodeRawFunc
+	tt   TimestampType
 }
 
 // Context returns the context encapsulated in the message.
@@ -64,6 +140,21 @@ func (m *message) Decode(v interface{}) error {
 	return m.dec(m.msg.Value, v)
 }
 
+// Timestamp returns the timestamp of the message, which is zero for brokers older than 0.10.
+func (m *message) Timestamp() time.Time {
+	return m.msg.Timestamp
+}
+
+// TimestampType returns the type of the timestamp of the message.
+func (m *message) TimestampType() TimestampType {
+	return m.tt
+}
+
+// Key returns the key of the message.
+func (m *message) Key() []byte {
+	return m.msg.Key
+}
+
 // Ack sends acknowledgment that the message has been processed.
 func (m *message) Ack() error {
 	if m.sess != nil {
@@ -79,6 +170,22 @@ func (m *message) Nack() error {
 	return nil
 }
 
+// unwrap returns the first of the message and the messages it wraps, e.g. by the in-flight limiter,
+// which satisfies the predicate, or nil if none does.
+func unwrap(msg async.Message, match func(async.Message) bool) async.Message {
+	for msg != nil {
+		if match(msg) {
+			return msg
+		}
+		w, ok := msg.(interface{ Unwrap() async.Message })
+		if !ok {
+			break
+		}
+		msg = w.Unwrap()
+	}
+	return nil
+}
+
 // DefaultSaramaConfig function creates a sarama config object with the default configuration set up.
 func DefaultSaramaConfig(name string) (*sarama.Config, error) {
 
@@ -95,21 +202,94 @@ func DefaultSaramaConfig(name string) (*sarama.Config, error) {
 	return config, nil
 }
 
+// ClaimOptions are the settings of ClaimMessage. The zero value creates a consumer span for every message,
+// tagged with the DefaultMessageTags, and reads CreateTime timestamps.
+type ClaimOptions struct {
+	// Group is the consumer group of the message, if consumed by a consumer group.
+	Group string
+	// BaggagePrefix selects the message headers which are set as baggage items of the consumer span.
+	BaggagePrefix string
+	// TimestampType is the timestamp type of the topic, which defaults to CreateTime.
+	TimestampType TimestampType
+	// TraceSampleRate is the fraction of the messages, between 0 and 1, which get a consumer span.
+	// If nil, every message gets a consumer span.
+	TraceSampleRate *float64
+	// MessageTags are the message attributes added as tags to the consumer span, which default to the DefaultMessageTags.
+	MessageTags []MessageTag
+}
+
+// ClaimOptions returns the settings of ClaimMessage for a consumer of the provided group, which is empty for consumers
+// without a group.
+func (c ConsumerConfig) ClaimOptions(group string) ClaimOptions {
+	rate := c.TraceSampleRate
+	return ClaimOptions{
+		Group:           group,
+		BaggagePrefix:   c.BaggagePrefix,
+		TimestampType:   c.TimestampType,
+		TraceSampleRate: &rate,
+		MessageTags:     c.MessageTags,
+	}
+}
+
 // ClaimMessage transforms a sarama.ConsumerMessage to an async.Message.
-func ClaimMessage(ctx context.Context, msg *sarama.ConsumerMessage, d encoding.DecodeRawFunc, sess sarama.ConsumerGroupSession) (async.Message, error) {
+// The consumer span is tagged with the selected message attributes, while both the span and the logger
+// of the message context are tagged with the group, if consumed by a consumer group, and the logger with the topic.
+// If a baggage prefix is provided, the message headers starting with it are set as baggage items of the span.
+// The time since the message timestamp is recorded as the message lag, with an exemplar linking to the trace of the message.
+// Only the sampled fraction of the messages get a consumer span, while the rest propagate the upstream trace.
+func ClaimMessage(ctx context.Context, msg *sarama.ConsumerMessage, d encoding.DecodeRawFunc, sess sarama.ConsumerGroupSession,
+	opts ClaimOptions) (async.Message, error) {
 	log.Debugf("data received from topic %s", msg.Topic)
+	group := opts.Group
+	received := time.Now()
+	tt := opts.TimestampType
+	if tt == "" {
+		tt = CreateTime
+	}
+	mt := opts.MessageTags
+	if mt == nil {
+		mt = DefaultMessageTags
+	}
+	sampleRate := 1.0
+	if opts.TraceSampleRate != nil {
+		sampleRate = *opts.TraceSampleRate
+	}
 
 	corID := getCorrelationID(msg.Headers)
 
-	sp, ctxCh := trace.ConsumerSpan(ctx, trace.ComponentOpName(trace.KafkaConsumerComponent, msg.Topic),
-		trace.KafkaConsumerComponent, corID, mapHeader(msg.Headers))
+	fields := map[string]interface{}{"correlationID": corID, "topic": msg.Topic}
+	if group != "" {
+		fields["group"] = group
+	}
+
+	var sp opentracing.Span
+	var ctxCh context.Context
+	if sampled(sampleRate) {
+		tags := messageTags(msg, mt)
+		if group != "" {
+			tags = append(tags, opentracing.Tag{Key: "group", Value: group})
+		}
+		sp, ctxCh = trace.ConsumerSpan(ctx, trace.ComponentOpName(trace.KafkaConsumerComponent, msg.Topic),
+			trace.KafkaConsumerComponent, corID, mapHeader(msg.Headers), tags...)
+		if opts.BaggagePrefix != "" {
+			setBaggage(sp, msg.Headers, opts.BaggagePrefix)
+		}
+	} else {
+		sp, ctxCh = trace.UnsampledConsumerSpan(ctx, mapHeader(msg.Headers))
+	}
+	observeMessageLag(group, msg.Topic, msg.Timestamp, received, sp)
 	ctxCh = correlation.ContextWithID(ctxCh, corID)
-	ctxCh = log.WithContext(ctxCh, log.Sub(map[string]interface{}{"correlationID": corID}))
+	ctxCh = log.WithContext(ctxCh, log.Sub(fields))
 
 	dec, err := determineDecoder(d, msg, sp)
 	if err != nil {
 		return nil, fmt.Errorf("Could not determine decoder  %w", err)
 	}
+	dec, err = decompressingDecoder(dec, msg.Headers)
+	if err != nil {
+		trace.SpanError(sp)
+		return nil, err
+	}
 
 	return &message{
 		ctx:  ctxCh,
@@ -117,9 +297,50 @@ func ClaimMessage(ctx context.Context, msg *sarama.ConsumerMessage, d encoding.D
 		span: sp,
 		msg:  msg,
 		sess: sess,
+		tt:   tt,
 	}, nil
 }
 
+// sampled returns whether a message gets a consumer span, for the provided fraction of the messages.
+func sampled(rate float64) bool {
+	if rate >= 1 {
+		return true
+	}
+	return rate > 0 && rand.Float64() < rate
+}
+
+// setBaggage sets the headers starting with the prefix as baggage items of the span, with the prefix trimmed from their key.
+func setBaggage(sp opentracing.Span, hh []*sarama.RecordHeader, prefix string) {
+	for _, h := range hh {
+		if h == nil {
+			continue
+		}
+		key := string(h.Key)
+		if strings.HasPrefix(key, prefix) && len(key) > len(prefix) {
+			sp.SetBaggageItem(strings.TrimPrefix(key, prefix), string(h.Value))
+		}
+	}
+}
+
+func messageTags(msg *sarama.ConsumerMessage, mt []MessageTag) []opentracing.Tag {
+	tags := make([]opentracing.Tag, 0, len(mt))
+	for _, t := range mt {
+		switch t {
+		case TopicTag:
+			tags = append(tags, opentracing.Tag{Key: string(t), Value: msg.Topic})
+		case PartitionTag:
+			tags = append(tags, opentracing.Tag{Key: string(t), Value: msg.Partition})
+		case OffsetTag:
+			tags = append(tags, opentracing.Tag{Key: string(t), Value: msg.Offset})
+		case KeyTag:
+			tags = append(tags, opentracing.Tag{Key: string(t), Value: string(msg.Key)})
+		case TimestampTag:
+			tags = append(tags, opentracing.Tag{Key: string(t), Value: msg.Timestamp.UTC().Format(time.RFC3339Nano)})
+		}
+	}
+	return tags
+}
+
 func determineDecoder(d encoding.DecodeRawFunc, msg *sarama.ConsumerMessage, sp opentracing.Span) (encoding.DecodeRawFunc, error) {
 
 	if d != nil {
@@ -151,7 +372,33 @@ func getCorrelationID(hh []*sarama.RecordHeader) string {
 			break
 		}
 	}
-	return uuid.New().String()
+	return correlation.NewID()
+}
+
+// decompressingDecoder returns a decoder which decompresses the value of the message before decoding it,
+// if the message has a content encoding header, e.g. set by the ValueCompression option of the trace/kafka producer.
+func decompressingDecoder(dec encoding.DecodeRawFunc, hh []*sarama.RecordHeader) (encoding.DecodeRawFunc, error) {
+	var codec compression.Codec
+	for _, h := range hh {
+		if string(h.Key) == encoding.ContentEncodingHeader {
+			codec = compression.Codec(h.Value)
+			break
+		}
+	}
+	if codec == "" {
+		return dec, nil
+	}
+	err := codec.Validate()
+	if err != nil {
+		return nil, fmt.Errorf("failed to determine the decompression of the message: %w", err)
+	}
+	return func(data []byte, v interface{}) error {
+		b, err := compression.Decompress(codec, data)
+		if err != nil {
+			return err
+		}
+		return dec(b, v)
+	}, nil
 }
 
 func determineContentType(hdr []*sarama.RecordHeader) (string, error) {
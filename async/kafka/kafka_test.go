@@ -16,9 +16,13 @@ import (
 	"github.com/beatlabs/patron/correlation"
 	"github.com/beatlabs/patron/encoding"
 	patron_json "github.com/beatlabs/patron/encoding/json"
+	"github.com/beatlabs/patron/log"
+	plog "github.com/beatlabs/patron/log/zerolog"
 	"github.com/opentracing/opentracing-go"
 	"github.com/opentracing/opentracing-go/mocktracer"
+	"github.com/rs/zerolog"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestDefaultSaramaConfig(t *testing.T) {
@@ -84,6 +88,205 @@ func Test_message(t *testing.T) {
 	assert.Equal(t, "value", m["key"])
 }
 
+func TestClaimMessage_SpanTags(t *testing.T) {
+	mtr := mocktracer.New()
+	opentracing.SetGlobalTracer(mtr)
+	defer opentracing.SetGlobalTracer(opentracing.NoopTracer{})
+	ts := time.Date(2019, 10, 1, 12, 0, 0, 0, time.UTC)
+	cm := &sarama.ConsumerMessage{
+		Topic:     "TOPIC",
+		Partition: 2,
+		Offset:    42,
+		Key:       []byte("key"),
+		Timestamp: ts,
+		Value:     []byte(`{"key":"value"}`),
+	}
+
+	tests := map[string]struct {
+		tags     []MessageTag
+		expected map[string]interface{}
+	}{
+		"default tags": {
+			expected: map[string]interface{}{"topic": "TOPIC", "partition": int32(2), "offset": int64(42)},
+		},
+		"all tags": {
+			tags: []MessageTag{TopicTag, PartitionTag, OffsetTag, KeyTag, TimestampTag},
+			expected: map[string]interface{}{"topic": "TOPIC", "partition": int32(2), "offset": int64(42),
+				"key": "key", "timestamp": "2019-10-01T12:00:00Z"},
+		},
+		"topic tag": {tags: []MessageTag{TopicTag}, expected: map[string]interface{}{"topic": "TOPIC"}},
+		"no tags":   {tags: []MessageTag{}, expected: map[string]interface{}{}},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			mtr.Reset()
+			msg, err := ClaimMessage(context.Background(), cm, patron_json.DecodeRaw, nil, ClaimOptions{MessageTags: tt.tags})
+			assert.NoError(t, err)
+			assert.NoError(t, msg.Ack())
+			sp := mtr.FinishedSpans()
+			assert.Len(t, sp, 1)
+			for _, k := range []string{"topic", "partition", "offset", "key", "timestamp"} {
+				v, ok := tt.expected[k]
+				if ok {
+					assert.Equal(t, v, sp[0].Tag(k))
+				} else {
+					assert.Nil(t, sp[0].Tag(k))
+				}
+			}
+		})
+	}
+}
+
+func TestClaimMessage_MessageTagsOption(t *testing.T) {
+	mtr := mocktracer.New()
+	opentracing.SetGlobalTracer(mtr)
+	defer opentracing.SetGlobalTracer(opentracing.NoopTracer{})
+	cm := &sarama.ConsumerMessage{Topic: "TOPIC", Partition: 2, Offset: 42, Value: []byte(`{"key":"value"}`)}
+
+	tests := map[string]struct {
+		oo       []OptionFunc
+		expected map[string]interface{}
+	}{
+		"default tags":  {expected: map[string]interface{}{"topic": "TOPIC", "partition": int32(2), "offset": int64(42)}},
+		"selected tags": {oo: []OptionFunc{MessageTags(OffsetTag)}, expected: map[string]interface{}{"offset": int64(42)}},
+		"tags off":      {oo: []OptionFunc{MessageTags()}, expected: map[string]interface{}{}},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			mtr.Reset()
+			cc, err := ApplyOptions(ConsumerConfig{MessageTags: DefaultMessageTags, TraceSampleRate: 1}, tt.oo...)
+			require.NoError(t, err)
+			msg, err := ClaimMessage(context.Background(), cm, patron_json.DecodeRaw, nil, cc.ClaimOptions(""))
+			require.NoError(t, err)
+			assert.NoError(t, msg.Ack())
+			sp := mtr.FinishedSpans()
+			require.Len(t, sp, 1)
+			for _, k := range []string{"topic", "partition", "offset"} {
+				assert.Equal(t, tt.expected[k], sp[0].Tag(k), k)
+			}
+		})
+	}
+}
+
+func TestClaimMessage_GroupTopicFields(t *testing.T) {
+	mtr := mocktracer.New()
+	opentracing.SetGlobalTracer(mtr)
+	defer opentracing.SetGlobalTracer(opentracing.NoopTracer{})
+	var buf bytes.Buffer
+	zl := zerolog.New(&buf)
+	prev := log.Sub(nil)
+	assert.NoError(t, log.Setup(func(f map[string]interface{}) log.Logger { return plog.NewLogger(&zl, log.InfoLevel, f) }, nil))
+	defer func() {
+		assert.NoError(t, log.Setup(func(map[string]interface{}) log.Logger { return prev }, nil))
+	}()
+	cm := &sarama.ConsumerMessage{Topic: "TOPIC", Value: []byte(`{"key":"value"}`)}
+
+	tests := map[string]struct {
+		group  string
+		fields map[string]interface{}
+	}{
+		"group consumer":  {group: "GROUP", fields: map[string]interface{}{"topic": "TOPIC", "group": "GROUP"}},
+		"simple consumer": {fields: map[string]interface{}{"topic": "TOPIC"}},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			mtr.Reset()
+			buf.Reset()
+			msg, err := ClaimMessage(context.Background(), cm, patron_json.DecodeRaw, nil, ClaimOptions{Group: tt.group})
+			assert.NoError(t, err)
+			log.FromContext(msg.Context()).Info("processing")
+			assert.NoError(t, msg.Ack())
+
+			entry := map[string]interface{}{}
+			assert.NoError(t, json.Unmarshal(buf.Bytes(), &entry))
+			sp := mtr.FinishedSpans()
+			assert.Len(t, sp, 1)
+			for _, k := range []string{"topic", "group"} {
+				if v, ok := tt.fields[k]; ok {
+					assert.Equal(t, v, entry[k])
+					assert.Equal(t, v, sp[0].Tag(k))
+				} else {
+					assert.NotContains(t, entry, k)
+					assert.Nil(t, sp[0].Tag(k))
+				}
+			}
+		})
+	}
+}
+
+func TestClaimMessage_TraceSampleRate(t *testing.T) {
+	mtr := mocktracer.New()
+	opentracing.SetGlobalTracer(mtr)
+	defer opentracing.SetGlobalTracer(opentracing.NoopTracer{})
+	cm := &sarama.ConsumerMessage{Topic: "TOPIC", Value: []byte(`{"key":"value"}`)}
+
+	tests := map[string]struct {
+		rate     float64
+		min, max int
+	}{
+		"all":      {rate: 1, min: 10000, max: 10000},
+		"none":     {rate: 0, min: 0, max: 0},
+		"fraction": {rate: 0.1, min: 800, max: 1200},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			mtr.Reset()
+			for i := 0; i < 10000; i++ {
+				msg, err := ClaimMessage(context.Background(), cm, patron_json.DecodeRaw, nil, ClaimOptions{TraceSampleRate: &tt.rate})
+				require.NoError(t, err)
+				assert.NoError(t, msg.Ack())
+			}
+			n := len(mtr.FinishedSpans())
+			assert.True(t, n >= tt.min && n <= tt.max, "%d spans", n)
+		})
+	}
+}
+
+func TestConsumerConfig_ClaimOptions(t *testing.T) {
+	cc := ConsumerConfig{BaggagePrefix: "baggage-", TimestampType: LogAppendTime, MessageTags: []MessageTag{KeyTag}}
+	opts := cc.ClaimOptions("group")
+	assert.Equal(t, "group", opts.Group)
+	assert.Equal(t, "baggage-", opts.BaggagePrefix)
+	assert.Equal(t, LogAppendTime, opts.TimestampType)
+	assert.Equal(t, []MessageTag{KeyTag}, opts.MessageTags)
+	// a zero sample rate of the configuration is kept, instead of defaulting to sampling every message
+	require.NotNil(t, opts.TraceSampleRate)
+	assert.Equal(t, 0.0, *opts.TraceSampleRate)
+}
+
+func TestClaimMessage_UnsampledPropagation(t *testing.T) {
+	mtr := mocktracer.New()
+	opentracing.SetGlobalTracer(mtr)
+	defer opentracing.SetGlobalTracer(opentracing.NoopTracer{})
+
+	up := mtr.StartSpan("upstream")
+	hdr := opentracing.TextMapCarrier{}
+	require.NoError(t, mtr.Inject(up.Context(), opentracing.TextMap, hdr))
+	cm := &sarama.ConsumerMessage{Topic: "TOPIC", Value: []byte(`{"key":"value"}`)}
+	for k, v := range hdr {
+		cm.Headers = append(cm.Headers, &sarama.RecordHeader{Key: []byte(k), Value: []byte(v)})
+	}
+
+	rate := 0.0
+	msg, err := ClaimMessage(context.Background(), cm, patron_json.DecodeRaw, nil, ClaimOptions{TraceSampleRate: &rate})
+	require.NoError(t, err)
+	// the spans of the processing are children of the upstream span
+	sp, _ := opentracing.StartSpanFromContext(msg.Context(), "downstream")
+	sp.Finish()
+	assert.NoError(t, msg.Ack())
+	spans := mtr.FinishedSpans()
+	require.Len(t, spans, 1)
+	assert.Equal(t, "downstream", spans[0].OperationName)
+	assert.Equal(t, up.Context().(mocktracer.MockSpanContext).TraceID, spans[0].SpanContext.TraceID)
+	assert.Equal(t, up.Context().(mocktracer.MockSpanContext).SpanID, spans[0].ParentID)
+
+	// without an upstream span there is nothing to propagate
+	msg, err = ClaimMessage(context.Background(), &sarama.ConsumerMessage{Topic: "TOPIC"}, patron_json.DecodeRaw, nil, ClaimOptions{TraceSampleRate: &rate})
+	require.NoError(t, err)
+	assert.Nil(t, opentracing.SpanFromContext(msg.Context()))
+	assert.NoError(t, msg.Ack())
+}
+
 func TestMapHeader(t *testing.T) {
 	hh := []*sarama.RecordHeader{
 		{
@@ -207,6 +410,59 @@ func TestNoDecoderNoContentType(t *testing.T) {
 	testMessageClaim(t, testData)
 }
 
+func TestSetDefaultDecoder(t *testing.T) {
+	defer func() { assert.NoError(t, SetDefaultDecoder(patron_json.DecodeRaw)) }()
+
+	assert.Error(t, SetDefaultDecoder(nil))
+	assert.NotNil(t, DefaultDecoder())
+
+	assert.NoError(t, SetDefaultDecoder(stringToSliceDecoder))
+
+	// consumers without a decoder fall back to the default decoder
+	cc, err := ApplyOptions(ConsumerConfig{})
+	require.NoError(t, err)
+	assert.Equal(t, reflect.ValueOf(stringToSliceDecoder).Pointer(), reflect.ValueOf(cc.DecoderFunc).Pointer())
+
+	testData := decodingTestData{
+		counter: eventCounter{
+			messageCount: 1,
+		},
+		msgs: []*sarama.ConsumerMessage{
+			saramaConsumerMessage("key value", &sarama.RecordHeader{
+				Key:   []byte(encoding.ContentTypeHeader),
+				Value: []byte(patron_json.Type),
+			}),
+		},
+		dmsgs:   [][]string{{"key", "value"}},
+		decoder: cc.DecoderFunc,
+	}
+	testMessageClaim(t, testData)
+
+	// the consumer decoder overrides the default decoder
+	cc, err = ApplyOptions(ConsumerConfig{}, Decoder(json.Unmarshal))
+	require.NoError(t, err)
+	assert.Equal(t, reflect.ValueOf(json.Unmarshal).Pointer(), reflect.ValueOf(cc.DecoderFunc).Pointer())
+
+	// consumers decoding by content type or per topic do not fall back to the default decoder
+	cc, err = ApplyOptions(ConsumerConfig{}, DecoderJSON(), DecoderByContentType())
+	require.NoError(t, err)
+	assert.Nil(t, cc.DecoderFunc)
+	assert.True(t, cc.DecodeByContentType)
+	cc, err = ApplyOptions(ConsumerConfig{}, TopicDecoders(map[string]encoding.DecodeRawFunc{"commands": json.Unmarshal}))
+	require.NoError(t, err)
+	assert.Nil(t, cc.DecoderFunc)
+}
+
+func TestResolveDecoder_NilDefaultDecoder(t *testing.T) {
+	defaultDecoderMu.Lock()
+	defaultDecoder = nil
+	defaultDecoderMu.Unlock()
+	defer func() { assert.NoError(t, SetDefaultDecoder(patron_json.DecodeRaw)) }()
+
+	_, err := ApplyOptions(ConsumerConfig{})
+	assert.EqualError(t, err, "no decoder provided and the default decoder is nil")
+}
+
 func TestMultipleMessagesJsonDecoder(t *testing.T) {
 
 	testData := decodingTestData{
@@ -327,7 +583,7 @@ func testMessageClaim(t *testing.T, data decodingTestData) {
 
 		}
 
-		msg, err := ClaimMessage(ctx, km, data.decoder, nil)
+		msg, err := ClaimMessage(ctx, km, data.decoder, nil, ClaimOptions{})
 
 		if err != nil {
 			counter.claimErr++
@@ -0,0 +1,25 @@
+package kafka
+
+import (
+	"github.com/beatlabs/patron/async"
+)
+
+// Keyed is implemented by the messages of the Kafka consumers.
+type Keyed interface {
+	// Key returns the key of the message, which is nil for messages produced without a key.
+	Key() []byte
+}
+
+// OrderingKey returns the key of a message consumed from Kafka, or an empty key if the message is not a Kafka message.
+// It can be used as the key function of the ordered lanes of the async component, in order for the messages
+// of a key to be processed in the order they were produced, while the messages of different keys are processed concurrently.
+func OrderingKey(msg async.Message) string {
+	m := unwrap(msg, func(m async.Message) bool {
+		_, ok := m.(Keyed)
+		return ok
+	})
+	if m == nil {
+		return ""
+	}
+	return string(m.(Keyed).Key())
+}
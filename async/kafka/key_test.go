@@ -0,0 +1,29 @@
+package kafka
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Shopify/sarama"
+	"github.com/beatlabs/patron/encoding/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOrderingKey(t *testing.T) {
+	cm := &sarama.ConsumerMessage{Topic: "key-topic", Key: []byte("driver-1"), Value: []byte(`"value"`)}
+	msg, err := ClaimMessage(context.Background(), cm, json.DecodeRaw, nil, ClaimOptions{Group: "key-group"})
+	require.NoError(t, err)
+	assert.Equal(t, "driver-1", OrderingKey(msg))
+
+	// the key is available through the wrappers of the message
+	l, err := NewInFlightLimiter(1)
+	require.NoError(t, err)
+	assert.Equal(t, "driver-1", OrderingKey(l.Track(msg)))
+
+	cm = &sarama.ConsumerMessage{Topic: "key-topic", Value: []byte(`"value"`)}
+	msg, err = ClaimMessage(context.Background(), cm, json.DecodeRaw, nil, ClaimOptions{Group: "key-group"})
+	require.NoError(t, err)
+	assert.Equal(t, "", OrderingKey(msg))
+	assert.Equal(t, "", OrderingKey(nil))
+}
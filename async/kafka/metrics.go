@@ -0,0 +1,109 @@
+package kafka
+
+import (
+	"errors"
+	"regexp"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	metrics "github.com/rcrowley/go-metrics"
+)
+
+const saramaMetricsPrefix = "sarama_"
+
+var (
+	saramaMetricsQuantiles = []float64{0.5, 0.75, 0.95, 0.99}
+	invalidMetricNameChars = regexp.MustCompile("[^a-zA-Z0-9_]")
+	saramaMetricsOnce      sync.Once
+	saramaMetricsBridge    = newSaramaCollector()
+)
+
+// RegisterSaramaMetrics exports the metrics of the sarama metrics registry of a client to prometheus,
+// prefixed with sarama_ and labeled with the client id, e.g. the request latency and the batch size per broker.
+// Registering the registry of a client id again replaces the previous registry, e.g. when a consumer is recreated.
+// The metrics of a client are many, since most of them are reported per broker and topic.
+func RegisterSaramaMetrics(clientID string, r metrics.Registry) error {
+	if clientID == "" {
+		return errors.New("client id is required")
+	}
+	if r == nil {
+		return errors.New("metrics registry is nil")
+	}
+	saramaMetricsOnce.Do(func() {
+		prometheus.MustRegister(saramaMetricsBridge)
+	})
+	saramaMetricsBridge.add(clientID, r)
+	return nil
+}
+
+// saramaCollector is an unchecked prometheus collector exporting the metrics of sarama metrics registries,
+// since the metrics are registered by sarama lazily, e.g. when a broker is first contacted.
+type saramaCollector struct {
+	mu         sync.Mutex
+	registries map[string]metrics.Registry
+}
+
+func newSaramaCollector() *saramaCollector {
+	return &saramaCollector{registries: make(map[string]metrics.Registry)}
+}
+
+func (sc *saramaCollector) add(clientID string, r metrics.Registry) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	sc.registries[clientID] = r
+}
+
+// Describe sends no descriptors, which makes the collector unchecked.
+func (sc *saramaCollector) Describe(chan<- *prometheus.Desc) {
+}
+
+// Collect sends the current values of the metrics of all registries.
+func (sc *saramaCollector) Collect(ch chan<- prometheus.Metric) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	for clientID, r := range sc.registries {
+		r.Each(func(name string, i interface{}) {
+			if m := saramaMetric(clientID, name, i); m != nil {
+				ch <- m
+			}
+		})
+	}
+}
+
+// saramaMetric converts a sarama metric to a prometheus metric, returning nil for unsupported metric types.
+func saramaMetric(clientID, name string, i interface{}) prometheus.Metric {
+	name = saramaMetricsPrefix + invalidMetricNameChars.ReplaceAllString(name, "_")
+	labels := prometheus.Labels{"client_id": clientID}
+	switch m := i.(type) {
+	case metrics.Counter:
+		desc := prometheus.NewDesc(name+"_total", "Sarama counter "+name, nil, labels)
+		return prometheus.MustNewConstMetric(desc, prometheus.CounterValue, float64(m.Count()))
+	case metrics.Gauge:
+		desc := prometheus.NewDesc(name, "Sarama gauge "+name, nil, labels)
+		return prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, float64(m.Value()))
+	case metrics.GaugeFloat64:
+		desc := prometheus.NewDesc(name, "Sarama gauge "+name, nil, labels)
+		return prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, m.Value())
+	case metrics.Meter:
+		desc := prometheus.NewDesc(name+"_total", "Sarama meter "+name, nil, labels)
+		return prometheus.MustNewConstMetric(desc, prometheus.CounterValue, float64(m.Count()))
+	case metrics.Histogram:
+		s := m.Snapshot()
+		desc := prometheus.NewDesc(name, "Sarama histogram "+name, nil, labels)
+		return prometheus.MustNewConstSummary(desc, uint64(s.Count()), float64(s.Sum()), quantiles(s.Percentiles(saramaMetricsQuantiles)))
+	case metrics.Timer:
+		s := m.Snapshot()
+		desc := prometheus.NewDesc(name, "Sarama timer "+name+" in nanoseconds", nil, labels)
+		return prometheus.MustNewConstSummary(desc, uint64(s.Count()), float64(s.Sum()), quantiles(s.Percentiles(saramaMetricsQuantiles)))
+	default:
+		return nil
+	}
+}
+
+func quantiles(values []float64) map[float64]float64 {
+	qq := make(map[float64]float64, len(saramaMetricsQuantiles))
+	for i, q := range saramaMetricsQuantiles {
+		qq[q] = values[i]
+	}
+	return qq
+}
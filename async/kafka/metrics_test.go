@@ -0,0 +1,74 @@
+package kafka
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	metrics "github.com/rcrowley/go-metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSaramaCollector(t *testing.T) {
+	r := metrics.NewRegistry()
+	metrics.GetOrRegisterMeter("incoming-byte-rate", r).Mark(512)
+	metrics.GetOrRegisterHistogram("request-latency-in-ms-for-broker-1", r, metrics.NewUniformSample(10)).Update(20)
+	metrics.GetOrRegisterCounter("requests-in-flight", r).Inc(3)
+	metrics.GetOrRegisterGauge("batch.size", r).Update(7)
+	metrics.GetOrRegisterTimer("timer", r).Update(time.Second)
+
+	sc := newSaramaCollector()
+	sc.add("client", r)
+	reg := prometheus.NewRegistry()
+	require.NoError(t, reg.Register(sc))
+
+	mfs, err := reg.Gather()
+	require.NoError(t, err)
+	got := make(map[string]*dto.MetricFamily, len(mfs))
+	for _, mf := range mfs {
+		got[mf.GetName()] = mf
+	}
+	assert.Len(t, got, 5)
+
+	m := got["sarama_incoming_byte_rate_total"].GetMetric()[0]
+	assert.Equal(t, 512.0, m.GetCounter().GetValue())
+	assert.Equal(t, "client_id", m.GetLabel()[0].GetName())
+	assert.Equal(t, "client", m.GetLabel()[0].GetValue())
+	assert.Equal(t, 3.0, got["sarama_requests_in_flight_total"].GetMetric()[0].GetCounter().GetValue())
+	assert.Equal(t, 7.0, got["sarama_batch_size"].GetMetric()[0].GetGauge().GetValue())
+	summary := got["sarama_request_latency_in_ms_for_broker_1"].GetMetric()[0].GetSummary()
+	assert.Equal(t, uint64(1), summary.GetSampleCount())
+	assert.Equal(t, 20.0, summary.GetSampleSum())
+	assert.Len(t, summary.GetQuantile(), 4)
+	assert.Equal(t, float64(time.Second), got["sarama_timer"].GetMetric()[0].GetSummary().GetSampleSum())
+
+	// registering a client again replaces its registry
+	sc.add("client", metrics.NewRegistry())
+	mfs, err = reg.Gather()
+	require.NoError(t, err)
+	assert.Empty(t, mfs)
+}
+
+func TestSaramaMetrics(t *testing.T) {
+	cfg, err := DefaultSaramaConfig("metrics")
+	require.NoError(t, err)
+	c := &ConsumerConfig{SaramaConfig: cfg}
+	assert.NoError(t, SaramaMetrics()(c))
+	metrics.GetOrRegisterMeter("incoming-byte-rate", cfg.MetricRegistry).Mark(1)
+
+	mfs, err := prometheus.DefaultGatherer.Gather()
+	require.NoError(t, err)
+	var found bool
+	for _, mf := range mfs {
+		if mf.GetName() == "sarama_incoming_byte_rate_total" {
+			found = true
+		}
+	}
+	assert.True(t, found)
+
+	assert.Error(t, SaramaMetrics()(&ConsumerConfig{}))
+	assert.Error(t, RegisterSaramaMetrics("", metrics.NewRegistry()))
+	assert.Error(t, RegisterSaramaMetrics("client", nil))
+}
@@ -1,18 +1,77 @@
 package kafka
 
 import (
+	"crypto/tls"
 	"errors"
 	"fmt"
+	"os"
+	"path"
+	"reflect"
+	"runtime"
+	"strings"
 	"time"
 
 	"github.com/Shopify/sarama"
 	"github.com/beatlabs/patron/encoding"
 	"github.com/beatlabs/patron/encoding/json"
+	"github.com/beatlabs/patron/log"
 )
 
 // OptionFunc definition for configuring the consumer in a functional way.
 type OptionFunc func(*ConsumerConfig) error
 
+// ApplyOptions applies the options to a copy of the config, including its sarama config, which is returned
+// only if all the options are applied, so a failing option does not leave the config partially configured.
+// The error identifies the failing option by its index and name.
+func ApplyOptions(cc ConsumerConfig, oo ...OptionFunc) (ConsumerConfig, error) {
+	applied := cc
+	if cc.SaramaConfig != nil {
+		sc := *cc.SaramaConfig
+		applied.SaramaConfig = &sc
+	}
+	if cc.MessageTags != nil {
+		applied.MessageTags = append([]MessageTag{}, cc.MessageTags...)
+	}
+	for i, o := range oo {
+		err := o(&applied)
+		if err != nil {
+			return cc, fmt.Errorf("failed to apply option %d (%s): %w", i, optionName(o), err)
+		}
+	}
+	err := resolveDecoder(&applied)
+	if err != nil {
+		return cc, err
+	}
+	return applied, nil
+}
+
+// resolveDecoder falls back to the default decoder if no decoder is provided, unless the messages are decoded based on
+// their content type, or decoders are provided per topic, which the consumer validates.
+func resolveDecoder(c *ConsumerConfig) error {
+	if c.DecoderFunc != nil || c.DecodeByContentType || len(c.TopicDecoders) > 0 {
+		return nil
+	}
+	c.DecoderFunc = DefaultDecoder()
+	if c.DecoderFunc == nil {
+		return errors.New("no decoder provided and the default decoder is nil")
+	}
+	return nil
+}
+
+// optionName returns the name of the function which created the option, e.g. kafka.Buffer.
+func optionName(o OptionFunc) string {
+	fn := runtime.FuncForPC(reflect.ValueOf(o).Pointer())
+	if fn == nil {
+		return "unknown"
+	}
+	name := fn.Name()
+	// options are closures, e.g. github.com/beatlabs/patron/async/kafka.Buffer.func1
+	if ext := path.Ext(name); strings.HasPrefix(ext, ".func") {
+		name = strings.TrimSuffix(name, ext)
+	}
+	return path.Base(name)
+}
+
 // Version option for setting the Kafka version.
 func Version(version string) OptionFunc {
 	return func(c *ConsumerConfig) error {
@@ -72,6 +131,20 @@ func StartFromNewest() OptionFunc {
 	}
 }
 
+// StartFromTimestamp option for consuming the partitions of a simple consumer from the first message at or after the timestamp,
+// e.g. in order to replay the messages of a period for a backfill. Partitions without a message at or after the timestamp are consumed
+// from the starting offset of the consumer, which is the newest by default. Consuming fails if the messages since the timestamp
+// may have been deleted, i.e. if the timestamp is before the earliest available message of a partition whose oldest messages have been deleted.
+func StartFromTimestamp(t time.Time) OptionFunc {
+	return func(c *ConsumerConfig) error {
+		if t.IsZero() {
+			return errors.New("start timestamp is required")
+		}
+		c.StartTimestamp = t
+		return nil
+	}
+}
+
 // Decoder option for injecting a specific decoder implementation
 func Decoder(dec encoding.DecodeRawFunc) OptionFunc {
 	return func(c *ConsumerConfig) error {
@@ -79,6 +152,7 @@ func Decoder(dec encoding.DecodeRawFunc) OptionFunc {
 			return errors.New("decoder is nil")
 		}
 		c.DecoderFunc = dec
+		c.DecodeByContentType = false
 		return nil
 	}
 }
@@ -87,6 +161,326 @@ func Decoder(dec encoding.DecodeRawFunc) OptionFunc {
 func DecoderJSON() OptionFunc {
 	return func(c *ConsumerConfig) error {
 		c.DecoderFunc = json.DecodeRaw
+		c.DecodeByContentType = false
+		return nil
+	}
+}
+
+// DecoderByContentType option for decoding each message with the decoder of its content type header,
+// instead of the default decoder. Messages without a content type header fail to be decoded.
+func DecoderByContentType() OptionFunc {
+	return func(c *ConsumerConfig) error {
+		c.DecoderFunc = nil
+		c.DecodeByContentType = true
+		return nil
+	}
+}
+
+// LeaderWaitTimeout option for waiting, up to the provided timeout, for all partitions of the topic to have a leader
+// before starting to consume. This avoids failing while brokers are restarting.
+func LeaderWaitTimeout(timeout time.Duration) OptionFunc {
+	return func(c *ConsumerConfig) error {
+		if timeout <= 0 {
+			return errors.New("leader wait timeout must be positive")
+		}
+		c.LeaderWaitTimeout = timeout
+		return nil
+	}
+}
+
+// MessageTags option for selecting the message attributes which are added as tags to the consumer span.
+// Providing no attributes turns the tags off.
+func MessageTags(mt ...MessageTag) OptionFunc {
+	return func(c *ConsumerConfig) error {
+		for _, t := range mt {
+			switch t {
+			case TopicTag, PartitionTag, OffsetTag, KeyTag, TimestampTag:
+			default:
+				return fmt.Errorf("invalid message tag %s", t)
+			}
+		}
+		// a non-nil slice, since a nil one falls back to the default tags
+		c.MessageTags = append([]MessageTag{}, mt...)
+		return nil
+	}
+}
+
+// Client option for injecting a shared sarama client, which is reused instead of opening new broker connections.
+// The connection settings of the client's config take precedence over the consumer's config.
+// The client is owned by the caller, who is responsible for closing it after all consumers using it are closed.
+func Client(client sarama.Client) OptionFunc {
+	return func(c *ConsumerConfig) error {
+		if client == nil {
+			return errors.New("client is nil")
+		}
+		if client.Closed() {
+			return errors.New("client is closed")
+		}
+		if !client.Config().Consumer.Return.Errors {
+			return errors.New("client config has to return consumer errors")
+		}
+		c.Client = client
+		return nil
+	}
+}
+
+// PropagateBaggage option for setting the message headers starting with the prefix as baggage items of the consumer span,
+// with the prefix trimmed from their key. It complements the PropagateBaggage option of the trace/kafka producer.
+func PropagateBaggage(headerPrefix string) OptionFunc {
+	return func(c *ConsumerConfig) error {
+		if headerPrefix == "" {
+			return errors.New("baggage header prefix is required")
+		}
+		c.BaggagePrefix = headerPrefix
+		return nil
+	}
+}
+
+// MaxInFlight option for limiting the messages which are delivered but not yet acked or nacked.
+// When the limit is reached, the consumer stops reading messages until a message is acked or nacked,
+// bounding the memory used even if the processing is slow.
+func MaxInFlight(n int) OptionFunc {
+	return func(c *ConsumerConfig) error {
+		if n <= 0 {
+			return errors.New("max in flight messages must be positive")
+		}
+		c.MaxInFlight = n
+		return nil
+	}
+}
+
+// TopicWeights option for favoring the messages of higher priority topics of a consumer of multiple topics.
+// When messages of multiple topics are available, up to weight messages of each topic are delivered in turn,
+// e.g. with weights 3 and 1 three messages of the first topic are delivered for every message of the second,
+// so lower priority topics are never starved. Topics without a weight have a weight of 1.
+func TopicWeights(weights map[string]int) OptionFunc {
+	return func(c *ConsumerConfig) error {
+		if len(weights) == 0 {
+			return errors.New("topic weights are empty")
+		}
+		for topic, w := range weights {
+			if w <= 0 {
+				return fmt.Errorf("weight of topic '%s' must be positive", topic)
+			}
+		}
+		c.TopicWeights = weights
+		return nil
+	}
+}
+
+// TopicDecoders option for injecting a decoder per topic of a consumer of multiple topics, since each topic
+// usually has its own payload type and codec. Messages of topics without a decoder are decoded with the
+// decoder of the Decoder option or, if it is not provided, with the decoder of their content type.
+func TopicDecoders(decoders map[string]encoding.DecodeRawFunc) OptionFunc {
+	return func(c *ConsumerConfig) error {
+		if len(decoders) == 0 {
+			return errors.New("topic decoders are empty")
+		}
+		for topic, dec := range decoders {
+			if dec == nil {
+				return fmt.Errorf("decoder of topic '%s' is nil", topic)
+			}
+		}
+		c.TopicDecoders = decoders
+		return nil
+	}
+}
+
+// MessageTimestampType option for setting the timestamp type of the consumed topics, i.e. the message.timestamp.type
+// topic configuration, which is returned by the TimestampType of the messages. It defaults to CreateTime.
+func MessageTimestampType(tt TimestampType) OptionFunc {
+	return func(c *ConsumerConfig) error {
+		switch tt {
+		case CreateTime, LogAppendTime:
+		default:
+			return fmt.Errorf("invalid timestamp type %s", tt)
+		}
+		c.TimestampType = tt
+		return nil
+	}
+}
+
+// Workers option for setting the number of workers of the simple consumer, which claim the messages of all partitions.
+// The workers bound the concurrency of the consumer. A single worker, which is the default, delivers the messages
+// of each partition in order, while multiple workers may reorder them.
+func Workers(n int) OptionFunc {
+	return func(c *ConsumerConfig) error {
+		if n <= 0 {
+			return errors.New("workers must be positive")
+		}
+		c.Workers = n
+		return nil
+	}
+}
+
+// ReadToEndThen option for consuming the topic of a simple consumer from the oldest offset and calling onCaughtUp once
+// the messages up to the high-water mark of every partition at the time of subscription have been acked or nacked,
+// e.g. in order to report the service as ready once a cache is rebuilt from a compacted topic.
+// The consumer continues tailing the topic afterwards. The callback is called once per consumer.
+func ReadToEndThen(onCaughtUp func()) OptionFunc {
+	return func(c *ConsumerConfig) error {
+		if onCaughtUp == nil {
+			return errors.New("caught up callback is nil")
+		}
+		c.SaramaConfig.Consumer.Offsets.Initial = sarama.OffsetOldest
+		c.OnCaughtUp = onCaughtUp
+		return nil
+	}
+}
+
+// RebalanceDrainTimeout option for waiting, up to the provided timeout, for the delivered messages to be acked or nacked
+// before the partitions of a group consumer are released on a rebalance, in order for their offsets to be committed
+// and the messages not to be redelivered to the new owner of the partitions.
+func RebalanceDrainTimeout(timeout time.Duration) OptionFunc {
+	return func(c *ConsumerConfig) error {
+		if timeout <= 0 {
+			return errors.New("rebalance drain timeout must be positive")
+		}
+		c.RebalanceDrainTimeout = timeout
+		return nil
+	}
+}
+
+// ShutdownTimeout option for waiting, up to the provided timeout, for the delivered messages of a group consumer to be acked or nacked
+// when it shuts down, in order for their offsets to be committed before the consumer leaves the group. It defaults to 10 seconds.
+func ShutdownTimeout(timeout time.Duration) OptionFunc {
+	return func(c *ConsumerConfig) error {
+		if timeout <= 0 {
+			return errors.New("shutdown timeout must be positive")
+		}
+		c.ShutdownTimeout = timeout
+		return nil
+	}
+}
+
+// Retries option for capping the number of times a group consumer retries to consume after a transient failure, e.g. when the brokers
+// are unreachable, before failing with the last error. Without the option the transient failures are retried indefinitely,
+// while zero fails on the first error. The retries are reset after every successful session.
+func Retries(count uint) OptionFunc {
+	return func(c *ConsumerConfig) error {
+		c.Retries = count
+		return nil
+	}
+}
+
+// RetryWait option for setting the wait before the first retry of a group consumer, which is doubled on every subsequent retry.
+func RetryWait(interval time.Duration) OptionFunc {
+	return func(c *ConsumerConfig) error {
+		if interval <= 0 {
+			return errors.New("retry wait must be positive")
+		}
+		c.RetryWait = interval
+		return nil
+	}
+}
+
+// MaxRetryWait option for capping the wait between the retries of a group consumer, which defaults to one minute.
+func MaxRetryWait(interval time.Duration) OptionFunc {
+	return func(c *ConsumerConfig) error {
+		if interval <= 0 {
+			return errors.New("max retry wait must be positive")
+		}
+		c.MaxRetryWait = interval
+		return nil
+	}
+}
+
+// RetryJitter option for randomizing the wait between the retries of a group consumer, which is reduced at random by up to
+// the given factor, between 0 and 1, in order for the consumers failing at the same time not to retry at the same time.
+func RetryJitter(factor float64) OptionFunc {
+	return func(c *ConsumerConfig) error {
+		if factor < 0 || factor > 1 {
+			return errors.New("retry jitter must be between 0 and 1")
+		}
+		c.RetryJitter = factor
+		return nil
+	}
+}
+
+// HeartbeatLog option for logging periodically, while consuming, that the consumer is alive along with the messages
+// delivered since the last heartbeat and the current lag of its partitions, e.g. to tell an idle consumer apart from a stalled one.
+func HeartbeatLog(interval time.Duration) OptionFunc {
+	return func(c *ConsumerConfig) error {
+		if interval <= 0 {
+			return errors.New("heartbeat interval must be positive")
+		}
+		c.HeartbeatInterval = interval
+		return nil
+	}
+}
+
+// ErrorDedupWindow option for collapsing the identical errors of a consumer within the window, e.g. during an outage.
+// The first error is reported, while its repetitions within the window are logged as a single line with their count,
+// e.g. "error 'broker unreachable' repeated 42 times", once the window has elapsed or a different error occurs.
+func ErrorDedupWindow(window time.Duration) OptionFunc {
+	return func(c *ConsumerConfig) error {
+		if window <= 0 {
+			return errors.New("error dedup window must be positive")
+		}
+		c.ErrorDedupWindow = window
+		return nil
+	}
+}
+
+// StuckPartitionTimeout option for flagging the partitions of a consumer whose offset has not advanced for the timeout,
+// although they have messages to consume, e.g. due to a poison message. The stuck partitions are logged, exposed by the
+// component_kafka_consumer_partition_stuck gauge and listed on the /info endpoint.
+func StuckPartitionTimeout(timeout time.Duration) OptionFunc {
+	return func(c *ConsumerConfig) error {
+		if timeout <= 0 {
+			return errors.New("stuck partition timeout must be positive")
+		}
+		c.StuckPartitionTimeout = timeout
+		return nil
+	}
+}
+
+// TraceSampleRate option for starting a consumer span for only the given fraction of the messages, between 0 and 1,
+// e.g. for consumers of a high throughput which cannot afford a span per message. The metrics cover all the messages,
+// while the messages without a span propagate the upstream trace, so that the downstream sampling decisions are consistent.
+func TraceSampleRate(rate float64) OptionFunc {
+	return func(c *ConsumerConfig) error {
+		if rate < 0 || rate > 1 {
+			return errors.New("trace sample rate must be between 0 and 1")
+		}
+		c.TraceSampleRate = rate
+		return nil
+	}
+}
+
+// insecureWarnf logs the warning of skipping the TLS verification, which tests replace.
+var insecureWarnf = log.Warnf
+
+// SaramaMetrics option for exporting the metrics of the sarama client to prometheus, e.g. the request latency
+// and the batch size per broker, which are otherwise not exported. The option is opt-in due to the number of series.
+// When a shared client is provided, its metrics are exported instead, so the option should follow the Client option.
+func SaramaMetrics() OptionFunc {
+	return func(c *ConsumerConfig) error {
+		cfg := c.SaramaConfig
+		if c.Client != nil {
+			cfg = c.Client.Config()
+		}
+		if cfg == nil {
+			return errors.New("sarama config is nil")
+		}
+		return RegisterSaramaMetrics(cfg.ClientID, cfg.MetricRegistry)
+	}
+}
+
+// InsecureSkipVerify option for connecting to brokers over TLS without verifying their certificate chain and host name,
+// e.g. brokers with self-signed certificates in development setups. The option is rejected when the PATRON_ENV
+// environment variable is set to production, since it allows man-in-the-middle attacks.
+func InsecureSkipVerify() OptionFunc {
+	return func(c *ConsumerConfig) error {
+		if os.Getenv("PATRON_ENV") == "production" {
+			return errors.New("skipping the TLS verification is not allowed in production")
+		}
+		insecureWarnf("INSECURE: TLS verification of the kafka brokers %v is skipped, which must never be used in production", c.Brokers)
+		if c.SaramaConfig.Net.TLS.Config == nil {
+			c.SaramaConfig.Net.TLS.Config = &tls.Config{}
+		}
+		c.SaramaConfig.Net.TLS.Enable = true
+		c.SaramaConfig.Net.TLS.Config.InsecureSkipVerify = true // nolint:gosec
 		return nil
 	}
 }
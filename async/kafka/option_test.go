@@ -1,6 +1,9 @@
 package kafka
 
 import (
+	"errors"
+	"fmt"
+	"os"
 	"reflect"
 	"testing"
 	"time"
@@ -150,3 +153,239 @@ func TestDecoderJSON(t *testing.T) {
 		reflect.ValueOf(c.DecoderFunc).Pointer(),
 	)
 }
+
+func TestLeaderWaitTimeout(t *testing.T) {
+	c := ConsumerConfig{}
+	assert.Error(t, LeaderWaitTimeout(0)(&c))
+	assert.NoError(t, LeaderWaitTimeout(time.Second)(&c))
+	assert.Equal(t, time.Second, c.LeaderWaitTimeout)
+}
+
+func TestMessageTags(t *testing.T) {
+	c := ConsumerConfig{}
+	assert.Error(t, MessageTags("invalid")(&c))
+	assert.NoError(t, MessageTags(KeyTag, TimestampTag)(&c))
+	assert.Equal(t, []MessageTag{KeyTag, TimestampTag}, c.MessageTags)
+	assert.NoError(t, MessageTags()(&c))
+	assert.Equal(t, []MessageTag{}, c.MessageTags)
+}
+
+type mockClient struct {
+	sarama.Client
+	cfg    *sarama.Config
+	closed bool
+}
+
+func (m *mockClient) Config() *sarama.Config { return m.cfg }
+func (m *mockClient) Closed() bool           { return m.closed }
+
+func TestClient(t *testing.T) {
+	noErrors := sarama.NewConfig()
+	returnErrors := sarama.NewConfig()
+	returnErrors.Consumer.Return.Errors = true
+	tests := map[string]struct {
+		client  sarama.Client
+		wantErr bool
+	}{
+		"success":               {client: &mockClient{cfg: returnErrors}},
+		"nil client":            {client: nil, wantErr: true},
+		"closed client":         {client: &mockClient{cfg: returnErrors, closed: true}, wantErr: true},
+		"errors are not return": {client: &mockClient{cfg: noErrors}, wantErr: true},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			c := ConsumerConfig{}
+			err := Client(tt.client)(&c)
+			if tt.wantErr {
+				assert.Error(t, err)
+				assert.Nil(t, c.Client)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.client, c.Client)
+			}
+		})
+	}
+}
+
+func TestPropagateBaggage(t *testing.T) {
+	c := &ConsumerConfig{}
+	assert.Error(t, PropagateBaggage("")(c))
+	assert.NoError(t, PropagateBaggage("baggage-")(c))
+	assert.Equal(t, "baggage-", c.BaggagePrefix)
+}
+
+func TestMaxInFlight(t *testing.T) {
+	c := &ConsumerConfig{}
+	assert.Error(t, MaxInFlight(0)(c))
+	assert.NoError(t, MaxInFlight(10)(c))
+	assert.Equal(t, 10, c.MaxInFlight)
+}
+
+func TestTopicWeights(t *testing.T) {
+	c := &ConsumerConfig{}
+	assert.Error(t, TopicWeights(nil)(c))
+	assert.Error(t, TopicWeights(map[string]int{"commands": 0})(c))
+	assert.NoError(t, TopicWeights(map[string]int{"commands": 3})(c))
+	assert.Equal(t, map[string]int{"commands": 3}, c.TopicWeights)
+}
+
+func TestTopicDecoders(t *testing.T) {
+	c := &ConsumerConfig{}
+	assert.Error(t, TopicDecoders(nil)(c))
+	assert.Error(t, TopicDecoders(map[string]encoding.DecodeRawFunc{"commands": nil})(c))
+	assert.NoError(t, TopicDecoders(map[string]encoding.DecodeRawFunc{"commands": json.DecodeRaw})(c))
+	assert.Len(t, c.TopicDecoders, 1)
+	assert.NotNil(t, c.TopicDecoders["commands"])
+}
+
+func TestMessageTimestampType(t *testing.T) {
+	c := &ConsumerConfig{}
+	assert.Error(t, MessageTimestampType("Unknown")(c))
+	assert.NoError(t, MessageTimestampType(LogAppendTime)(c))
+	assert.Equal(t, LogAppendTime, c.TimestampType)
+}
+
+func TestReadToEndThen(t *testing.T) {
+	c := &ConsumerConfig{SaramaConfig: sarama.NewConfig()}
+	assert.Error(t, ReadToEndThen(nil)(c))
+	assert.NoError(t, ReadToEndThen(func() {})(c))
+	assert.NotNil(t, c.OnCaughtUp)
+	assert.Equal(t, sarama.OffsetOldest, c.SaramaConfig.Consumer.Offsets.Initial)
+}
+
+func TestWorkers(t *testing.T) {
+	c := &ConsumerConfig{}
+	assert.Error(t, Workers(0)(c))
+	assert.NoError(t, Workers(4)(c))
+	assert.Equal(t, 4, c.Workers)
+}
+
+func TestInsecureSkipVerify(t *testing.T) {
+	var warnings []string
+	defer func(f func(string, ...interface{})) { insecureWarnf = f }(insecureWarnf)
+	insecureWarnf = func(msg string, args ...interface{}) { warnings = append(warnings, fmt.Sprintf(msg, args...)) }
+
+	c := &ConsumerConfig{Brokers: []string{"broker:9093"}, SaramaConfig: sarama.NewConfig()}
+	assert.NoError(t, InsecureSkipVerify()(c))
+	assert.True(t, c.SaramaConfig.Net.TLS.Enable)
+	assert.True(t, c.SaramaConfig.Net.TLS.Config.InsecureSkipVerify)
+	assert.Len(t, warnings, 1)
+	assert.Contains(t, warnings[0], "INSECURE")
+	assert.Contains(t, warnings[0], "broker:9093")
+
+	assert.NoError(t, os.Setenv("PATRON_ENV", "production"))
+	defer func() { assert.NoError(t, os.Unsetenv("PATRON_ENV")) }()
+	c = &ConsumerConfig{SaramaConfig: sarama.NewConfig()}
+	assert.EqualError(t, InsecureSkipVerify()(c), "skipping the TLS verification is not allowed in production")
+	assert.False(t, c.SaramaConfig.Net.TLS.Enable)
+	assert.Len(t, warnings, 1)
+}
+
+func TestApplyOptions(t *testing.T) {
+	sc := sarama.NewConfig()
+	cc := ConsumerConfig{Buffer: 10, SaramaConfig: sc, MessageTags: DefaultMessageTags}
+
+	got, err := ApplyOptions(cc, Buffer(100), Version(sarama.V2_1_0_0.String()), RetryWait(0))
+	assert.EqualError(t, err, "failed to apply option 2 (kafka.RetryWait): retry wait must be positive")
+	// the options applied before the failing one do not leak
+	assert.Equal(t, 10, got.Buffer)
+	assert.True(t, sc == got.SaramaConfig)
+	assert.Equal(t, sarama.NewConfig().Version, sc.Version)
+
+	got, err = ApplyOptions(cc, Buffer(100), Version(sarama.V2_1_0_0.String()))
+	assert.NoError(t, err)
+	assert.Equal(t, 100, got.Buffer)
+	assert.Equal(t, sarama.V2_1_0_0, got.SaramaConfig.Version)
+	assert.Equal(t, 10, cc.Buffer)
+	assert.Equal(t, sarama.NewConfig().Version, sc.Version)
+}
+
+func Test_optionName(t *testing.T) {
+	assert.Equal(t, "kafka.Buffer", optionName(Buffer(1)))
+	assert.Equal(t, "kafka.failingOption", optionName(failingOption))
+}
+
+func failingOption(*ConsumerConfig) error {
+	return errors.New("failed")
+}
+
+func TestRetries(t *testing.T) {
+	c := &ConsumerConfig{}
+	assert.NoError(t, Retries(3)(c))
+	assert.Equal(t, uint(3), c.Retries)
+}
+
+func TestRetryWait(t *testing.T) {
+	c := &ConsumerConfig{}
+	assert.Error(t, RetryWait(0)(c))
+	assert.NoError(t, RetryWait(time.Second)(c))
+	assert.Equal(t, time.Second, c.RetryWait)
+}
+
+func TestMaxRetryWait(t *testing.T) {
+	c := &ConsumerConfig{}
+	assert.Error(t, MaxRetryWait(0)(c))
+	assert.NoError(t, MaxRetryWait(time.Minute)(c))
+	assert.Equal(t, time.Minute, c.MaxRetryWait)
+}
+
+func TestRetryJitter(t *testing.T) {
+	c := &ConsumerConfig{}
+	assert.Error(t, RetryJitter(-0.1)(c))
+	assert.Error(t, RetryJitter(1.1)(c))
+	assert.NoError(t, RetryJitter(0.5)(c))
+	assert.Equal(t, 0.5, c.RetryJitter)
+}
+
+func TestHeartbeatLog(t *testing.T) {
+	c := &ConsumerConfig{}
+	assert.Error(t, HeartbeatLog(-time.Second)(c))
+	assert.NoError(t, HeartbeatLog(time.Minute)(c))
+	assert.Equal(t, time.Minute, c.HeartbeatInterval)
+}
+
+func TestErrorDedupWindow(t *testing.T) {
+	c := &ConsumerConfig{}
+	assert.Error(t, ErrorDedupWindow(0)(c))
+	assert.NoError(t, ErrorDedupWindow(time.Minute)(c))
+	assert.Equal(t, time.Minute, c.ErrorDedupWindow)
+}
+
+func TestStuckPartitionTimeout(t *testing.T) {
+	c := &ConsumerConfig{}
+	assert.Error(t, StuckPartitionTimeout(0)(c))
+	assert.NoError(t, StuckPartitionTimeout(time.Minute)(c))
+	assert.Equal(t, time.Minute, c.StuckPartitionTimeout)
+}
+
+func TestTraceSampleRate(t *testing.T) {
+	c := &ConsumerConfig{}
+	assert.Error(t, TraceSampleRate(-0.1)(c))
+	assert.Error(t, TraceSampleRate(1.1)(c))
+	assert.NoError(t, TraceSampleRate(0)(c))
+	assert.Equal(t, 0.0, c.TraceSampleRate)
+	assert.NoError(t, TraceSampleRate(0.25)(c))
+	assert.Equal(t, 0.25, c.TraceSampleRate)
+}
+
+func TestStartFromTimestamp(t *testing.T) {
+	c := &ConsumerConfig{}
+	assert.Error(t, StartFromTimestamp(time.Time{})(c))
+	ts := time.Now().Add(-time.Hour)
+	assert.NoError(t, StartFromTimestamp(ts)(c))
+	assert.Equal(t, ts, c.StartTimestamp)
+}
+
+func TestShutdownTimeout(t *testing.T) {
+	c := &ConsumerConfig{}
+	assert.Error(t, ShutdownTimeout(0)(c))
+	assert.NoError(t, ShutdownTimeout(time.Second)(c))
+	assert.Equal(t, time.Second, c.ShutdownTimeout)
+}
+
+func TestRebalanceDrainTimeout(t *testing.T) {
+	c := &ConsumerConfig{}
+	assert.Error(t, RebalanceDrainTimeout(0)(c))
+	assert.NoError(t, RebalanceDrainTimeout(time.Second)(c))
+	assert.Equal(t, time.Second, c.RebalanceDrainTimeout)
+}
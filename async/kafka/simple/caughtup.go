@@ -0,0 +1,99 @@
+package simple
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/Shopify/sarama"
+	"github.com/beatlabs/patron/async"
+	"github.com/beatlabs/patron/log"
+)
+
+// catchUp tracks the partitions whose messages up to the high-water mark at the time of subscription,
+// i.e. the backlog, have not been acked or nacked yet, and calls the callback once no partition is pending.
+type catchUp struct {
+	mu         sync.Mutex
+	topic      string
+	pending    map[int32]int64
+	onCaughtUp func()
+}
+
+// newCatchUp records the last offset of the backlog of every partition of the topic, while empty partitions are caught up.
+func newCatchUp(client sarama.Client, topic string, partitions []int32, onCaughtUp func()) (*catchUp, error) {
+	cu := &catchUp{topic: topic, pending: make(map[int32]int64), onCaughtUp: onCaughtUp}
+	for _, p := range partitions {
+		oldest, err := client.GetOffset(topic, p, sarama.OffsetOldest)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get oldest offset of partition %d: %w", p, err)
+		}
+		newest, err := client.GetOffset(topic, p, sarama.OffsetNewest)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get high-water mark of partition %d: %w", p, err)
+		}
+		if newest > oldest {
+			cu.pending[p] = newest - 1
+		}
+	}
+	return cu, nil
+}
+
+// start calls the callback if all the partitions are empty.
+func (cu *catchUp) start() {
+	cu.mu.Lock()
+	caughtUp := len(cu.pending) == 0
+	cu.mu.Unlock()
+	if caughtUp {
+		cu.caughtUp()
+	}
+}
+
+// track returns a message which completes the backlog of its partition when acked or nacked,
+// if it is the last message of the backlog or, in case the last message is missed, a later message.
+func (cu *catchUp) track(msg async.Message, m *sarama.ConsumerMessage) async.Message {
+	cu.mu.Lock()
+	defer cu.mu.Unlock()
+	last, ok := cu.pending[m.Partition]
+	if !ok || m.Offset < last {
+		return msg
+	}
+	return &caughtUpMessage{Message: msg, done: func() { cu.done(m.Partition) }}
+}
+
+func (cu *catchUp) done(partition int32) {
+	cu.mu.Lock()
+	_, ok := cu.pending[partition]
+	delete(cu.pending, partition)
+	caughtUp := ok && len(cu.pending) == 0
+	cu.mu.Unlock()
+	if caughtUp {
+		cu.caughtUp()
+	}
+}
+
+func (cu *catchUp) caughtUp() {
+	log.Infof("caught up with the messages of topic '%s'", cu.topic)
+	cu.onCaughtUp()
+}
+
+type caughtUpMessage struct {
+	async.Message
+	once sync.Once
+	done func()
+}
+
+// Ack acknowledges the message and completes the backlog of its partition.
+func (m *caughtUpMessage) Ack() error {
+	defer m.once.Do(m.done)
+	return m.Message.Ack()
+}
+
+// Nack nacks the message and completes the backlog of its partition.
+func (m *caughtUpMessage) Nack() error {
+	defer m.once.Do(m.done)
+	return m.Message.Nack()
+}
+
+// Unwrap returns the wrapped message.
+func (m *caughtUpMessage) Unwrap() async.Message {
+	return m.Message
+}
@@ -0,0 +1,112 @@
+package simple
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/beatlabs/patron/async"
+	"github.com/beatlabs/patron/async/kafka"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newCompactedBroker serves a partition of a compacted topic with a high-water mark of 6, whose backlog has the messages
+// of the offsets 0, 2 and 5, since the messages of the offsets 1, 3 and 4 have been compacted.
+func newCompactedBroker(t *testing.T) *sarama.MockBroker {
+	broker := sarama.NewMockBroker(t, 0)
+	broker.SetHandlerByMap(map[string]sarama.MockResponse{
+		"MetadataRequest": sarama.NewMockMetadataResponse(t).
+			SetBroker(broker.Addr(), broker.BrokerID()).
+			SetLeader(fooTopic, 0, broker.BrokerID()),
+		"OffsetRequest": sarama.NewMockOffsetResponse(t).
+			SetVersion(1).
+			SetOffset(fooTopic, 0, sarama.OffsetNewest, 6).
+			SetOffset(fooTopic, 0, sarama.OffsetOldest, 0),
+		"FetchRequest": sarama.NewMockFetchResponse(t, 1).SetVersion(4).
+			SetMessage(fooTopic, 0, 0, sarama.StringEncoder(`"0"`)).
+			SetMessage(fooTopic, 0, 2, sarama.StringEncoder(`"2"`)).
+			SetMessage(fooTopic, 0, 5, sarama.StringEncoder(`"5"`)).
+			SetMessage(fooTopic, 0, 7, sarama.StringEncoder(`"7"`)),
+	})
+	return broker
+}
+
+func TestConsumer_ReadToEndThen(t *testing.T) {
+	broker := newCompactedBroker(t)
+	defer broker.Close()
+	caughtUp := make(chan struct{})
+	f, err := New("name", fooTopic, []string{broker.Addr()}, kafka.DecoderJSON(), kafka.Version(sarama.V2_1_0_0.String()),
+		kafka.ReadToEndThen(func() { close(caughtUp) }))
+	require.NoError(t, err)
+	_, c, chMsg, chErr := consume(t, f)
+
+	receive := func() async.Message {
+		select {
+		case msg := <-chMsg:
+			return msg
+		case err := <-chErr:
+			t.Fatal(err)
+		case <-time.After(5 * time.Second):
+			t.Fatal("no message received")
+		}
+		return nil
+	}
+	assertPending := func() {
+		select {
+		case <-caughtUp:
+			t.Fatal("caught up before the backlog is processed")
+		default:
+		}
+	}
+
+	for _, want := range []string{"0", "2"} {
+		msg := receive()
+		var str string
+		require.NoError(t, msg.Decode(&str))
+		assert.Equal(t, want, str)
+		require.NoError(t, msg.Ack())
+		assertPending()
+	}
+	// the last message of the backlog completes it when processed
+	last := receive()
+	assertPending()
+	require.NoError(t, last.Nack())
+	select {
+	case <-caughtUp:
+	case <-time.After(time.Second):
+		t.Fatal("not caught up after the backlog is processed")
+	}
+
+	// the consumer continues tailing the topic
+	var str string
+	require.NoError(t, receive().Decode(&str))
+	assert.Equal(t, "7", str)
+	assert.NoError(t, c.Close())
+}
+
+func TestConsumer_ReadToEndThen_EmptyTopic(t *testing.T) {
+	broker := sarama.NewMockBroker(t, 0)
+	defer broker.Close()
+	broker.SetHandlerByMap(map[string]sarama.MockResponse{
+		"MetadataRequest": sarama.NewMockMetadataResponse(t).
+			SetBroker(broker.Addr(), broker.BrokerID()).
+			SetLeader(fooTopic, 0, broker.BrokerID()),
+		"OffsetRequest": sarama.NewMockOffsetResponse(t).
+			SetVersion(1).
+			SetOffset(fooTopic, 0, sarama.OffsetNewest, 3).
+			SetOffset(fooTopic, 0, sarama.OffsetOldest, 3),
+		"FetchRequest": sarama.NewMockFetchResponse(t, 1).SetVersion(4),
+	})
+	caughtUp := make(chan struct{})
+	f, err := New("name", fooTopic, []string{broker.Addr()}, kafka.DecoderJSON(), kafka.Version(sarama.V2_1_0_0.String()),
+		kafka.ReadToEndThen(func() { close(caughtUp) }))
+	require.NoError(t, err)
+	_, c, _, _ := consume(t, f)
+	select {
+	case <-caughtUp:
+	case <-time.After(time.Second):
+		t.Fatal("empty topic not caught up")
+	}
+	assert.NoError(t, c.Close())
+}
@@ -4,10 +4,15 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/Shopify/sarama"
 	"github.com/beatlabs/patron/async"
 	"github.com/beatlabs/patron/async/kafka"
+	patronErrors "github.com/beatlabs/patron/errors"
+	"github.com/beatlabs/patron/info"
 	"github.com/beatlabs/patron/log"
 )
 
@@ -39,51 +44,129 @@ func New(name, topic string, brokers []string, oo ...kafka.OptionFunc) (*Factory
 
 // Create a new consumer.
 func (f *Factory) Create() (async.Consumer, error) {
-
-	config, err := kafka.DefaultSaramaConfig(f.name)
+	cc, err := f.config()
 	if err != nil {
 		return nil, err
 	}
 
-	cc := kafka.ConsumerConfig{
-		Brokers:      f.brokers,
-		Buffer:       1000,
-		SaramaConfig: config,
-	}
-
-	c := &consumer{
+	info.AddDependency(info.Dependency{Type: "kafka", Name: f.topic, Direction: info.Inbound})
+	return &consumer{
+		name:   f.name,
 		topic:  f.topic,
 		config: cc,
+		status: kafka.NewConsumerStatus("kafka-simple"),
+	}, nil
+}
+
+// Info returns the topic and a summary of the effective sarama config of the consumers, without credentials.
+func (f *Factory) Info() map[string]interface{} {
+	in := map[string]interface{}{
+		"type":    "kafka-simple",
+		"topic":   f.topic,
+		"brokers": f.brokers,
+	}
+	cc, err := f.config()
+	if err != nil {
+		in["error"] = err.Error()
+		return in
 	}
+	in["sarama"] = kafka.SaramaConfigInfo(cc.SaramaConfig)
+	return in
+}
 
-	for _, o := range f.oo {
-		err = o(&c.config)
-		if err != nil {
-			return nil, err
-		}
+// config returns the config of the consumers, with the options applied.
+func (f *Factory) config() (kafka.ConsumerConfig, error) {
+	config, err := kafka.DefaultSaramaConfig(f.name)
+	if err != nil {
+		return kafka.ConsumerConfig{}, err
+	}
+
+	cc := kafka.ConsumerConfig{
+		Brokers:         f.brokers,
+		Buffer:          1000,
+		SaramaConfig:    config,
+		MessageTags:     kafka.DefaultMessageTags,
+		TraceSampleRate: 1,
 	}
 
-	return c, nil
+	cc, err = kafka.ApplyOptions(cc, f.oo...)
+	if err != nil {
+		return kafka.ConsumerConfig{}, err
+	}
+	return cc, nil
 }
 
+// defaultWorkers is the number of workers of a consumer without the kafka.Workers option,
+// which preserves the ordering of the messages of each partition.
+const defaultWorkers = 1
+
+// claimMessage is replaced in tests.
+var claimMessage = kafka.ClaimMessage
+
 // consumer members can be injected or overwritten with the usage of OptionFunc arguments.
 type consumer struct {
+	name   string
 	topic  string
 	cnl    context.CancelFunc
+	wg     *sync.WaitGroup
+	client sarama.Client
 	ms     sarama.Consumer
 	config kafka.ConsumerConfig
+	// caughtUp tracks the backlog of the partitions, when the consumer reads to the end of the topic.
+	caughtUp *catchUp
+	// status tracks the partition readers and the workers, which is dumped by name while consuming.
+	status *kafka.ConsumerStatus
+	// heartbeat logs periodically that the consumer is alive, when the kafka.HeartbeatLog option is provided.
+	heartbeat *kafka.Heartbeat
+	// dedup collapses the identical errors of the messages, when the kafka.ErrorDedupWindow option is provided.
+	dedup *kafka.ErrorDedup
+	// closeOnce closes the clients once, either when closing the consumer or when a partition fails.
+	closeOnce sync.Once
+	closeErr  error
 }
 
-// Close handles closing consumer.
+// Close handles closing consumer, after the partition readers and the workers have stopped.
 func (c *consumer) Close() error {
 	if c.cnl != nil {
 		c.cnl()
 	}
+	if c.wg != nil {
+		c.wg.Wait()
+		async.UnregisterDump(c.name)
+	}
+	c.dedup.Flush()
 
-	return nil
+	return c.closeClients()
+}
+
+// closeClients closes the sarama consumer and the client, unless the client is shared and owned by the caller.
+func (c *consumer) closeClients() error {
+	c.closeOnce.Do(func() {
+		c.closeErr = c.doCloseClients()
+	})
+	return c.closeErr
+}
+
+func (c *consumer) doCloseClients() error {
+	var ee []error
+	if c.ms != nil {
+		err := c.ms.Close()
+		if err != nil {
+			ee = append(ee, fmt.Errorf("failed to close simple consumer: %w", err))
+		}
+	}
+	if c.client != nil && c.config.Client == nil && !c.client.Closed() {
+		err := c.client.Close()
+		if err != nil {
+			ee = append(ee, fmt.Errorf("failed to close client: %w", err))
+		}
+	}
+	return patronErrors.Aggregate(ee...)
 }
 
 // Consume starts consuming messages from a Kafka topic.
+// The messages of all partitions are claimed by a fixed pool of workers, which is sized with the kafka.Workers option.
+// A single worker delivers the messages of each partition in order, while multiple workers may reorder them.
 func (c *consumer) Consume(ctx context.Context) (<-chan async.Message, <-chan error, error) {
 	ctx, cnl := context.WithCancel(ctx)
 	c.cnl = cnl
@@ -94,71 +177,362 @@ func (c *consumer) Consume(ctx context.Context) (<-chan async.Message, <-chan er
 	log.Infof("consuming messages from topic '%s' without using consumer group", c.topic)
 	pcs, err := c.partitions()
 	if err != nil {
+		c.releaseClients()
 		return nil, nil, fmt.Errorf("failed to get partitions: %w", err)
 	}
-	// When kafka cluster is not fully initialized, we may get 0 partitions.
-	if len(pcs) == 0 {
-		return nil, nil, errors.New("got 0 partitions")
+	var limiter *kafka.InFlightLimiter
+	if c.config.MaxInFlight > 0 {
+		// the limit is validated by the option
+		limiter, _ = kafka.NewInFlightLimiter(c.config.MaxInFlight)
 	}
 
+	c.run(ctx, pcs, chMsg, chErr, limiter)
+
+	return chMsg, chErr, nil
+}
+
+// run starts the partition readers and the workers. A failing partition stops the consumer,
+// so that the rest of the partitions are not consumed by a partially failed consumer. The partition consumers
+// are closed by their readers, while the clients are closed and the message channel is closed after all of them have stopped,
+// which tells the caller that the consumer has stopped.
+func (c *consumer) run(ctx context.Context, pcs []sarama.PartitionConsumer, chMsg chan async.Message, chErr chan error,
+	limiter *kafka.InFlightLimiter) {
+	// the heartbeat counts the messages delivered by the workers
+	c.heartbeat = kafka.NewHeartbeat(c.name, c.config.HeartbeatInterval, c.status)
+	c.dedup = kafka.NewErrorDedup(c.name, c.config.ErrorDedupWindow)
+	stuck := kafka.NewStuckDetector(c.name, c.config.StuckPartitionTimeout, c.status)
+
+	workers := c.config.Workers
+	if workers == 0 {
+		workers = defaultWorkers
+	}
+	jobs := make(chan *sarama.ConsumerMessage)
+	wg := &sync.WaitGroup{}
+	var failed int32
+	wg.Add(len(pcs) + workers)
 	for _, pc := range pcs {
-		go func(consumer sarama.PartitionConsumer) {
-			for {
+		go func(pc sarama.PartitionConsumer) {
+			defer wg.Done()
+			if !readPartition(ctx, pc, jobs, chErr, limiter, c.status, c.dedup) {
+				atomic.StoreInt32(&failed, 1)
+				c.cnl()
+			}
+		}(pc)
+	}
+	for i := 0; i < workers; i++ {
+		c.status.SetWorker(i, kafka.WorkerIdle, "", 0, -1)
+		go func(id int) {
+			defer wg.Done()
+			c.work(ctx, id, jobs, chMsg, chErr, limiter)
+		}(i)
+	}
+	c.wg = wg
+	async.RegisterDump(c.name, func() interface{} { return c.status.Dump() })
+	go c.heartbeat.Run(ctx)
+	go stuck.Run(ctx)
+	if c.caughtUp != nil {
+		c.caughtUp.start()
+	}
+	go func() {
+		wg.Wait()
+		if atomic.LoadInt32(&failed) == 1 {
+			log.Errorf("consumer of topic '%s' stopped due to a partition failure", c.topic)
+			c.releaseClients()
+			close(chMsg)
+		}
+	}()
+}
+
+// readPartition passes the messages of the partition to the workers until the context is done
+// or the partition consumer fails, in which case it returns false after reporting the error,
+// unless it repeats the error of another partition.
+func readPartition(ctx context.Context, pc sarama.PartitionConsumer, jobs chan<- *sarama.ConsumerMessage, chErr chan<- error,
+	limiter *kafka.InFlightLimiter, status *kafka.ConsumerStatus, dedup *kafka.ErrorDedup) bool {
+	defer closePartitionConsumer(pc)
+	for {
+		select {
+		case <-ctx.Done():
+			log.Info("canceling consuming messages requested")
+			return true
+		case err := <-pc.Errors():
+			if !dedup.Allow(err) {
+				return false
+			}
+			select {
+			case chErr <- err:
+			case <-ctx.Done():
+			}
+			return false
+		case m := <-pc.Messages():
+			kafka.TopicPartitionOffsetDiffGaugeSet("", m.Topic, m.Partition, pc.HighWaterMarkOffset(), m.Offset)
+			if limiter != nil {
+				status.SetPartition(m.Topic, m.Partition, kafka.PartitionThrottled, m.Offset, pc.HighWaterMarkOffset())
+				if limiter.Acquire(ctx) != nil {
+					log.Info("canceling consuming messages requested")
+					return true
+				}
+			}
+			status.SetPartition(m.Topic, m.Partition, kafka.PartitionDelivering, m.Offset, pc.HighWaterMarkOffset())
+			select {
+			case jobs <- m:
+				status.SetPartitionState(m.Topic, m.Partition, kafka.PartitionFetching)
+			case <-ctx.Done():
+				if limiter != nil {
+					limiter.Release()
+				}
+				return true
+			}
+		}
+	}
+}
+
+// work claims the messages of the partitions and delivers them until the context is done.
+// Messages which are not delivered are not acked, so they are consumed again, preserving the at-least-once delivery.
+func (c *consumer) work(ctx context.Context, id int, jobs <-chan *sarama.ConsumerMessage, chMsg chan<- async.Message, chErr chan<- error,
+	limiter *kafka.InFlightLimiter) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case m := <-jobs:
+			msg, err := claimMessage(ctx, m, c.config.DecoderFunc, nil, c.config.ClaimOptions(""))
+			if err != nil {
+				if limiter != nil {
+					limiter.Release()
+				}
+				if !c.dedup.Allow(err) {
+					continue
+				}
 				select {
+				case chErr <- err:
 				case <-ctx.Done():
-					log.Info("canceling consuming messages requested")
-					closePartitionConsumer(consumer)
-					return
-				case consumerError := <-consumer.Errors():
-					closePartitionConsumer(consumer)
-					chErr <- consumerError
 					return
-				case m := <-consumer.Messages():
-					kafka.TopicPartitionOffsetDiffGaugeSet("", m.Topic, m.Partition, consumer.HighWaterMarkOffset(), m.Offset)
-
-					go func(message *sarama.ConsumerMessage) {
-						msg, err := kafka.ClaimMessage(ctx, message, c.config.DecoderFunc, nil)
-						if err != nil {
-							chErr <- err
-							return
-						}
-						chMsg <- msg
-					}(m)
 				}
+				continue
 			}
-		}(pc)
+			if c.caughtUp != nil {
+				msg = c.caughtUp.track(msg, m)
+			}
+			if limiter != nil {
+				msg = limiter.Track(msg)
+			}
+			c.status.SetWorker(id, kafka.WorkerDelivering, m.Topic, m.Partition, m.Offset)
+			select {
+			case chMsg <- msg:
+				c.heartbeat.Processed()
+				c.status.SetWorker(id, kafka.WorkerIdle, "", 0, -1)
+			case <-ctx.Done():
+				if limiter != nil {
+					limiter.Release()
+				}
+				return
+			}
+		}
 	}
-
-	return chMsg, chErr, nil
 }
 
 func (c *consumer) partitions() ([]sarama.PartitionConsumer, error) {
 
-	ms, err := sarama.NewConsumer(c.config.Brokers, c.config.SaramaConfig)
+	client := c.config.Client
+	if client == nil {
+		var err error
+		client, err = sarama.NewClient(c.config.Brokers, c.config.SaramaConfig)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create client: %w", err)
+		}
+	}
+	c.client = client
+
+	ms, err := sarama.NewConsumerFromClient(client)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create simple consumer: %w", err)
 	}
 	c.ms = ms
 
-	partitions, err := c.ms.Partitions(c.topic)
+	var partitions []int32
+	if c.config.LeaderWaitTimeout > 0 {
+		deadline := time.Now().Add(c.config.LeaderWaitTimeout)
+		err = retryWithBackoff(deadline, func() error {
+			partitions, err = c.topicPartitions()
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+		err = waitForLeaders(c.client, c.topic, partitions, deadline)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		partitions, err = c.topicPartitions()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if c.config.OnCaughtUp != nil {
+		c.caughtUp, err = newCatchUp(c.client, c.topic, partitions, c.config.OnCaughtUp)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	offsets, err := startOffsets(c.client, c.topic, partitions, c.config.StartTimestamp, c.config.SaramaConfig.Consumer.Offsets.Initial,
+		c.messageTimestamp)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get partitions: %w", err)
+		return nil, err
 	}
 
 	pcs := make([]sarama.PartitionConsumer, len(partitions))
 
 	for i, partition := range partitions {
 
-		pc, err := c.ms.ConsumePartition(c.topic, partition, c.config.SaramaConfig.Consumer.Offsets.Initial)
+		pc, err := c.ms.ConsumePartition(c.topic, partition, offsets[partition])
 		if nil != err {
+			for _, created := range pcs[:i] {
+				closePartitionConsumer(created)
+			}
 			return nil, fmt.Errorf("failed to get partition consumer: %w", err)
 		}
 		pcs[i] = pc
+		c.status.SetPartition(c.topic, partition, kafka.PartitionFetching, -1, pc.HighWaterMarkOffset())
 	}
 
 	return pcs, nil
 }
 
+// startOffsets returns the starting offset of every partition, which is the initial offset of the consumer unless a start timestamp is set,
+// in which case it is the offset of the first message at or after the timestamp. Partitions without such a message fall back to the initial offset.
+// The timestamp of the earliest available message, returned by timestampOf, is compared to the start timestamp, in order to fail
+// when the messages since the start timestamp have been deleted.
+func startOffsets(client sarama.Client, topic string, partitions []int32, ts time.Time, initial int64,
+	timestampOf func(partition int32, offset int64) (time.Time, error)) (map[int32]int64, error) {
+	offsets := make(map[int32]int64, len(partitions))
+	for _, p := range partitions {
+		offsets[p] = initial
+		if ts.IsZero() {
+			continue
+		}
+		millis := ts.UnixNano() / int64(time.Millisecond)
+		offset, err := client.GetOffset(topic, p, millis)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get offset of partition %d at %v: %w", p, ts, err)
+		}
+		if offset == sarama.OffsetNewest {
+			log.Infof("no message of partition %d of topic '%s' at or after %v, consuming from the initial offset", p, topic, ts)
+			continue
+		}
+		oldest, err := client.GetOffset(topic, p, sarama.OffsetOldest)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get oldest offset of partition %d: %w", p, err)
+		}
+		// the earliest available message is returned for a timestamp before it, in which case the messages since the timestamp
+		// have been deleted, unless the partition has not deleted any message yet
+		if offset == oldest && oldest > 0 {
+			earliest, err := timestampOf(p, oldest)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get the timestamp of the earliest available offset %d of partition %d: %w", oldest, p, err)
+			}
+			// the timestamps of the messages have a millisecond precision
+			if millis < earliest.UnixNano()/int64(time.Millisecond) {
+				return nil, fmt.Errorf("timestamp %v is before the earliest available message at %v, offset %d, of partition %d of topic '%s'",
+					ts, earliest, oldest, p, topic)
+			}
+		}
+		offsets[p] = offset
+	}
+	return offsets, nil
+}
+
+// messageTimestamp returns the timestamp of the message of the partition at the offset,
+// which is consumed with a partition consumer closed before the partition is consumed.
+func (c *consumer) messageTimestamp(partition int32, offset int64) (time.Time, error) {
+	pc, err := c.ms.ConsumePartition(c.topic, partition, offset)
+	if err != nil {
+		return time.Time{}, err
+	}
+	defer closePartitionConsumer(pc)
+	tm := time.NewTimer(c.config.SaramaConfig.Net.ReadTimeout)
+	defer tm.Stop()
+	select {
+	case msg := <-pc.Messages():
+		return msg.Timestamp, nil
+	case consumerErr := <-pc.Errors():
+		return time.Time{}, consumerErr
+	case <-tm.C:
+		return time.Time{}, errors.New("timed out waiting for the message")
+	}
+}
+
+// releaseClients closes the clients after a failure to start consuming.
+func (c *consumer) releaseClients() {
+	err := c.closeClients()
+	if err != nil {
+		log.Errorf("failed to release clients of topic '%s': %v", c.topic, err)
+	}
+}
+
+const (
+	leaderWaitInitialBackoff = 50 * time.Millisecond
+	leaderWaitMaxBackoff     = time.Second
+)
+
+// topicPartitions returns the partitions of the topic, distinguishing a topic which does not exist
+// from a topic which has no partitions yet.
+func (c *consumer) topicPartitions() ([]int32, error) {
+	partitions, err := c.ms.Partitions(c.topic)
+	if err != nil {
+		if errors.Is(err, sarama.ErrUnknownTopicOrPartition) {
+			return nil, fmt.Errorf("%w: %s", kafka.ErrTopicNotFound, c.topic)
+		}
+		return nil, err
+	}
+	// When kafka cluster is not fully initialized, we may get 0 partitions.
+	if len(partitions) == 0 {
+		return nil, fmt.Errorf("got 0 partitions for topic '%s'", c.topic)
+	}
+	return partitions, nil
+}
+
+// waitForLeaders waits with an exponential backoff until all partitions have a leader or the deadline expires.
+func waitForLeaders(client sarama.Client, topic string, partitions []int32, deadline time.Time) error {
+	return retryWithBackoff(deadline, func() error {
+		var pending []int32
+		for _, p := range partitions {
+			_, err := client.Leader(topic, p)
+			if err != nil {
+				pending = append(pending, p)
+			}
+		}
+		if len(pending) == 0 {
+			return nil
+		}
+		return fmt.Errorf("partitions %v of topic '%s' have no leader", pending, topic)
+	})
+}
+
+// retryWithBackoff calls fn with an exponential backoff until it succeeds or the deadline expires,
+// in which case the last error is returned.
+func retryWithBackoff(deadline time.Time, fn func() error) error {
+	backoff := leaderWaitInitialBackoff
+	for {
+		err := fn()
+		if err == nil {
+			return nil
+		}
+		if time.Now().Add(backoff).After(deadline) {
+			log.Errorf("giving up after retrying: %v", err)
+			return err
+		}
+		log.Warnf("%v, retrying in %v", err, backoff)
+		time.Sleep(backoff)
+		backoff *= 2
+		if backoff > leaderWaitMaxBackoff {
+			backoff = leaderWaitMaxBackoff
+		}
+	}
+}
+
 func closePartitionConsumer(cns sarama.PartitionConsumer) {
 	if cns == nil {
 		return
@@ -2,12 +2,20 @@ package simple
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/Shopify/sarama"
 	"github.com/beatlabs/patron/async"
 	"github.com/beatlabs/patron/async/kafka"
+	"github.com/beatlabs/patron/encoding"
+	"github.com/beatlabs/patron/info"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 const fooTopic = "foo_topic"
@@ -92,6 +100,28 @@ func TestFactory_Create(t *testing.T) {
 	}
 }
 
+func TestFactory_Create_Buffer(t *testing.T) {
+	f, err := New("test", "topic", []string{"192.168.1.1"})
+	require.NoError(t, err)
+	got, err := f.Create()
+	require.NoError(t, err)
+	assert.Equal(t, 1000, got.(*consumer).config.Buffer)
+
+	f, err = New("test", "topic", []string{"192.168.1.1"}, kafka.Buffer(50))
+	require.NoError(t, err)
+	got, err = f.Create()
+	require.NoError(t, err)
+	assert.Equal(t, 50, got.(*consumer).config.Buffer)
+}
+
+func TestFactory_Create_Dependency(t *testing.T) {
+	f, err := New("test", "consumed-topic", []string{"192.168.1.1"})
+	require.NoError(t, err)
+	_, err = f.Create()
+	require.NoError(t, err)
+	assert.Contains(t, info.Dependencies(), info.Dependency{Type: "kafka", Name: "consumed-topic", Direction: info.Inbound})
+}
+
 func newBroker(t *testing.T, topic string) *sarama.MockBroker {
 	broker := sarama.NewMockBroker(t, 0)
 	broker.SetHandlerByMap(map[string]sarama.MockResponse{
@@ -147,7 +177,7 @@ func TestConsumer_ConsumeFromOldest(t *testing.T) {
 	ctx.Done()
 }
 
-func TestConsumer_ClaimMessageError(t *testing.T) {
+func TestConsumer_DefaultDecoder(t *testing.T) {
 	broker := newBroker(t, fooTopic)
 
 	f, err := New("name", fooTopic, []string{broker.Addr()}, kafka.Version(sarama.V2_1_0_0.String()), kafka.StartFromNewest())
@@ -156,10 +186,12 @@ func TestConsumer_ClaimMessageError(t *testing.T) {
 	ctx, c, chMsg, chErr := consume(t, f)
 
 	select {
-	case <-chMsg:
-		t.Error("Message arrived in message channel")
+	case msg := <-chMsg:
+		var str string
+		assert.NoError(t, msg.Decode(&str))
+		assert.Equal(t, "Foo", str)
 	case err = <-chErr:
-		assert.Error(t, err)
+		t.Fatal(err)
 	}
 
 	err = c.Close()
@@ -247,3 +279,658 @@ func TestConsumer_NoLeaderError(t *testing.T) {
 	assert.NoError(t, err)
 	broker.Close()
 }
+
+func TestConsumer_WaitForLeader(t *testing.T) {
+	broker := sarama.NewMockBroker(t, 0)
+	broker.SetHandlerByMap(map[string]sarama.MockResponse{
+		"MetadataRequest": sarama.NewMockMetadataResponse(t).
+			SetBroker(broker.Addr(), broker.BrokerID()).
+			SetLeader(fooTopic, 0, 123),
+	})
+	defer broker.Close()
+
+	f, err := New("name", fooTopic, []string{broker.Addr()}, kafka.DecoderJSON(), kafka.Version(sarama.V2_1_0_0.String()),
+		kafka.StartFromNewest(), kafka.LeaderWaitTimeout(5*time.Second))
+	assert.NoError(t, err)
+
+	go func() {
+		time.Sleep(200 * time.Millisecond)
+		broker.SetHandlerByMap(map[string]sarama.MockResponse{
+			"MetadataRequest": sarama.NewMockMetadataResponse(t).
+				SetBroker(broker.Addr(), broker.BrokerID()).
+				SetLeader(fooTopic, 0, broker.BrokerID()),
+			"OffsetRequest": sarama.NewMockOffsetResponse(t).
+				SetVersion(1).
+				SetOffset(fooTopic, 0, sarama.OffsetNewest, 10).
+				SetOffset(fooTopic, 0, sarama.OffsetOldest, 0),
+			"FetchRequest": sarama.NewMockFetchResponse(t, 1).
+				SetVersion(4).
+				SetMessage(fooTopic, 0, 10, sarama.StringEncoder(`"Foo"`)),
+		})
+	}()
+
+	_, c, chMsg, chErr := consume(t, f)
+
+	select {
+	case msg := <-chMsg:
+		var str string
+		assert.NoError(t, msg.Decode(&str))
+		assert.Equal(t, "Foo", str)
+	case err = <-chErr:
+		t.Fatal(err)
+	}
+
+	assert.NoError(t, c.Close())
+}
+
+func TestConsumer_WaitForLeaderTimeout(t *testing.T) {
+	broker := sarama.NewMockBroker(t, 0)
+	broker.SetHandlerByMap(map[string]sarama.MockResponse{
+		"MetadataRequest": sarama.NewMockMetadataResponse(t).
+			SetBroker(broker.Addr(), broker.BrokerID()).
+			SetLeader(fooTopic, 0, 123),
+	})
+	defer broker.Close()
+
+	f, err := New("name", fooTopic, []string{broker.Addr()}, kafka.Version(sarama.V2_1_0_0.String()),
+		kafka.LeaderWaitTimeout(300*time.Millisecond))
+	assert.NoError(t, err)
+
+	c, err := f.Create()
+	assert.NoError(t, err)
+	_, _, err = c.Consume(context.Background())
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "have no leader")
+	assert.NoError(t, c.Close())
+}
+
+func TestConsumer_SharedClient(t *testing.T) {
+	broker := newBroker(t, fooTopic)
+	defer broker.Close()
+	cfg := sarama.NewConfig()
+	cfg.Version = sarama.V2_1_0_0
+	cfg.Consumer.Return.Errors = true
+	client, err := sarama.NewClient([]string{broker.Addr()}, cfg)
+	assert.NoError(t, err)
+
+	f, err := New("name", fooTopic, []string{broker.Addr()}, kafka.DecoderJSON(), kafka.StartFromNewest(), kafka.Client(client))
+	assert.NoError(t, err)
+
+	_, c, chMsg, chErr := consume(t, f)
+
+	select {
+	case msg := <-chMsg:
+		var str string
+		assert.NoError(t, msg.Decode(&str))
+		assert.Equal(t, "Foo", str)
+	case err = <-chErr:
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, client, c.(*consumer).client)
+	assert.NoError(t, c.Close())
+	// the shared client is owned by the caller
+	assert.False(t, client.Closed())
+	assert.NoError(t, client.Close())
+}
+
+func TestConsumer_ClientClosedOnFailure(t *testing.T) {
+	broker := sarama.NewMockBroker(t, 0)
+	broker.SetHandlerByMap(map[string]sarama.MockResponse{
+		"MetadataRequest": sarama.NewMockMetadataResponse(t).
+			SetBroker(broker.Addr(), broker.BrokerID()).
+			SetLeader(fooTopic, 0, 123),
+	})
+	defer broker.Close()
+
+	f, err := New("name", fooTopic, []string{broker.Addr()}, kafka.Version(sarama.V2_1_0_0.String()),
+		kafka.LeaderWaitTimeout(100*time.Millisecond))
+	assert.NoError(t, err)
+
+	c, err := f.Create()
+	assert.NoError(t, err)
+	_, _, err = c.Consume(context.Background())
+	assert.Error(t, err)
+
+	cns := c.(*consumer)
+	assert.NotNil(t, cns.ms)
+	assert.True(t, cns.client.Closed())
+	assert.NoError(t, c.Close())
+}
+
+func TestConsumer_SharedClientNotClosedOnFailure(t *testing.T) {
+	broker := sarama.NewMockBroker(t, 0)
+	broker.SetHandlerByMap(map[string]sarama.MockResponse{
+		"MetadataRequest": sarama.NewMockMetadataResponse(t).
+			SetBroker(broker.Addr(), broker.BrokerID()).
+			SetLeader(fooTopic, 0, 123),
+	})
+	defer broker.Close()
+	cfg := sarama.NewConfig()
+	cfg.Version = sarama.V2_1_0_0
+	cfg.Consumer.Return.Errors = true
+	client, err := sarama.NewClient([]string{broker.Addr()}, cfg)
+	assert.NoError(t, err)
+
+	f, err := New("name", fooTopic, []string{broker.Addr()}, kafka.Client(client),
+		kafka.LeaderWaitTimeout(100*time.Millisecond))
+	assert.NoError(t, err)
+
+	c, err := f.Create()
+	assert.NoError(t, err)
+	_, _, err = c.Consume(context.Background())
+	assert.Error(t, err)
+	assert.NoError(t, c.Close())
+	assert.False(t, client.Closed())
+	assert.NoError(t, client.Close())
+}
+
+func TestConsumer_MaxInFlight(t *testing.T) {
+	broker := sarama.NewMockBroker(t, 0)
+	broker.SetHandlerByMap(map[string]sarama.MockResponse{
+		"MetadataRequest": sarama.NewMockMetadataResponse(t).
+			SetBroker(broker.Addr(), broker.BrokerID()).
+			SetLeader(fooTopic, 0, broker.BrokerID()),
+		"OffsetRequest": sarama.NewMockOffsetResponse(t).
+			SetVersion(1).
+			SetOffset(fooTopic, 0, sarama.OffsetNewest, 10).
+			SetOffset(fooTopic, 0, sarama.OffsetOldest, 0),
+		"FetchRequest": sarama.NewMockFetchResponse(t, 2).
+			SetVersion(4).
+			SetMessage(fooTopic, 0, 10, sarama.StringEncoder(`"Foo"`)).
+			SetMessage(fooTopic, 0, 11, sarama.StringEncoder(`"Bar"`)),
+	})
+	defer broker.Close()
+
+	f, err := New("name", fooTopic, []string{broker.Addr()}, kafka.DecoderJSON(), kafka.Version(sarama.V2_1_0_0.String()),
+		kafka.StartFromNewest(), kafka.MaxInFlight(1))
+	assert.NoError(t, err)
+
+	_, c, chMsg, chErr := consume(t, f)
+
+	var msg async.Message
+	select {
+	case msg = <-chMsg:
+	case err = <-chErr:
+		t.Fatal(err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("no message received")
+	}
+
+	// consumption pauses until the message in flight is acked
+	select {
+	case <-chMsg:
+		t.Fatal("message received over the in flight limit")
+	case err = <-chErr:
+		t.Fatal(err)
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	assert.NoError(t, msg.Ack())
+	select {
+	case msg = <-chMsg:
+		var str string
+		assert.NoError(t, msg.Decode(&str))
+		assert.Equal(t, "Bar", str)
+	case err = <-chErr:
+		t.Fatal(err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("consumption not resumed after ack")
+	}
+
+	assert.NoError(t, c.Close())
+}
+
+func TestConsumer_Dump(t *testing.T) {
+	broker := sarama.NewMockBroker(t, 0)
+	broker.SetHandlerByMap(map[string]sarama.MockResponse{
+		"MetadataRequest": sarama.NewMockMetadataResponse(t).
+			SetBroker(broker.Addr(), broker.BrokerID()).
+			SetLeader(fooTopic, 0, broker.BrokerID()),
+		"OffsetRequest": sarama.NewMockOffsetResponse(t).
+			SetVersion(1).
+			SetOffset(fooTopic, 0, sarama.OffsetNewest, 10).
+			SetOffset(fooTopic, 0, sarama.OffsetOldest, 0),
+		"FetchRequest": sarama.NewMockFetchResponse(t, 2).
+			SetVersion(4).
+			SetMessage(fooTopic, 0, 10, sarama.StringEncoder(`"Foo"`)).
+			SetMessage(fooTopic, 0, 11, sarama.StringEncoder(`"Bar"`)),
+	})
+	defer broker.Close()
+
+	f, err := New("dump", fooTopic, []string{broker.Addr()}, kafka.DecoderJSON(), kafka.Version(sarama.V2_1_0_0.String()),
+		kafka.StartFromNewest(), kafka.MaxInFlight(1))
+	assert.NoError(t, err)
+
+	_, c, chMsg, chErr := consume(t, f)
+	select {
+	case <-chMsg:
+	case err = <-chErr:
+		t.Fatal(err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("no message received")
+	}
+
+	// the partition waits for the message in flight to be acked, while the worker is idle
+	var d kafka.ConsumerDump
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		d, _ = async.Dumps()["dump"].(kafka.ConsumerDump)
+		if len(d.Partitions) == 1 && d.Partitions[0].State == kafka.PartitionThrottled {
+			break
+		}
+	}
+	require.Len(t, d.Partitions, 1)
+	assert.Equal(t, fooTopic, d.Partitions[0].Topic)
+	assert.Equal(t, int32(0), d.Partitions[0].Partition)
+	assert.Equal(t, kafka.PartitionThrottled, d.Partitions[0].State)
+	assert.Equal(t, int64(11), d.Partitions[0].Offset)
+	assert.Equal(t, "kafka-simple", d.Type)
+	require.Len(t, d.Workers, 1)
+	assert.Equal(t, kafka.WorkerIdle, d.Workers[0].State)
+
+	assert.NoError(t, c.Close())
+	assert.NotContains(t, async.Dumps(), "dump")
+}
+
+func TestConsumer_TopicNotFound(t *testing.T) {
+	broker := sarama.NewMockBroker(t, 0)
+	broker.SetHandlerByMap(map[string]sarama.MockResponse{
+		"MetadataRequest": sarama.NewMockMetadataResponse(t).
+			SetBroker(broker.Addr(), broker.BrokerID()),
+	})
+	defer broker.Close()
+
+	f, err := New("name", fooTopic, []string{broker.Addr()}, kafka.Version(sarama.V2_1_0_0.String()))
+	assert.NoError(t, err)
+
+	c, err := f.Create()
+	assert.NoError(t, err)
+	_, _, err = c.Consume(context.Background())
+	assert.True(t, errors.Is(err, kafka.ErrTopicNotFound))
+	assert.NoError(t, c.Close())
+}
+
+func TestConsumer_WaitForTopic(t *testing.T) {
+	broker := sarama.NewMockBroker(t, 0)
+	broker.SetHandlerByMap(map[string]sarama.MockResponse{
+		"MetadataRequest": sarama.NewMockMetadataResponse(t).
+			SetBroker(broker.Addr(), broker.BrokerID()),
+	})
+	defer broker.Close()
+
+	f, err := New("name", fooTopic, []string{broker.Addr()}, kafka.DecoderJSON(), kafka.Version(sarama.V2_1_0_0.String()),
+		kafka.StartFromNewest(), kafka.LeaderWaitTimeout(5*time.Second))
+	assert.NoError(t, err)
+
+	go func() {
+		time.Sleep(200 * time.Millisecond)
+		broker.SetHandlerByMap(map[string]sarama.MockResponse{
+			"MetadataRequest": sarama.NewMockMetadataResponse(t).
+				SetBroker(broker.Addr(), broker.BrokerID()).
+				SetLeader(fooTopic, 0, broker.BrokerID()),
+			"OffsetRequest": sarama.NewMockOffsetResponse(t).
+				SetVersion(1).
+				SetOffset(fooTopic, 0, sarama.OffsetNewest, 10).
+				SetOffset(fooTopic, 0, sarama.OffsetOldest, 0),
+			"FetchRequest": sarama.NewMockFetchResponse(t, 1).
+				SetVersion(4).
+				SetMessage(fooTopic, 0, 10, sarama.StringEncoder(`"Foo"`)),
+		})
+	}()
+
+	_, c, chMsg, chErr := consume(t, f)
+
+	select {
+	case msg := <-chMsg:
+		var str string
+		assert.NoError(t, msg.Decode(&str))
+		assert.Equal(t, "Foo", str)
+	case err = <-chErr:
+		t.Fatal(err)
+	}
+
+	assert.NoError(t, c.Close())
+}
+
+func newMultiMessageBroker(t *testing.T, messages int) *sarama.MockBroker {
+	broker := sarama.NewMockBroker(t, 0)
+	fetch := sarama.NewMockFetchResponse(t, messages).SetVersion(4)
+	for i := 0; i < messages; i++ {
+		fetch = fetch.SetMessage(fooTopic, 0, int64(10+i), sarama.StringEncoder(fmt.Sprintf(`"%d"`, i)))
+	}
+	broker.SetHandlerByMap(map[string]sarama.MockResponse{
+		"MetadataRequest": sarama.NewMockMetadataResponse(t).
+			SetBroker(broker.Addr(), broker.BrokerID()).
+			SetLeader(fooTopic, 0, broker.BrokerID()),
+		"OffsetRequest": sarama.NewMockOffsetResponse(t).
+			SetVersion(1).
+			SetOffset(fooTopic, 0, sarama.OffsetNewest, 10).
+			SetOffset(fooTopic, 0, sarama.OffsetOldest, 0),
+		"FetchRequest": fetch,
+	})
+	return broker
+}
+
+func TestConsumer_SingleWorkerOrdering(t *testing.T) {
+	broker := newMultiMessageBroker(t, 10)
+	defer broker.Close()
+
+	f, err := New("name", fooTopic, []string{broker.Addr()}, kafka.DecoderJSON(), kafka.Version(sarama.V2_1_0_0.String()),
+		kafka.StartFromNewest(), kafka.Buffer(0))
+	assert.NoError(t, err)
+	_, c, chMsg, chErr := consume(t, f)
+
+	for i := 0; i < 10; i++ {
+		select {
+		case msg := <-chMsg:
+			var str string
+			assert.NoError(t, msg.Decode(&str))
+			assert.Equal(t, strconv.Itoa(i), str)
+		case err = <-chErr:
+			t.Fatal(err)
+		case <-time.After(5 * time.Second):
+			t.Fatal("no message received")
+		}
+	}
+	assert.NoError(t, c.Close())
+}
+
+func TestConsumer_Workers(t *testing.T) {
+	var active, maxActive int32
+	defer func(f func(context.Context, *sarama.ConsumerMessage, encoding.DecodeRawFunc, sarama.ConsumerGroupSession,
+		kafka.ClaimOptions) (async.Message, error)) {
+		claimMessage = f
+	}(claimMessage)
+	claimMessage = func(ctx context.Context, msg *sarama.ConsumerMessage, d encoding.DecodeRawFunc, sess sarama.ConsumerGroupSession,
+		opts kafka.ClaimOptions) (async.Message, error) {
+		n := atomic.AddInt32(&active, 1)
+		defer atomic.AddInt32(&active, -1)
+		for {
+			m := atomic.LoadInt32(&maxActive)
+			if n <= m || atomic.CompareAndSwapInt32(&maxActive, m, n) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+		return kafka.ClaimMessage(ctx, msg, d, sess, opts)
+	}
+
+	broker := newMultiMessageBroker(t, 20)
+	defer broker.Close()
+
+	f, err := New("name", fooTopic, []string{broker.Addr()}, kafka.DecoderJSON(), kafka.Version(sarama.V2_1_0_0.String()),
+		kafka.StartFromNewest(), kafka.Workers(3))
+	assert.NoError(t, err)
+	_, c, chMsg, chErr := consume(t, f)
+
+	for i := 0; i < 20; i++ {
+		select {
+		case <-chMsg:
+		case err = <-chErr:
+			t.Fatal(err)
+		case <-time.After(5 * time.Second):
+			t.Fatal("no message received")
+		}
+	}
+	assert.NoError(t, c.Close())
+	assert.True(t, atomic.LoadInt32(&maxActive) <= 3)
+	assert.True(t, atomic.LoadInt32(&maxActive) > 1)
+}
+
+func TestConsumer_CloseWithUndeliveredMessages(t *testing.T) {
+	broker := newMultiMessageBroker(t, 10)
+	defer broker.Close()
+
+	f, err := New("name", fooTopic, []string{broker.Addr()}, kafka.DecoderJSON(), kafka.Version(sarama.V2_1_0_0.String()),
+		kafka.StartFromNewest(), kafka.Buffer(0), kafka.Workers(2), kafka.MaxInFlight(5))
+	assert.NoError(t, err)
+	_, c, chMsg, _ := consume(t, f)
+
+	// the workers block delivering messages which are never read
+	select {
+	case <-chMsg:
+	case <-time.After(5 * time.Second):
+		t.Fatal("no message received")
+	}
+
+	closed := make(chan error)
+	go func() { closed <- c.Close() }()
+	select {
+	case err = <-closed:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("consumer not closed")
+	}
+
+	// no messages are delivered after closing
+	select {
+	case <-chMsg:
+		t.Fatal("message received after close")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestFactory_Info(t *testing.T) {
+	f, err := New("name", fooTopic, []string{"192.168.1.1"}, kafka.StartFromOldest())
+	assert.NoError(t, err)
+	in := f.Info()
+	assert.Equal(t, "kafka-simple", in["type"])
+	assert.Equal(t, fooTopic, in["topic"])
+	assert.Equal(t, "oldest", in["sarama"].(map[string]interface{})["consumer"].(map[string]interface{})["offsetsInitial"])
+
+	f, err = New("name", fooTopic, []string{"192.168.1.1"}, kafka.Buffer(-1))
+	assert.NoError(t, err)
+	in = f.Info()
+	assert.Contains(t, in, "error")
+	assert.NotContains(t, in, "sarama")
+}
+
+// offsetClient returns the offsets of the partitions at the timestamps in milliseconds, as well as their oldest offset.
+type offsetClient struct {
+	sarama.Client
+	offsets map[int32]map[int64]int64
+}
+
+func (c *offsetClient) GetOffset(_ string, partition int32, time int64) (int64, error) {
+	offset, ok := c.offsets[partition][time]
+	if !ok {
+		return 0, errors.New("offset not found")
+	}
+	return offset, nil
+}
+
+func Test_startOffsets(t *testing.T) {
+	ts := time.Unix(1000, 0)
+	ms := int64(1000000)
+	client := &offsetClient{offsets: map[int32]map[int64]int64{
+		// messages at or after the timestamp from offset 5
+		0: {ms: 5, sarama.OffsetOldest: 0},
+		// no message at or after the timestamp
+		1: {ms: sarama.OffsetNewest, sarama.OffsetOldest: 3},
+		// the topic starts after the timestamp
+		2: {ms: 0, sarama.OffsetOldest: 0},
+		// the messages since the timestamp have been deleted
+		3: {ms: 7, sarama.OffsetOldest: 7},
+		// the earliest available message is at the timestamp
+		5: {ms: 9, sarama.OffsetOldest: 9},
+		// the earliest available message precedes the timestamp, e.g. with out of order create times
+		6: {ms: 2, sarama.OffsetOldest: 2},
+	}}
+	earliest := map[int32]time.Time{3: ts.Add(time.Hour), 5: ts.Add(500 * time.Microsecond), 6: ts.Add(-time.Second)}
+	timestampOf := func(partition int32, offset int64) (time.Time, error) {
+		tm, ok := earliest[partition]
+		if !ok {
+			return time.Time{}, errors.New("message not found")
+		}
+		return tm, nil
+	}
+
+	got, err := startOffsets(client, fooTopic, []int32{0, 1, 2, 5, 6}, ts, sarama.OffsetNewest, timestampOf)
+	require.NoError(t, err)
+	assert.Equal(t, map[int32]int64{0: 5, 1: sarama.OffsetNewest, 2: 0, 5: 9, 6: 2}, got)
+
+	got, err = startOffsets(client, fooTopic, []int32{0, 1}, ts, sarama.OffsetOldest, timestampOf)
+	require.NoError(t, err)
+	assert.Equal(t, map[int32]int64{0: 5, 1: sarama.OffsetOldest}, got)
+
+	_, err = startOffsets(client, fooTopic, []int32{0, 3}, ts, sarama.OffsetNewest, timestampOf)
+	assert.EqualError(t, err, fmt.Sprintf("timestamp %v is before the earliest available message at %v, offset 7, of partition 3 of topic 'foo_topic'",
+		ts, ts.Add(time.Hour)))
+
+	_, err = startOffsets(client, fooTopic, []int32{4}, ts, sarama.OffsetNewest, timestampOf)
+	assert.Error(t, err)
+
+	delete(earliest, 3)
+	_, err = startOffsets(client, fooTopic, []int32{3}, ts, sarama.OffsetNewest, timestampOf)
+	assert.Error(t, err)
+
+	// without a timestamp the initial offset is used without requests
+	got, err = startOffsets(nil, fooTopic, []int32{0, 1}, time.Time{}, sarama.OffsetOldest, nil)
+	require.NoError(t, err)
+	assert.Equal(t, map[int32]int64{0: sarama.OffsetOldest, 1: sarama.OffsetOldest}, got)
+}
+
+// partitionsConsumer returns the partition consumers of the partitions.
+type partitionsConsumer struct {
+	sarama.Consumer
+	pcs map[int32]*fakePartitionConsumer
+}
+
+func (c *partitionsConsumer) ConsumePartition(_ string, partition int32, _ int64) (sarama.PartitionConsumer, error) {
+	pc, ok := c.pcs[partition]
+	if !ok {
+		return nil, errors.New("partition not found")
+	}
+	return pc, nil
+}
+
+func TestConsumer_messageTimestamp(t *testing.T) {
+	ts := time.Unix(1000, 0)
+	pcs := map[int32]*fakePartitionConsumer{0: newFakePartitionConsumer(), 1: newFakePartitionConsumer(), 2: newFakePartitionConsumer()}
+	cfg := sarama.NewConfig()
+	cfg.Net.ReadTimeout = 10 * time.Millisecond
+	c := &consumer{topic: fooTopic, ms: &partitionsConsumer{pcs: pcs}, config: kafka.ConsumerConfig{SaramaConfig: cfg}}
+	go func() { pcs[0].msgs <- &sarama.ConsumerMessage{Timestamp: ts} }()
+	go func() { pcs[1].errs <- &sarama.ConsumerError{Err: errors.New("fetch failed")} }()
+
+	got, err := c.messageTimestamp(0, 7)
+	require.NoError(t, err)
+	assert.Equal(t, ts, got)
+	_, err = c.messageTimestamp(1, 7)
+	assert.Error(t, err)
+	_, err = c.messageTimestamp(2, 7)
+	assert.EqualError(t, err, "timed out waiting for the message")
+	_, err = c.messageTimestamp(3, 7)
+	assert.Error(t, err)
+	for _, pc := range pcs {
+		assert.Equal(t, int32(1), atomic.LoadInt32(&pc.closed))
+	}
+}
+
+type fakePartitionConsumer struct {
+	sarama.PartitionConsumer
+	msgs   chan *sarama.ConsumerMessage
+	errs   chan *sarama.ConsumerError
+	closed int32
+}
+
+func newFakePartitionConsumer() *fakePartitionConsumer {
+	return &fakePartitionConsumer{msgs: make(chan *sarama.ConsumerMessage), errs: make(chan *sarama.ConsumerError)}
+}
+
+func (pc *fakePartitionConsumer) Messages() <-chan *sarama.ConsumerMessage { return pc.msgs }
+
+func (pc *fakePartitionConsumer) Errors() <-chan *sarama.ConsumerError { return pc.errs }
+
+func (pc *fakePartitionConsumer) HighWaterMarkOffset() int64 { return 0 }
+
+func (pc *fakePartitionConsumer) Close() error {
+	atomic.AddInt32(&pc.closed, 1)
+	return nil
+}
+
+func TestConsumer_PartitionError(t *testing.T) {
+	f, err := New("name", fooTopic, []string{"localhost:9092"}, kafka.DecoderJSON(), kafka.Buffer(1))
+	require.NoError(t, err)
+	cns, err := f.Create()
+	require.NoError(t, err)
+	c := cns.(*consumer)
+
+	ctx, cnl := context.WithCancel(context.Background())
+	c.cnl = cnl
+	failing, healthy := newFakePartitionConsumer(), newFakePartitionConsumer()
+	chMsg, chErr := make(chan async.Message, 1), make(chan error, 1)
+	c.run(ctx, []sarama.PartitionConsumer{failing, healthy}, chMsg, chErr, nil)
+
+	failing.errs <- &sarama.ConsumerError{Topic: fooTopic, Partition: 0, Err: errors.New("partition failure")}
+	assert.EqualError(t, <-chErr, "kafka: error while consuming foo_topic/0: partition failure")
+
+	// the message channel is closed after all partitions have stopped
+	select {
+	case _, ok := <-chMsg:
+		assert.False(t, ok)
+	case <-time.After(5 * time.Second):
+		t.Fatal("consumer not stopped")
+	}
+	assert.Equal(t, int32(1), atomic.LoadInt32(&failing.closed))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&healthy.closed))
+
+	// the healthy partition is not consumed anymore
+	select {
+	case healthy.msgs <- &sarama.ConsumerMessage{Topic: fooTopic, Partition: 1}:
+		t.Fatal("message consumed after the partition failure")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	assert.NoError(t, c.Close())
+	assert.Equal(t, int32(1), atomic.LoadInt32(&healthy.closed))
+}
+
+func TestConsumer_ErrorDedup(t *testing.T) {
+	defer func(f func(context.Context, *sarama.ConsumerMessage, encoding.DecodeRawFunc, sarama.ConsumerGroupSession,
+		kafka.ClaimOptions) (async.Message, error)) {
+		claimMessage = f
+	}(claimMessage)
+	claimMessage = func(ctx context.Context, msg *sarama.ConsumerMessage, d encoding.DecodeRawFunc, sess sarama.ConsumerGroupSession,
+		opts kafka.ClaimOptions) (async.Message, error) {
+		if msg.Offset < 3 {
+			return nil, errors.New("decoding failed")
+		}
+		return nil, errors.New("unknown content type")
+	}
+
+	f, err := New("name", fooTopic, []string{"localhost:9092"}, kafka.ErrorDedupWindow(time.Minute))
+	require.NoError(t, err)
+	cns, err := f.Create()
+	require.NoError(t, err)
+	c := cns.(*consumer)
+
+	ctx, cnl := context.WithCancel(context.Background())
+	c.cnl = cnl
+	pc := newFakePartitionConsumer()
+	chErr := make(chan error, 10)
+	c.run(ctx, []sarama.PartitionConsumer{pc}, make(chan async.Message), chErr, nil)
+	for i := 0; i < 4; i++ {
+		pc.msgs <- &sarama.ConsumerMessage{Topic: fooTopic, Offset: int64(i)}
+	}
+
+	// the repeated identical errors are collapsed, while the distinct error passes through
+	assert.EqualError(t, <-chErr, "decoding failed")
+	assert.EqualError(t, <-chErr, "unknown content type")
+	assert.NoError(t, c.Close())
+	assert.Empty(t, chErr)
+}
+
+func TestReadPartition_ErrorDedup(t *testing.T) {
+	dedup := kafka.NewErrorDedup("name", time.Minute)
+	chErr := make(chan error, 10)
+	for i := 0; i < 2; i++ {
+		pc := &fakePartitionConsumer{msgs: make(chan *sarama.ConsumerMessage), errs: make(chan *sarama.ConsumerError, 1)}
+		pc.errs <- &sarama.ConsumerError{Topic: fooTopic, Partition: 0, Err: sarama.ErrOutOfBrokers}
+		ok := readPartition(context.Background(), pc, nil, chErr, nil, kafka.NewConsumerStatus("kafka-simple"), dedup)
+		assert.False(t, ok)
+	}
+	// the repeated identical error is collapsed
+	assert.Len(t, chErr, 1)
+	dedup.Flush()
+}
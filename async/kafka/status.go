@@ -0,0 +1,193 @@
+package kafka
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// PartitionState is the state of the goroutine reading a partition of a consumer.
+type PartitionState string
+
+const (
+	// PartitionFetching waits for messages to be fetched from the broker.
+	PartitionFetching PartitionState = "fetching"
+	// PartitionThrottled waits for a message in flight to be acked or nacked, due to the in-flight limit.
+	PartitionThrottled PartitionState = "throttled"
+	// PartitionDelivering waits for a message to be taken by a worker or the processing component,
+	// which is usually due to slow processing.
+	PartitionDelivering PartitionState = "delivering"
+)
+
+// WorkerState is the state of a worker of a consumer, which claims the messages of the partitions and delivers them.
+type WorkerState string
+
+const (
+	// WorkerIdle waits for a message of the partitions.
+	WorkerIdle WorkerState = "idle"
+	// WorkerDelivering waits for a claimed message to be taken by the processing component.
+	WorkerDelivering WorkerState = "delivering"
+)
+
+// PartitionStatus is the processing position of a partition of a consumer.
+type PartitionStatus struct {
+	Topic     string         `json:"topic"`
+	Partition int32          `json:"partition"`
+	State     PartitionState `json:"state"`
+	// Offset is the offset of the last message read from the partition, which is -1 before the first message.
+	Offset        int64 `json:"offset"`
+	HighWaterMark int64 `json:"highWaterMark"`
+	// Since is the time the partition entered its state, so a long-lasting state indicates a stalled partition.
+	Since time.Time `json:"since"`
+	// Advanced is the time the offset of the partition last advanced, or the partition was assigned.
+	Advanced time.Time `json:"advanced"`
+}
+
+// lag returns the number of messages after the last message read, which is unknown before the first message.
+func (p PartitionStatus) lag() int64 {
+	if p.Offset < 0 || p.HighWaterMark <= p.Offset {
+		return 0
+	}
+	return p.HighWaterMark - p.Offset - 1
+}
+
+// WorkerStatus is the state of a worker of a consumer and the message it handles.
+type WorkerStatus struct {
+	ID        int         `json:"id"`
+	State     WorkerState `json:"state"`
+	Topic     string      `json:"topic,omitempty"`
+	Partition int32       `json:"partition"`
+	Offset    int64       `json:"offset"`
+	Since     time.Time   `json:"since"`
+}
+
+// ConsumerDump is the state of the partitions and the workers of a consumer, sorted by topic and partition and by worker id.
+type ConsumerDump struct {
+	Type       string            `json:"type"`
+	Partitions []PartitionStatus `json:"partitions"`
+	Workers    []WorkerStatus    `json:"workers,omitempty"`
+}
+
+type topicPartition struct {
+	topic     string
+	partition int32
+}
+
+// ConsumerStatus tracks the state of the goroutines of a consumer, in order to be dumped when the consumer stalls,
+// e.g. with async.RegisterDump, which is more targeted than a full goroutine dump. A nil status tracks nothing.
+type ConsumerStatus struct {
+	typ        string
+	mu         sync.Mutex
+	partitions map[topicPartition]*PartitionStatus
+	workers    map[int]*WorkerStatus
+}
+
+// NewConsumerStatus creates the status of a consumer of the provided type, e.g. kafka-simple.
+func NewConsumerStatus(typ string) *ConsumerStatus {
+	return &ConsumerStatus{
+		typ:        typ,
+		partitions: make(map[topicPartition]*PartitionStatus),
+		workers:    make(map[int]*WorkerStatus),
+	}
+}
+
+// SetPartition sets the state and the position of a partition.
+// The time of the state is kept while the partition stays in the same state.
+func (s *ConsumerStatus) SetPartition(topic string, partition int32, state PartitionState, offset, highWaterMark int64) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p := s.partition(topic, partition)
+	if p.Offset != offset {
+		p.Advanced = time.Now()
+	}
+	p.Offset = offset
+	p.HighWaterMark = highWaterMark
+	p.setState(state)
+}
+
+// SetPartitionState sets the state of a partition, keeping its position.
+func (s *ConsumerStatus) SetPartitionState(topic string, partition int32, state PartitionState) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.partition(topic, partition).setState(state)
+}
+
+// partition returns the status of a partition, adding it without a position if it is not tracked yet.
+func (s *ConsumerStatus) partition(topic string, partition int32) *PartitionStatus {
+	k := topicPartition{topic: topic, partition: partition}
+	p, ok := s.partitions[k]
+	if !ok {
+		p = &PartitionStatus{Topic: topic, Partition: partition, Offset: -1, HighWaterMark: -1, Advanced: time.Now()}
+		s.partitions[k] = p
+	}
+	return p
+}
+
+func (p *PartitionStatus) setState(state PartitionState) {
+	if p.State != state {
+		p.State = state
+		p.Since = time.Now()
+	}
+}
+
+// RemovePartition removes a partition which is no longer consumed, e.g. after a rebalance.
+func (s *ConsumerStatus) RemovePartition(topic string, partition int32) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.partitions, topicPartition{topic: topic, partition: partition})
+}
+
+// SetWorker sets the state of a worker and the message it handles.
+func (s *ConsumerStatus) SetWorker(id int, state WorkerState, topic string, partition int32, offset int64) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.workers[id] = &WorkerStatus{ID: id, State: state, Topic: topic, Partition: partition, Offset: offset, Since: time.Now()}
+}
+
+// Dump returns a copy of the status of the partitions and the workers.
+func (s *ConsumerStatus) Dump() ConsumerDump {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	d := ConsumerDump{Type: s.typ, Partitions: make([]PartitionStatus, 0, len(s.partitions))}
+	for _, p := range s.partitions {
+		d.Partitions = append(d.Partitions, *p)
+	}
+	sort.Slice(d.Partitions, func(i, j int) bool {
+		if d.Partitions[i].Topic != d.Partitions[j].Topic {
+			return d.Partitions[i].Topic < d.Partitions[j].Topic
+		}
+		return d.Partitions[i].Partition < d.Partitions[j].Partition
+	})
+	for _, w := range s.workers {
+		d.Workers = append(d.Workers, *w)
+	}
+	sort.Slice(d.Workers, func(i, j int) bool { return d.Workers[i].ID < d.Workers[j].ID })
+	return d
+}
+
+// StuckPartitions returns the partitions whose offset has not advanced for the timeout, although they have messages to consume,
+// sorted by topic and partition, e.g. due to a poison message which fails the processing.
+func (s *ConsumerStatus) StuckPartitions(timeout time.Duration) []PartitionStatus {
+	if s == nil {
+		return nil
+	}
+	var pp []PartitionStatus
+	for _, p := range s.Dump().Partitions {
+		if p.lag() > 0 && time.Since(p.Advanced) >= timeout {
+			pp = append(pp, p)
+		}
+	}
+	return pp
+}
@@ -0,0 +1,53 @@
+package kafka
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConsumerStatus(t *testing.T) {
+	s := NewConsumerStatus("kafka-simple")
+	s.SetPartition("topic", 1, PartitionFetching, -1, 10)
+	s.SetPartition("topic", 0, PartitionDelivering, 5, 10)
+	s.SetPartitionState("other", 0, PartitionFetching)
+	s.SetWorker(1, WorkerIdle, "", 0, -1)
+	s.SetWorker(0, WorkerDelivering, "topic", 0, 4)
+
+	d := s.Dump()
+	assert.Equal(t, "kafka-simple", d.Type)
+	require.Len(t, d.Partitions, 3)
+	assert.Equal(t, "other", d.Partitions[0].Topic)
+	assert.Equal(t, int64(-1), d.Partitions[0].Offset)
+	assert.Equal(t, PartitionStatus{Topic: "topic", Partition: 0, State: PartitionDelivering, Offset: 5, HighWaterMark: 10, Since: d.Partitions[1].Since,
+		Advanced: d.Partitions[1].Advanced}, d.Partitions[1])
+	assert.Equal(t, int32(1), d.Partitions[2].Partition)
+	require.Len(t, d.Workers, 2)
+	assert.Equal(t, 0, d.Workers[0].ID)
+	assert.Equal(t, WorkerDelivering, d.Workers[0].State)
+	assert.Equal(t, int64(4), d.Workers[0].Offset)
+	assert.Equal(t, WorkerIdle, d.Workers[1].State)
+
+	// the time of the state is kept while the partition stays in the same state
+	since := d.Partitions[1].Since
+	assert.False(t, since.IsZero())
+	s.SetPartition("topic", 0, PartitionDelivering, 6, 10)
+	d = s.Dump()
+	assert.Equal(t, since, d.Partitions[1].Since)
+	assert.Equal(t, int64(6), d.Partitions[1].Offset)
+	s.SetPartitionState("topic", 0, PartitionFetching)
+	d = s.Dump()
+	assert.Equal(t, PartitionFetching, d.Partitions[1].State)
+	assert.Equal(t, int64(6), d.Partitions[1].Offset)
+
+	s.RemovePartition("other", 0)
+	assert.Len(t, s.Dump().Partitions, 2)
+
+	// a nil status tracks nothing
+	var ns *ConsumerStatus
+	ns.SetPartition("topic", 0, PartitionFetching, 0, 0)
+	ns.SetPartitionState("topic", 0, PartitionFetching)
+	ns.SetWorker(0, WorkerIdle, "", 0, 0)
+	ns.RemovePartition("topic", 0)
+}
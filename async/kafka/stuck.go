@@ -0,0 +1,155 @@
+package kafka
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/beatlabs/patron/info"
+	"github.com/beatlabs/patron/log"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var partitionStuck *prometheus.GaugeVec
+
+func init() {
+	partitionStuck = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "component",
+			Subsystem: "kafka_consumer",
+			Name:      "partition_stuck",
+			Help:      "Partitions whose offset has not advanced for the stuck partition timeout, although they have messages to consume, classified by consumer, topic and partition",
+		},
+		[]string{"consumer", "topic", "partition"},
+	)
+	prometheus.MustRegister(partitionStuck)
+	info.AddStatus("stuckPartitions", func() interface{} {
+		pp := StuckPartitions()
+		if len(pp) == 0 {
+			return nil
+		}
+		return pp
+	})
+}
+
+var (
+	detectorsMu sync.Mutex
+	detectors   = make(map[string]*StuckDetector)
+)
+
+// StuckPartitions returns the stuck partitions of the consumers by name, e.g. for the /info endpoint,
+// which lists only the consumers with stuck partitions.
+func StuckPartitions() map[string][]PartitionStatus {
+	detectorsMu.Lock()
+	dd := make([]*StuckDetector, 0, len(detectors))
+	for _, d := range detectors {
+		dd = append(dd, d)
+	}
+	detectorsMu.Unlock()
+
+	pp := make(map[string][]PartitionStatus)
+	for _, d := range dd {
+		if stuck := d.Stuck(); len(stuck) > 0 {
+			pp[d.name] = stuck
+		}
+	}
+	return pp
+}
+
+// NoStuckPartitions reports whether no consumer has stuck partitions, which can be surfaced on the readiness check
+// of the service with http.ConsumersReadyCheck.
+func NoStuckPartitions() bool {
+	return len(StuckPartitions()) == 0
+}
+
+// StuckDetector flags the partitions of a consumer whose offset has not advanced for a timeout, although they have messages
+// to consume, e.g. due to a poison message. The stuck partitions are logged, exposed by the partition_stuck gauge and listed
+// on the /info endpoint. A nil detector flags nothing.
+type StuckDetector struct {
+	name    string
+	timeout time.Duration
+	status  *ConsumerStatus
+}
+
+// NewStuckDetector creates the stuck partition detector of a consumer, which checks the positions of its status.
+// It returns nil if the timeout is not positive, i.e. when the kafka.StuckPartitionTimeout option is not provided.
+func NewStuckDetector(name string, timeout time.Duration, status *ConsumerStatus) *StuckDetector {
+	if timeout <= 0 {
+		return nil
+	}
+	return &StuckDetector{name: name, timeout: timeout, status: status}
+}
+
+// Stuck returns the stuck partitions of the consumer.
+func (d *StuckDetector) Stuck() []PartitionStatus {
+	if d == nil {
+		return nil
+	}
+	return d.status.StuckPartitions(d.timeout)
+}
+
+// Run registers the detector and updates the gauge of the partitions twice per timeout, until the context is canceled
+// when the consumer shuts down, after which the detector is unregistered and the gauge of its partitions removed.
+func (d *StuckDetector) Run(ctx context.Context) {
+	if d == nil {
+		return
+	}
+	detectorsMu.Lock()
+	detectors[d.name] = d
+	detectorsMu.Unlock()
+
+	stuck := make(map[topicPartition]bool)
+	defer func() {
+		detectorsMu.Lock()
+		if detectors[d.name] == d {
+			delete(detectors, d.name)
+		}
+		detectorsMu.Unlock()
+		for tp := range stuck {
+			partitionStuck.DeleteLabelValues(d.name, tp.topic, strconv.FormatInt(int64(tp.partition), 10))
+		}
+	}()
+
+	ticker := time.NewTicker(d.timeout / 2)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			d.check(stuck)
+		}
+	}
+}
+
+// check updates the gauge of the partitions and logs the partitions which got stuck since the previous check.
+// The gauge of the partitions which are no longer consumed, e.g. after a rebalance, is removed.
+func (d *StuckDetector) check(stuck map[topicPartition]bool) {
+	current := make(map[topicPartition]PartitionStatus)
+	for _, p := range d.Stuck() {
+		current[topicPartition{topic: p.Topic, partition: p.Partition}] = p
+	}
+	consumed := make(map[topicPartition]bool)
+	for _, p := range d.status.Dump().Partitions {
+		tp := topicPartition{topic: p.Topic, partition: p.Partition}
+		consumed[tp] = true
+		sp, isStuck := current[tp]
+		if isStuck && !stuck[tp] {
+			log.Warnf("partition %d of topic '%s' of consumer %s is stuck at offset %d with lag %d since %v",
+				sp.Partition, sp.Topic, d.name, sp.Offset, sp.lag(), sp.Advanced)
+		}
+		stuck[tp] = isStuck
+		v := 0.0
+		if isStuck {
+			v = 1
+		}
+		partitionStuck.WithLabelValues(d.name, p.Topic, strconv.FormatInt(int64(p.Partition), 10)).Set(v)
+	}
+	for tp := range stuck {
+		if !consumed[tp] {
+			delete(stuck, tp)
+			partitionStuck.DeleteLabelValues(d.name, tp.topic, strconv.FormatInt(int64(tp.partition), 10))
+		}
+	}
+}
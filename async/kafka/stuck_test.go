@@ -0,0 +1,85 @@
+package kafka
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/beatlabs/patron/info"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stalledStatus returns the status of a consumer whose partition 0 is stalled with messages to consume,
+// while partition 1 advances and partition 2 has caught up.
+func stalledStatus(stall time.Duration) *ConsumerStatus {
+	s := NewConsumerStatus("kafka-simple")
+	s.SetPartition("topic", 0, PartitionDelivering, 4, 10)
+	s.SetPartition("topic", 1, PartitionDelivering, 4, 10)
+	s.SetPartition("topic", 2, PartitionFetching, 9, 10)
+	time.Sleep(stall)
+	s.SetPartition("topic", 1, PartitionDelivering, 5, 10)
+	return s
+}
+
+func TestConsumerStatus_StuckPartitions(t *testing.T) {
+	s := stalledStatus(30 * time.Millisecond)
+	pp := s.StuckPartitions(20 * time.Millisecond)
+	require.Len(t, pp, 1)
+	assert.Equal(t, "topic", pp[0].Topic)
+	assert.Equal(t, int32(0), pp[0].Partition)
+	assert.Equal(t, int64(4), pp[0].Offset)
+	assert.Empty(t, s.StuckPartitions(time.Minute))
+
+	var ns *ConsumerStatus
+	assert.Empty(t, ns.StuckPartitions(time.Millisecond))
+}
+
+func TestNewStuckDetector(t *testing.T) {
+	assert.Nil(t, NewStuckDetector("name", 0, nil))
+	var d *StuckDetector
+	assert.Empty(t, d.Stuck())
+	d.Run(context.Background())
+}
+
+func stuckGauge(t *testing.T, partition string) float64 {
+	m := &dto.Metric{}
+	require.NoError(t, partitionStuck.WithLabelValues("orders", "topic", partition).Write(m))
+	return m.GetGauge().GetValue()
+}
+
+func TestStuckDetector_Run(t *testing.T) {
+	d := NewStuckDetector("orders", 20*time.Millisecond, stalledStatus(30*time.Millisecond))
+	ctx, cnl := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		d.Run(ctx)
+		close(done)
+	}()
+
+	for i := 0; i < 100 && stuckGauge(t, "0") == 0; i++ {
+		time.Sleep(5 * time.Millisecond)
+	}
+	assert.Equal(t, 1.0, stuckGauge(t, "0"))
+	assert.Equal(t, 0.0, stuckGauge(t, "2"))
+	assert.False(t, NoStuckPartitions())
+	pp := StuckPartitions()["orders"]
+	require.Len(t, pp, 1)
+	assert.Equal(t, int32(0), pp[0].Partition)
+
+	// the stuck partitions are listed on /info
+	b, err := info.Marshal()
+	require.NoError(t, err)
+	var out map[string]interface{}
+	require.NoError(t, json.Unmarshal(b, &out))
+	assert.Contains(t, out["stuckPartitions"], "orders")
+
+	cnl()
+	<-done
+	assert.True(t, NoStuckPartitions())
+	b, err = info.Marshal()
+	require.NoError(t, err)
+	assert.NotContains(t, string(b), "stuckPartitions")
+}
@@ -0,0 +1,73 @@
+package kafka
+
+import (
+	"time"
+
+	"github.com/beatlabs/patron/async"
+	"github.com/beatlabs/patron/trace"
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// TimestampType defines whether the timestamp of a message is set by the producer or by the broker.
+// The timestamp type is a topic configuration (message.timestamp.type), since it is not delivered to the consumers.
+type TimestampType string
+
+const (
+	// CreateTime is the time the message was created by the producer, which is the default of Kafka.
+	CreateTime TimestampType = "CreateTime"
+	// LogAppendTime is the time the message was appended to the log by the broker.
+	LogAppendTime TimestampType = "LogAppendTime"
+)
+
+// Timestamped is implemented by the messages of the Kafka consumers.
+type Timestamped interface {
+	// Timestamp returns the timestamp of the message, which is zero for brokers older than 0.10.
+	Timestamp() time.Time
+	// TimestampType returns the type of the timestamp of the message.
+	TimestampType() TimestampType
+}
+
+// MessageTimestamp returns the timestamp and the timestamp type of a message consumed from Kafka,
+// and false if the message is not a Kafka message.
+func MessageTimestamp(msg async.Message) (time.Time, TimestampType, bool) {
+	m := unwrap(msg, func(m async.Message) bool {
+		_, ok := m.(Timestamped)
+		return ok
+	})
+	if m == nil {
+		return time.Time{}, "", false
+	}
+	ts := m.(Timestamped)
+	return ts.Timestamp(), ts.TimestampType(), true
+}
+
+var messageLag *prometheus.HistogramVec
+
+func init() {
+	messageLag = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: "component",
+			Subsystem: "kafka_consumer",
+			Name:      "message_lag_seconds",
+			Help:      "Time between the timestamp of a message and its consumption, classified by group and topic",
+			Buckets:   prometheus.ExponentialBuckets(0.01, 4, 10),
+		},
+		[]string{"group", "topic"},
+	)
+	prometheus.MustRegister(messageLag)
+}
+
+// observeMessageLag records the time since the timestamp of the message, unless the timestamp is not set,
+// along with an exemplar holding the trace ID of the span of the message, if it is sampled.
+func observeMessageLag(group, topic string, timestamp, now time.Time, sp opentracing.Span) {
+	if timestamp.IsZero() {
+		return
+	}
+	lag := now.Sub(timestamp)
+	// clocks of producers and consumers are not in sync
+	if lag < 0 {
+		lag = 0
+	}
+	trace.ObserveWithExemplar(messageLag.WithLabelValues(group, topic), lag.Seconds(), sp)
+}
@@ -0,0 +1,92 @@
+package kafka
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/beatlabs/patron/encoding/json"
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	jaeger "github.com/uber/jaeger-client-go"
+)
+
+func TestClaimMessage_Timestamp(t *testing.T) {
+	timestamp := time.Now().Add(-2 * time.Second)
+	cm := &sarama.ConsumerMessage{Topic: "timestamp-topic", Value: []byte(`"value"`), Timestamp: timestamp}
+
+	msg, err := ClaimMessage(context.Background(), cm, json.DecodeRaw, nil, ClaimOptions{Group: "timestamp-group"})
+	require.NoError(t, err)
+	ts, tt, ok := MessageTimestamp(msg)
+	assert.True(t, ok)
+	assert.Equal(t, timestamp, ts)
+	assert.Equal(t, CreateTime, tt)
+
+	m := &dto.Metric{}
+	require.NoError(t, messageLag.WithLabelValues("timestamp-group", "timestamp-topic").(prometheus.Histogram).Write(m))
+	assert.Equal(t, uint64(1), m.GetHistogram().GetSampleCount())
+	assert.InDelta(t, 2, m.GetHistogram().GetSampleSum(), 1)
+
+	// the timestamp is available through the wrappers of the message
+	l, err := NewInFlightLimiter(1)
+	require.NoError(t, err)
+	msg, err = ClaimMessage(context.Background(), cm, json.DecodeRaw, nil, ClaimOptions{Group: "timestamp-group", TimestampType: LogAppendTime})
+	require.NoError(t, err)
+	ts, tt, ok = MessageTimestamp(l.Track(msg))
+	assert.True(t, ok)
+	assert.Equal(t, timestamp, ts)
+	assert.Equal(t, LogAppendTime, tt)
+
+	// messages without a timestamp do not record a lag
+	cm = &sarama.ConsumerMessage{Topic: "timestamp-topic", Value: []byte(`"value"`)}
+	msg, err = ClaimMessage(context.Background(), cm, json.DecodeRaw, nil, ClaimOptions{Group: "timestamp-group"})
+	require.NoError(t, err)
+	ts, _, ok = MessageTimestamp(msg)
+	assert.True(t, ok)
+	assert.True(t, ts.IsZero())
+	m = &dto.Metric{}
+	require.NoError(t, messageLag.WithLabelValues("timestamp-group", "timestamp-topic").(prometheus.Histogram).Write(m))
+	assert.Equal(t, uint64(2), m.GetHistogram().GetSampleCount())
+
+	_, _, ok = MessageTimestamp(nil)
+	assert.False(t, ok)
+}
+
+func TestObserveMessageLag_ClockSkew(t *testing.T) {
+	now := time.Now()
+	observeMessageLag("skew-group", "skew-topic", now.Add(time.Second), now, nil)
+	m := &dto.Metric{}
+	require.NoError(t, messageLag.WithLabelValues("skew-group", "skew-topic").(prometheus.Histogram).Write(m))
+	assert.Equal(t, uint64(1), m.GetHistogram().GetSampleCount())
+	assert.Equal(t, 0.0, m.GetHistogram().GetSampleSum())
+}
+
+func TestClaimMessage_MessageLagExemplar(t *testing.T) {
+	tr, cls := jaeger.NewTracer("test", jaeger.NewConstSampler(true), jaeger.NewInMemoryReporter())
+	defer cls.Close()
+	opentracing.SetGlobalTracer(tr)
+	defer opentracing.SetGlobalTracer(opentracing.NoopTracer{})
+
+	cm := &sarama.ConsumerMessage{Topic: "exemplar-topic", Value: []byte(`{}`), Timestamp: time.Now().Add(-time.Hour)}
+	msg, err := ClaimMessage(context.Background(), cm, json.DecodeRaw, nil, ClaimOptions{Group: "exemplar-group"})
+	require.NoError(t, err)
+	sp := opentracing.SpanFromContext(msg.Context())
+	require.NotNil(t, sp)
+	assert.NoError(t, msg.Ack())
+
+	m := &dto.Metric{}
+	require.NoError(t, messageLag.WithLabelValues("exemplar-group", "exemplar-topic").(prometheus.Histogram).Write(m))
+	var ee []*dto.Exemplar
+	for _, b := range m.GetHistogram().GetBucket() {
+		if b.GetExemplar() != nil {
+			ee = append(ee, b.GetExemplar())
+		}
+	}
+	require.Len(t, ee, 1)
+	assert.Equal(t, "trace_id", ee[0].GetLabel()[0].GetName())
+	assert.Equal(t, sp.Context().(jaeger.SpanContext).TraceID().String(), ee[0].GetLabel()[0].GetValue())
+}
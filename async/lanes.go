@@ -0,0 +1,108 @@
+package async
+
+import (
+	"context"
+	"hash/fnv"
+	"sync"
+	"sync/atomic"
+)
+
+// laneBuffer is the number of messages queued per lane.
+const laneBuffer = 16
+
+// KeyFunc definition of a function which returns the ordering key of a message, e.g. the key of a Kafka message.
+// Messages with an empty key have no ordering guarantee.
+type KeyFunc func(Message) string
+
+// lanes processes messages concurrently on a fixed number of lanes, while the messages of a key
+// are always routed to the same lane and are therefore processed in the order they were consumed.
+type lanes struct {
+	key    KeyFunc
+	chs    []chan Message
+	errs   chan error
+	failed int32
+	next   uint32
+	wg     sync.WaitGroup
+}
+
+func newLanes(n int, key KeyFunc, process func(Message) error) *lanes {
+	l := &lanes{key: key, chs: make([]chan Message, n), errs: make(chan error, 1)}
+	l.wg.Add(n)
+	for i := range l.chs {
+		ch := make(chan Message, laneBuffer)
+		l.chs[i] = ch
+		go l.run(ch, process)
+	}
+	return l
+}
+
+func (l *lanes) run(ch <-chan Message, process func(Message) error) {
+	defer l.wg.Done()
+	for msg := range ch {
+		// the component stops after the first failure, so the queued messages are left to be redelivered
+		if atomic.LoadInt32(&l.failed) == 1 {
+			continue
+		}
+		if err := process(msg); err != nil {
+			if atomic.CompareAndSwapInt32(&l.failed, 0, 1) {
+				l.errs <- err
+			}
+		}
+	}
+}
+
+// lane returns the lane of a message, distributing the messages without a key evenly.
+func (l *lanes) lane(msg Message) chan<- Message {
+	k := l.key(msg)
+	if k == "" {
+		return l.chs[atomic.AddUint32(&l.next, 1)%uint32(len(l.chs))]
+	}
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(k))
+	return l.chs[h.Sum32()%uint32(len(l.chs))]
+}
+
+// dispatch queues the message on its lane, waiting while the lane is full.
+// It returns the error of a failed lane, which stops the component.
+func (l *lanes) dispatch(ctx context.Context, msg Message) error {
+	select {
+	case l.lane(msg) <- msg:
+		return nil
+	case <-ctx.Done():
+		return nil
+	case err := <-l.errs:
+		return err
+	}
+}
+
+// failures returns the channel of the first failure of the lanes, which is nil for a nil lanes
+// in order to be used in a select regardless of whether the messages are processed on lanes.
+func (l *lanes) failures() <-chan error {
+	if l == nil {
+		return nil
+	}
+	return l.errs
+}
+
+// queued returns the number of messages queued on the lanes, which is zero for a nil lanes.
+func (l *lanes) queued() int {
+	if l == nil {
+		return 0
+	}
+	n := 0
+	for _, ch := range l.chs {
+		n += len(ch)
+	}
+	return n
+}
+
+// stop waits for the lanes to process the queued messages.
+func (l *lanes) stop() {
+	if l == nil {
+		return
+	}
+	for _, ch := range l.chs {
+		close(ch)
+	}
+	l.wg.Wait()
+}
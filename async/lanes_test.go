@@ -0,0 +1,105 @@
+package async
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type keyedMessage struct {
+	mockMessage
+	key   string
+	seq   int
+	acked chan struct{}
+}
+
+func (km *keyedMessage) Ack() error {
+	close(km.acked)
+	return nil
+}
+
+func messageKey(msg Message) string {
+	return msg.(*keyedMessage).key
+}
+
+func TestBuilder_WithOrderedLanes(t *testing.T) {
+	proc := mockProcessor{}
+	_, err := New("name", &mockConsumerFactory{}, proc.Process).WithOrderedLanes(0, messageKey).Create()
+	assert.EqualError(t, err, "lanes must be positive\n")
+	_, err = New("name", &mockConsumerFactory{}, proc.Process).WithOrderedLanes(2, nil).Create()
+	assert.EqualError(t, err, "nil key func provided\n")
+	cmp, err := New("name", &mockConsumerFactory{}, proc.Process).WithOrderedLanes(2, messageKey).Create()
+	assert.NoError(t, err)
+	assert.Equal(t, 2, cmp.lanes)
+}
+
+func TestRun_OrderedLanes(t *testing.T) {
+	const keys, perKey = 4, 25
+	cnr := mockConsumer{chMsg: make(chan Message, keys*perKey), chErr: make(chan error)}
+	var mm []*keyedMessage
+	// the messages of the keys are interleaved, as if they were consumed from multiple partitions
+	for i := 0; i < perKey; i++ {
+		for k := 0; k < keys; k++ {
+			m := &keyedMessage{mockMessage: mockMessage{ctx: context.Background()}, key: fmt.Sprintf("key-%d", k), seq: i, acked: make(chan struct{})}
+			mm = append(mm, m)
+			cnr.chMsg <- m
+		}
+	}
+
+	var mu sync.Mutex
+	processed := make(map[string][]int)
+	var running, maxRunning int32
+	proc := func(msg Message) error {
+		n := atomic.AddInt32(&running, 1)
+		defer atomic.AddInt32(&running, -1)
+		for {
+			m := atomic.LoadInt32(&maxRunning)
+			if n <= m || atomic.CompareAndSwapInt32(&maxRunning, m, n) {
+				break
+			}
+		}
+		km := msg.(*keyedMessage)
+		time.Sleep(time.Duration(km.seq%3) * time.Millisecond)
+		mu.Lock()
+		processed[km.key] = append(processed[km.key], km.seq)
+		mu.Unlock()
+		return nil
+	}
+
+	cmp, err := New("name", &mockConsumerFactory{c: &cnr}, proc).WithOrderedLanes(keys, messageKey).Create()
+	require.NoError(t, err)
+	ctx, cnl := context.WithCancel(context.Background())
+	chDone := make(chan error)
+	go func() { chDone <- cmp.Run(ctx) }()
+
+	for _, m := range mm {
+		<-m.acked
+	}
+	cnl()
+	assert.NoError(t, <-chDone)
+
+	assert.Len(t, processed, keys)
+	for key, seqs := range processed {
+		require.Len(t, seqs, perKey, key)
+		for i, seq := range seqs {
+			assert.Equal(t, i, seq, key)
+		}
+	}
+	assert.True(t, atomic.LoadInt32(&maxRunning) > 1)
+}
+
+func TestRun_OrderedLanes_Error(t *testing.T) {
+	cnr := mockConsumer{chMsg: make(chan Message, 1), chErr: make(chan error)}
+	cnr.chMsg <- &keyedMessage{mockMessage: mockMessage{ctx: context.Background()}, key: "key", acked: make(chan struct{})}
+	proc := mockProcessor{errReturn: true}
+	cmp, err := New("name", &mockConsumerFactory{c: &cnr}, proc.Process).WithOrderedLanes(2, messageKey).Create()
+	require.NoError(t, err)
+	assert.Equal(t, errProcess, cmp.Run(context.Background()))
+	assert.Equal(t, 1, proc.execs)
+}
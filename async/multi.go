@@ -0,0 +1,172 @@
+package async
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	patronErrors "github.com/beatlabs/patron/errors"
+	"github.com/beatlabs/patron/log"
+)
+
+// ConsumerError wraps an error received from one of the consumers of a multi consumer
+// with the index of the consumer it originated from.
+type ConsumerError struct {
+	Index int
+	Err   error
+}
+
+func (e *ConsumerError) Error() string {
+	return fmt.Sprintf("consumer %d: %v", e.Index, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *ConsumerError) Unwrap() error {
+	return e.Err
+}
+
+type multiConsumer struct {
+	cc    []Consumer
+	done  chan struct{}
+	close sync.Once
+}
+
+// MultiConsumer creates a consumer which fans the message and error channels of the provided
+// consumers into one, allowing a single component to consume from many sources.
+// Errors are wrapped in a ConsumerError in order to identify the consumer they originated from.
+func MultiConsumer(consumers ...Consumer) Consumer {
+	return &multiConsumer{cc: consumers, done: make(chan struct{})}
+}
+
+// Consume starts consuming from all consumers. If one of them fails to start, only the already started ones are closed.
+func (mc *multiConsumer) Consume(ctx context.Context) (<-chan Message, <-chan error, error) {
+	if len(mc.cc) == 0 {
+		return nil, nil, errors.New("no consumers provided")
+	}
+
+	chMsg := make(chan Message)
+	chErr := make(chan error)
+	wg := sync.WaitGroup{}
+
+	for i, c := range mc.cc {
+		cMsg, cErr, err := c.Consume(ctx)
+		if err != nil {
+			mc.close.Do(func() {
+				close(mc.done)
+			})
+			if clsErr := closeConsumers(mc.cc[:i]); clsErr != nil {
+				log.Warnf("failed to close consumers: %v", clsErr)
+			}
+			return nil, nil, &ConsumerError{Index: i, Err: err}
+		}
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			forwardMessages(ctx, mc.done, cMsg, chMsg)
+		}()
+		go func(i int) {
+			defer wg.Done()
+			forwardErrors(ctx, mc.done, cErr, chErr, func(err error) error { return &ConsumerError{Index: i, Err: err} })
+		}(i)
+	}
+
+	go func() {
+		wg.Wait()
+		close(chMsg)
+		close(chErr)
+	}()
+
+	return chMsg, chErr, nil
+}
+
+// Close closes all consumers.
+func (mc *multiConsumer) Close() error {
+	mc.close.Do(func() {
+		close(mc.done)
+	})
+	return closeConsumers(mc.cc)
+}
+
+func closeConsumers(cc []Consumer) error {
+	ee := make([]error, 0, len(cc))
+	for i, c := range cc {
+		if err := c.Close(); err != nil {
+			ee = append(ee, &ConsumerError{Index: i, Err: err})
+		}
+	}
+	return patronErrors.Aggregate(ee...)
+}
+
+func forwardMessages(ctx context.Context, done <-chan struct{}, in <-chan Message, out chan<- Message) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-done:
+			return
+		case msg, ok := <-in:
+			if !ok {
+				return
+			}
+			select {
+			case out <- msg:
+			case <-ctx.Done():
+				return
+			case <-done:
+				return
+			}
+		}
+	}
+}
+
+func forwardErrors(ctx context.Context, done <-chan struct{}, in <-chan error, out chan<- error, wrap func(error) error) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-done:
+			return
+		case err, ok := <-in:
+			if !ok {
+				return
+			}
+			select {
+			case out <- wrap(err):
+			case <-ctx.Done():
+				return
+			case <-done:
+				return
+			}
+		}
+	}
+}
+
+type multiConsumerFactory struct {
+	ff []ConsumerFactory
+}
+
+// MultiConsumerFactory creates a consumer factory which creates all the provided factories' consumers
+// and combines them with MultiConsumer.
+func MultiConsumerFactory(factories ...ConsumerFactory) ConsumerFactory {
+	return &multiConsumerFactory{ff: factories}
+}
+
+// Create creates a multi consumer out of the consumers of all factories.
+func (mcf *multiConsumerFactory) Create() (Consumer, error) {
+	if len(mcf.ff) == 0 {
+		return nil, errors.New("no consumer factories provided")
+	}
+	cc := make([]Consumer, 0, len(mcf.ff))
+	for i, f := range mcf.ff {
+		c, err := f.Create()
+		if err != nil {
+			for _, created := range cc {
+				_ = created.Close()
+			}
+			return nil, &ConsumerError{Index: i, Err: err}
+		}
+		cc = append(cc, c)
+	}
+	return MultiConsumer(cc...), nil
+}
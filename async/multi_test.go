@@ -0,0 +1,135 @@
+package async
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newMockConsumer() *mockConsumer {
+	return &mockConsumer{chMsg: make(chan Message, 10), chErr: make(chan error, 10)}
+}
+
+func TestMultiConsumer_Consume(t *testing.T) {
+	c1, c2 := newMockConsumer(), newMockConsumer()
+	mc := MultiConsumer(c1, c2)
+	ctx, cnl := context.WithCancel(context.Background())
+	defer cnl()
+
+	chMsg, chErr, err := mc.Consume(ctx)
+	assert.NoError(t, err)
+
+	m1, m2 := &mockMessage{ctx: ctx}, &mockMessage{ctx: ctx}
+	c1.chMsg <- m1
+	c2.chMsg <- m2
+	got := []Message{<-chMsg, <-chMsg}
+	assert.ElementsMatch(t, []Message{m1, m2}, got)
+
+	c2.chErr <- errConsumer
+	err = <-chErr
+	var ce *ConsumerError
+	assert.True(t, errors.As(err, &ce))
+	assert.Equal(t, 1, ce.Index)
+	assert.True(t, errors.Is(err, errConsumer))
+
+	assert.NoError(t, mc.Close())
+}
+
+func TestMultiConsumer_ConsumeFailure(t *testing.T) {
+	mc := MultiConsumer(newMockConsumer(), &mockConsumer{consumeError: true})
+	chMsg, chErr, err := mc.Consume(context.Background())
+	assert.Error(t, err)
+	assert.True(t, errors.Is(err, errConsumer))
+	assert.Nil(t, chMsg)
+	assert.Nil(t, chErr)
+
+	_, _, err = MultiConsumer().Consume(context.Background())
+	assert.Error(t, err)
+}
+
+func TestMultiConsumer_ChannelsClosedOnShutdown(t *testing.T) {
+	mc := MultiConsumer(newMockConsumer(), newMockConsumer())
+	ctx, cnl := context.WithCancel(context.Background())
+	chMsg, chErr, err := mc.Consume(ctx)
+	assert.NoError(t, err)
+	cnl()
+
+	select {
+	case _, ok := <-chMsg:
+		assert.False(t, ok)
+	case <-time.After(time.Second):
+		t.Fatal("message channel not closed")
+	}
+	_, ok := <-chErr
+	assert.False(t, ok)
+}
+
+func TestMultiConsumer_Close(t *testing.T) {
+	mc := MultiConsumer(newMockConsumer(), &mockConsumer{clsError: true})
+	err := mc.Close()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "consumer 1: "+errConsumerClose.Error())
+	// closing twice is safe
+	assert.Error(t, mc.Close())
+}
+
+func TestMultiConsumerFactory_Create(t *testing.T) {
+	c1, c2 := newMockConsumer(), newMockConsumer()
+	got, err := MultiConsumerFactory(&mockConsumerFactory{c: c1}, &mockConsumerFactory{c: c2}).Create()
+	assert.NoError(t, err)
+	assert.NotNil(t, got)
+
+	got, err = MultiConsumerFactory(&mockConsumerFactory{c: c1}, &mockConsumerFactory{errRet: true}).Create()
+	assert.Error(t, err)
+	assert.True(t, errors.Is(err, errFactory))
+	assert.Nil(t, got)
+
+	got, err = MultiConsumerFactory().Create()
+	assert.Error(t, err)
+	assert.Nil(t, got)
+}
+
+func TestComponent_MultiConsumer(t *testing.T) {
+	c1, c2 := newMockConsumer(), newMockConsumer()
+	proc := mockProcessor{}
+	cmp, err := New("test", MultiConsumerFactory(&mockConsumerFactory{c: c1}, &mockConsumerFactory{c: c2}), proc.Process).
+		WithErrorHandler(func(error) bool { return true }).Create()
+	assert.NoError(t, err)
+
+	c1.chMsg <- &mockMessage{ctx: context.Background()}
+	c2.chMsg <- &mockMessage{ctx: context.Background()}
+	c2.chErr <- errConsumer
+
+	err = cmp.Run(context.Background())
+	assert.Error(t, err)
+	assert.True(t, errors.Is(err, errConsumer))
+}
+
+func TestComponent_MultiConsumer_Shutdown(t *testing.T) {
+	for i := 0; i < 100; i++ {
+		c1, c2 := newMockConsumer(), newMockConsumer()
+		processing := make(chan struct{})
+		proc := func(msg Message) error {
+			close(processing)
+			time.Sleep(time.Millisecond)
+			return nil
+		}
+		cmp, err := New("test", MultiConsumerFactory(&mockConsumerFactory{c: c1}, &mockConsumerFactory{c: c2}), proc).Create()
+		require.NoError(t, err)
+
+		ctx, cnl := context.WithCancel(context.Background())
+		chDone := make(chan error)
+		go func() {
+			chDone <- cmp.Run(ctx)
+		}()
+		c1.chMsg <- &mockMessage{ctx: context.Background()}
+		<-processing
+		cnl()
+		// the channels of the multi consumer are closed on the cancellation, which is a clean shutdown
+		require.NoError(t, <-chDone)
+	}
+}
@@ -14,9 +14,9 @@ import (
 	"github.com/beatlabs/patron/correlation"
 	"github.com/beatlabs/patron/encoding"
 	"github.com/beatlabs/patron/encoding/json"
+	"github.com/beatlabs/patron/info"
 	"github.com/beatlabs/patron/log"
 	"github.com/beatlabs/patron/trace"
-	"github.com/google/uuid"
 	opentracing "github.com/opentracing/opentracing-go"
 	"github.com/prometheus/client_golang/prometheus"
 )
@@ -169,6 +169,7 @@ func NewFactory(queue sqsiface.SQSAPI, queueName string, oo ...OptionFunc) (*Fac
 
 // Create a new SQS consumer.
 func (f *Factory) Create() (async.Consumer, error) {
+	info.AddDependency(info.Dependency{Type: "sqs", Name: f.queueName, Direction: info.Inbound})
 	return &consumer{
 		queueName:         f.queueName,
 		queue:             f.queue,
@@ -358,7 +359,7 @@ func getCorrelationID(ma map[string]*sqs.MessageAttributeValue) string {
 			break
 		}
 	}
-	return uuid.New().String()
+	return correlation.NewID()
 }
 
 func mapHeader(ma map[string]*sqs.MessageAttributeValue) map[string]string {
@@ -0,0 +1,117 @@
+package async
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/beatlabs/patron/log"
+)
+
+// TransformFunc definition of a function which transforms a message before it is delivered to the processor,
+// e.g. in order to decompress, decrypt or map its payload.
+type TransformFunc func(Message) (Message, error)
+
+type transformConsumer struct {
+	cns   Consumer
+	fn    TransformFunc
+	done  chan struct{}
+	close sync.Once
+}
+
+// WithTransformer wraps a consumer in order to apply the transform function to each message before its delivery.
+// Messages which fail to be transformed are nacked and the error is sent to the error channel.
+func WithTransformer(consumer Consumer, fn TransformFunc) Consumer {
+	return &transformConsumer{cns: consumer, fn: fn, done: make(chan struct{})}
+}
+
+// Consume starts consuming from the wrapped consumer.
+func (tc *transformConsumer) Consume(ctx context.Context) (<-chan Message, <-chan error, error) {
+	cMsg, cErr, err := tc.cns.Consume(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	chMsg := make(chan Message)
+	chErr := make(chan error)
+	wg := sync.WaitGroup{}
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		tc.transform(ctx, cMsg, chMsg, chErr)
+	}()
+	go func() {
+		defer wg.Done()
+		forwardErrors(ctx, tc.done, cErr, chErr, func(err error) error { return err })
+	}()
+	go func() {
+		wg.Wait()
+		close(chMsg)
+		close(chErr)
+	}()
+
+	return chMsg, chErr, nil
+}
+
+func (tc *transformConsumer) transform(ctx context.Context, in <-chan Message, out chan<- Message, chErr chan<- error) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-tc.done:
+			return
+		case msg, ok := <-in:
+			if !ok {
+				return
+			}
+			tMsg, err := tc.fn(msg)
+			if err != nil {
+				if nackErr := msg.Nack(); nackErr != nil {
+					log.FromContext(msg.Context()).Errorf("failed to NACK message: %v", nackErr)
+				}
+				select {
+				case chErr <- fmt.Errorf("failed to transform message: %w", err):
+				case <-ctx.Done():
+					return
+				case <-tc.done:
+					return
+				}
+				continue
+			}
+			select {
+			case out <- tMsg:
+			case <-ctx.Done():
+				return
+			case <-tc.done:
+				return
+			}
+		}
+	}
+}
+
+// Close closes the wrapped consumer.
+func (tc *transformConsumer) Close() error {
+	tc.close.Do(func() {
+		close(tc.done)
+	})
+	return tc.cns.Close()
+}
+
+type transformConsumerFactory struct {
+	cf ConsumerFactory
+	fn TransformFunc
+}
+
+// WithTransformerFactory wraps a consumer factory in order to apply the transform function to the messages of its consumers.
+func WithTransformerFactory(cf ConsumerFactory, fn TransformFunc) ConsumerFactory {
+	return &transformConsumerFactory{cf: cf, fn: fn}
+}
+
+// Create creates a consumer of the wrapped factory applying the transform function.
+func (tcf *transformConsumerFactory) Create() (Consumer, error) {
+	c, err := tcf.cf.Create()
+	if err != nil {
+		return nil, err
+	}
+	return WithTransformer(c, tcf.fn), nil
+}
@@ -0,0 +1,128 @@
+package async
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type rawMessage struct {
+	mockMessage
+	data   []byte
+	nacked bool
+}
+
+func (rm *rawMessage) Decode(v interface{}) error {
+	return json.Unmarshal(rm.data, v)
+}
+
+func (rm *rawMessage) Nack() error {
+	rm.nacked = true
+	return rm.mockMessage.Nack()
+}
+
+func gzipTransformer(msg Message) (Message, error) {
+	rm, ok := msg.(*rawMessage)
+	if !ok {
+		return nil, errors.New("unsupported message")
+	}
+	r, err := gzip.NewReader(bytes.NewReader(rm.data))
+	if err != nil {
+		return nil, err
+	}
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return &rawMessage{mockMessage: rm.mockMessage, data: data}, nil
+}
+
+func gzipData(t *testing.T, s string) []byte {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	_, err := w.Write([]byte(s))
+	assert.NoError(t, err)
+	assert.NoError(t, w.Close())
+	return buf.Bytes()
+}
+
+func TestWithTransformer(t *testing.T) {
+	cnr := newMockConsumer()
+	tc := WithTransformer(cnr, gzipTransformer)
+	ctx, cnl := context.WithCancel(context.Background())
+	defer cnl()
+	chMsg, chErr, err := tc.Consume(ctx)
+	assert.NoError(t, err)
+
+	cnr.chMsg <- &rawMessage{mockMessage: mockMessage{ctx: ctx}, data: gzipData(t, `"decompressed"`)}
+	msg := <-chMsg
+	var got string
+	assert.NoError(t, msg.Decode(&got))
+	assert.Equal(t, "decompressed", got)
+
+	invalid := &rawMessage{mockMessage: mockMessage{ctx: ctx}, data: []byte(`"plain"`)}
+	cnr.chMsg <- invalid
+	err = <-chErr
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to transform message")
+	assert.True(t, invalid.nacked)
+
+	cnr.chErr <- errConsumer
+	assert.Equal(t, errConsumer, <-chErr)
+
+	assert.NoError(t, tc.Close())
+	_, ok := <-chMsg
+	assert.False(t, ok)
+}
+
+func TestWithTransformer_ConsumeFailure(t *testing.T) {
+	tc := WithTransformer(&mockConsumer{consumeError: true}, gzipTransformer)
+	chMsg, chErr, err := tc.Consume(context.Background())
+	assert.Equal(t, errConsumer, err)
+	assert.Nil(t, chMsg)
+	assert.Nil(t, chErr)
+}
+
+func TestWithTransformerFactory(t *testing.T) {
+	got, err := WithTransformerFactory(&mockConsumerFactory{c: newMockConsumer()}, gzipTransformer).Create()
+	assert.NoError(t, err)
+	assert.IsType(t, &transformConsumer{}, got)
+
+	got, err = WithTransformerFactory(&mockConsumerFactory{errRet: true}, gzipTransformer).Create()
+	assert.Equal(t, errFactory, err)
+	assert.Nil(t, got)
+}
+
+func TestComponent_TransformerShutdown(t *testing.T) {
+	for i := 0; i < 100; i++ {
+		cnr := newMockConsumer()
+		processing := make(chan struct{})
+		proc := func(msg Message) error {
+			close(processing)
+			time.Sleep(time.Millisecond)
+			return nil
+		}
+		cmp, err := New("test", WithTransformerFactory(&mockConsumerFactory{c: cnr}, gzipTransformer), proc).Create()
+		assert.NoError(t, err)
+
+		ctx, cnl := context.WithCancel(context.Background())
+		chDone := make(chan error)
+		go func() {
+			chDone <- cmp.Run(ctx)
+		}()
+		cnr.chMsg <- &rawMessage{mockMessage: mockMessage{ctx: ctx}, data: gzipData(t, `"decompressed"`)}
+		<-processing
+		cnl()
+		// the channels of the transforming consumer are closed on the cancellation, which is a clean shutdown
+		if !assert.NoError(t, <-chDone) {
+			return
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package patron
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+)
+
+// funcComponent is a component running a function.
+type funcComponent struct {
+	name string
+	run  func(ctx context.Context) error
+	info map[string]interface{}
+}
+
+// FuncComponent wraps a function, e.g. the loop of a file watcher or a queue poller, as a component,
+// whose name and information are reported along with the other components of the service.
+// The function has to return when the context is done, which stops the component. The context errors it returns
+// when the context is done, as well as a nil error, are not failures, while a panic of the function is returned as an error.
+func FuncComponent(name string, run func(ctx context.Context) error, info map[string]interface{}) (Component, error) {
+	if name == "" {
+		return nil, errors.New("name is required")
+	}
+	if run == nil {
+		return nil, errors.New("run func is required")
+	}
+	return &funcComponent{name: name, run: run, info: info}, nil
+}
+
+// Run runs the function until it returns.
+func (fc *funcComponent) Run(ctx context.Context) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("component %s panicked: %v\n%s", fc.name, r, debug.Stack())
+		}
+	}()
+	err = fc.run(ctx)
+	if ctx.Err() != nil && errors.Is(err, ctx.Err()) {
+		return nil
+	}
+	return err
+}
+
+// Name returns the name of the component.
+func (fc *funcComponent) Name() string {
+	return fc.name
+}
+
+// Info returns the name and the information of the component.
+func (fc *funcComponent) Info() map[string]interface{} {
+	in := make(map[string]interface{}, len(fc.info)+1)
+	for k, v := range fc.info {
+		in[k] = v
+	}
+	in["name"] = fc.name
+	return in
+}
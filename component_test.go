@@ -0,0 +1,55 @@
+package patron
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFuncComponent(t *testing.T) {
+	run := func(ctx context.Context) error { return nil }
+	_, err := FuncComponent("", run, nil)
+	assert.EqualError(t, err, "name is required")
+	_, err = FuncComponent("watcher", nil, nil)
+	assert.EqualError(t, err, "run func is required")
+
+	started := make(chan struct{})
+	cp, err := FuncComponent("watcher", func(ctx context.Context) error {
+		close(started)
+		<-ctx.Done()
+		return ctx.Err()
+	}, map[string]interface{}{"path": "/etc/config"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"name": "watcher", "path": "/etc/config"}, cp.(Informer).Info())
+	assert.Equal(t, "watcher", componentName(cp))
+
+	ctx, cnl := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- cp.Run(ctx) }()
+	<-started
+	select {
+	case <-done:
+		t.Fatal("component stopped before the context is canceled")
+	case <-time.After(10 * time.Millisecond):
+	}
+	cnl()
+	// the cancellation of the context is not a failure
+	assert.NoError(t, <-done)
+}
+
+func TestFuncComponent_Failure(t *testing.T) {
+	errPoll := errors.New("poll failed")
+	cp, err := FuncComponent("poller", func(ctx context.Context) error { return errPoll }, nil)
+	require.NoError(t, err)
+	assert.Equal(t, errPoll, cp.Run(context.Background()))
+
+	cp, err = FuncComponent("poller", func(ctx context.Context) error { panic("boom") }, nil)
+	require.NoError(t, err)
+	err = cp.Run(context.Background())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "component poller panicked: boom")
+}
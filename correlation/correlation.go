@@ -2,13 +2,13 @@ package correlation
 
 import (
 	"context"
-
-	"github.com/google/uuid"
 )
 
 const (
 	// HeaderID constant.
 	HeaderID string = "X-Correlation-Id"
+	// RequestHeaderID constant, the header of the request ID, which is an alternative to the correlation ID header.
+	RequestHeaderID string = "X-Request-ID"
 	// ID constant.
 	ID string = "correlationID"
 )
@@ -18,12 +18,12 @@ type idContextKey struct{}
 var idKey = idContextKey{}
 
 // IDFromContext returns the correlation ID from the context.
-// If no ID is set a new one is generated.
+// If no ID is set a new one is generated with the strategy of the process.
 func IDFromContext(ctx context.Context) string {
 	if id, ok := ctx.Value(idKey).(string); ok {
 		return id
 	}
-	return uuid.New().String()
+	return NewID()
 }
 
 // ContextWithID sets a correlation ID to a context.
@@ -0,0 +1,91 @@
+package correlation
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Strategy generates the correlation IDs of the requests and the messages which do not carry one.
+// A strategy is called concurrently, so it has to be safe for concurrent use.
+type Strategy func() string
+
+var (
+	// UUIDv4 generates random UUIDs, which is the default strategy.
+	UUIDv4 Strategy = func() string { return uuid.New().String() }
+	// UUIDv7 generates UUIDs which are ordered by the millisecond of their generation.
+	UUIDv7 Strategy = newUUIDv7
+	// ULID generates ULIDs, which are ordered by the millisecond of their generation and shorter than UUIDs.
+	ULID Strategy = newULID
+	// Sequence generates IDs from a random prefix of the process and an increasing sequence number,
+	// which are the cheapest to generate, but are unique only with high probability across processes.
+	Sequence Strategy = newSequence
+)
+
+// NewID returns a new correlation ID generated by the default strategy, i.e. UUIDv4.
+func NewID() string {
+	return UUIDv4()
+}
+
+// random fills the bytes with cryptographically secure random bytes, which is safe for concurrent use without locking.
+func random(b []byte) {
+	if _, err := rand.Read(b); err != nil {
+		// the system's random source is never expected to fail, as with uuid.New
+		panic(err)
+	}
+}
+
+// millis writes the unix time in milliseconds to the first 6 bytes, in big-endian order.
+func millis(b []byte) {
+	var ts [8]byte
+	binary.BigEndian.PutUint64(ts[:], uint64(time.Now().UnixNano()/int64(time.Millisecond)))
+	copy(b[:6], ts[2:])
+}
+
+func newUUIDv7() string {
+	var u uuid.UUID
+	random(u[6:])
+	millis(u[:])
+	u[6] = (u[6] & 0x0f) | 0x70 // version 7
+	u[8] = (u[8] & 0x3f) | 0x80 // RFC 4122 variant
+	return u.String()
+}
+
+// crockford is the base32 alphabet of the ULIDs, without the letters I, L, O and U.
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+func newULID() string {
+	var b [16]byte
+	random(b[6:])
+	millis(b[:])
+	// the 128 bits are encoded to 26 characters of 5 bits, the first of which has only 3 significant bits
+	hi := binary.BigEndian.Uint64(b[:8])
+	lo := binary.BigEndian.Uint64(b[8:])
+	var id [26]byte
+	for i := 25; i >= 0; i-- {
+		id[i] = crockford[lo&0x1f]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+	return string(id[:])
+}
+
+var (
+	sequencePrefix = newSequencePrefix()
+	sequence       uint64
+)
+
+func newSequencePrefix() string {
+	var b [8]byte
+	random(b[:])
+	return hex.EncodeToString(b[:]) + "-"
+}
+
+func newSequence() string {
+	return sequencePrefix + strconv.FormatUint(atomic.AddUint64(&sequence, 1), 10)
+}
@@ -0,0 +1,101 @@
+package correlation
+
+import (
+	"regexp"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStrategies(t *testing.T) {
+	tests := map[string]struct {
+		strategy Strategy
+		format   *regexp.Regexp
+		ordered  bool
+	}{
+		"uuid v4":  {strategy: UUIDv4, format: regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)},
+		"uuid v7":  {strategy: UUIDv7, format: regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-7[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`), ordered: true},
+		"ulid":     {strategy: ULID, format: regexp.MustCompile(`^[0-7][0-9A-HJKMNP-TV-Z]{25}$`), ordered: true},
+		"sequence": {strategy: Sequence, format: regexp.MustCompile(`^[0-9a-f]{16}-[0-9]+$`)},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			const goroutines, ids = 8, 1000
+			var mu sync.Mutex
+			seen := make(map[string]struct{}, goroutines*ids)
+			var wg sync.WaitGroup
+			wg.Add(goroutines)
+			for i := 0; i < goroutines; i++ {
+				go func() {
+					defer wg.Done()
+					generated := make([]string, ids)
+					for j := range generated {
+						generated[j] = tt.strategy()
+					}
+					mu.Lock()
+					defer mu.Unlock()
+					for _, id := range generated {
+						seen[id] = struct{}{}
+					}
+				}()
+			}
+			wg.Wait()
+			assert.Len(t, seen, goroutines*ids)
+			for id := range seen {
+				assert.Regexp(t, tt.format, id)
+				break
+			}
+
+			if tt.ordered {
+				// the IDs of different milliseconds are sorted in the order of their generation
+				var generated []string
+				for i := 0; i < 3; i++ {
+					generated = append(generated, tt.strategy())
+					time.Sleep(2 * time.Millisecond)
+				}
+				assert.True(t, sort.StringsAreSorted(generated))
+			}
+		})
+	}
+}
+
+func TestNewULID_Timestamp(t *testing.T) {
+	// the first 10 characters encode the unix time in milliseconds
+	before := time.Now().UnixNano() / int64(time.Millisecond)
+	id := ULID()
+	after := time.Now().UnixNano() / int64(time.Millisecond)
+	var ms int64
+	for _, c := range id[:10] {
+		ms = ms<<5 | int64(indexOf(byte(c)))
+	}
+	assert.True(t, ms >= before && ms <= after)
+}
+
+func indexOf(c byte) int {
+	for i := 0; i < len(crockford); i++ {
+		if crockford[i] == c {
+			return i
+		}
+	}
+	return -1
+}
+
+func TestNewID(t *testing.T) {
+	assert.Regexp(t, `^[0-9a-f-]{36}$`, NewID())
+}
+
+func BenchmarkStrategies(b *testing.B) {
+	for name, s := range map[string]Strategy{"uuid v4": UUIDv4, "uuid v7": UUIDv7, "ulid": ULID, "sequence": Sequence} {
+		b.Run(name, func(b *testing.B) {
+			b.ReportAllocs()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					_ = s()
+				}
+			})
+		})
+	}
+}
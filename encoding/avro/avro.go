@@ -0,0 +1,316 @@
+// Package avro provides decoding of Avro binary encoded data into generic Go values.
+package avro
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// Schema is a parsed Avro schema which is able to decode Avro binary encoded data.
+type Schema struct {
+	root *schema
+}
+
+type schema struct {
+	typ     string
+	name    string
+	fields  []field
+	items   *schema
+	values  *schema
+	union   []*schema
+	symbols []string
+	size    int
+}
+
+type field struct {
+	name string
+	typ  *schema
+}
+
+// ParseSchema parses an Avro schema definition in its JSON form.
+func ParseSchema(def string) (*Schema, error) {
+	var raw interface{}
+	if err := json.Unmarshal([]byte(def), &raw); err != nil {
+		// a primitive type can be provided without quotes, e.g. string
+		raw = def
+	}
+	root, err := parse(raw, map[string]*schema{})
+	if err != nil {
+		return nil, err
+	}
+	return &Schema{root: root}, nil
+}
+
+func parse(raw interface{}, names map[string]*schema) (*schema, error) {
+	switch v := raw.(type) {
+	case string:
+		switch v {
+		case "null", "boolean", "int", "long", "float", "double", "bytes", "string":
+			return &schema{typ: v}, nil
+		}
+		s, ok := names[v]
+		if !ok {
+			return nil, fmt.Errorf("unknown type %q", v)
+		}
+		return s, nil
+	case []interface{}:
+		s := &schema{typ: "union", union: make([]*schema, 0, len(v))}
+		for _, u := range v {
+			us, err := parse(u, names)
+			if err != nil {
+				return nil, err
+			}
+			s.union = append(s.union, us)
+		}
+		return s, nil
+	case map[string]interface{}:
+		return parseComplex(v, names)
+	}
+	return nil, fmt.Errorf("invalid schema definition %v", raw)
+}
+
+func parseComplex(def map[string]interface{}, names map[string]*schema) (*schema, error) {
+	typ, ok := def["type"]
+	if !ok {
+		return nil, errors.New("schema type is missing")
+	}
+	t, ok := typ.(string)
+	if !ok {
+		return parse(typ, names)
+	}
+	name, _ := def["name"].(string)
+	switch t {
+	case "record", "error":
+		s := &schema{typ: "record", name: name}
+		if name != "" {
+			names[name] = s
+		}
+		ff, ok := def["fields"].([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("record %q has no fields", name)
+		}
+		for _, f := range ff {
+			fd, ok := f.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("invalid field definition in record %q", name)
+			}
+			fn, _ := fd["name"].(string)
+			fs, err := parse(fd["type"], names)
+			if err != nil {
+				return nil, fmt.Errorf("invalid type of field %q: %w", fn, err)
+			}
+			s.fields = append(s.fields, field{name: fn, typ: fs})
+		}
+		return s, nil
+	case "enum":
+		s := &schema{typ: t, name: name}
+		ss, _ := def["symbols"].([]interface{})
+		for _, sym := range ss {
+			str, _ := sym.(string)
+			s.symbols = append(s.symbols, str)
+		}
+		if name != "" {
+			names[name] = s
+		}
+		return s, nil
+	case "fixed":
+		size, ok := def["size"].(float64)
+		if !ok {
+			return nil, fmt.Errorf("fixed %q has no size", name)
+		}
+		s := &schema{typ: t, name: name, size: int(size)}
+		if name != "" {
+			names[name] = s
+		}
+		return s, nil
+	case "array":
+		items, err := parse(def["items"], names)
+		if err != nil {
+			return nil, fmt.Errorf("invalid array items: %w", err)
+		}
+		return &schema{typ: t, items: items}, nil
+	case "map":
+		values, err := parse(def["values"], names)
+		if err != nil {
+			return nil, fmt.Errorf("invalid map values: %w", err)
+		}
+		return &schema{typ: t, values: values}, nil
+	}
+	return parse(t, names)
+}
+
+// Decode decodes Avro binary encoded data into generic Go values.
+// Records and maps are decoded into map[string]interface{}, arrays into []interface{},
+// enums into their symbol string and unions into the value of the selected branch.
+func (s *Schema) Decode(data []byte) (interface{}, error) {
+	d := &decoder{buf: data}
+	v, err := d.decode(s.root)
+	if err != nil {
+		return nil, err
+	}
+	if len(d.buf) > 0 {
+		return nil, fmt.Errorf("%d trailing bytes after decoding", len(d.buf))
+	}
+	return v, nil
+}
+
+var errShortBuffer = errors.New("unexpected end of data")
+
+type decoder struct {
+	buf []byte
+}
+
+func (d *decoder) decode(s *schema) (interface{}, error) {
+	switch s.typ {
+	case "null":
+		return nil, nil
+	case "boolean":
+		b, err := d.read(1)
+		if err != nil {
+			return nil, err
+		}
+		return b[0] != 0, nil
+	case "int":
+		l, err := d.long()
+		if err != nil {
+			return nil, err
+		}
+		return int32(l), nil
+	case "long":
+		return d.long()
+	case "float":
+		b, err := d.read(4)
+		if err != nil {
+			return nil, err
+		}
+		return math.Float32frombits(binary.LittleEndian.Uint32(b)), nil
+	case "double":
+		b, err := d.read(8)
+		if err != nil {
+			return nil, err
+		}
+		return math.Float64frombits(binary.LittleEndian.Uint64(b)), nil
+	case "bytes":
+		return d.bytes()
+	case "string":
+		b, err := d.bytes()
+		if err != nil {
+			return nil, err
+		}
+		return string(b), nil
+	case "fixed":
+		b, err := d.read(s.size)
+		if err != nil {
+			return nil, err
+		}
+		return append([]byte(nil), b...), nil
+	case "enum":
+		idx, err := d.long()
+		if err != nil {
+			return nil, err
+		}
+		if idx < 0 || int(idx) >= len(s.symbols) {
+			return nil, fmt.Errorf("enum index %d out of range", idx)
+		}
+		return s.symbols[idx], nil
+	case "union":
+		idx, err := d.long()
+		if err != nil {
+			return nil, err
+		}
+		if idx < 0 || int(idx) >= len(s.union) {
+			return nil, fmt.Errorf("union index %d out of range", idx)
+		}
+		return d.decode(s.union[idx])
+	case "record":
+		rec := make(map[string]interface{}, len(s.fields))
+		for _, f := range s.fields {
+			v, err := d.decode(f.typ)
+			if err != nil {
+				return nil, fmt.Errorf("failed to decode field %q: %w", f.name, err)
+			}
+			rec[f.name] = v
+		}
+		return rec, nil
+	case "array":
+		arr := make([]interface{}, 0)
+		err := d.blocks(func() error {
+			v, err := d.decode(s.items)
+			if err != nil {
+				return err
+			}
+			arr = append(arr, v)
+			return nil
+		})
+		return arr, err
+	case "map":
+		m := make(map[string]interface{})
+		err := d.blocks(func() error {
+			k, err := d.bytes()
+			if err != nil {
+				return err
+			}
+			v, err := d.decode(s.values)
+			if err != nil {
+				return err
+			}
+			m[string(k)] = v
+			return nil
+		})
+		return m, err
+	}
+	return nil, fmt.Errorf("unsupported type %q", s.typ)
+}
+
+func (d *decoder) read(n int) ([]byte, error) {
+	if n < 0 || len(d.buf) < n {
+		return nil, errShortBuffer
+	}
+	b := d.buf[:n]
+	d.buf = d.buf[n:]
+	return b, nil
+}
+
+func (d *decoder) long() (int64, error) {
+	v, n := binary.Varint(d.buf)
+	if n <= 0 {
+		return 0, errShortBuffer
+	}
+	d.buf = d.buf[n:]
+	return v, nil
+}
+
+func (d *decoder) bytes() ([]byte, error) {
+	l, err := d.long()
+	if err != nil {
+		return nil, err
+	}
+	return d.read(int(l))
+}
+
+func (d *decoder) blocks(item func() error) error {
+	for {
+		count, err := d.long()
+		if err != nil {
+			return err
+		}
+		if count == 0 {
+			return nil
+		}
+		if count < 0 {
+			// a negative count is followed by the size of the block in bytes
+			count = -count
+			if _, err := d.long(); err != nil {
+				return err
+			}
+		}
+		for i := int64(0); i < count; i++ {
+			if err := item(); err != nil {
+				return err
+			}
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package avro
+
+import (
+	"encoding/binary"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const userSchema = `{
+  "type": "record",
+  "name": "User",
+  "fields": [
+    {"name": "name", "type": "string"},
+    {"name": "age", "type": "int"},
+    {"name": "score", "type": "double"},
+    {"name": "active", "type": "boolean"},
+    {"name": "email", "type": ["null", "string"]},
+    {"name": "role", "type": {"type": "enum", "name": "Role", "symbols": ["ADMIN", "USER"]}},
+    {"name": "tags", "type": {"type": "array", "items": "string"}},
+    {"name": "attrs", "type": {"type": "map", "values": "long"}}
+  ]
+}`
+
+func long(v int64) []byte {
+	b := make([]byte, binary.MaxVarintLen64)
+	n := binary.PutVarint(b, v)
+	return b[:n]
+}
+
+func str(s string) []byte {
+	return append(long(int64(len(s))), s...)
+}
+
+// encodeUser encodes a sample user record in Avro binary format.
+func encodeUser() []byte {
+	var b []byte
+	b = append(b, str("John")...)
+	b = append(b, long(42)...)
+	d := make([]byte, 8)
+	binary.LittleEndian.PutUint64(d, math.Float64bits(9.5))
+	b = append(b, d...)
+	b = append(b, 1)
+	b = append(b, long(1)...)
+	b = append(b, str("john@example.com")...)
+	b = append(b, long(1)...)
+	b = append(b, long(2)...)
+	b = append(b, str("a")...)
+	b = append(b, str("b")...)
+	b = append(b, long(0)...)
+	// block with negative count and byte size
+	b = append(b, long(-1)...)
+	b = append(b, long(3)...)
+	b = append(b, str("k")...)
+	b = append(b, long(7)...)
+	b = append(b, long(0)...)
+	return b
+}
+
+func TestSchema_Decode(t *testing.T) {
+	s, err := ParseSchema(userSchema)
+	assert.NoError(t, err)
+
+	got, err := s.Decode(encodeUser())
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"name":   "John",
+		"age":    int32(42),
+		"score":  9.5,
+		"active": true,
+		"email":  "john@example.com",
+		"role":   "USER",
+		"tags":   []interface{}{"a", "b"},
+		"attrs":  map[string]interface{}{"k": int64(7)},
+	}, got)
+}
+
+func TestSchema_DecodeErrors(t *testing.T) {
+	s, err := ParseSchema(userSchema)
+	assert.NoError(t, err)
+
+	data := encodeUser()
+	_, err = s.Decode(data[:len(data)-3])
+	assert.Error(t, err)
+	_, err = s.Decode(append(data, 0))
+	assert.Error(t, err)
+}
+
+func TestParseSchema(t *testing.T) {
+	tests := map[string]struct {
+		def     string
+		wantErr bool
+	}{
+		"primitive":           {def: `"string"`},
+		"unquoted primitive":  {def: `long`},
+		"named reference":     {def: `{"type":"record","name":"A","fields":[{"name":"b","type":["null","A"]}]}`},
+		"fixed":               {def: `{"type":"fixed","name":"F","size":4}`},
+		"unknown type":        {def: `"foo"`, wantErr: true},
+		"missing type":        {def: `{"name":"A"}`, wantErr: true},
+		"record no fields":    {def: `{"type":"record","name":"A"}`, wantErr: true},
+		"invalid field type":  {def: `{"type":"record","name":"A","fields":[{"name":"b","type":"foo"}]}`, wantErr: true},
+		"fixed without size":  {def: `{"type":"fixed","name":"F"}`, wantErr: true},
+		"invalid array items": {def: `{"type":"array","items":"foo"}`, wantErr: true},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := ParseSchema(tt.def)
+			if tt.wantErr {
+				assert.Error(t, err)
+				assert.Nil(t, got)
+			} else {
+				assert.NoError(t, err)
+				assert.NotNil(t, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,75 @@
+// Package compression provides the codecs compressing the encoded payloads, e.g. the values of Kafka messages,
+// which are identified by the content encoding header.
+package compression
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io/ioutil"
+)
+
+// Codec is a compression codec, which is the value of the content encoding header.
+type Codec string
+
+const (
+	// Gzip compresses with gzip.
+	Gzip Codec = "gzip"
+	// Zstd compresses with zstd, which requires cgo.
+	Zstd Codec = "zstd"
+)
+
+// Validate returns an error if the codec is not supported.
+func (c Codec) Validate() error {
+	switch c {
+	case Gzip:
+		return nil
+	case Zstd:
+		return zstdSupported()
+	default:
+		return fmt.Errorf("compression codec %q is unsupported", string(c))
+	}
+}
+
+// Compress compresses the data with the codec.
+func Compress(c Codec, data []byte) ([]byte, error) {
+	switch c {
+	case Gzip:
+		var buf bytes.Buffer
+		w := gzip.NewWriter(&buf)
+		_, err := w.Write(data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to gzip: %w", err)
+		}
+		err = w.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to gzip: %w", err)
+		}
+		return buf.Bytes(), nil
+	case Zstd:
+		return zstdCompress(data)
+	default:
+		return nil, c.Validate()
+	}
+}
+
+// Decompress decompresses the data with the codec.
+func Decompress(c Codec, data []byte) ([]byte, error) {
+	switch c {
+	case Gzip:
+		r, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("failed to gunzip: %w", err)
+		}
+		defer r.Close()
+		b, err := ioutil.ReadAll(r)
+		if err != nil {
+			return nil, fmt.Errorf("failed to gunzip: %w", err)
+		}
+		return b, nil
+	case Zstd:
+		return zstdDecompress(data)
+	default:
+		return nil, c.Validate()
+	}
+}
@@ -0,0 +1,36 @@
+package compression
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompressDecompress(t *testing.T) {
+	data := bytes.Repeat([]byte(`{"name":"patron"}`), 100)
+	for _, c := range []Codec{Gzip, Zstd} {
+		t.Run(string(c), func(t *testing.T) {
+			if c.Validate() != nil {
+				t.Skip("codec is not supported in this build")
+			}
+			compressed, err := Compress(c, data)
+			require.NoError(t, err)
+			assert.True(t, len(compressed) < len(data))
+			decompressed, err := Decompress(c, compressed)
+			require.NoError(t, err)
+			assert.Equal(t, data, decompressed)
+		})
+	}
+}
+
+func TestUnsupportedCodec(t *testing.T) {
+	assert.EqualError(t, Codec("lz4").Validate(), `compression codec "lz4" is unsupported`)
+	_, err := Compress("lz4", []byte("data"))
+	assert.Error(t, err)
+	_, err = Decompress("lz4", []byte("data"))
+	assert.Error(t, err)
+	_, err = Decompress(Gzip, []byte("not gzip"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,29 @@
+// +build cgo
+
+package compression
+
+import (
+	"fmt"
+
+	"github.com/DataDog/zstd"
+)
+
+func zstdSupported() error {
+	return nil
+}
+
+func zstdCompress(data []byte) ([]byte, error) {
+	b, err := zstd.Compress(nil, data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compress with zstd: %w", err)
+	}
+	return b, nil
+}
+
+func zstdDecompress(data []byte) ([]byte, error) {
+	b, err := zstd.Decompress(nil, data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress with zstd: %w", err)
+	}
+	return b, nil
+}
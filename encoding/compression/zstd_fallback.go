@@ -0,0 +1,19 @@
+// +build !cgo
+
+package compression
+
+import "errors"
+
+var errZstdCgo = errors.New("zstd compression requires cgo")
+
+func zstdSupported() error {
+	return errZstdCgo
+}
+
+func zstdCompress([]byte) ([]byte, error) {
+	return nil, errZstdCgo
+}
+
+func zstdDecompress([]byte) ([]byte, error) {
+	return nil, errZstdCgo
+}
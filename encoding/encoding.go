@@ -9,6 +9,8 @@ const (
 	AcceptHeader string = "Accept"
 	// ContentTypeHeader for defining content type headers.
 	ContentTypeHeader string = "Content-Type"
+	// ContentEncodingHeader for defining the compression of the content.
+	ContentEncodingHeader string = "Content-Encoding"
 )
 
 // DecodeFunc function definition of a JSON decoding function.
@@ -1,8 +1,12 @@
 package json
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
 	"io"
+	"io/ioutil"
+	"sync/atomic"
 )
 
 const (
@@ -12,17 +16,128 @@ const (
 	TypeCharset string = "application/json; charset=utf-8"
 )
 
+// Library is the JSON implementation used by the package, which allows replacing encoding/json
+// with a faster implementation, e.g. jsoniter, on hot paths. Implementations have to be compatible with
+// encoding/json, e.g. regarding the field tags and omitempty.
+type Library interface {
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+	Decode(r io.Reader, v interface{}) error
+}
+
+// StandardLibrary is the Library implemented by encoding/json, which is the default.
+type StandardLibrary struct{}
+
+// Marshal a model to JSON.
+func (StandardLibrary) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+// Unmarshal a JSON input in the form of a byte slice.
+func (StandardLibrary) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+// Decode a JSON input in the form of a reader.
+func (StandardLibrary) Decode(r io.Reader, v interface{}) error {
+	return json.NewDecoder(r).Decode(v)
+}
+
+// API is the API of the JSON implementations which are compatible with encoding/json,
+// e.g. jsoniter.ConfigCompatibleWithStandardLibrary.
+type API interface {
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+}
+
+// NewLibrary adapts the API of a JSON implementation to a Library, e.g. NewLibrary(jsoniter.ConfigCompatibleWithStandardLibrary).
+func NewLibrary(api API) (Library, error) {
+	if api == nil {
+		return nil, errors.New("JSON API is nil")
+	}
+	return apiLibrary{api: api}, nil
+}
+
+type apiLibrary struct {
+	api API
+}
+
+// Marshal a model to JSON.
+func (l apiLibrary) Marshal(v interface{}) ([]byte, error) {
+	return l.api.Marshal(v)
+}
+
+// Unmarshal a JSON input in the form of a byte slice.
+func (l apiLibrary) Unmarshal(data []byte, v interface{}) error {
+	return l.api.Unmarshal(data, v)
+}
+
+// Decode a JSON input in the form of a reader, which is read completely.
+func (l apiLibrary) Decode(r io.Reader, v interface{}) error {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	return l.api.Unmarshal(data, v)
+}
+
+// libraryHolder keeps the concrete type stored in the atomic value constant.
+type libraryHolder struct {
+	Library
+}
+
+var library atomic.Value
+
+func init() {
+	library.Store(libraryHolder{StandardLibrary{}})
+}
+
+// SetLibrary sets the JSON implementation used by the package, and therefore by every user of it, e.g. the HTTP
+// handlers and the Kafka JSON decoder. Services set it with the JSONLibrary option of the service.
+func SetLibrary(lib Library) error {
+	if lib == nil {
+		return errors.New("JSON library is nil")
+	}
+	library.Store(libraryHolder{lib})
+	return nil
+}
+
+func currentLibrary() Library {
+	return library.Load().(libraryHolder).Library
+}
+
 // Decode a JSON input in the form of a read.
 func Decode(data io.Reader, v interface{}) error {
-	return json.NewDecoder(data).Decode(v)
+	return currentLibrary().Decode(data, v)
 }
 
 // DecodeRaw a JSON input in the form of a byte slice.
 func DecodeRaw(data []byte, v interface{}) error {
-	return json.Unmarshal(data, v)
+	return currentLibrary().Unmarshal(data, v)
 }
 
 // Encode a model to JSON.
 func Encode(v interface{}) ([]byte, error) {
-	return json.Marshal(v)
+	return currentLibrary().Marshal(v)
+}
+
+// EncodeTo encodes a model to JSON into the buffer, with the same output as Encode. The standard library encodes
+// directly into the buffer, without allocating the encoded payload, while the output of other libraries is copied to it.
+func EncodeTo(buf *bytes.Buffer, v interface{}) error {
+	lib := currentLibrary()
+	if _, ok := lib.(StandardLibrary); !ok {
+		b, err := lib.Marshal(v)
+		if err != nil {
+			return err
+		}
+		_, err = buf.Write(b)
+		return err
+	}
+	err := json.NewEncoder(buf).Encode(v)
+	if err != nil {
+		return err
+	}
+	// unlike Marshal, the encoder terminates the value with a newline
+	buf.Truncate(buf.Len() - 1)
+	return nil
 }
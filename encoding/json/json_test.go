@@ -2,9 +2,12 @@ package json
 
 import (
 	"bytes"
+	"encoding/json"
+	"io"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestEncodeDecode(t *testing.T) {
@@ -19,3 +22,201 @@ func TestEncodeDecode(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, "string", data)
 }
+
+type model struct {
+	Name     string            `json:"name"`
+	Optional string            `json:"optional,omitempty"`
+	Ignored  string            `json:"-"`
+	Tags     map[string]string `json:"tags"`
+}
+
+type spyLibrary struct {
+	StandardLibrary
+	calls int
+}
+
+func (s *spyLibrary) Marshal(v interface{}) ([]byte, error) {
+	s.calls++
+	return s.StandardLibrary.Marshal(v)
+}
+
+func (s *spyLibrary) Unmarshal(data []byte, v interface{}) error {
+	s.calls++
+	return s.StandardLibrary.Unmarshal(data, v)
+}
+
+func (s *spyLibrary) Decode(r io.Reader, v interface{}) error {
+	s.calls++
+	return s.StandardLibrary.Decode(r, v)
+}
+
+func TestSetLibrary(t *testing.T) {
+	assert.Error(t, SetLibrary(nil))
+
+	spy := &spyLibrary{}
+	require.NoError(t, SetLibrary(spy))
+	defer func() { require.NoError(t, SetLibrary(StandardLibrary{})) }()
+
+	in := model{Name: "name", Ignored: "ignored", Tags: map[string]string{"key": "value"}}
+	b, err := Encode(in)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"name":"name","tags":{"key":"value"}}`, string(b))
+
+	var out model
+	assert.NoError(t, DecodeRaw(b, &out))
+	var outDecoded model
+	assert.NoError(t, Decode(bytes.NewReader(b), &outDecoded))
+
+	in.Ignored = ""
+	assert.Equal(t, in, out)
+	assert.Equal(t, in, outDecoded)
+	assert.Equal(t, 3, spy.calls)
+}
+
+func TestEncodeTo(t *testing.T) {
+	in := model{Name: "<name>", Tags: map[string]string{"key": "value"}}
+	expected, err := Encode(in)
+	require.NoError(t, err)
+
+	buf := &bytes.Buffer{}
+	assert.NoError(t, EncodeTo(buf, in))
+	assert.Equal(t, string(expected), buf.String())
+	assert.Error(t, EncodeTo(&bytes.Buffer{}, make(chan bool)))
+
+	spy := &spyLibrary{}
+	require.NoError(t, SetLibrary(spy))
+	defer func() { require.NoError(t, SetLibrary(StandardLibrary{})) }()
+	buf.Reset()
+	assert.NoError(t, EncodeTo(buf, in))
+	assert.Equal(t, string(expected), buf.String())
+	assert.Equal(t, 1, spy.calls)
+}
+
+// stdAPI is the API of encoding/json, which is adapted by NewLibrary.
+type stdAPI struct{}
+
+func (stdAPI) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (stdAPI) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+// libraries are the implementations compared with encoding/json, which include jsoniter when built with the jsoniter tag.
+var libraries = map[string]Library{
+	"encoding/json": StandardLibrary{},
+	"adapter":       mustLibrary(stdAPI{}),
+}
+
+func mustLibrary(api API) Library {
+	lib, err := NewLibrary(api)
+	if err != nil {
+		panic(err)
+	}
+	return lib
+}
+
+func TestNewLibrary(t *testing.T) {
+	lib, err := NewLibrary(nil)
+	assert.Error(t, err)
+	assert.Nil(t, lib)
+	lib, err = NewLibrary(stdAPI{})
+	assert.NoError(t, err)
+	assert.NotNil(t, lib)
+}
+
+type nested struct {
+	Count   int      `json:"count,omitempty"`
+	Enabled *bool    `json:"enabled,omitempty"`
+	Items   []string `json:"items"`
+}
+
+type compatibilityModel struct {
+	model
+	Nested   nested            `json:"nested"`
+	Pointer  *nested           `json:"pointer,omitempty"`
+	Raw      json.RawMessage   `json:"raw"`
+	Any      interface{}       `json:"any"`
+	HTML     string            `json:"html"`
+	Untagged string            ``
+	Numbers  map[string]uint64 `json:"numbers"`
+}
+
+func TestLibraries_RoundTrip(t *testing.T) {
+	enabled := false
+	in := compatibilityModel{
+		model:    model{Name: "name", Ignored: "ignored", Tags: map[string]string{"b": "2", "a": "1"}},
+		Nested:   nested{Enabled: &enabled, Items: []string{"x", "y"}},
+		Raw:      json.RawMessage(`{"raw":true}`),
+		Any:      map[string]interface{}{"float": 1.5, "list": []interface{}{"a", nil}},
+		HTML:     "<a href=\"/\">&</a>",
+		Untagged: "untagged",
+		Numbers:  map[string]uint64{"max": 1<<53 - 1},
+	}
+	expected, err := StandardLibrary{}.Marshal(in)
+	require.NoError(t, err)
+
+	for name, lib := range libraries {
+		t.Run(name, func(t *testing.T) {
+			b, err := lib.Marshal(in)
+			require.NoError(t, err)
+			// the field tags, omitempty and the escaping of HTML are compatible with encoding/json
+			assert.Equal(t, string(expected), string(b))
+
+			var out, outDecoded compatibilityModel
+			require.NoError(t, lib.Unmarshal(b, &out))
+			require.NoError(t, lib.Decode(bytes.NewReader(b), &outDecoded))
+			var stdOut compatibilityModel
+			require.NoError(t, StandardLibrary{}.Unmarshal(b, &stdOut))
+			assert.Equal(t, stdOut, out)
+			assert.Equal(t, stdOut, outDecoded)
+			assert.Error(t, lib.Unmarshal([]byte(`{"name":`), &out))
+		})
+	}
+}
+
+// The benchmarks use the current library, in order to compare an implementation with encoding/json
+// it has to be set with SetLibrary.
+func BenchmarkEncode(b *testing.B) {
+	m := model{Name: "name", Optional: "optional", Tags: map[string]string{"key": "value"}}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_, _ = Encode(m)
+	}
+}
+
+func BenchmarkDecodeRaw(b *testing.B) {
+	data := []byte(`{"name":"name","optional":"optional","tags":{"key":"value"}}`)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		var m model
+		_ = DecodeRaw(data, &m)
+	}
+}
+
+// BenchmarkLibraries compares the libraries, e.g. jsoniter with encoding/json with: go test -tags jsoniter -bench Libraries
+func BenchmarkLibraries(b *testing.B) {
+	m := compatibilityModel{
+		model:  model{Name: "name", Optional: "optional", Tags: map[string]string{"key": "value"}},
+		Nested: nested{Count: 3, Items: []string{"x", "y", "z"}},
+		Raw:    json.RawMessage(`{}`),
+	}
+	data, err := StandardLibrary{}.Marshal(m)
+	require.NoError(b, err)
+	for name, lib := range libraries {
+		b.Run(name+"/marshal", func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				_, _ = lib.Marshal(m)
+			}
+		})
+		b.Run(name+"/unmarshal", func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				var out compatibilityModel
+				_ = lib.Unmarshal(data, &out)
+			}
+		})
+	}
+}
@@ -0,0 +1,13 @@
+// +build jsoniter
+
+package json
+
+import (
+	jsoniter "github.com/json-iterator/go"
+)
+
+// The comparison with jsoniter requires github.com/json-iterator/go in the module graph, which patron does not depend on:
+// go test -tags jsoniter -run Libraries -bench Libraries ./encoding/json
+func init() {
+	libraries["jsoniter"] = mustLibrary(jsoniter.ConfigCompatibleWithStandardLibrary)
+}
@@ -0,0 +1,62 @@
+package info
+
+import "sort"
+
+// Direction of a dependency, from the point of view of the service.
+type Direction string
+
+const (
+	// Inbound dependencies deliver data to the service, e.g. a consumed topic.
+	Inbound Direction = "inbound"
+	// Outbound dependencies receive data from the service, e.g. a produced topic or a downstream HTTP service.
+	Outbound Direction = "outbound"
+)
+
+// Dependency of the service, e.g. a Kafka topic or a downstream HTTP service, which is exposed
+// via the /info endpoint in order to map the dependencies across services.
+type Dependency struct {
+	// Type of the dependency, e.g. kafka or http.
+	Type string `json:"type"`
+	// Name of the dependency, e.g. the name of a topic or a service.
+	Name string `json:"name"`
+	// Direction of the dependency.
+	Direction Direction `json:"direction"`
+}
+
+// AddDependency adds a dependency of the service. A dependency is added only once.
+func AddDependency(d Dependency) {
+	srv.RLock()
+	_, ok := srv.dependencies[d]
+	srv.RUnlock()
+	if ok {
+		return
+	}
+	srv.Lock()
+	defer srv.Unlock()
+	srv.dependencies[d] = struct{}{}
+}
+
+// Dependencies returns the dependencies of the service, sorted by type, name and direction.
+func Dependencies() []Dependency {
+	srv.RLock()
+	defer srv.RUnlock()
+	return dependencies()
+}
+
+// dependencies returns the sorted dependencies of the service, when the service information is locked.
+func dependencies() []Dependency {
+	dd := make([]Dependency, 0, len(srv.dependencies))
+	for d := range srv.dependencies {
+		dd = append(dd, d)
+	}
+	sort.Slice(dd, func(i, j int) bool {
+		if dd[i].Type != dd[j].Type {
+			return dd[i].Type < dd[j].Type
+		}
+		if dd[i].Name != dd[j].Name {
+			return dd[i].Name < dd[j].Name
+		}
+		return dd[i].Direction < dd[j].Direction
+	})
+	return dd
+}
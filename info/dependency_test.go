@@ -0,0 +1,34 @@
+package info
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDependencies(t *testing.T) {
+	defer func() {
+		srv.Lock()
+		srv.dependencies = make(map[Dependency]struct{})
+		srv.Unlock()
+	}()
+	AddDependency(Dependency{Type: "kafka", Name: "orders", Direction: Outbound})
+	AddDependency(Dependency{Type: "http", Name: "payments", Direction: Outbound})
+	AddDependency(Dependency{Type: "kafka", Name: "orders", Direction: Inbound})
+	AddDependency(Dependency{Type: "kafka", Name: "orders", Direction: Outbound})
+
+	assert.Equal(t, []Dependency{
+		{Type: "http", Name: "payments", Direction: Outbound},
+		{Type: "kafka", Name: "orders", Direction: Inbound},
+		{Type: "kafka", Name: "orders", Direction: Outbound},
+	}, Dependencies())
+
+	b, err := Marshal()
+	require.NoError(t, err)
+	var got map[string]interface{}
+	require.NoError(t, json.Unmarshal(b, &got))
+	assert.Len(t, got["dependencies"], 3)
+	assert.Equal(t, map[string]interface{}{"type": "http", "name": "payments", "direction": "outbound"}, got["dependencies"].([]interface{})[0])
+}
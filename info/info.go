@@ -0,0 +1,249 @@
+// Package info holds information about the running service, which is exposed via the /info endpoint.
+package info
+
+import (
+	"encoding/json"
+	"runtime"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	// Commit of the build of the service, which is set with the linker, e.g. -ldflags "-X github.com/beatlabs/patron/info.Commit=$(git rev-parse HEAD)".
+	Commit = "unknown"
+	// BuildDate of the service, which is set with the linker, e.g. -ldflags "-X github.com/beatlabs/patron/info.BuildDate=$(date -u +%FT%TZ)".
+	BuildDate = "unknown"
+)
+
+var buildInfo *prometheus.GaugeVec
+
+func init() {
+	buildInfo = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "service",
+			Name:      "build_info",
+			Help:      "Build information of the service, which is always 1, labeled by name, version, Go version, commit and build date",
+		},
+		[]string{"name", "version", "goversion", "commit", "build_date"},
+	)
+	prometheus.MustRegister(buildInfo)
+}
+
+type info struct {
+	sync.RWMutex
+	name       string
+	version    string
+	host       string
+	started    time.Time
+	startOnce  sync.Once
+	deprecated map[string]deprecatedRoute
+	components []ComponentInfo
+	// dependencies is a set, since consumers and producers add their topics repeatedly.
+	dependencies map[Dependency]struct{}
+	// statuses are evaluated on every marshaling, since they change while the service runs.
+	statuses map[string]func() interface{}
+}
+
+// ComponentInfo describes a component registered in the service.
+type ComponentInfo struct {
+	// Type of the component, e.g. *http.Component.
+	Type string
+	// Fields of the component, e.g. the port of an HTTP component.
+	Fields map[string]interface{}
+}
+
+func (ci ComponentInfo) toMap() map[string]interface{} {
+	m := make(map[string]interface{}, len(ci.Fields)+1)
+	for k, v := range ci.Fields {
+		m[k] = v
+	}
+	m["type"] = ci.Type
+	return m
+}
+
+type deprecatedRoute struct {
+	Method string `json:"method"`
+	Path   string `json:"path"`
+	Since  string `json:"since"`
+	Sunset string `json:"sunset,omitempty"`
+}
+
+var srv = &info{
+	deprecated:   make(map[string]deprecatedRoute),
+	dependencies: make(map[Dependency]struct{}),
+	statuses:     make(map[string]func() interface{}),
+}
+
+// UpdateName updates the name and the version of the service, which are also exposed by the service_build_info gauge.
+func UpdateName(name, version string) {
+	srv.Lock()
+	defer srv.Unlock()
+	srv.name = name
+	srv.version = version
+	buildInfo.Reset()
+	buildInfo.WithLabelValues(name, version, runtime.Version(), Commit, BuildDate).Set(1)
+}
+
+// UpdateHost updates the host of the service.
+func UpdateHost(host string) {
+	srv.Lock()
+	defer srv.Unlock()
+	srv.host = host
+}
+
+// AddStatus adds a status of the service by name, e.g. the stuck partitions of the Kafka consumers, which changes while the service runs.
+// The status is evaluated every time the information is marshaled, and is omitted when nil.
+func AddStatus(name string, f func() interface{}) {
+	srv.Lock()
+	defer srv.Unlock()
+	srv.statuses[name] = f
+}
+
+// AddDeprecatedRoute adds a deprecated route of the service, along with the time it was deprecated
+// and the optional time it will be removed.
+func AddDeprecatedRoute(method, path string, since, sunset time.Time) {
+	srv.Lock()
+	defer srv.Unlock()
+	r := deprecatedRoute{Method: method, Path: path, Since: since.UTC().Format(time.RFC3339)}
+	if !sunset.IsZero() {
+		r.Sunset = sunset.UTC().Format(time.RFC3339)
+	}
+	srv.deprecated[method+" "+path] = r
+}
+
+// AddComponent adds the information of a component registered in the service.
+func AddComponent(ci ComponentInfo) {
+	AddComponents(ci)
+}
+
+// AddComponents adds the information of multiple components registered in the service at once.
+func AddComponents(cc ...ComponentInfo) {
+	srv.Lock()
+	defer srv.Unlock()
+	srv.components = append(srv.components, cc...)
+}
+
+// Components returns the information of the components registered in the service, in the order of registration.
+func Components() []map[string]interface{} {
+	srv.RLock()
+	defer srv.RUnlock()
+	cc := make([]map[string]interface{}, 0, len(srv.components))
+	for _, ci := range srv.components {
+		cc = append(cc, ci.toMap())
+	}
+	return cc
+}
+
+// MarkStarted records the start time of the service. Only the first call has an effect.
+// The start time is also exposed as the standard process_start_time_seconds gauge,
+// unless the platform's process collector already provides it.
+func MarkStarted() {
+	srv.startOnce.Do(func() {
+		now := time.Now()
+		srv.Lock()
+		srv.started = now
+		srv.Unlock()
+
+		g := prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "process_start_time_seconds",
+			Help: "Start time of the process since unix epoch in seconds.",
+		})
+		g.Set(float64(now.UnixNano()) / 1e9)
+		// the process collector of the default registry exports the same metric on Linux, in which case registration fails
+		_ = prometheus.Register(g)
+	})
+}
+
+// Started returns the start time of the service or the zero time if the service has not started yet.
+func Started() time.Time {
+	srv.RLock()
+	defer srv.RUnlock()
+	return srv.started
+}
+
+// Uptime returns the duration since the service started or zero if the service has not started yet.
+func Uptime() time.Duration {
+	started := Started()
+	if started.IsZero() {
+		return 0
+	}
+	return time.Since(started)
+}
+
+// Marshal returns the service information in JSON format.
+func Marshal() ([]byte, error) {
+	return json.Marshal(fields())
+}
+
+// MarshalWithRuntime returns the service information in JSON format, along with a snapshot of the runtime statistics,
+// i.e. the number of goroutines, the allocated heap, the number of garbage collections and the last GC pause.
+// Reading the memory statistics stops the world for a short time.
+func MarshalWithRuntime() ([]byte, error) {
+	out := fields()
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+	var lastPause time.Duration
+	if ms.NumGC > 0 {
+		lastPause = time.Duration(ms.PauseNs[(ms.NumGC+255)%256])
+	}
+	out["runtime"] = map[string]interface{}{
+		"goroutines":   runtime.NumGoroutine(),
+		"heapAlloc":    ms.HeapAlloc,
+		"numGC":        ms.NumGC,
+		"lastGCPause":  lastPause.String(),
+		"totalGCPause": time.Duration(ms.PauseTotalNs).String(),
+	}
+	return json.Marshal(out)
+}
+
+func fields() map[string]interface{} {
+	srv.RLock()
+	out := map[string]interface{}{
+		"name":    srv.name,
+		"version": srv.version,
+		"host":    srv.host,
+	}
+	if len(srv.deprecated) > 0 {
+		rr := make([]deprecatedRoute, 0, len(srv.deprecated))
+		for _, r := range srv.deprecated {
+			rr = append(rr, r)
+		}
+		sort.Slice(rr, func(i, j int) bool {
+			if rr[i].Path == rr[j].Path {
+				return rr[i].Method < rr[j].Method
+			}
+			return rr[i].Path < rr[j].Path
+		})
+		out["deprecatedRoutes"] = rr
+	}
+	if len(srv.components) > 0 {
+		cc := make([]map[string]interface{}, 0, len(srv.components))
+		for _, ci := range srv.components {
+			cc = append(cc, ci.toMap())
+		}
+		out["components"] = cc
+	}
+	if len(srv.dependencies) > 0 {
+		out["dependencies"] = dependencies()
+	}
+	started := srv.started
+	statuses := make(map[string]func() interface{}, len(srv.statuses))
+	for name, f := range srv.statuses {
+		statuses[name] = f
+	}
+	srv.RUnlock()
+	// the statuses are evaluated without holding the lock, since they may lock the state of the components
+	for name, f := range statuses {
+		if v := f(); v != nil {
+			out[name] = v
+		}
+	}
+	if !started.IsZero() {
+		out["started"] = started.UTC().Format(time.RFC3339)
+		out["uptime"] = time.Since(started).String()
+	}
+	return out
+}
@@ -0,0 +1,146 @@
+package info
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInfo(t *testing.T) {
+	UpdateName("test", "1.0.0")
+	UpdateHost("host")
+	assert.Equal(t, time.Duration(0), Uptime())
+
+	b, err := Marshal()
+	assert.NoError(t, err)
+	var got map[string]interface{}
+	assert.NoError(t, json.Unmarshal(b, &got))
+	assert.Equal(t, map[string]interface{}{"name": "test", "version": "1.0.0", "host": "host"}, got)
+
+	wg := sync.WaitGroup{}
+	wg.Add(10)
+	for i := 0; i < 10; i++ {
+		go func() {
+			defer wg.Done()
+			MarkStarted()
+			_ = Uptime()
+		}()
+	}
+	wg.Wait()
+	started := Started()
+	assert.False(t, started.IsZero())
+
+	MarkStarted()
+	assert.Equal(t, started, Started())
+	assert.True(t, Uptime() > 0)
+
+	b, err = Marshal()
+	assert.NoError(t, err)
+	assert.NoError(t, json.Unmarshal(b, &got))
+	assert.Equal(t, started.UTC().Format(time.RFC3339), got["started"])
+	assert.NotEmpty(t, got["uptime"])
+}
+
+func TestAddDeprecatedRoute(t *testing.T) {
+	defer func() { srv.deprecated = make(map[string]deprecatedRoute) }()
+	since := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	sunset := time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC)
+	AddDeprecatedRoute("GET", "/b", since, sunset)
+	AddDeprecatedRoute("POST", "/a", since, time.Time{})
+	AddDeprecatedRoute("GET", "/a", since, time.Time{})
+	AddDeprecatedRoute("GET", "/a", since, time.Time{})
+
+	b, err := Marshal()
+	assert.NoError(t, err)
+	var got struct {
+		DeprecatedRoutes []map[string]string `json:"deprecatedRoutes"`
+	}
+	assert.NoError(t, json.Unmarshal(b, &got))
+	assert.Equal(t, []map[string]string{
+		{"method": "GET", "path": "/a", "since": "2020-01-01T00:00:00Z"},
+		{"method": "POST", "path": "/a", "since": "2020-01-01T00:00:00Z"},
+		{"method": "GET", "path": "/b", "since": "2020-01-01T00:00:00Z", "sunset": "2020-06-01T00:00:00Z"},
+	}, got.DeprecatedRoutes)
+}
+
+func TestMarshalWithRuntime(t *testing.T) {
+	b, err := MarshalWithRuntime()
+	assert.NoError(t, err)
+	var got map[string]interface{}
+	assert.NoError(t, json.Unmarshal(b, &got))
+	assert.Contains(t, got, "name")
+	assert.Contains(t, got["runtime"], "goroutines")
+	assert.Contains(t, got["runtime"], "heapAlloc")
+}
+
+func TestComponents(t *testing.T) {
+	defer func() { srv.components = nil }()
+	assert.Empty(t, Components())
+
+	AddComponent(ComponentInfo{Type: "*http.Component", Fields: map[string]interface{}{"port": 50000}})
+	AddComponents(ComponentInfo{Type: "*async.Component"})
+	expected := []map[string]interface{}{
+		{"type": "*http.Component", "port": 50000},
+		{"type": "*async.Component"},
+	}
+	assert.Equal(t, expected, Components())
+
+	b, err := Marshal()
+	assert.NoError(t, err)
+	var got struct {
+		Components []map[string]interface{} `json:"components"`
+	}
+	assert.NoError(t, json.Unmarshal(b, &got))
+	assert.Equal(t, []map[string]interface{}{
+		{"type": "*http.Component", "port": float64(50000)},
+		{"type": "*async.Component"},
+	}, got.Components)
+}
+
+func TestBuildInfo(t *testing.T) {
+	defer func(commit, date string) {
+		Commit, BuildDate = commit, date
+	}(Commit, BuildDate)
+	Commit, BuildDate = "abc123", "2020-01-02T03:04:05Z"
+	UpdateName("old", "0.9.0")
+	UpdateName("test", "1.0.0")
+
+	rc := httptest.NewRecorder()
+	promhttp.Handler().ServeHTTP(rc, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	b, err := ioutil.ReadAll(rc.Body)
+	assert.NoError(t, err)
+	assert.Contains(t, string(b), fmt.Sprintf(`service_build_info{build_date="2020-01-02T03:04:05Z",commit="abc123",goversion="%s",name="test",version="1.0.0"} 1`,
+		runtime.Version()))
+	// the labels of a previous name are replaced
+	assert.NotContains(t, string(b), `name="old"`)
+}
+
+func TestAddStatus(t *testing.T) {
+	defer func() {
+		srv.Lock()
+		delete(srv.statuses, "queue")
+		srv.Unlock()
+	}()
+	var status interface{}
+	AddStatus("queue", func() interface{} { return status })
+
+	b, err := Marshal()
+	assert.NoError(t, err)
+	assert.NotContains(t, string(b), "queue")
+
+	status = map[string]int{"pending": 3}
+	b, err = Marshal()
+	assert.NoError(t, err)
+	var got map[string]interface{}
+	assert.NoError(t, json.Unmarshal(b, &got))
+	assert.Equal(t, map[string]interface{}{"pending": 3.0}, got["queue"])
+}
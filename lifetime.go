@@ -0,0 +1,45 @@
+package patron
+
+import (
+	"time"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+)
+
+const (
+	lifetimeSpanName = "service"
+
+	shutdownReasonFailed    = "component failed"
+	shutdownReasonCompleted = "component completed"
+	shutdownReasonCanceled  = "context canceled"
+)
+
+// startLifetimeSpan starts the span covering the lifetime of the service, when the LifetimeSpan option is provided,
+// which starts at the creation of the service and has a child span for each startup phase.
+func (s *Service) startLifetimeSpan() opentracing.Span {
+	if !s.lifetimeSpan {
+		return nil
+	}
+	sp := opentracing.StartSpan(lifetimeSpanName, opentracing.StartTime(s.created))
+	for _, p := range s.startupPhases {
+		psp := opentracing.StartSpan(p.phase, opentracing.ChildOf(sp.Context()), opentracing.StartTime(p.start))
+		psp.FinishWithOptions(opentracing.FinishOptions{FinishTime: p.end})
+	}
+	return sp
+}
+
+// finishLifetimeSpan finishes the lifetime span of the service, if started, with the uptime and the reason of the shutdown.
+func finishLifetimeSpan(sp opentracing.Span, created time.Time, reason string, failed bool) {
+	if sp == nil {
+		return
+	}
+	uptime := time.Since(created)
+	sp.SetTag("uptime", uptime.String())
+	sp.SetTag("reason", reason)
+	if failed {
+		ext.Error.Set(sp, true)
+	}
+	sp.LogKV("event", "shutdown", "uptime", uptime.String(), "reason", reason)
+	sp.Finish()
+}
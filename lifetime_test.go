@@ -0,0 +1,77 @@
+package patron
+
+import (
+	"context"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/mocktracer"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestService_LifetimeSpan(t *testing.T) {
+	assert.NoError(t, os.Setenv("PATRON_HTTP_DEFAULT_PORT", getRandomPort()))
+	s, err := New("test", "", Components(&blockingComponent{}), LifetimeSpan())
+	assert.NoError(t, err)
+	mtr := mocktracer.New()
+	defer func(tr opentracing.Tracer) { opentracing.SetGlobalTracer(tr) }(opentracing.GlobalTracer())
+	opentracing.SetGlobalTracer(mtr)
+
+	done := make(chan error)
+	go func() { done <- s.Run(context.Background()) }()
+
+	// the startup phases are finished, while the lifetime span is open until the shutdown
+	for i := 0; i < 50 && len(mtr.FinishedSpans()) < 3; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	phases := mtr.FinishedSpans()
+	assert.Len(t, phases, 3)
+
+	s.termSig <- syscall.SIGTERM
+	assert.NoError(t, <-done)
+
+	spans := mtr.FinishedSpans()
+	assert.Len(t, spans, 4)
+	sp := spans[3]
+	assert.Equal(t, "service", sp.OperationName)
+	assert.Equal(t, s.created, sp.StartTime)
+	assert.Equal(t, "signal terminated", sp.Tag("reason"))
+	uptime, err := time.ParseDuration(sp.Tag("uptime").(string))
+	assert.NoError(t, err)
+	assert.True(t, uptime > 0)
+	assert.Nil(t, sp.Tag("error"))
+	for i, phase := range []string{startupPhaseSetup, startupPhaseTracing, startupPhaseComponents} {
+		assert.Equal(t, phase, phases[i].OperationName)
+		assert.Equal(t, sp.SpanContext.SpanID, phases[i].ParentID)
+	}
+}
+
+func TestService_LifetimeSpan_Failure(t *testing.T) {
+	assert.NoError(t, os.Setenv("PATRON_HTTP_DEFAULT_PORT", getRandomPort()))
+	s, err := New("test", "", Components(&blockingComponent{}, &testComponent{errorRunning: true}), LifetimeSpan())
+	assert.NoError(t, err)
+	mtr := mocktracer.New()
+	defer func(tr opentracing.Tracer) { opentracing.SetGlobalTracer(tr) }(opentracing.GlobalTracer())
+	opentracing.SetGlobalTracer(mtr)
+
+	assert.Error(t, s.Run(context.Background()))
+	spans := mtr.FinishedSpans()
+	assert.Len(t, spans, 4)
+	assert.Equal(t, "component failed", spans[3].Tag("reason"))
+	assert.Equal(t, true, spans[3].Tag("error"))
+}
+
+func TestService_NoLifetimeSpan(t *testing.T) {
+	assert.NoError(t, os.Setenv("PATRON_HTTP_DEFAULT_PORT", getRandomPort()))
+	s, err := New("test", "", Components(&testComponent{}))
+	assert.NoError(t, err)
+	mtr := mocktracer.New()
+	defer func(tr opentracing.Tracer) { opentracing.SetGlobalTracer(tr) }(opentracing.GlobalTracer())
+	opentracing.SetGlobalTracer(mtr)
+
+	assert.NoError(t, s.Run(context.Background()))
+	assert.Empty(t, mtr.FinishedSpans())
+}
@@ -0,0 +1,121 @@
+package log
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// fallbackLogger is a minimal JSON logger writing to stderr, which is used until logging is set up,
+// in order for early failures, e.g. during the setup of the service, not to get lost.
+type fallbackLogger struct {
+	mu     *sync.Mutex
+	out    io.Writer
+	fields map[string]interface{}
+	exit   func(int)
+}
+
+func newFallbackLogger(out io.Writer) *fallbackLogger {
+	return &fallbackLogger{mu: &sync.Mutex{}, out: out, fields: map[string]interface{}{}, exit: os.Exit}
+}
+
+func (fl *fallbackLogger) log(lvl Level, msg string) {
+	entry := make(map[string]interface{}, len(fl.fields)+3)
+	for k, v := range fl.fields {
+		entry[k] = v
+	}
+	entry["lvl"] = lvl
+	entry["msg"] = msg
+	entry["time"] = time.Now().Format(time.RFC3339Nano)
+	b, err := json.Marshal(entry)
+	if err != nil {
+		b = []byte(fmt.Sprintf(`{"lvl":%q,"msg":%q}`, lvl, msg))
+	}
+	fl.mu.Lock()
+	defer fl.mu.Unlock()
+	_, _ = fl.out.Write(append(b, '\n'))
+}
+
+// Sub returns a sub logger with new fields attached.
+func (fl *fallbackLogger) Sub(ff map[string]interface{}) Logger {
+	fields := make(map[string]interface{}, len(fl.fields)+len(ff))
+	for k, v := range fl.fields {
+		fields[k] = v
+	}
+	for k, v := range ff {
+		fields[k] = v
+	}
+	return &fallbackLogger{mu: fl.mu, out: fl.out, fields: fields, exit: fl.exit}
+}
+
+// Panic logging.
+func (fl *fallbackLogger) Panic(args ...interface{}) {
+	msg := fmt.Sprint(args...)
+	fl.log(PanicLevel, msg)
+	panic(msg)
+}
+
+// Panicf logging.
+func (fl *fallbackLogger) Panicf(msg string, args ...interface{}) {
+	msg = fmt.Sprintf(msg, args...)
+	fl.log(PanicLevel, msg)
+	panic(msg)
+}
+
+// Fatal logging.
+func (fl *fallbackLogger) Fatal(args ...interface{}) {
+	fl.log(FatalLevel, fmt.Sprint(args...))
+	fl.exit(1)
+}
+
+// Fatalf logging.
+func (fl *fallbackLogger) Fatalf(msg string, args ...interface{}) {
+	fl.log(FatalLevel, fmt.Sprintf(msg, args...))
+	fl.exit(1)
+}
+
+// Error logging.
+func (fl *fallbackLogger) Error(args ...interface{}) {
+	fl.log(ErrorLevel, fmt.Sprint(args...))
+}
+
+// Errorf logging.
+func (fl *fallbackLogger) Errorf(msg string, args ...interface{}) {
+	fl.log(ErrorLevel, fmt.Sprintf(msg, args...))
+}
+
+// Warn logging.
+func (fl *fallbackLogger) Warn(args ...interface{}) {
+	fl.log(WarnLevel, fmt.Sprint(args...))
+}
+
+// Warnf logging.
+func (fl *fallbackLogger) Warnf(msg string, args ...interface{}) {
+	fl.log(WarnLevel, fmt.Sprintf(msg, args...))
+}
+
+// Info logging.
+func (fl *fallbackLogger) Info(args ...interface{}) {
+	fl.log(InfoLevel, fmt.Sprint(args...))
+}
+
+// Infof logging.
+func (fl *fallbackLogger) Infof(msg string, args ...interface{}) {
+	fl.log(InfoLevel, fmt.Sprintf(msg, args...))
+}
+
+// Debug logging, which is discarded by the fallback logger.
+func (fl *fallbackLogger) Debug(args ...interface{}) {
+}
+
+// Debugf logging, which is discarded by the fallback logger.
+func (fl *fallbackLogger) Debugf(msg string, args ...interface{}) {
+}
+
+// Level returns the level of the fallback logger.
+func (fl *fallbackLogger) Level() Level {
+	return InfoLevel
+}
@@ -0,0 +1,60 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFallbackLogger(t *testing.T) {
+	var b bytes.Buffer
+	fl := newFallbackLogger(&b)
+	exitCode := -1
+	fl.exit = func(code int) { exitCode = code }
+
+	sl := fl.Sub(map[string]interface{}{"srv": "test"})
+	sl.Errorf("failed to get hostname: %s", "err")
+	sl.Info("info")
+	sl.Debug("debug")
+	fl.Warn("warn")
+	fl.Fatal("fatal")
+	assert.Equal(t, 1, exitCode)
+	assert.Panics(t, func() { fl.Panicf("panic %d", 1) })
+
+	lines := strings.Split(strings.TrimSpace(b.String()), "\n")
+	assert.Len(t, lines, 5)
+	want := []map[string]interface{}{
+		{"lvl": "error", "msg": "failed to get hostname: err", "srv": "test"},
+		{"lvl": "info", "msg": "info", "srv": "test"},
+		{"lvl": "warn", "msg": "warn"},
+		{"lvl": "fatal", "msg": "fatal"},
+		{"lvl": "panic", "msg": "panic 1"},
+	}
+	for i, line := range lines {
+		var got map[string]interface{}
+		assert.NoError(t, json.Unmarshal([]byte(line), &got))
+		assert.NotEmpty(t, got["time"])
+		delete(got, "time")
+		assert.Equal(t, want[i], got)
+	}
+	assert.Equal(t, InfoLevel, fl.Level())
+}
+
+func TestFallbackLogger_BeforeSetup(t *testing.T) {
+	defer func(l Logger) { logger = l }(logger)
+	var b bytes.Buffer
+	logger = newFallbackLogger(&b)
+
+	Errorf("early failure: %s", "err")
+	assert.Contains(t, b.String(), `"msg":"early failure: err"`)
+
+	// the fallback logger is replaced on setup
+	l := &testLogger{}
+	assert.NoError(t, Setup(func(map[string]interface{}) Logger { return l }, nil))
+	Error("error")
+	assert.Equal(t, 1, l.errorCount)
+	assert.NotContains(t, b.String(), `"msg":"error"`)
+}
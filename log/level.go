@@ -0,0 +1,83 @@
+package log
+
+// levelLogger filters the messages of a logger below a level, for loggers which are not created with a level,
+// e.g. custom loggers provided to the service. The fatal and panic messages are always logged, since they terminate.
+type levelLogger struct {
+	Logger
+	lvl Level
+}
+
+// NewLevelLogger returns a logger which logs the messages of the logger at the level or above.
+func NewLevelLogger(l Logger, lvl Level) Logger {
+	return &levelLogger{Logger: l, lvl: lvl}
+}
+
+func (ll *levelLogger) enabled(lvl Level) bool {
+	return levelPriorities[ll.lvl] <= levelPriorities[lvl]
+}
+
+// Sub returns a sub logger with new fields attached, which logs at the same level.
+func (ll *levelLogger) Sub(ff map[string]interface{}) Logger {
+	return &levelLogger{Logger: ll.Logger.Sub(ff), lvl: ll.lvl}
+}
+
+// Error logging.
+func (ll *levelLogger) Error(args ...interface{}) {
+	if ll.enabled(ErrorLevel) {
+		ll.Logger.Error(args...)
+	}
+}
+
+// Errorf logging.
+func (ll *levelLogger) Errorf(msg string, args ...interface{}) {
+	if ll.enabled(ErrorLevel) {
+		ll.Logger.Errorf(msg, args...)
+	}
+}
+
+// Warn logging.
+func (ll *levelLogger) Warn(args ...interface{}) {
+	if ll.enabled(WarnLevel) {
+		ll.Logger.Warn(args...)
+	}
+}
+
+// Warnf logging.
+func (ll *levelLogger) Warnf(msg string, args ...interface{}) {
+	if ll.enabled(WarnLevel) {
+		ll.Logger.Warnf(msg, args...)
+	}
+}
+
+// Info logging.
+func (ll *levelLogger) Info(args ...interface{}) {
+	if ll.enabled(InfoLevel) {
+		ll.Logger.Info(args...)
+	}
+}
+
+// Infof logging.
+func (ll *levelLogger) Infof(msg string, args ...interface{}) {
+	if ll.enabled(InfoLevel) {
+		ll.Logger.Infof(msg, args...)
+	}
+}
+
+// Debug logging.
+func (ll *levelLogger) Debug(args ...interface{}) {
+	if ll.enabled(DebugLevel) {
+		ll.Logger.Debug(args...)
+	}
+}
+
+// Debugf logging.
+func (ll *levelLogger) Debugf(msg string, args ...interface{}) {
+	if ll.enabled(DebugLevel) {
+		ll.Logger.Debugf(msg, args...)
+	}
+}
+
+// Level returns the level of the logger.
+func (ll *levelLogger) Level() Level {
+	return ll.lvl
+}
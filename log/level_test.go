@@ -0,0 +1,45 @@
+package log
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type recordingLogger struct {
+	nilLogger
+	fields map[string]interface{}
+	msgs   *[]string
+}
+
+func (rl *recordingLogger) Sub(ff map[string]interface{}) Logger {
+	return &recordingLogger{fields: ff, msgs: rl.msgs}
+}
+
+func (rl *recordingLogger) Warn(args ...interface{}) {
+	*rl.msgs = append(*rl.msgs, "warn "+fmt.Sprint(args...))
+}
+
+func (rl *recordingLogger) Infof(msg string, args ...interface{}) {
+	*rl.msgs = append(*rl.msgs, "info "+fmt.Sprintf(msg, args...))
+}
+
+func (rl *recordingLogger) Debug(args ...interface{}) {
+	*rl.msgs = append(*rl.msgs, "debug "+fmt.Sprint(args...))
+}
+
+func TestNewLevelLogger(t *testing.T) {
+	var msgs []string
+	l := NewLevelLogger(&recordingLogger{msgs: &msgs}, InfoLevel)
+	assert.Equal(t, InfoLevel, l.Level())
+
+	sub := l.Sub(map[string]interface{}{"key": "value"})
+	assert.Equal(t, InfoLevel, sub.Level())
+	assert.Equal(t, map[string]interface{}{"key": "value"}, sub.(*levelLogger).Logger.(*recordingLogger).fields)
+
+	sub.Debug("debug")
+	sub.Infof("info %d", 1)
+	sub.Warn("warn")
+	assert.Equal(t, []string{"info info 1", "warn warn"}, msgs)
+}
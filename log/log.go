@@ -3,6 +3,7 @@ package log
 import (
 	"context"
 	"errors"
+	"os"
 )
 
 // The Level type definition.
@@ -48,7 +49,8 @@ type ctxKey struct{}
 // FactoryFunc function type for creating loggers.
 type FactoryFunc func(map[string]interface{}) Logger
 
-var logger Logger = &nilLogger{}
+// logger defaults to a JSON logger writing to stderr, until logging is set up.
+var logger Logger = newFallbackLogger(os.Stderr)
 
 // Setup logging by providing a logger factory.
 func Setup(f FactoryFunc, fls map[string]interface{}) error {
@@ -14,12 +14,18 @@ import (
 
 // Create creates a zerolog factory with default settings.
 func Create(lvl log.Level) log.FactoryFunc {
+	return CreateWithRedaction(lvl, nil)
+}
+
+// CreateWithRedaction creates a zerolog factory with default settings, whose loggers mask
+// the fields and the messages according to the redaction.
+func CreateWithRedaction(lvl log.Level, r *Redaction) log.FactoryFunc {
 	zerolog.LevelFieldName = "lvl"
 	zerolog.MessageFieldName = "msg"
 	zerolog.TimeFieldFormat = time.RFC3339Nano
 	zl := zerolog.New(os.Stdout).With().Timestamp().Logger().Hook(sourceHook{skip: 7})
 	return func(f map[string]interface{}) log.Logger {
-		return NewLogger(&zl, lvl, f)
+		return NewRedactingLogger(&zl, lvl, f, r)
 	}
 }
 
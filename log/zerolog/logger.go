@@ -19,17 +19,23 @@ var levelMap = map[log.Level]zerolog.Level{
 
 // Logger abstraction based on zerolog.
 type Logger struct {
-	logger *zerolog.Logger
-	level  log.Level
+	logger    *zerolog.Logger
+	level     log.Level
+	redaction *Redaction
 }
 
 // NewLogger creates a new logger.
 func NewLogger(l *zerolog.Logger, lvl log.Level, f map[string]interface{}) log.Logger {
+	return NewRedactingLogger(l, lvl, f, nil)
+}
+
+// NewRedactingLogger creates a new logger, which masks the fields and the messages according to the redaction.
+func NewRedactingLogger(l *zerolog.Logger, lvl log.Level, f map[string]interface{}, r *Redaction) log.Logger {
 	if len(f) == 0 {
 		f = make(map[string]interface{})
 	}
-	zl := l.Level(levelMap[lvl]).With().Fields(f).Logger()
-	return &Logger{logger: &zl, level: lvl}
+	zl := l.Level(levelMap[lvl]).With().Fields(r.fields(f)).Logger()
+	return &Logger{logger: &zl, level: lvl, redaction: r}
 }
 
 // Sub returns a sub logger with new fields attached.
@@ -37,68 +43,82 @@ func (l *Logger) Sub(ff map[string]interface{}) log.Logger {
 	if ff == nil {
 		return l
 	}
-	sl := l.logger.With().Fields(ff).Logger()
-	return &Logger{logger: &sl, level: l.level}
+	sl := l.logger.With().Fields(l.redaction.fields(ff)).Logger()
+	return &Logger{logger: &sl, level: l.level, redaction: l.redaction}
 }
 
 // Panic logging.
 func (l *Logger) Panic(args ...interface{}) {
-	l.logger.Panic().Msg(fmt.Sprint(args...))
+	l.msg(l.logger.Panic(), args...)
 }
 
 // Panicf logging.
 func (l *Logger) Panicf(msg string, args ...interface{}) {
-	l.logger.Panic().Msgf(msg, args...)
+	l.msgf(l.logger.Panic(), msg, args...)
 }
 
 // Fatal logging.
 func (l *Logger) Fatal(args ...interface{}) {
-	l.logger.Fatal().Msg(fmt.Sprint(args...))
+	l.msg(l.logger.Fatal(), args...)
 }
 
 // Fatalf logging.
 func (l *Logger) Fatalf(msg string, args ...interface{}) {
-	l.logger.Fatal().Msgf(msg, args...)
+	l.msgf(l.logger.Fatal(), msg, args...)
 }
 
 // Error logging.
 func (l *Logger) Error(args ...interface{}) {
-	l.logger.Error().Msg(fmt.Sprint(args...))
+	l.msg(l.logger.Error(), args...)
 }
 
 // Errorf logging.
 func (l *Logger) Errorf(msg string, args ...interface{}) {
-	l.logger.Error().Msgf(msg, args...)
+	l.msgf(l.logger.Error(), msg, args...)
 }
 
 // Warn logging.
 func (l *Logger) Warn(args ...interface{}) {
-	l.logger.Warn().Msg(fmt.Sprint(args...))
+	l.msg(l.logger.Warn(), args...)
 }
 
 // Warnf logging.
 func (l *Logger) Warnf(msg string, args ...interface{}) {
-	l.logger.Warn().Msgf(msg, args...)
+	l.msgf(l.logger.Warn(), msg, args...)
 }
 
 // Info logging.
 func (l *Logger) Info(args ...interface{}) {
-	l.logger.Info().Msg(fmt.Sprint(args...))
+	l.msg(l.logger.Info(), args...)
 }
 
 // Infof logging.
 func (l *Logger) Infof(msg string, args ...interface{}) {
-	l.logger.Info().Msgf(msg, args...)
+	l.msgf(l.logger.Info(), msg, args...)
 }
 
 // Debug logging.
 func (l *Logger) Debug(args ...interface{}) {
-	l.logger.Debug().Msg(fmt.Sprint(args...))
+	l.msg(l.logger.Debug(), args...)
 }
 
 // Debugf logging.
 func (l *Logger) Debugf(msg string, args ...interface{}) {
-	l.logger.Debug().Msgf(msg, args...)
+	l.msgf(l.logger.Debug(), msg, args...)
+}
+
+// msg masks the message according to the redaction.
+func (l *Logger) msg(e *zerolog.Event, args ...interface{}) {
+	e.Msg(l.redaction.value(fmt.Sprint(args...)))
+}
+
+// msgf formats the message only if it needs to be redacted, leaving it to zerolog otherwise.
+func (l *Logger) msgf(e *zerolog.Event, msg string, args ...interface{}) {
+	if !l.redaction.enabled() {
+		e.Msgf(msg, args...)
+		return
+	}
+	e.Msg(l.redaction.value(fmt.Sprintf(msg, args...)))
 }
 
 // Level return the logging level.
@@ -0,0 +1,120 @@
+package zerolog
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// RedactedValue replaces the redacted values in the logs.
+const RedactedValue = "[REDACTED]"
+
+// Redaction defines the fields and the values which are masked before being logged.
+type Redaction struct {
+	// Keys of the fields whose values are masked, e.g. password, which are matched case-insensitively.
+	Keys []string
+	// Patterns of the values which are masked in the string, error and fmt.Stringer fields and in the messages, e.g. emails or card numbers.
+	Patterns []*regexp.Regexp
+}
+
+// enabled returns true if the redaction has any rules.
+func (r *Redaction) enabled() bool {
+	return r != nil && (len(r.Keys) > 0 || len(r.Patterns) > 0)
+}
+
+// fields returns a copy of the fields with the values of the redacted keys and the matching values masked,
+// including the fields of nested maps, e.g. of the request of the access log.
+func (r *Redaction) fields(ff map[string]interface{}) map[string]interface{} {
+	if !r.enabled() || len(ff) == 0 {
+		return ff
+	}
+	return r.redactMap(ff)
+}
+
+func (r *Redaction) redactMap(ff map[string]interface{}) map[string]interface{} {
+	rf := make(map[string]interface{}, len(ff))
+	for k, v := range ff {
+		if r.key(k) {
+			rf[k] = RedactedValue
+			continue
+		}
+		rf[k] = r.redactValue(v)
+	}
+	return rf
+}
+
+// redactValue masks a field value. URLs have the values of their redacted query parameters masked, while errors and
+// fmt.Stringer values are masked by the patterns as strings, keeping their value if no pattern matches.
+func (r *Redaction) redactValue(v interface{}) interface{} {
+	switch tv := v.(type) {
+	case string:
+		return r.value(tv)
+	case map[string]interface{}:
+		return r.redactMap(tv)
+	case *url.URL:
+		if tv == nil {
+			return v
+		}
+		return r.value(r.url(tv).String())
+	case error:
+		return r.stringer(v, tv.Error())
+	case fmt.Stringer:
+		return r.stringer(v, tv.String())
+	default:
+		return v
+	}
+}
+
+// stringer returns the masked string of a value, or the value itself if no pattern matches.
+func (r *Redaction) stringer(v interface{}, s string) interface{} {
+	if rs := r.value(s); rs != s {
+		return rs
+	}
+	return v
+}
+
+// url returns a copy of the URL with the values of the query parameters of the redacted keys masked, along with the
+// values which match the patterns once unescaped, e.g. an escaped email.
+func (r *Redaction) url(u *url.URL) *url.URL {
+	if u.RawQuery == "" {
+		return u
+	}
+	pp := strings.Split(u.RawQuery, "&")
+	for i, p := range pp {
+		kv := strings.SplitN(p, "=", 2)
+		if uk, err := url.QueryUnescape(kv[0]); err == nil && r.key(uk) {
+			pp[i] = kv[0] + "=" + RedactedValue
+			continue
+		}
+		if len(kv) < 2 {
+			continue
+		}
+		if uv, err := url.QueryUnescape(kv[1]); err == nil && r.value(uv) != uv {
+			pp[i] = kv[0] + "=" + RedactedValue
+		}
+	}
+	ru := *u
+	ru.RawQuery = strings.Join(pp, "&")
+	return &ru
+}
+
+func (r *Redaction) key(k string) bool {
+	for _, rk := range r.Keys {
+		if strings.EqualFold(rk, k) {
+			return true
+		}
+	}
+	return false
+}
+
+// value masks the parts of a value, e.g. a message, which match the patterns.
+func (r *Redaction) value(s string) string {
+	if !r.enabled() {
+		return s
+	}
+	for _, p := range r.Patterns {
+		s = p.ReplaceAllString(s, RedactedValue)
+	}
+	return s
+}
@@ -0,0 +1,83 @@
+package zerolog
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"regexp"
+	"testing"
+
+	"github.com/beatlabs/patron/log"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+)
+
+var email = regexp.MustCompile(`[a-zA-Z0-9._%+-]+@[a-zA-Z0-9.-]+\.[a-zA-Z]{2,}`)
+
+func TestRedactingLogger_Fields(t *testing.T) {
+	var b bytes.Buffer
+	zl := zerolog.New(&b)
+	r := &Redaction{Keys: []string{"password"}, Patterns: []*regexp.Regexp{email}}
+	l := NewRedactingLogger(&zl, log.DebugLevel, map[string]interface{}{"Password": "secret"}, r)
+	l.Sub(map[string]interface{}{"password": 1234, "user": "john@example.com", "id": 1}).Debug("testing")
+	assert.Equal(t, "{\"lvl\":\"debug\",\"Password\":\"[REDACTED]\",\"id\":1,\"password\":\"[REDACTED]\",\"user\":\"[REDACTED]\",\"msg\":\"testing\"}\n", b.String())
+}
+
+func TestRedactingLogger_RequestLog(t *testing.T) {
+	var b bytes.Buffer
+	zl := zerolog.New(&b)
+	token := regexp.MustCompile(`token=[^&]+`)
+	r := &Redaction{Keys: []string{"password"}, Patterns: []*regexp.Regexp{email, token}}
+	l := NewRedactingLogger(&zl, log.DebugLevel, nil, r)
+	u, err := url.Parse("http://localhost/login?user=john%40example.com&password=secret&token=abc&page=1")
+	assert.NoError(t, err)
+	// the fields of the access log of the HTTP component
+	l.Sub(map[string]interface{}{
+		"request": map[string]interface{}{
+			"method":   "POST",
+			"url":      u,
+			"password": "secret",
+			"status":   200,
+			"error":    errors.New("invalid user jane@example.com"),
+		},
+	}).Debug()
+
+	entry := map[string]interface{}{}
+	assert.NoError(t, json.Unmarshal(b.Bytes(), &entry))
+	req := entry["request"].(map[string]interface{})
+	assert.Equal(t, "POST", req["method"])
+	assert.Equal(t, "http://localhost/login?user=[REDACTED]&password=[REDACTED]&[REDACTED]&page=1", req["url"])
+	assert.Equal(t, RedactedValue, req["password"])
+	assert.Equal(t, 200.0, req["status"])
+	assert.Equal(t, "invalid user [REDACTED]", req["error"])
+	assert.NotContains(t, b.String(), "secret")
+	assert.Equal(t, "http://localhost/login?user=john%40example.com&password=secret&token=abc&page=1", u.String())
+}
+
+func TestRedaction_Fields_StringerWithoutMatch(t *testing.T) {
+	r := &Redaction{Patterns: []*regexp.Regexp{email}}
+	err := errors.New("timeout")
+	ff := r.fields(map[string]interface{}{"err": err})
+	assert.Equal(t, err, ff["err"])
+}
+
+func TestRedactingLogger_Message(t *testing.T) {
+	var b bytes.Buffer
+	zl := zerolog.New(&b)
+	r := &Redaction{Patterns: []*regexp.Regexp{email}}
+	l := NewRedactingLogger(&zl, log.DebugLevel, nil, r)
+	l.Infof("user %s logged in", "john@example.com")
+	assert.Equal(t, "{\"lvl\":\"info\",\"msg\":\"user [REDACTED] logged in\"}\n", b.String())
+	b.Reset()
+	l.Info("contact ", "jane@example.com")
+	assert.Equal(t, "{\"lvl\":\"info\",\"msg\":\"contact [REDACTED]\"}\n", b.String())
+}
+
+func TestRedaction_Fields_Disabled(t *testing.T) {
+	ff := map[string]interface{}{"password": "secret"}
+	var r *Redaction
+	assert.Equal(t, ff, r.fields(ff))
+	assert.Equal(t, ff, (&Redaction{}).fields(ff))
+	assert.Equal(t, "secret", r.value("secret"))
+}
@@ -2,9 +2,16 @@ package patron
 
 import (
 	"errors"
+	"fmt"
+	"time"
 
+	"github.com/beatlabs/patron/encoding/json"
+	"github.com/beatlabs/patron/info"
 	"github.com/beatlabs/patron/log"
+	"github.com/beatlabs/patron/log/zerolog"
+	"github.com/beatlabs/patron/reliability/budget"
 	"github.com/beatlabs/patron/sync/http"
+	"github.com/beatlabs/patron/trace"
 )
 
 // OptionFunc definition for configuring the service in a functional way.
@@ -81,3 +88,165 @@ func SIGHUP(handler func()) OptionFunc {
 		return nil
 	}
 }
+
+// RestartOnSIGHUP option for restarting the components implementing the Restartable interface
+// when the service receives a SIGHUP, instead of calling a SIGHUP handler.
+func RestartOnSIGHUP() OptionFunc {
+	return func(s *Service) error {
+		s.sighupHandler = s.Restart
+		log.Info("restart on SIGHUP set")
+		return nil
+	}
+}
+
+// ExitOnFatal option for exiting the process with a non-zero exit code when a component fails,
+// e.g. for workers which should be restarted by the orchestrator, instead of returning a *FatalError from Run.
+func ExitOnFatal() OptionFunc {
+	return func(s *Service) error {
+		s.exitOnFatal = true
+		log.Info("exit on fatal error set")
+		return nil
+	}
+}
+
+// Dependencies option for declaring dependencies of the service, e.g. downstream HTTP services, which are exposed
+// via the /info endpoint along with the topics and queues the consumers and producers register automatically.
+func Dependencies(dd ...info.Dependency) OptionFunc {
+	return func(s *Service) error {
+		if len(dd) == 0 {
+			return errors.New("dependencies are required")
+		}
+		for _, d := range dd {
+			if d.Type == "" || d.Name == "" || (d.Direction != info.Inbound && d.Direction != info.Outbound) {
+				return fmt.Errorf("invalid dependency provided: %+v", d)
+			}
+		}
+		for _, d := range dd {
+			info.AddDependency(d)
+		}
+		log.Info("dependencies set")
+		return nil
+	}
+}
+
+// TLS option for serving HTTPS with the default HTTP component, using the given certificate and key files,
+// which take precedence over the PATRON_HTTP_TLS_CERT and PATRON_HTTP_TLS_KEY env vars.
+func TLS(cert, key string) OptionFunc {
+	return func(s *Service) error {
+		if cert == "" || key == "" {
+			return errors.New("cert and key are required")
+		}
+		s.tlsCert = cert
+		s.tlsKey = key
+		log.Info("TLS set")
+		return nil
+	}
+}
+
+// WithoutMetrics option for disabling the /metrics route of the default HTTP component, which serves the prometheus metrics,
+// for services which serve the metrics themselves, e.g. with their own route.
+func WithoutMetrics() OptionFunc {
+	return func(s *Service) error {
+		s.noMetrics = true
+		log.Info("metrics route disabled")
+		return nil
+	}
+}
+
+// LifetimeSpan option for tracing the lifetime of the service with a span named service, which starts at the creation of the service,
+// has a child span for each startup phase, and finishes at the shutdown with the uptime and the reason of the shutdown.
+// The span is open for as long as the service runs, so it is reported only at the shutdown.
+func LifetimeSpan() OptionFunc {
+	return func(s *Service) error {
+		s.lifetimeSpan = true
+		log.Info("lifetime span enabled")
+		return nil
+	}
+}
+
+// MaxGoroutines option for bounding the number of goroutines executing work across the components of the service,
+// i.e. the requests of the worker pools of the HTTP components and the messages of the async components.
+// The work queues until a goroutine of the budget is available. The goroutines in use are exposed by the goroutines_in_use metric.
+// The budget is passed to the components with the context they run with, so it is shared only by the components of the service.
+func MaxGoroutines(n int) OptionFunc {
+	return func(s *Service) error {
+		b, err := budget.New(n)
+		if err != nil {
+			return fmt.Errorf("invalid max goroutines provided: %w", err)
+		}
+		s.budget = b
+		log.Infof("max goroutines set to %d", n)
+		return nil
+	}
+}
+
+// JSONLibrary option for replacing encoding/json with a faster implementation compatible with it, e.g. jsoniter on hot JSON paths,
+// which is adapted with json.NewLibrary. The library is used by the whole process, e.g. the HTTP handlers and the Kafka JSON decoder.
+func JSONLibrary(lib json.Library) OptionFunc {
+	return func(s *Service) error {
+		if err := json.SetLibrary(lib); err != nil {
+			return err
+		}
+		log.Infof("JSON library set to %T", lib)
+		return nil
+	}
+}
+
+// Tracing option for replacing the default Jaeger tracing, configured by the PATRON_JAEGER_* env vars, with another tracing provider,
+// e.g. trace.OTLPProvider. The tracer of the provider is closed, flushing its spans, when the service stops.
+func Tracing(p trace.Provider) OptionFunc {
+	return func(s *Service) error {
+		if p == nil {
+			return errors.New("tracing provider is required")
+		}
+		s.tracing = p
+		log.Infof("tracing provider set to %T", p)
+		return nil
+	}
+}
+
+// ShutdownTimeout option for bounding the wait for the components to stop after the shutdown of the service,
+// e.g. on a termination signal, and for the tracer to close. The components which did not stop in time are logged,
+// and Run returns an error without waiting for them. By default the service waits for the components to stop.
+func ShutdownTimeout(d time.Duration) OptionFunc {
+	return func(s *Service) error {
+		if d <= 0 {
+			return errors.New("shutdown timeout must be positive")
+		}
+		s.shutdownTimeout = d
+		log.Infof("shutdown timeout set to %v", d)
+		return nil
+	}
+}
+
+// Logger option for replacing the default zerolog logging with a custom logger, e.g. a logger shared across services.
+// The fields of the service, i.e. srv, ver and host, are attached to the logger, and its messages are filtered according to the
+// PATRON_LOG_LEVEL env var. The messages logged while the service is created before the option is applied use the default logging.
+func Logger(l log.Logger) OptionFunc {
+	return func(s *Service) error {
+		if l == nil {
+			return errors.New("logger is required")
+		}
+		s.logger = l
+		log.Info("logger set")
+		return nil
+	}
+}
+
+// LogRedaction option for masking the fields with the given keys, e.g. password, and the values matching the given patterns,
+// e.g. emails or card numbers, in the fields and the messages of the default logging, before they are logged.
+func LogRedaction(r zerolog.Redaction) OptionFunc {
+	return func(s *Service) error {
+		if len(r.Keys) == 0 && len(r.Patterns) == 0 {
+			return errors.New("redaction keys or patterns are required")
+		}
+		for _, p := range r.Patterns {
+			if p == nil {
+				return errors.New("nil redaction pattern provided")
+			}
+		}
+		s.logRedaction = &r
+		log.Info("log redaction set")
+		return nil
+	}
+}
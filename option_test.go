@@ -1,12 +1,25 @@
 package patron
 
 import (
+	"fmt"
+	"io"
 	"net/http"
+	"os"
+	"regexp"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 
+	"github.com/beatlabs/patron/encoding/json"
+	"github.com/beatlabs/patron/info"
+	"github.com/beatlabs/patron/log"
+	"github.com/beatlabs/patron/log/zerolog"
 	phttp "github.com/beatlabs/patron/sync/http"
+	"github.com/beatlabs/patron/trace"
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/mocktracer"
 )
 
 func middleware(h http.Handler) http.Handler {
@@ -173,3 +186,213 @@ func TestSIGHUP(t *testing.T) {
 		})
 	}
 }
+
+// recordingLogger records the fields and the messages of the loggers created from it.
+type recordingLogger struct {
+	mu     *sync.Mutex
+	fields *[]map[string]interface{}
+	msgs   *[]string
+}
+
+func newRecordingLogger() *recordingLogger {
+	return &recordingLogger{mu: &sync.Mutex{}, fields: &[]map[string]interface{}{}, msgs: &[]string{}}
+}
+
+func (rl *recordingLogger) record(lvl log.Level, msg string) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	*rl.msgs = append(*rl.msgs, fmt.Sprintf("%s %s", lvl, msg))
+}
+
+func (rl *recordingLogger) messages() []string {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	return append([]string(nil), *rl.msgs...)
+}
+
+func (rl *recordingLogger) subFields() []map[string]interface{} {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	return append([]map[string]interface{}(nil), *rl.fields...)
+}
+
+func (rl *recordingLogger) Sub(ff map[string]interface{}) log.Logger {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	*rl.fields = append(*rl.fields, ff)
+	return rl
+}
+func (rl *recordingLogger) Fatal(args ...interface{}) { rl.record(log.FatalLevel, fmt.Sprint(args...)) }
+func (rl *recordingLogger) Fatalf(m string, args ...interface{}) {
+	rl.record(log.FatalLevel, fmt.Sprintf(m, args...))
+}
+func (rl *recordingLogger) Panic(args ...interface{}) { rl.record(log.PanicLevel, fmt.Sprint(args...)) }
+func (rl *recordingLogger) Panicf(m string, args ...interface{}) {
+	rl.record(log.PanicLevel, fmt.Sprintf(m, args...))
+}
+func (rl *recordingLogger) Error(args ...interface{}) { rl.record(log.ErrorLevel, fmt.Sprint(args...)) }
+func (rl *recordingLogger) Errorf(m string, args ...interface{}) {
+	rl.record(log.ErrorLevel, fmt.Sprintf(m, args...))
+}
+func (rl *recordingLogger) Warn(args ...interface{}) { rl.record(log.WarnLevel, fmt.Sprint(args...)) }
+func (rl *recordingLogger) Warnf(m string, args ...interface{}) {
+	rl.record(log.WarnLevel, fmt.Sprintf(m, args...))
+}
+func (rl *recordingLogger) Info(args ...interface{}) { rl.record(log.InfoLevel, fmt.Sprint(args...)) }
+func (rl *recordingLogger) Infof(m string, args ...interface{}) {
+	rl.record(log.InfoLevel, fmt.Sprintf(m, args...))
+}
+func (rl *recordingLogger) Debug(args ...interface{}) { rl.record(log.DebugLevel, fmt.Sprint(args...)) }
+func (rl *recordingLogger) Debugf(m string, args ...interface{}) {
+	rl.record(log.DebugLevel, fmt.Sprintf(m, args...))
+}
+func (rl *recordingLogger) Level() log.Level { return log.DebugLevel }
+
+func TestLogger(t *testing.T) {
+	s, err := New("test", "1.0.0")
+	assert.NoError(t, err)
+	assert.Error(t, Logger(nil)(s))
+
+	f, err := logFields("test", "1.0.0")
+	assert.NoError(t, err)
+	defer func() { assert.NoError(t, setupLogging(f, nil)) }()
+	defer func() { assert.NoError(t, os.Unsetenv("PATRON_LOG_LEVEL")) }()
+	assert.NoError(t, os.Setenv("PATRON_LOG_LEVEL", "warn"))
+
+	rl := newRecordingLogger()
+	_, err = New("test", "1.0.0", Logger(rl))
+	assert.NoError(t, err)
+	hostname, err := os.Hostname()
+	assert.NoError(t, err)
+	assert.Equal(t, []map[string]interface{}{{"srv": "test", "ver": "1.0.0", "host": hostname}}, rl.subFields())
+
+	assert.Equal(t, log.WarnLevel, log.Sub(nil).Level())
+	log.Info("filtered")
+	log.Warnf("logged %d", 1)
+	assert.Equal(t, []string{"warn logged 1"}, rl.messages())
+
+	_, err = New("test", "1.0.0", Logger(rl), LogRedaction(zerolog.Redaction{Keys: []string{"password"}}))
+	assert.Error(t, err)
+}
+
+func TestLogRedaction(t *testing.T) {
+	tests := []struct {
+		name    string
+		r       zerolog.Redaction
+		wantErr bool
+	}{
+		{name: "no rules", r: zerolog.Redaction{}, wantErr: true},
+		{name: "nil pattern", r: zerolog.Redaction{Patterns: []*regexp.Regexp{nil}}, wantErr: true},
+		{name: "success", r: zerolog.Redaction{Keys: []string{"password"}, Patterns: []*regexp.Regexp{regexp.MustCompile(`\d{16}`)}}, wantErr: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := New("test", "1.0.0")
+			assert.NoError(t, err)
+			err = LogRedaction(tt.r)(s)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.r.Keys, s.logRedaction.Keys)
+			}
+		})
+	}
+}
+
+func TestShutdownTimeout(t *testing.T) {
+	s, err := New("test", "1.0.0")
+	assert.NoError(t, err)
+	assert.Error(t, ShutdownTimeout(0)(s))
+	assert.NoError(t, ShutdownTimeout(time.Second)(s))
+	assert.Equal(t, time.Second, s.shutdownTimeout)
+}
+
+func TestWithoutMetrics(t *testing.T) {
+	s, err := New("test", "1.0.0")
+	assert.NoError(t, err)
+	assert.NoError(t, WithoutMetrics()(s))
+	assert.True(t, s.noMetrics)
+}
+
+func TestLifetimeSpan(t *testing.T) {
+	s, err := New("test", "1.0.0")
+	assert.NoError(t, err)
+	assert.NoError(t, LifetimeSpan()(s))
+	assert.True(t, s.lifetimeSpan)
+}
+
+func TestMaxGoroutines(t *testing.T) {
+	s, err := New("test", "1.0.0")
+	assert.NoError(t, err)
+	assert.Error(t, MaxGoroutines(0)(s))
+	assert.Nil(t, s.budget)
+	assert.NoError(t, MaxGoroutines(10)(s))
+	assert.NotNil(t, s.budget)
+}
+
+type testJSONLibrary struct {
+	json.StandardLibrary
+}
+
+func (testJSONLibrary) Marshal(interface{}) ([]byte, error) {
+	return []byte(`"test"`), nil
+}
+
+func TestJSONLibrary(t *testing.T) {
+	s, err := New("test", "1.0.0")
+	assert.NoError(t, err)
+	assert.Error(t, JSONLibrary(nil)(s))
+
+	defer func() { assert.NoError(t, json.SetLibrary(json.StandardLibrary{})) }()
+	assert.NoError(t, JSONLibrary(testJSONLibrary{})(s))
+	b, err := json.Encode("value")
+	assert.NoError(t, err)
+	assert.Equal(t, `"test"`, string(b))
+}
+
+func TestTLS(t *testing.T) {
+	s, err := New("test", "1.0.0")
+	assert.NoError(t, err)
+	assert.Error(t, TLS("cert.pem", "")(s))
+	assert.NoError(t, TLS("cert.pem", "key.pem")(s))
+	assert.Equal(t, "cert.pem", s.tlsCert)
+	assert.Equal(t, "key.pem", s.tlsKey)
+}
+
+func TestDependencies(t *testing.T) {
+	s, err := New("test", "1.0.0")
+	assert.NoError(t, err)
+	assert.Error(t, Dependencies()(s))
+	assert.Error(t, Dependencies(info.Dependency{Type: "http", Name: "payments"})(s))
+	d := info.Dependency{Type: "http", Name: "payments", Direction: info.Outbound}
+	assert.NoError(t, Dependencies(d)(s))
+	assert.Contains(t, info.Dependencies(), d)
+}
+
+// testTracingProvider creates a mock tracer, whose closer counts the closes.
+type testTracingProvider struct {
+	tracer *mocktracer.MockTracer
+	closes int
+}
+
+func (p *testTracingProvider) Tracer(_, _ string) (opentracing.Tracer, io.Closer, error) {
+	return p.tracer, p, nil
+}
+
+func (p *testTracingProvider) Close() error {
+	p.closes++
+	return nil
+}
+
+func TestTracing(t *testing.T) {
+	_, err := New("test", "1.0.0", Tracing(nil))
+	assert.EqualError(t, err, "tracing provider is required")
+
+	p := &testTracingProvider{tracer: mocktracer.New()}
+	_, err = New("test", "1.0.0", Tracing(p))
+	assert.NoError(t, err)
+	assert.Equal(t, p.tracer, opentracing.GlobalTracer())
+	assert.NoError(t, trace.Close())
+	assert.Equal(t, 1, p.closes)
+}
@@ -0,0 +1,193 @@
+// Package pushgateway provides a component which pushes metrics to a Prometheus Pushgateway,
+// for services which are too short-lived to be scraped, e.g. batch jobs.
+package pushgateway
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/beatlabs/patron/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/expfmt"
+)
+
+const (
+	defaultRetries   = 3
+	defaultRetryWait = time.Second
+	pushTimeout      = 10 * time.Second
+)
+
+// OptionFunc definition for configuring the component in a functional way.
+type OptionFunc func(*Component) error
+
+// Retries option for setting the number of retries of a failed push and the wait between them.
+func Retries(retries int, wait time.Duration) OptionFunc {
+	return func(c *Component) error {
+		if retries < 0 {
+			return errors.New("retries must be zero or positive")
+		}
+		if wait < 0 {
+			return errors.New("retry wait must be zero or positive")
+		}
+		c.retries = retries
+		c.retryWait = wait
+		return nil
+	}
+}
+
+// Instance option for setting the instance of the grouping key, which defaults to the hostname.
+func Instance(instance string) OptionFunc {
+	return func(c *Component) error {
+		if instance == "" {
+			return errors.New("instance is required")
+		}
+		c.instance = instance
+		return nil
+	}
+}
+
+// Gatherer option for setting the gatherer of the pushed metrics, which defaults to the default prometheus registry.
+func Gatherer(g prometheus.Gatherer) OptionFunc {
+	return func(c *Component) error {
+		if g == nil {
+			return errors.New("gatherer is nil")
+		}
+		c.gatherer = g
+		return nil
+	}
+}
+
+// Component pushes the metrics to a Pushgateway at an interval and a final time when it is shut down.
+// The metrics are grouped by job and instance, and every push replaces the metrics of the group.
+type Component struct {
+	url       string
+	job       string
+	instance  string
+	interval  time.Duration
+	retries   int
+	retryWait time.Duration
+	gatherer  prometheus.Gatherer
+	client    *http.Client
+}
+
+// New constructor, where the job of the grouping key is typically the name of the service.
+func New(gatewayURL, job string, interval time.Duration, oo ...OptionFunc) (*Component, error) {
+	if gatewayURL == "" {
+		return nil, errors.New("gateway URL is required")
+	}
+	if job == "" {
+		return nil, errors.New("job is required")
+	}
+	if interval <= 0 {
+		return nil, errors.New("interval must be positive")
+	}
+
+	c := &Component{
+		url:       strings.TrimSuffix(gatewayURL, "/"),
+		job:       job,
+		interval:  interval,
+		retries:   defaultRetries,
+		retryWait: defaultRetryWait,
+		gatherer:  prometheus.DefaultGatherer,
+		client:    &http.Client{Timeout: pushTimeout},
+	}
+
+	for _, o := range oo {
+		err := o(c)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if c.instance == "" {
+		host, err := os.Hostname()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get hostname: %w", err)
+		}
+		c.instance = host
+	}
+
+	return c, nil
+}
+
+// Run pushes the metrics at the interval until the context is done, when the metrics are pushed a final time.
+// Failed pushes are logged, while the failure of the final push is returned.
+func (c *Component) Run(ctx context.Context) error {
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			err := c.pushWithRetries(context.Background())
+			if err != nil {
+				return fmt.Errorf("final push of metrics failed: %w", err)
+			}
+			log.Info("final push of metrics completed")
+			return nil
+		case <-ticker.C:
+			err := c.pushWithRetries(ctx)
+			if err != nil {
+				log.Errorf("failed to push metrics: %v", err)
+			}
+		}
+	}
+}
+
+func (c *Component) pushWithRetries(ctx context.Context) error {
+	var err error
+	for i := 0; i <= c.retries; i++ {
+		if i > 0 {
+			log.Warnf("failed to push metrics, retry %d/%d: %v", i, c.retries, err)
+			select {
+			case <-ctx.Done():
+				return err
+			case <-time.After(c.retryWait):
+			}
+		}
+		err = c.push(ctx)
+		if err == nil {
+			return nil
+		}
+	}
+	return err
+}
+
+func (c *Component) push(ctx context.Context) error {
+	mfs, err := c.gatherer.Gather()
+	if err != nil {
+		return fmt.Errorf("failed to gather metrics: %w", err)
+	}
+
+	buf := &bytes.Buffer{}
+	enc := expfmt.NewEncoder(buf, expfmt.FmtProtoDelim)
+	for _, mf := range mfs {
+		err = enc.Encode(mf)
+		if err != nil {
+			return fmt.Errorf("failed to encode metric family %s: %w", mf.GetName(), err)
+		}
+	}
+
+	u := fmt.Sprintf("%s/metrics/job/%s/instance/%s", c.url, url.PathEscape(c.job), url.PathEscape(c.instance))
+	req, err := http.NewRequest(http.MethodPut, u, buf)
+	if err != nil {
+		return fmt.Errorf("failed to create push request: %w", err)
+	}
+	req.Header.Set("Content-Type", string(expfmt.FmtProtoDelim))
+
+	rsp, err := c.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return fmt.Errorf("failed to push metrics: %w", err)
+	}
+	defer func() { _ = rsp.Body.Close() }()
+	if rsp.StatusCode != http.StatusOK && rsp.StatusCode != http.StatusAccepted {
+		return fmt.Errorf("unexpected status %d pushing metrics", rsp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,126 @@
+package pushgateway
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew(t *testing.T) {
+	tests := map[string]struct {
+		url      string
+		job      string
+		interval time.Duration
+		oo       []OptionFunc
+		wantErr  string
+	}{
+		"success":          {url: "http://localhost:9091", job: "job", interval: time.Second},
+		"missing url":      {job: "job", interval: time.Second, wantErr: "gateway URL is required"},
+		"missing job":      {url: "http://localhost:9091", interval: time.Second, wantErr: "job is required"},
+		"invalid interval": {url: "http://localhost:9091", job: "job", wantErr: "interval must be positive"},
+		"invalid retries": {url: "http://localhost:9091", job: "job", interval: time.Second,
+			oo: []OptionFunc{Retries(-1, time.Second)}, wantErr: "retries must be zero or positive"},
+		"invalid instance": {url: "http://localhost:9091", job: "job", interval: time.Second,
+			oo: []OptionFunc{Instance("")}, wantErr: "instance is required"},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := New(tt.url, tt.job, tt.interval, tt.oo...)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				assert.Nil(t, got)
+			} else {
+				assert.NoError(t, err)
+				assert.NotEmpty(t, got.instance)
+			}
+		})
+	}
+}
+
+type gateway struct {
+	sync.Mutex
+	paths    []string
+	families []*dto.MetricFamily
+	failures int
+}
+
+func (g *gateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	g.Lock()
+	defer g.Unlock()
+	if g.failures > 0 {
+		g.failures--
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	g.paths = append(g.paths, r.Method+" "+r.URL.Path)
+	dec := expfmt.NewDecoder(r.Body, expfmt.ResponseFormat(r.Header))
+	for {
+		mf := &dto.MetricFamily{}
+		if dec.Decode(mf) != nil {
+			break
+		}
+		g.families = append(g.families, mf)
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (g *gateway) pushes() int {
+	g.Lock()
+	defer g.Unlock()
+	return len(g.paths)
+}
+
+func TestComponent_Run(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	cnt := prometheus.NewCounter(prometheus.CounterOpts{Name: "test_total", Help: "test"})
+	reg.MustRegister(cnt)
+	cnt.Inc()
+
+	gw := &gateway{failures: 1}
+	srv := httptest.NewServer(gw)
+	defer srv.Close()
+
+	c, err := New(srv.URL, "service", 20*time.Millisecond, Instance("host"), Gatherer(reg), Retries(1, time.Millisecond))
+	require.NoError(t, err)
+
+	ctx, cnl := context.WithCancel(context.Background())
+	chDone := make(chan error)
+	go func() { chDone <- c.Run(ctx) }()
+	for i := 0; i < 100 && gw.pushes() == 0; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	cnl()
+	assert.NoError(t, <-chDone)
+
+	gw.Lock()
+	defer gw.Unlock()
+	// at least one periodic push, after retrying the failed one, and the final push
+	assert.True(t, len(gw.paths) >= 2)
+	assert.Equal(t, "PUT /metrics/job/service/instance/host", gw.paths[0])
+	assert.Equal(t, "test_total", gw.families[0].GetName())
+	assert.Equal(t, 1.0, gw.families[0].GetMetric()[0].GetCounter().GetValue())
+}
+
+func TestComponent_Run_FinalPushFailure(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer srv.Close()
+
+	c, err := New(srv.URL, "service", time.Hour, Gatherer(prometheus.NewRegistry()), Retries(0, 0))
+	require.NoError(t, err)
+
+	ctx, cnl := context.WithCancel(context.Background())
+	cnl()
+	err = c.Run(ctx)
+	assert.EqualError(t, err, "final push of metrics failed: unexpected status 400 pushing metrics")
+}
@@ -0,0 +1,95 @@
+// Package budget provides a budget of the goroutines executing work across the components of a service,
+// e.g. the requests of the HTTP worker pool and the messages of the async components, which bounds the concurrency of the whole service.
+package budget
+
+import (
+	"context"
+	"errors"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var goroutinesInUse prometheus.Gauge
+
+func init() {
+	goroutinesInUse = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "goroutines_in_use",
+			Help: "Number of goroutines of the goroutine budget of the service executing work",
+		},
+	)
+	prometheus.MustRegister(goroutinesInUse)
+}
+
+// Budget bounds the number of goroutines executing work concurrently.
+// A nil budget is unlimited, so the components acquire from the budget of their context regardless of whether it carries one.
+type Budget struct {
+	slots chan struct{}
+}
+
+// New creates a budget of n goroutines.
+func New(n int) (*Budget, error) {
+	if n <= 0 {
+		return nil, errors.New("budget must be positive")
+	}
+	return &Budget{slots: make(chan struct{}, n)}, nil
+}
+
+// Acquire waits for a goroutine of the budget to be available, queueing the work, until the context is done.
+func (b *Budget) Acquire(ctx context.Context) error {
+	if b == nil {
+		return nil
+	}
+	select {
+	case b.slots <- struct{}{}:
+		goroutinesInUse.Inc()
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// TryAcquire acquires a goroutine of the budget without waiting, and returns false if the budget is exhausted, rejecting the work.
+func (b *Budget) TryAcquire() bool {
+	if b == nil {
+		return true
+	}
+	select {
+	case b.slots <- struct{}{}:
+		goroutinesInUse.Inc()
+		return true
+	default:
+		return false
+	}
+}
+
+// Release returns an acquired goroutine to the budget.
+func (b *Budget) Release() {
+	if b == nil {
+		return
+	}
+	<-b.slots
+	goroutinesInUse.Dec()
+}
+
+// InUse returns the number of acquired goroutines.
+func (b *Budget) InUse() int {
+	if b == nil {
+		return 0
+	}
+	return len(b.slots)
+}
+
+type contextKey struct{}
+
+// WithContext returns a context carrying the budget, e.g. the context the service runs its components with,
+// so that the components of a service share its budget.
+func WithContext(ctx context.Context, b *Budget) context.Context {
+	return context.WithValue(ctx, contextKey{}, b)
+}
+
+// FromContext returns the budget carried by the context, which is nil if the context carries none.
+func FromContext(ctx context.Context) *Budget {
+	b, _ := ctx.Value(contextKey{}).(*Budget)
+	return b
+}
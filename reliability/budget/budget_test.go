@@ -0,0 +1,56 @@
+package budget
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNew(t *testing.T) {
+	_, err := New(0)
+	assert.Error(t, err)
+	b, err := New(2)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, b.InUse())
+}
+
+func TestBudget(t *testing.T) {
+	b, err := New(2)
+	assert.NoError(t, err)
+	assert.True(t, b.TryAcquire())
+	assert.NoError(t, b.Acquire(context.Background()))
+	assert.Equal(t, 2, b.InUse())
+
+	// the budget is exhausted
+	assert.False(t, b.TryAcquire())
+	ctx, cnl := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cnl()
+	assert.Equal(t, context.DeadlineExceeded, b.Acquire(ctx))
+
+	// the queued work acquires the released goroutine
+	acquired := make(chan error)
+	go func() { acquired <- b.Acquire(context.Background()) }()
+	b.Release()
+	assert.NoError(t, <-acquired)
+	assert.Equal(t, 2, b.InUse())
+	b.Release()
+	b.Release()
+	assert.Equal(t, 0, b.InUse())
+}
+
+func TestBudget_Nil(t *testing.T) {
+	var b *Budget
+	assert.True(t, b.TryAcquire())
+	assert.NoError(t, b.Acquire(context.Background()))
+	b.Release()
+	assert.Equal(t, 0, b.InUse())
+}
+
+func TestContext(t *testing.T) {
+	assert.Nil(t, FromContext(context.Background()))
+	b, err := New(1)
+	assert.NoError(t, err)
+	assert.True(t, b == FromContext(WithContext(context.Background(), b)))
+}
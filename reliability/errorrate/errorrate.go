@@ -0,0 +1,109 @@
+// Package errorrate provides tracking of the error rate over a rolling time window.
+package errorrate
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+const buckets = 10
+
+// Setting definition.
+type Setting struct {
+	// The rolling time window over which the error rate is calculated.
+	Window time.Duration
+	// The error rate, between 0 and 1, above which the threshold is exceeded.
+	Threshold float64
+	// The minimum number of events within the window before the threshold can be exceeded.
+	MinEvents uint
+}
+
+type bucket struct {
+	start     time.Time
+	successes uint
+	failures  uint
+}
+
+// Tracker tracks successes and failures over a rolling time window.
+type Tracker struct {
+	set Setting
+	sync.Mutex
+	buckets [buckets]bucket
+	now     func() time.Time
+}
+
+// New constructs a new error rate tracker.
+func New(s Setting) (*Tracker, error) {
+	if s.Window < buckets*time.Millisecond {
+		return nil, errors.New("window must be at least 10ms")
+	}
+	if s.Threshold <= 0 || s.Threshold > 1 {
+		return nil, errors.New("threshold must be greater than 0 and less or equal than 1")
+	}
+	return &Tracker{set: s, now: time.Now}, nil
+}
+
+// Success records a successful event.
+func (t *Tracker) Success() {
+	t.Lock()
+	defer t.Unlock()
+	t.current().successes++
+}
+
+// Failure records a failed event.
+func (t *Tracker) Failure() {
+	t.Lock()
+	defer t.Unlock()
+	t.current().failures++
+}
+
+// Rate returns the error rate of the window, or 0 if there are no events.
+func (t *Tracker) Rate() float64 {
+	t.Lock()
+	defer t.Unlock()
+	s, f := t.count()
+	if s+f == 0 {
+		return 0
+	}
+	return float64(f) / float64(s+f)
+}
+
+// Exceeded returns true if the error rate of the window exceeds the threshold.
+func (t *Tracker) Exceeded() bool {
+	t.Lock()
+	defer t.Unlock()
+	s, f := t.count()
+	if s+f == 0 || s+f < t.set.MinEvents {
+		return false
+	}
+	return float64(f)/float64(s+f) > t.set.Threshold
+}
+
+func (t *Tracker) bucketSize() time.Duration {
+	return t.set.Window / buckets
+}
+
+// current returns the bucket of the current time, resetting it if it belongs to a previous window.
+func (t *Tracker) current() *bucket {
+	now := t.now()
+	size := t.bucketSize()
+	start := now.Truncate(size)
+	b := &t.buckets[(start.UnixNano()/int64(size))%buckets]
+	if !b.start.Equal(start) {
+		*b = bucket{start: start}
+	}
+	return b
+}
+
+func (t *Tracker) count() (uint, uint) {
+	oldest := t.now().Add(-t.set.Window)
+	var s, f uint
+	for _, b := range t.buckets {
+		if b.start.After(oldest) {
+			s += b.successes
+			f += b.failures
+		}
+	}
+	return s, f
+}
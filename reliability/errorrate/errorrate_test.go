@@ -0,0 +1,67 @@
+package errorrate
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNew(t *testing.T) {
+	tests := map[string]struct {
+		set     Setting
+		wantErr bool
+	}{
+		"success":           {set: Setting{Window: time.Minute, Threshold: 0.5}},
+		"invalid window":    {set: Setting{Window: time.Millisecond, Threshold: 0.5}, wantErr: true},
+		"zero threshold":    {set: Setting{Window: time.Minute, Threshold: 0}, wantErr: true},
+		"invalid threshold": {set: Setting{Window: time.Minute, Threshold: 1.1}, wantErr: true},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := New(tt.set)
+			if tt.wantErr {
+				assert.Error(t, err)
+				assert.Nil(t, got)
+			} else {
+				assert.NoError(t, err)
+				assert.NotNil(t, got)
+			}
+		})
+	}
+}
+
+func TestTracker(t *testing.T) {
+	tr, err := New(Setting{Window: 10 * time.Second, Threshold: 0.5, MinEvents: 4})
+	assert.NoError(t, err)
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	tr.now = func() time.Time { return now }
+
+	assert.Equal(t, 0.0, tr.Rate())
+	assert.False(t, tr.Exceeded())
+
+	tr.Failure()
+	tr.Failure()
+	tr.Failure()
+	assert.Equal(t, 1.0, tr.Rate())
+	// not enough events
+	assert.False(t, tr.Exceeded())
+
+	now = now.Add(5 * time.Second)
+	tr.Success()
+	assert.Equal(t, 0.75, tr.Rate())
+	assert.True(t, tr.Exceeded())
+
+	// the failures roll out of the window
+	now = now.Add(6 * time.Second)
+	tr.Success()
+	tr.Success()
+	tr.Success()
+	assert.Equal(t, 0.0, tr.Rate())
+	assert.False(t, tr.Exceeded())
+
+	// a bucket of a previous window is reset when reused
+	now = now.Add(10 * time.Second)
+	tr.Failure()
+	assert.Equal(t, 1.0, tr.Rate())
+}
@@ -0,0 +1,23 @@
+package retry
+
+import (
+	"math/rand"
+	"time"
+)
+
+// Backoff returns the wait before a retry, which starts from initial and is doubled on every subsequent retry, capped to max if positive.
+// The wait is reduced at random by up to the jitter factor, between 0 and 1, in order for the clients failing at the same time
+// not to retry at the same time.
+func Backoff(initial, max time.Duration, retry uint, jitter float64) time.Duration {
+	wait := initial
+	for i := uint(0); i < retry && (max <= 0 || wait < max); i++ {
+		wait *= 2
+	}
+	if max > 0 && wait > max {
+		wait = max
+	}
+	if jitter > 0 {
+		wait -= time.Duration(rand.Float64() * jitter * float64(wait))
+	}
+	return wait
+}
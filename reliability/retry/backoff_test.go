@@ -0,0 +1,24 @@
+package retry
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBackoff(t *testing.T) {
+	assert.Equal(t, time.Second, Backoff(time.Second, time.Minute, 0, 0))
+	assert.Equal(t, 4*time.Second, Backoff(time.Second, time.Minute, 2, 0))
+	assert.Equal(t, time.Minute, Backoff(time.Second, time.Minute, 10, 0))
+	assert.Equal(t, time.Minute, Backoff(time.Second, time.Minute, 1000, 0))
+	assert.Equal(t, 1024*time.Second, Backoff(time.Second, 0, 10, 0))
+}
+
+func TestBackoff_Jitter(t *testing.T) {
+	for i := 0; i < 100; i++ {
+		wait := Backoff(time.Second, time.Minute, 2, 0.5)
+		assert.True(t, wait > 2*time.Second, wait)
+		assert.True(t, wait <= 4*time.Second, wait)
+	}
+}
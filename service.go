@@ -7,24 +7,90 @@ import (
 	"os"
 	"os/signal"
 	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"syscall"
+	"time"
 
 	patronErrors "github.com/beatlabs/patron/errors"
+	"github.com/beatlabs/patron/info"
 	"github.com/beatlabs/patron/log"
 	"github.com/beatlabs/patron/log/zerolog"
+	"github.com/beatlabs/patron/reliability/budget"
 	"github.com/beatlabs/patron/sync/http"
 	"github.com/beatlabs/patron/trace"
+	"github.com/opentracing/opentracing-go"
 	jaeger "github.com/uber/jaeger-client-go"
 )
 
-var logSetupOnce sync.Once
+var (
+	logSetupOnce sync.Once
+	// exit terminates the process, which is replaced in tests.
+	exit = os.Exit
+)
 
 // Component interface for implementing service components.
 type Component interface {
 	Run(ctx context.Context) error
 }
 
+// ShutdownReporter is an optional interface which components can implement in order to
+// add their own statistics (e.g. drained messages) to the shutdown report of the service.
+type ShutdownReporter interface {
+	ShutdownStats() map[string]interface{}
+}
+
+// Restartable is an optional interface which components can implement in order to be restarted,
+// e.g. to apply a changed configuration, without restarting the service.
+// On a restart the context of the component's Run is canceled, which drains its in-flight work,
+// Restart is called after Run returns, and the component is run again.
+type Restartable interface {
+	Restart() error
+}
+
+// StartNotifier is an optional interface which components can implement in order to signal that they have started,
+// e.g. once their listener is up or their consumer is consuming. The service reports that it has started, on the startup
+// and the readiness checks, once all its components have started, where the components which do not implement it
+// are considered started once they begin running.
+type StartNotifier interface {
+	// Started returns a channel which is closed once the component has started.
+	Started() <-chan struct{}
+}
+
+// Informer is an optional interface which components can implement in order to
+// expose information about themselves (e.g. the port they listen to) in the /info endpoint.
+type Informer interface {
+	Info() map[string]interface{}
+}
+
+// FatalError is returned by Run when the service terminated because a component failed, as opposed to
+// a termination signal or a canceled context, in order for main to exit with a non-zero exit code
+// and the orchestrator, e.g. Kubernetes, to restart the service.
+type FatalError struct {
+	// Component is the name of the component which failed.
+	Component string
+	// Err holds the errors of all the components.
+	Err error
+}
+
+// Error returns the description of the failure.
+func (e *FatalError) Error() string {
+	return fmt.Sprintf("component %s failed: %v", e.Component, e.Err)
+}
+
+// Unwrap returns the errors of the components.
+func (e *FatalError) Unwrap() error {
+	return e.Err
+}
+
+// restarter holds the restart state of a running component.
+type restarter struct {
+	sync.Mutex
+	cnl       context.CancelFunc
+	requested bool
+}
+
 // Service is responsible for managing and setting up everything.
 // The service will start by default a HTTP component in order to host management endpoint.
 type Service struct {
@@ -35,6 +101,33 @@ type Service struct {
 	rcf           http.ReadyCheckFunc
 	termSig       chan os.Signal
 	sighupHandler func()
+	restartMu     sync.Mutex
+	restarters    []*restarter
+	exitOnFatal   bool
+	// starting is the number of components which have not started yet.
+	starting int32
+	// created is the time the creation of the service started, which is the start of the startup.
+	created time.Time
+	// tracing is the tracing provider, which defaults to OTLP if the PATRON_OTLP_ENDPOINT env var is set,
+	// or else to Jaeger configured by the PATRON_JAEGER_* env vars.
+	tracing trace.Provider
+	// shutdownTimeout bounds the wait for the components to stop and for the tracer to close, if set.
+	shutdownTimeout time.Duration
+	// tlsCert and tlsKey are the files of the certificate and the key of the default HTTP component, if it serves HTTPS.
+	tlsCert string
+	tlsKey  string
+	// logRedaction masks the fields and the messages of the default logging.
+	logRedaction *zerolog.Redaction
+	// logger is the custom logger of the default logging, which replaces zerolog, if set.
+	logger log.Logger
+	// noMetrics disables the /metrics route of the default HTTP component.
+	noMetrics bool
+	// lifetimeSpan enables the span covering the lifetime of the service.
+	lifetimeSpan bool
+	// startupPhases holds the times of the startup phases, which are traced by the lifetime span.
+	startupPhases []startupPhaseRecord
+	// budget bounds the goroutines executing work across the components, which receive it with their context, if set.
+	budget *budget.Budget
 }
 
 // New creates a new named service and allows for customization through functional options.
@@ -47,7 +140,9 @@ func New(name, version string, oo ...OptionFunc) (*Service, error) {
 		version = "dev"
 	}
 
+	start := time.Now()
 	s := Service{
+		created:       start,
 		cps:           []Component{},
 		acf:           http.DefaultAliveCheck,
 		rcf:           http.DefaultReadyCheck,
@@ -60,14 +155,34 @@ func New(name, version string, oo ...OptionFunc) (*Service, error) {
 	if err != nil {
 		return nil, err
 	}
+	phaseStart := s.recordStartupPhase(startupPhaseSetup, start)
+
+	// the options are applied first, since they may set the tracing provider
+	for _, o := range oo {
+		err = o(&s)
+		if err != nil {
+			return nil, err
+		}
+	}
 
-	err = s.setupDefaultTracing(name, version)
+	err = s.setupTracing(name, version)
 	if err != nil {
 		return nil, err
 	}
+	phaseStart = s.recordStartupPhase(startupPhaseTracing, phaseStart)
 
-	for _, o := range oo {
-		err = o(&s)
+	if s.logger != nil {
+		if s.logRedaction != nil {
+			return nil, errors.New("log redaction is supported only by the default logger")
+		}
+		err = s.setupLogger(name, version)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if s.logRedaction != nil {
+		err = s.setupLogRedaction(name, version)
 		if err != nil {
 			return nil, err
 		}
@@ -79,74 +194,385 @@ func New(name, version string, oo ...OptionFunc) (*Service, error) {
 	}
 
 	s.cps = append(s.cps, httpCp)
+	s.recordStartupPhase(startupPhaseComponents, phaseStart)
+	s.starting = int32(len(s.cps))
+	s.setupInfo()
 	s.setupOSSignal()
 	return &s, nil
 }
 
+// setupInfo adds the information of the service components to the info package.
+// The information of each component is collected once, before being added to the info package at once.
+func (s *Service) setupInfo() {
+	cc := make([]info.ComponentInfo, 0, len(s.cps))
+	for _, cp := range s.cps {
+		cc = append(cc, componentInfo(cp))
+	}
+	info.AddComponents(cc...)
+}
+
+// componentInfo returns the information of the component. A panic of the component's Info is recovered,
+// since the information is not essential for the service to run, and only the type of the component is returned.
+func componentInfo(cp Component) (ci info.ComponentInfo) {
+	ci.Type = fmt.Sprintf("%T", cp)
+	in, ok := cp.(Informer)
+	if !ok {
+		return ci
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			log.Errorf("failed to get the info of component %s: %v", ci.Type, r)
+			ci.Fields = nil
+		}
+	}()
+	ci.Fields = in.Info()
+	return ci
+}
+
 func (s *Service) setupOSSignal() {
 	signal.Notify(s.termSig, os.Interrupt, syscall.SIGTERM, syscall.SIGHUP)
 }
 
 // Run starts up all service components and monitors for errors.
 // If a component returns a error the service is responsible for shutting down
-// all components and terminate itself.
+// all components and terminate itself, returning a *FatalError.
+// A component returning nil before the shutdown is requested has completed its work, e.g. a one-off job of a worker-only service,
+// in which case the service shuts down all components and returns without an error.
 func (s *Service) Run(ctx context.Context) error {
-	defer func() {
-		err := trace.Close()
-		if err != nil {
-			log.Errorf("failed to close trace %v", err)
-		}
-	}()
+	err := s.run(ctx)
+	var fe *FatalError
+	if s.exitOnFatal && errors.As(err, &fe) {
+		log.Errorf("exiting due to fatal error: %v", err)
+		exit(1)
+	}
+	return err
+}
+
+func (s *Service) run(ctx context.Context) error {
+	info.MarkStarted()
+	defer s.closeTrace()
+	// the lifetime span finishes before the tracer closes, in order to be reported
+	sp := s.startLifetimeSpan()
+	if s.budget != nil {
+		ctx = budget.WithContext(ctx, s.budget)
+	}
 	cctx, cnl := context.WithCancel(ctx)
 	chErr := make(chan error, len(s.cps))
+	// the components which do not stop within the shutdown timeout keep running, so their results are guarded
+	var mu sync.Mutex
+	stopped := make([]time.Time, len(s.cps))
+	errs := make([]error, len(s.cps))
 	wg := sync.WaitGroup{}
 	wg.Add(len(s.cps))
-	for _, cp := range s.cps {
-		go func(c Component) {
+	s.restartMu.Lock()
+	s.restarters = make([]*restarter, len(s.cps))
+	for i := range s.restarters {
+		s.restarters[i] = &restarter{}
+	}
+	s.restartMu.Unlock()
+	for i, cp := range s.cps {
+		go func(i int, c Component) {
 			defer wg.Done()
-			chErr <- c.Run(cctx)
-		}(cp)
+			go s.waitStarted(cctx, c, sp)
+			err := s.runComponent(cctx, s.restarters[i], c)
+			if err == nil && cctx.Err() == nil {
+				log.Infof("component %s completed, shutting down the service", componentName(c))
+			}
+			mu.Lock()
+			stopped[i] = time.Now()
+			errs[i] = err
+			mu.Unlock()
+			chErr <- err
+		}(i, cp)
 	}
 
 	ee := make([]error, 0, len(s.cps))
-	ee = append(ee, s.waitTermination(chErr))
+	reason, failure := s.waitTermination(chErr)
+	if reason == shutdownReasonCompleted && ctx.Err() != nil {
+		reason = shutdownReasonCanceled
+	}
+	ee = append(ee, failure)
+	shutdownStart := time.Now()
 	cnl()
 
-	wg.Wait()
-	close(chErr)
+	timedOut := !s.waitShutdown(&wg)
 
-	for err := range chErr {
-		ee = append(ee, err)
+	// the errors of the components which have not stopped are not received
+	for done := false; !done; {
+		select {
+		case err := <-chErr:
+			ee = append(ee, err)
+		default:
+			done = true
+		}
+	}
+
+	mu.Lock()
+	stopped = append([]time.Time(nil), stopped...)
+	errs = append([]error(nil), errs...)
+	mu.Unlock()
+	if timedOut {
+		ee = append(ee, s.shutdownTimeoutError(stopped))
+	}
+
+	log.Sub(map[string]interface{}{
+		"components": s.shutdownReport(shutdownStart, stopped, errs),
+		"duration":   time.Since(shutdownStart).String(),
+	}).Info("service stopped")
+	err := patronErrors.Aggregate(ee...)
+	finishLifetimeSpan(sp, s.created, reason, failure != nil)
+	if failure != nil {
+		return &FatalError{Component: s.failedComponent(shutdownStart, stopped, errs), Err: err}
 	}
-	return patronErrors.Aggregate(ee...)
+	return err
+}
+
+// waitShutdown waits for the components to stop, for at most the shutdown timeout, if set.
+// It returns false if the components did not stop in time.
+func (s *Service) waitShutdown(wg *sync.WaitGroup) bool {
+	if s.shutdownTimeout <= 0 {
+		wg.Wait()
+		return true
+	}
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	t := time.NewTimer(s.shutdownTimeout)
+	defer t.Stop()
+	select {
+	case <-done:
+		return true
+	case <-t.C:
+		return false
+	}
+}
+
+// shutdownTimeoutError returns the error of the components which did not stop within the shutdown timeout.
+func (s *Service) shutdownTimeoutError(stopped []time.Time) error {
+	var nn []string
+	for i, cp := range s.cps {
+		if stopped[i].IsZero() {
+			nn = append(nn, componentName(cp))
+		}
+	}
+	err := fmt.Errorf("components %s did not stop within the shutdown timeout of %v", strings.Join(nn, ", "), s.shutdownTimeout)
+	log.Error(err.Error())
+	return err
+}
+
+// closeTrace closes the tracer, waiting for at most the shutdown timeout, if set.
+func (s *Service) closeTrace() {
+	chErr := make(chan error, 1)
+	go func() { chErr <- trace.Close() }()
+	var timeout <-chan time.Time
+	if s.shutdownTimeout > 0 {
+		t := time.NewTimer(s.shutdownTimeout)
+		defer t.Stop()
+		timeout = t.C
+	}
+	select {
+	case err := <-chErr:
+		if err != nil {
+			log.Errorf("failed to close trace %v", err)
+		}
+	case <-timeout:
+		log.Errorf("failed to close trace within the shutdown timeout of %v", s.shutdownTimeout)
+	}
+}
+
+// failedComponent returns the name of the first component which failed before the shutdown.
+func (s *Service) failedComponent(start time.Time, stopped []time.Time, errs []error) string {
+	name := ""
+	first := start
+	for i, cp := range s.cps {
+		if errs[i] != nil && stopped[i].Before(first) {
+			name = componentName(cp)
+			first = stopped[i]
+		}
+	}
+	return name
+}
+
+// runComponent runs the component, re-running it after each requested restart until the context is done.
+func (s *Service) runComponent(ctx context.Context, rs *restarter, cp Component) error {
+	for {
+		rctx, cnl := context.WithCancel(ctx)
+		rs.Lock()
+		rs.cnl = cnl
+		rs.requested = false
+		rs.Unlock()
+
+		err := cp.Run(rctx)
+		cnl()
+
+		rs.Lock()
+		requested := rs.requested
+		rs.Unlock()
+		if !requested || ctx.Err() != nil {
+			return err
+		}
+		if err != nil {
+			log.Errorf("component %s failed to stop for restart: %v", componentName(cp), err)
+			return err
+		}
+		err = cp.(Restartable).Restart()
+		if err != nil {
+			return fmt.Errorf("failed to restart component %s: %w", componentName(cp), err)
+		}
+		log.Infof("component %s restarted", componentName(cp))
+	}
+}
+
+// Restart restarts all running components implementing the Restartable interface.
+// Components are restarted concurrently, each after its in-flight work is drained.
+func (s *Service) Restart() {
+	s.restartMu.Lock()
+	defer s.restartMu.Unlock()
+	for i, rs := range s.restarters {
+		if _, ok := s.cps[i].(Restartable); !ok {
+			continue
+		}
+		rs.Lock()
+		if rs.cnl != nil && !rs.requested {
+			log.Infof("restarting component %s", componentName(s.cps[i]))
+			rs.requested = true
+			rs.cnl()
+		}
+		rs.Unlock()
+	}
+}
+
+// shutdownReport returns a structured summary of how each component stopped.
+func (s *Service) shutdownReport(start time.Time, stopped []time.Time, errs []error) []map[string]interface{} {
+	report := make([]map[string]interface{}, 0, len(s.cps))
+	for i, cp := range s.cps {
+		r := map[string]interface{}{
+			"name": componentName(cp),
+		}
+		if stopped[i].IsZero() {
+			r["duration"] = s.shutdownTimeout.String()
+			r["timedOut"] = true
+		} else if stopped[i].Before(start) {
+			r["duration"] = time.Duration(0).String()
+			r["stoppedBeforeShutdown"] = true
+		} else {
+			r["duration"] = stopped[i].Sub(start).String()
+		}
+		if errs[i] != nil {
+			r["error"] = errs[i].Error()
+		}
+		if sr, ok := cp.(ShutdownReporter); ok {
+			for k, v := range sr.ShutdownStats() {
+				r[k] = v
+			}
+		}
+		report = append(report, r)
+	}
+	return report
+}
+
+// componentName returns the name of the component, if it has one, e.g. a FuncComponent, or its type otherwise.
+func componentName(cp Component) string {
+	if n, ok := cp.(interface{ Name() string }); ok {
+		return n.Name()
+	}
+	return fmt.Sprintf("%T", cp)
 }
 
 // Setup set's up metrics and default logging.
 func Setup(name, version string) error {
-
-	lvl, ok := os.LookupEnv("PATRON_LOG_LEVEL")
-	if !ok {
-		lvl = string(log.InfoLevel)
+	f, err := logFields(name, version)
+	if err != nil {
+		return err
 	}
 
+	info.UpdateName(name, version)
+	info.UpdateHost(f["host"].(string))
+
+	logSetupOnce.Do(func() {
+		err = setupLogging(f, nil)
+	})
+
+	return err
+}
+
+// logFields returns the fields of the default logging, which are logged along with every message.
+func logFields(name, version string) (map[string]interface{}, error) {
 	hostname, err := os.Hostname()
 	if err != nil {
-		return fmt.Errorf("failed to get hostname: %w", err)
+		// logged by the fallback logger, since logging is not set up yet
+		log.Sub(map[string]interface{}{"srv": name, "ver": version}).Errorf("failed to get hostname: %v", err)
+		return nil, fmt.Errorf("failed to get hostname: %w", err)
 	}
 
-	f := map[string]interface{}{
+	return map[string]interface{}{
 		"srv":  name,
 		"ver":  version,
 		"host": hostname,
-	}
-	logSetupOnce.Do(func() {
-		err = log.Setup(zerolog.Create(log.Level(lvl)), f)
-	})
+	}, nil
+}
 
+// setupLogging sets up the default logging, masking the fields and the messages according to the redaction, if any.
+func setupLogging(f map[string]interface{}, r *zerolog.Redaction) error {
+	err := log.Setup(zerolog.CreateWithRedaction(logLevel(), r), f)
+	if err != nil {
+		log.Sub(f).Errorf("failed to set up logging: %v", err)
+	}
 	return err
 }
 
+// logLevel returns the level of the default logging, which is set with the PATRON_LOG_LEVEL env var.
+func logLevel() log.Level {
+	lvl, ok := os.LookupEnv("PATRON_LOG_LEVEL")
+	if !ok {
+		return log.InfoLevel
+	}
+	return log.Level(lvl)
+}
+
+// setupLogger sets up the default logging with the custom logger of the service, along with the fields of the service
+// and the level of the PATRON_LOG_LEVEL env var.
+func (s *Service) setupLogger(name, version string) error {
+	f, err := logFields(name, version)
+	if err != nil {
+		return err
+	}
+	return log.Setup(func(ff map[string]interface{}) log.Logger {
+		return log.NewLevelLogger(s.logger.Sub(ff), logLevel())
+	}, f)
+}
+
+// setupLogRedaction sets up the default logging again, in order for the framework and the user fields
+// and messages to be masked according to the redaction of the service.
+func (s *Service) setupLogRedaction(name, version string) error {
+	f, err := logFields(name, version)
+	if err != nil {
+		return err
+	}
+	return setupLogging(f, s.logRedaction)
+}
+
+// setupTracing sets up the tracing provider set with the Tracing option, or else the default OTLP or Jaeger tracing.
+func (s *Service) setupTracing(name, version string) error {
+	if s.tracing != nil {
+		log.Infof("setting up tracing with provider %T", s.tracing)
+		return trace.SetupProvider(name, version, s.tracing)
+	}
+	return s.setupDefaultTracing(name, version)
+}
+
 func (s *Service) setupDefaultTracing(name, version string) error {
+	if endpoint, ok := os.LookupEnv("PATRON_OTLP_ENDPOINT"); ok {
+		p, err := otlpProvider(endpoint)
+		if err != nil {
+			return err
+		}
+		log.Infof("setting up OTLP tracing %s with sample ratio %v", p.Endpoint, p.SampleRatio)
+		return trace.SetupProvider(name, version, p)
+	}
+
 	var err error
 
 	host, ok := os.LookupEnv("PATRON_JAEGER_AGENT_HOST")
@@ -176,6 +602,26 @@ func (s *Service) setupDefaultTracing(name, version string) error {
 	return trace.Setup(name, version, agent, tp, prmVal)
 }
 
+// otlpProvider returns the OTLP tracing provider exporting to the endpoint, configured by the PATRON_OTLP_* env vars.
+func otlpProvider(endpoint string) (trace.OTLPProvider, error) {
+	p := trace.OTLPProvider{Endpoint: endpoint}
+	if insecure, ok := os.LookupEnv("PATRON_OTLP_INSECURE"); ok {
+		v, err := strconv.ParseBool(insecure)
+		if err != nil {
+			return trace.OTLPProvider{}, fmt.Errorf("env var for OTLP insecure is not valid: %w", err)
+		}
+		p.Insecure = v
+	}
+	if ratio, ok := os.LookupEnv("PATRON_OTLP_SAMPLE_RATIO"); ok {
+		v, err := strconv.ParseFloat(ratio, 64)
+		if err != nil {
+			return trace.OTLPProvider{}, fmt.Errorf("env var for OTLP sample ratio is not valid: %w", err)
+		}
+		p.SampleRatio = v
+	}
+	return p, nil
+}
+
 func (s *Service) createHTTPComponent() (Component, error) {
 	var err error
 	var portVal = int64(50000)
@@ -191,6 +637,23 @@ func (s *Service) createHTTPComponent() (Component, error) {
 
 	b := http.NewBuilder().WithPort(int(portVal))
 
+	drain, ok := os.LookupEnv("PATRON_HTTP_DRAIN_TIMEOUT")
+	if ok {
+		drainVal, err := time.ParseDuration(drain)
+		if err != nil {
+			return nil, fmt.Errorf("env var for HTTP drain timeout is not valid: %w", err)
+		}
+		b.WithDrainTimeout(drainVal)
+	}
+
+	cert, key := s.tlsCert, s.tlsKey
+	if cert == "" && key == "" {
+		cert, key = os.Getenv("PATRON_HTTP_TLS_CERT"), os.Getenv("PATRON_HTTP_TLS_KEY")
+	}
+	if cert != "" || key != "" {
+		b.WithSSL(cert, key)
+	}
+
 	if s.acf != nil {
 		b.WithAliveCheckFunc(s.acf)
 	}
@@ -199,6 +662,12 @@ func (s *Service) createHTTPComponent() (Component, error) {
 		b.WithReadyCheckFunc(s.rcf)
 	}
 
+	b.WithStartupCheckFunc(s.started)
+
+	if s.noMetrics {
+		b.WithoutMetrics()
+	}
+
 	if s.routes != nil {
 		b.WithRoutes(s.routes)
 	}
@@ -215,7 +684,33 @@ func (s *Service) createHTTPComponent() (Component, error) {
 	return cp, nil
 }
 
-func (s *Service) waitTermination(chErr <-chan error) error {
+// waitStarted waits for the component to start, if it signals it, and records the end of the startup
+// once all the components have started.
+func (s *Service) waitStarted(ctx context.Context, c Component, sp opentracing.Span) {
+	if sn, ok := c.(StartNotifier); ok {
+		select {
+		case <-sn.Started():
+		case <-ctx.Done():
+			return
+		}
+	}
+	if atomic.AddInt32(&s.starting, -1) == 0 {
+		log.Info("all components started")
+		recordStartupPhase(startupPhaseTotal, s.created)
+		if sp != nil {
+			sp.LogKV("event", "started")
+		}
+	}
+}
+
+// started reports whether all the components of the service have started.
+func (s *Service) started() bool {
+	return atomic.LoadInt32(&s.starting) <= 0
+}
+
+// waitTermination waits for a termination signal or for a component to stop, and returns the reason of the shutdown
+// along with the error of the component.
+func (s *Service) waitTermination(chErr <-chan error) (string, error) {
 	for {
 		select {
 		case sig := <-s.termSig:
@@ -224,11 +719,14 @@ func (s *Service) waitTermination(chErr <-chan error) error {
 			case syscall.SIGHUP:
 				s.sighupHandler()
 			default:
-				return nil
+				return "signal " + sig.String(), nil
 			}
 		case err := <-chErr:
-			log.Info("component error received")
-			return err
+			if err != nil {
+				log.Info("component error received")
+				return shutdownReasonFailed, err
+			}
+			return shutdownReasonCompleted, nil
 		}
 	}
 }
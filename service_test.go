@@ -2,19 +2,30 @@ package patron
 
 import (
 	"context"
+	"crypto/tls"
 	"errors"
+	"io/ioutil"
 	"math/rand"
 	"net/http"
 	"os"
 	"strconv"
+	"sync/atomic"
+	"syscall"
 	"testing"
+	"time"
 
+	"github.com/beatlabs/patron/info"
+	"github.com/beatlabs/patron/reliability/budget"
+	"github.com/beatlabs/patron/sync"
 	phttp "github.com/beatlabs/patron/sync/http"
+	"github.com/beatlabs/patron/trace"
+	dto "github.com/prometheus/client_model/go"
 	"github.com/stretchr/testify/assert"
 )
 
 func TestNewServer(t *testing.T) {
-	route := phttp.NewRoute("/", "GET", nil, true, nil)
+	proc := func(context.Context, *sync.Request) (*sync.Response, error) { return nil, nil }
+	route := phttp.NewRoute("/", "GET", proc, true, nil)
 	middleware := func(h http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			h.ServeHTTP(w, r)
@@ -48,6 +59,124 @@ func TestNewServer(t *testing.T) {
 	}
 }
 
+func TestNewServer_DrainTimeout(t *testing.T) {
+	defer func() {
+		assert.NoError(t, os.Unsetenv("PATRON_HTTP_DRAIN_TIMEOUT"))
+	}()
+	assert.NoError(t, os.Setenv("PATRON_HTTP_DRAIN_TIMEOUT", "5s"))
+	got, err := New("test", "")
+	assert.NoError(t, err)
+	assert.NotNil(t, got)
+
+	assert.NoError(t, os.Setenv("PATRON_HTTP_DRAIN_TIMEOUT", "invalid"))
+	got, err = New("test", "")
+	assert.Error(t, err)
+	assert.Nil(t, got)
+}
+
+func TestNewServer_OTLPTracing(t *testing.T) {
+	defer func() {
+		assert.NoError(t, os.Unsetenv("PATRON_OTLP_ENDPOINT"))
+		assert.NoError(t, os.Unsetenv("PATRON_OTLP_INSECURE"))
+		assert.NoError(t, os.Unsetenv("PATRON_OTLP_SAMPLE_RATIO"))
+	}()
+	assert.NoError(t, os.Setenv("PATRON_OTLP_ENDPOINT", "localhost:4317"))
+	assert.NoError(t, os.Setenv("PATRON_OTLP_INSECURE", "true"))
+	assert.NoError(t, os.Setenv("PATRON_OTLP_SAMPLE_RATIO", "0.5"))
+	p, err := otlpProvider("localhost:4317")
+	assert.NoError(t, err)
+	assert.Equal(t, trace.OTLPProvider{Endpoint: "localhost:4317", Insecure: true, SampleRatio: 0.5}, p)
+
+	got, err := New("test", "")
+	assert.NoError(t, err)
+	assert.NotNil(t, got)
+	assert.NoError(t, trace.Close())
+
+	assert.NoError(t, os.Setenv("PATRON_OTLP_SAMPLE_RATIO", "invalid"))
+	_, err = New("test", "")
+	assert.Error(t, err)
+
+	assert.NoError(t, os.Setenv("PATRON_OTLP_INSECURE", "invalid"))
+	_, err = New("test", "")
+	assert.Error(t, err)
+}
+
+func TestNewServer_TLS(t *testing.T) {
+	defer func() {
+		assert.NoError(t, os.Unsetenv("PATRON_HTTP_TLS_CERT"))
+		assert.NoError(t, os.Unsetenv("PATRON_HTTP_TLS_KEY"))
+	}()
+	assert.NoError(t, os.Setenv("PATRON_HTTP_TLS_CERT", "sync/http/testdata/server.pem"))
+	_, err := New("test", "")
+	assert.Error(t, err)
+
+	assert.NoError(t, os.Setenv("PATRON_HTTP_TLS_KEY", "sync/http/testdata/missing.key"))
+	_, err = New("test", "")
+	assert.Error(t, err)
+
+	assert.NoError(t, os.Setenv("PATRON_HTTP_TLS_KEY", "sync/http/testdata/server.key"))
+	got, err := New("test", "")
+	assert.NoError(t, err)
+	assert.NotNil(t, got)
+
+	// the option takes precedence over the env vars
+	_, err = New("test", "", TLS("sync/http/testdata/server.pem", "sync/http/testdata/missing.key"))
+	assert.Error(t, err)
+}
+
+func TestServer_Run_TLS(t *testing.T) {
+	port := getRandomPort()
+	assert.NoError(t, os.Setenv("PATRON_HTTP_DEFAULT_PORT", port))
+	s, err := New("test", "", TLS("sync/http/testdata/server.pem", "sync/http/testdata/server.key"))
+	assert.NoError(t, err)
+	ctx, cnl := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- s.Run(ctx) }()
+
+	cl := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}}} // nolint:gosec
+	var rsp *http.Response
+	for i := 0; i < 50; i++ {
+		rsp, err = cl.Get("https://localhost:" + port + "/alive")
+		if err == nil {
+			break
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, rsp.StatusCode)
+	assert.NoError(t, rsp.Body.Close())
+	cnl()
+	assert.NoError(t, <-done)
+}
+
+func TestServer_Run_Metrics(t *testing.T) {
+	port := getRandomPort()
+	assert.NoError(t, os.Setenv("PATRON_HTTP_DEFAULT_PORT", port))
+	s, err := New("test", "")
+	assert.NoError(t, err)
+	ctx, cnl := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- s.Run(ctx) }()
+
+	var rsp *http.Response
+	for i := 0; i < 50; i++ {
+		rsp, err = http.Get("http://localhost:" + port + "/metrics")
+		if err == nil {
+			break
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, rsp.StatusCode)
+	assert.Contains(t, rsp.Header.Get("Content-Type"), "text/plain; version=0.0.4")
+	b, err := ioutil.ReadAll(rsp.Body)
+	assert.NoError(t, err)
+	assert.Contains(t, string(b), "# TYPE go_goroutines gauge")
+	assert.NoError(t, rsp.Body.Close())
+	cnl()
+	assert.NoError(t, <-done)
+}
+
 func TestServer_Run_Shutdown(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -74,6 +203,75 @@ func TestServer_Run_Shutdown(t *testing.T) {
 	}
 }
 
+func TestServer_Run_ComponentCompletes(t *testing.T) {
+	assert.NoError(t, os.Setenv("PATRON_HTTP_DEFAULT_PORT", getRandomPort()))
+	bc := &blockingComponent{}
+	s, err := New("test", "", Components(bc, &testComponent{}))
+	assert.NoError(t, err)
+
+	// the completion of a component shuts down the other components, including the blocking one, without an error
+	done := make(chan error)
+	go func() { done <- s.Run(context.Background()) }()
+	select {
+	case err = <-done:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("service not stopped after a component completed")
+	}
+	assert.Equal(t, int32(1), atomic.LoadInt32(&bc.runs))
+}
+
+func TestServer_Run_FatalError(t *testing.T) {
+	assert.NoError(t, os.Setenv("PATRON_HTTP_DEFAULT_PORT", getRandomPort()))
+	s, err := New("test", "", Components(&blockingComponent{}, &testComponent{errorRunning: true}))
+	assert.NoError(t, err)
+	err = s.Run(context.Background())
+	var fe *FatalError
+	assert.True(t, errors.As(err, &fe))
+	assert.Equal(t, "*patron.testComponent", fe.Component)
+	assert.Equal(t, "component *patron.testComponent failed: failed to run component\n", err.Error())
+
+	// a canceled context is not a failure
+	assert.NoError(t, os.Setenv("PATRON_HTTP_DEFAULT_PORT", getRandomPort()))
+	s, err = New("test", "", Components(&blockingComponent{}))
+	assert.NoError(t, err)
+	ctx, cnl := context.WithCancel(context.Background())
+	cnl()
+	err = s.Run(ctx)
+	assert.False(t, errors.As(err, &fe))
+}
+
+func TestServer_Run_ShutdownTimeout(t *testing.T) {
+	assert.NoError(t, os.Setenv("PATRON_HTTP_DEFAULT_PORT", getRandomPort()))
+	hc := &hangingComponent{release: make(chan struct{})}
+	defer close(hc.release)
+	s, err := New("test", "", Components(hc), ShutdownTimeout(100*time.Millisecond))
+	assert.NoError(t, err)
+
+	ctx, cnl := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- s.Run(ctx) }()
+	cnl()
+	select {
+	case err = <-done:
+		assert.EqualError(t, err, "components *patron.hangingComponent did not stop within the shutdown timeout of 100ms\n")
+	case <-time.After(5 * time.Second):
+		t.Fatal("service not stopped within the shutdown timeout")
+	}
+}
+
+func TestServer_Run_ExitOnFatal(t *testing.T) {
+	var code int
+	defer func(f func(int)) { exit = f }(exit)
+	exit = func(c int) { code = c }
+
+	assert.NoError(t, os.Setenv("PATRON_HTTP_DEFAULT_PORT", getRandomPort()))
+	s, err := New("test", "", Components(&testComponent{errorRunning: true}), ExitOnFatal())
+	assert.NoError(t, err)
+	assert.Error(t, s.Run(context.Background()))
+	assert.Equal(t, 1, code)
+}
+
 func TestServer_SetupTracing(t *testing.T) {
 	tests := []struct {
 		name string
@@ -105,6 +303,91 @@ func TestServer_SetupTracing(t *testing.T) {
 	}
 }
 
+func TestService_shutdownReport(t *testing.T) {
+	start := time.Now()
+	s := &Service{cps: []Component{&testComponent{errorRunning: true}, &testComponent{}, &reportingComponent{}}}
+	stopped := []time.Time{start.Add(-time.Second), start.Add(time.Second), start.Add(2 * time.Second)}
+	errs := []error{errors.New("failed to run component"), nil, nil}
+
+	cc := s.shutdownReport(start, stopped, errs)
+	assert.Len(t, cc, 3)
+	assert.Equal(t, "*patron.testComponent", cc[0]["name"])
+	assert.Equal(t, "failed to run component", cc[0]["error"])
+	assert.Equal(t, true, cc[0]["stoppedBeforeShutdown"])
+	assert.Equal(t, "0s", cc[0]["duration"])
+	assert.Equal(t, "1s", cc[1]["duration"])
+	assert.NotContains(t, cc[1], "error")
+	assert.Equal(t, "*patron.reportingComponent", cc[2]["name"])
+	assert.Equal(t, "2s", cc[2]["duration"])
+	assert.Equal(t, 42, cc[2]["processed"])
+
+	s.shutdownTimeout = time.Second
+	cc = s.shutdownReport(start, []time.Time{{}, start, start}, errs)
+	assert.Equal(t, true, cc[0]["timedOut"])
+	assert.Equal(t, "1s", cc[0]["duration"])
+	assert.NotContains(t, cc[1], "timedOut")
+}
+
+func TestService_Run_ShutdownReportLogged(t *testing.T) {
+	f, err := logFields("test", "1.0.0")
+	assert.NoError(t, err)
+	defer func() { assert.NoError(t, setupLogging(f, nil)) }()
+
+	worker, err := FuncComponent("worker", func(ctx context.Context) error {
+		<-ctx.Done()
+		return nil
+	}, nil)
+	assert.NoError(t, err)
+	rl := newRecordingLogger()
+	s, err := New("test", "1.0.0", Logger(rl), Components(worker, &reportingComponent{}))
+	assert.NoError(t, err)
+
+	ctx, cnl := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cnl()
+	assert.NoError(t, s.Run(ctx))
+
+	assert.Contains(t, rl.messages(), "info service stopped")
+	var report []map[string]interface{}
+	for _, ff := range rl.subFields() {
+		if cc, ok := ff["components"]; ok {
+			report = cc.([]map[string]interface{})
+			assert.Contains(t, ff, "duration")
+		}
+	}
+	names := make([]interface{}, 0, len(report))
+	for _, r := range report {
+		names = append(names, r["name"])
+	}
+	assert.Contains(t, names, "worker")
+	assert.Contains(t, names, "*patron.reportingComponent")
+	for _, r := range report {
+		if r["name"] == "*patron.reportingComponent" {
+			assert.Equal(t, 42, r["processed"])
+			assert.Equal(t, true, r["stoppedBeforeShutdown"])
+		}
+	}
+}
+
+type budgetComponent struct {
+	budget *budget.Budget
+}
+
+func (bc *budgetComponent) Run(ctx context.Context) error {
+	bc.budget = budget.FromContext(ctx)
+	return nil
+}
+
+func TestServer_Run_GoroutineBudget(t *testing.T) {
+	assert.NoError(t, os.Setenv("PATRON_HTTP_DEFAULT_PORT", getRandomPort()))
+	bc := &budgetComponent{}
+	s, err := New("test", "", Components(bc), MaxGoroutines(2))
+	assert.NoError(t, err)
+	assert.NoError(t, s.Run(context.Background()))
+	// the components receive the budget of the service, which is not shared with the rest of the process
+	assert.True(t, bc.budget == s.budget)
+	assert.NotNil(t, bc.budget)
+}
+
 func getRandomPort() string {
 	rnd := 50000 + rand.Int63n(10000)
 	return strconv.FormatInt(rnd, 10)
@@ -120,3 +403,223 @@ func (ts testComponent) Run(ctx context.Context) error {
 	}
 	return nil
 }
+
+type reportingComponent struct {
+	testComponent
+}
+
+func (rc reportingComponent) ShutdownStats() map[string]interface{} {
+	return map[string]interface{}{"processed": 42}
+}
+
+type informingComponent struct {
+	testComponent
+}
+
+func (ic informingComponent) Info() map[string]interface{} {
+	return map[string]interface{}{"topic": "orders"}
+}
+
+func TestService_setupInfo(t *testing.T) {
+	s, err := New("test", "", Components(informingComponent{}))
+	assert.NoError(t, err)
+	assert.NotNil(t, s)
+
+	cc := info.Components()
+	assert.True(t, len(cc) >= 2)
+	assert.Equal(t, map[string]interface{}{"type": "patron.informingComponent", "topic": "orders"}, cc[len(cc)-2])
+	assert.Equal(t, "*http.Component", cc[len(cc)-1]["type"])
+	assert.Contains(t, cc[len(cc)-1], "port")
+	assert.Equal(t, false, cc[len(cc)-1]["ssl"])
+}
+
+type panickingInfoComponent struct {
+	testComponent
+}
+
+func (pc panickingInfoComponent) Info() map[string]interface{} {
+	panic("info not available")
+}
+
+func TestService_setupInfo_Panic(t *testing.T) {
+	s, err := New("test", "", Components(panickingInfoComponent{}, informingComponent{}))
+	assert.NoError(t, err)
+	assert.NotNil(t, s)
+
+	cc := info.Components()
+	assert.True(t, len(cc) >= 3)
+	assert.Equal(t, map[string]interface{}{"type": "patron.panickingInfoComponent"}, cc[len(cc)-3])
+	assert.Equal(t, map[string]interface{}{"type": "patron.informingComponent", "topic": "orders"}, cc[len(cc)-2])
+	assert.Equal(t, "*http.Component", cc[len(cc)-1]["type"])
+
+	ctx, cnl := context.WithCancel(context.Background())
+	cnl()
+	assert.NoError(t, s.Run(ctx))
+}
+
+type restartableComponent struct {
+	runs     int32
+	restarts int32
+	started  chan struct{}
+}
+
+func (rc *restartableComponent) Run(ctx context.Context) error {
+	atomic.AddInt32(&rc.runs, 1)
+	rc.started <- struct{}{}
+	<-ctx.Done()
+	return nil
+}
+
+func (rc *restartableComponent) Restart() error {
+	atomic.AddInt32(&rc.restarts, 1)
+	return nil
+}
+
+type blockingComponent struct {
+	runs int32
+}
+
+func (bc *blockingComponent) Run(ctx context.Context) error {
+	atomic.AddInt32(&bc.runs, 1)
+	<-ctx.Done()
+	return nil
+}
+
+// hangingComponent ignores the cancellation of its context, until it is released.
+type hangingComponent struct {
+	release chan struct{}
+}
+
+func (hc *hangingComponent) Run(_ context.Context) error {
+	<-hc.release
+	return nil
+}
+
+func TestService_Restart(t *testing.T) {
+	rc := &restartableComponent{started: make(chan struct{}, 1)}
+	bc := &blockingComponent{}
+	s := &Service{cps: []Component{rc, bc}, termSig: make(chan os.Signal, 1)}
+	assert.NoError(t, RestartOnSIGHUP()(s))
+	done := make(chan error)
+	go func() {
+		done <- s.Run(context.Background())
+	}()
+	<-rc.started
+
+	s.Restart()
+	<-rc.started
+	s.termSig <- syscall.SIGHUP
+	<-rc.started
+
+	s.termSig <- syscall.SIGTERM
+	assert.NoError(t, <-done)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&rc.runs))
+	assert.Equal(t, int32(2), atomic.LoadInt32(&rc.restarts))
+	// components which are not restartable are not affected
+	assert.Equal(t, int32(1), atomic.LoadInt32(&bc.runs))
+}
+
+func TestService_Startup(t *testing.T) {
+	port := getRandomPort()
+	assert.NoError(t, os.Setenv("PATRON_HTTP_DEFAULT_PORT", port))
+	s, err := New("test", "", Components(&blockingComponent{}))
+	assert.NoError(t, err)
+	assert.False(t, s.started())
+
+	done := make(chan error)
+	go func() {
+		done <- s.Run(context.Background())
+	}()
+	var status int
+	for i := 0; i < 50 && status != http.StatusOK; i++ {
+		time.Sleep(10 * time.Millisecond)
+		rsp, err := http.Get("http://localhost:" + port + "/startup")
+		if err != nil {
+			continue
+		}
+		status = rsp.StatusCode
+		assert.NoError(t, rsp.Body.Close())
+	}
+	assert.Equal(t, http.StatusOK, status)
+	assert.True(t, s.started())
+
+	// the startup phases are recorded once the components have started
+	var total float64
+	for i := 0; i < 50 && total == 0; i++ {
+		total = startupPhaseDuration(t, startupPhaseTotal)
+		time.Sleep(time.Millisecond)
+	}
+	assert.True(t, total > 0)
+	for _, phase := range []string{startupPhaseSetup, startupPhaseTracing, startupPhaseComponents} {
+		d := startupPhaseDuration(t, phase)
+		assert.True(t, d > 0, phase)
+		assert.True(t, d <= total, phase)
+	}
+
+	s.termSig <- syscall.SIGTERM
+	assert.NoError(t, <-done)
+}
+
+// startingComponent signals that it has started once it is released.
+type startingComponent struct {
+	blockingComponent
+	started chan struct{}
+}
+
+func (sc *startingComponent) Started() <-chan struct{} {
+	return sc.started
+}
+
+func TestService_Startup_WaitsForComponents(t *testing.T) {
+	port := getRandomPort()
+	assert.NoError(t, os.Setenv("PATRON_HTTP_DEFAULT_PORT", port))
+	sc := &startingComponent{started: make(chan struct{})}
+	s, err := New("test", "", Components(sc))
+	assert.NoError(t, err)
+
+	done := make(chan error)
+	go func() {
+		done <- s.Run(context.Background())
+	}()
+	get := func(path string) (int, string) {
+		rsp, err := http.Get("http://localhost:" + port + path)
+		if err != nil {
+			return 0, ""
+		}
+		defer func() { assert.NoError(t, rsp.Body.Close()) }()
+		body, err := ioutil.ReadAll(rsp.Body)
+		assert.NoError(t, err)
+		return rsp.StatusCode, string(body)
+	}
+
+	// the HTTP component is up, while the other component has begun running but has not started yet
+	var status int
+	for i := 0; i < 50 && status == 0; i++ {
+		time.Sleep(10 * time.Millisecond)
+		status, _ = get("/startup")
+	}
+	assert.Equal(t, http.StatusServiceUnavailable, status)
+	status, body := get("/ready")
+	assert.Equal(t, http.StatusServiceUnavailable, status)
+	assert.Equal(t, "starting", body)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&sc.runs))
+	assert.False(t, s.started())
+
+	close(sc.started)
+	for i := 0; i < 50 && status != http.StatusOK; i++ {
+		time.Sleep(10 * time.Millisecond)
+		status, _ = get("/startup")
+	}
+	assert.Equal(t, http.StatusOK, status)
+	status, _ = get("/ready")
+	assert.Equal(t, http.StatusOK, status)
+
+	s.termSig <- syscall.SIGTERM
+	assert.NoError(t, <-done)
+}
+
+func startupPhaseDuration(t *testing.T, phase string) float64 {
+	m := &dto.Metric{}
+	assert.NoError(t, startupDuration.WithLabelValues(phase).Write(m))
+	return m.GetGauge().GetValue()
+}
@@ -0,0 +1,53 @@
+package patron
+
+import (
+	"time"
+
+	"github.com/beatlabs/patron/log"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	startupPhaseSetup      = "setup"
+	startupPhaseTracing    = "tracing"
+	startupPhaseComponents = "components"
+	startupPhaseTotal      = "total"
+)
+
+var startupDuration *prometheus.GaugeVec
+
+func init() {
+	startupDuration = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "service",
+			Name:      "startup_duration_seconds",
+			Help:      "Duration of the startup of the service, classified by phase",
+		},
+		[]string{"phase"},
+	)
+	prometheus.MustRegister(startupDuration)
+}
+
+// recordStartupPhase records the duration of a startup phase, which started at start, and returns the current time
+// in order for the next phase to start.
+func recordStartupPhase(phase string, start time.Time) time.Time {
+	now := time.Now()
+	d := now.Sub(start)
+	startupDuration.WithLabelValues(phase).Set(d.Seconds())
+	log.Infof("startup phase %s took %v", phase, d)
+	return now
+}
+
+// startupPhaseRecord holds the times of a startup phase, which are traced by the lifetime span of the service.
+type startupPhaseRecord struct {
+	phase string
+	start time.Time
+	end   time.Time
+}
+
+// recordStartupPhase records the duration of a startup phase of the service, along with its times.
+func (s *Service) recordStartupPhase(phase string, start time.Time) time.Time {
+	end := recordStartupPhase(phase, start)
+	s.startupPhases = append(s.startupPhases, startupPhaseRecord{phase: phase, start: start, end: end})
+	return end
+}
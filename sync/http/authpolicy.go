@@ -0,0 +1,148 @@
+package http
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+
+	"github.com/beatlabs/patron/encoding/json"
+	"github.com/beatlabs/patron/log"
+	"github.com/beatlabs/patron/sync/http/auth"
+)
+
+// AuthPolicyPath is the path of the routes which report and override the authentication of the routes.
+const AuthPolicyPath = "/auth-policy"
+
+// AuthPolicy overrides at runtime whether the authentication of the routes with an authenticator is enforced,
+// e.g. in order to turn it off for an endpoint during an incident without redeploying.
+// Routes without an override enforce the authentication they were created with.
+// Every change is logged as an audit trail.
+type AuthPolicy struct {
+	mu        sync.RWMutex
+	overrides map[string]bool
+}
+
+// NewAuthPolicy creates an auth policy without overrides.
+func NewAuthPolicy() *AuthPolicy {
+	return &AuthPolicy{overrides: make(map[string]bool)}
+}
+
+// Override sets whether the authentication of the route is enforced, overriding the authenticator of the route.
+// The overrides apply to routes created with an authenticator, since routes without one can not authenticate.
+func (p *AuthPolicy) Override(method, pattern string, enforce bool) {
+	p.set(log.Sub(nil), method, pattern, &enforce)
+}
+
+// Reset removes the override of the route, which enforces the authentication it was created with.
+func (p *AuthPolicy) Reset(method, pattern string) {
+	p.set(log.Sub(nil), method, pattern, nil)
+}
+
+// Enforced returns whether the authentication of the route is enforced, which is true unless it is overridden.
+func (p *AuthPolicy) Enforced(method, pattern string) bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	enforce, ok := p.overrides[routeKey(method, pattern)]
+	return !ok || enforce
+}
+
+// Overrides returns whether the authentication is enforced for the overridden routes, keyed by method and pattern, e.g. "GET /orders".
+func (p *AuthPolicy) Overrides() map[string]bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	oo := make(map[string]bool, len(p.overrides))
+	for k, v := range p.overrides {
+		oo[k] = v
+	}
+	return oo
+}
+
+func (p *AuthPolicy) set(logger log.Logger, method, pattern string, enforce *bool) {
+	key := routeKey(method, pattern)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if enforce == nil {
+		if _, ok := p.overrides[key]; ok {
+			delete(p.overrides, key)
+			logger.Infof("auth policy: override of the authentication of route %s removed", key)
+		}
+		return
+	}
+	if prev, ok := p.overrides[key]; ok && prev == *enforce {
+		return
+	}
+	p.overrides[key] = *enforce
+	if *enforce {
+		logger.Infof("auth policy: authentication of route %s enforced", key)
+	} else {
+		logger.Warnf("auth policy: authentication of route %s disabled", key)
+	}
+}
+
+func routeKey(method, pattern string) string {
+	return method + " " + pattern
+}
+
+// policyAuthenticator authenticates the requests of a route unless the auth policy of the component,
+// which is set when the component is created, disables the authentication of the route.
+type policyAuthenticator struct {
+	auth.Authenticator
+	method  string
+	pattern string
+	policy  *AuthPolicy
+}
+
+func newPolicyAuthenticator(method, pattern string, a auth.Authenticator) *policyAuthenticator {
+	if a == nil {
+		return nil
+	}
+	return &policyAuthenticator{Authenticator: a, method: method, pattern: pattern}
+}
+
+// Authenticate authenticates the request with the authenticator of the route, if the authentication is enforced.
+func (pa *policyAuthenticator) Authenticate(req *http.Request) (bool, error) {
+	if pa.policy != nil && !pa.policy.Enforced(pa.method, pa.pattern) {
+		return true, nil
+	}
+	return pa.Authenticator.Authenticate(req)
+}
+
+// authPolicyRoutes returns the routes which report the overrides of the auth policy, override the authentication of a route with a PUT,
+// e.g. /auth-policy?method=GET&path=/orders&enforce=false, and remove the override with a DELETE.
+// The routes are authenticated with the admin authenticator, which the policy can not override,
+// and the changes are logged along with the address of the client which requested them.
+func authPolicyRoutes(p *AuthPolicy, admin auth.Authenticator) []Route {
+	status := func(w http.ResponseWriter, r *http.Request) {
+		body, err := json.Encode(p.Overrides())
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", json.TypeCharset)
+		_, _ = w.Write(body)
+	}
+	change := func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		method, pattern := q.Get("method"), q.Get("path")
+		if method == "" || pattern == "" {
+			writeError(w, r, http.StatusBadRequest, "the method and the path of the route are required")
+			return
+		}
+		var enforce *bool
+		if r.Method == http.MethodPut {
+			e, err := strconv.ParseBool(q.Get("enforce"))
+			if err != nil {
+				writeError(w, r, http.StatusBadRequest, "enforce has to be a boolean")
+				return
+			}
+			enforce = &e
+		}
+		p.set(log.Sub(map[string]interface{}{"remoteAddr": r.RemoteAddr}), method, pattern, enforce)
+		status(w, r)
+	}
+	return []Route{
+		NewRouteRaw(AuthPolicyPath, http.MethodGet, status, false, NewAuthMiddleware(admin)),
+		NewRouteRaw(AuthPolicyPath, http.MethodPut, change, false, NewAuthMiddleware(admin)),
+		NewRouteRaw(AuthPolicyPath, http.MethodDelete, change, false, NewAuthMiddleware(admin)),
+	}
+}
@@ -0,0 +1,92 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuthPolicy(t *testing.T) {
+	p := NewAuthPolicy()
+	assert.True(t, p.Enforced(http.MethodGet, "/orders"))
+	p.Override(http.MethodGet, "/orders", false)
+	assert.False(t, p.Enforced(http.MethodGet, "/orders"))
+	assert.True(t, p.Enforced(http.MethodPost, "/orders"))
+	p.Override(http.MethodPost, "/orders", true)
+	assert.Equal(t, map[string]bool{"GET /orders": false, "POST /orders": true}, p.Overrides())
+	p.Reset(http.MethodGet, "/orders")
+	p.Reset(http.MethodGet, "/missing")
+	assert.True(t, p.Enforced(http.MethodGet, "/orders"))
+	assert.Equal(t, map[string]bool{"POST /orders": true}, p.Overrides())
+}
+
+// adminAuthenticator authenticates the requests of the admin routes carrying the admin token.
+type adminAuthenticator struct{}
+
+func (adminAuthenticator) Authenticate(req *http.Request) (bool, error) {
+	return req.Header.Get("Authorization") == "admin", nil
+}
+
+func TestBuilder_WithAuthPolicy(t *testing.T) {
+	_, err := NewBuilder().WithAuthPolicy(nil, adminAuthenticator{}).Create()
+	assert.EqualError(t, err, "Nil AuthPolicy provided\n")
+	_, err = NewBuilder().WithAuthPolicy(NewAuthPolicy(), nil).Create()
+	assert.EqualError(t, err, "Nil admin authenticator of the auth policy provided\n")
+}
+
+func TestComponent_AuthPolicy(t *testing.T) {
+	ok := func(w http.ResponseWriter, r *http.Request) {}
+	rr := []Route{
+		NewAuthRouteRaw("/orders", http.MethodGet, ok, false, &MockAuthenticator{}),
+		NewAuthRouteRaw("/orders", http.MethodPost, ok, false, &MockAuthenticator{}),
+		NewRouteRaw("/public", http.MethodGet, ok, false),
+	}
+	p := NewAuthPolicy()
+	cmp, err := NewBuilder().WithRoutes(rr).WithAuthPolicy(p, adminAuthenticator{}).Create()
+	require.NoError(t, err)
+	h := cmp.createHTTPServer().Handler
+	serve := func(method, path string) *httptest.ResponseRecorder {
+		rc := httptest.NewRecorder()
+		req := httptest.NewRequest(method, path, nil)
+		if strings.HasPrefix(path, AuthPolicyPath) {
+			req.Header.Set("Authorization", "admin")
+		}
+		h.ServeHTTP(rc, req)
+		return rc
+	}
+
+	// the compiled-in authentication is enforced by default
+	assert.Equal(t, http.StatusUnauthorized, serve(http.MethodGet, "/orders").Code)
+	assert.Equal(t, http.StatusOK, serve(http.MethodGet, "/public").Code)
+
+	p.Override(http.MethodGet, "/orders", false)
+	assert.Equal(t, http.StatusOK, serve(http.MethodGet, "/orders").Code)
+	assert.Equal(t, http.StatusUnauthorized, serve(http.MethodPost, "/orders").Code)
+
+	// the policy is changed with the admin route as well
+	assert.JSONEq(t, `{"GET /orders":false}`, serve(http.MethodGet, AuthPolicyPath).Body.String())
+	assert.JSONEq(t, `{"GET /orders":false,"POST /orders":false}`,
+		serve(http.MethodPut, AuthPolicyPath+"?method=POST&path=/orders&enforce=false").Body.String())
+	assert.Equal(t, http.StatusOK, serve(http.MethodPost, "/orders").Code)
+	assert.JSONEq(t, `{"GET /orders":false}`, serve(http.MethodDelete, AuthPolicyPath+"?method=POST&path=/orders").Body.String())
+	assert.Equal(t, http.StatusUnauthorized, serve(http.MethodPost, "/orders").Code)
+
+	assert.Equal(t, http.StatusBadRequest, serve(http.MethodPut, AuthPolicyPath+"?method=POST&path=/orders&enforce=maybe").Code)
+	assert.Equal(t, http.StatusBadRequest, serve(http.MethodPut, AuthPolicyPath+"?path=/orders&enforce=true").Code)
+
+	p.Reset(http.MethodGet, "/orders")
+	assert.Equal(t, http.StatusUnauthorized, serve(http.MethodGet, "/orders").Code)
+
+	// the admin route requires the admin authentication, which the policy can not override
+	p.Override(http.MethodPut, AuthPolicyPath, false)
+	for _, method := range []string{http.MethodGet, http.MethodPut, http.MethodDelete} {
+		rc := httptest.NewRecorder()
+		h.ServeHTTP(rc, httptest.NewRequest(method, AuthPolicyPath+"?method=GET&path=/orders&enforce=false", nil))
+		assert.Equal(t, http.StatusUnauthorized, rc.Code, method)
+	}
+	assert.True(t, p.Enforced(http.MethodGet, "/orders"))
+}
@@ -0,0 +1,53 @@
+package http
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net/http"
+)
+
+// BodyKey is the key of the request body, which is stored as a []byte by the buffer body middleware.
+var BodyKey = NewKey("body")
+
+// NewBufferBodyMiddleware creates a MiddlewareFunc which reads the request body, up to maxBytes, into memory
+// and stores it in the request context, in order for the subsequent middlewares, e.g. auditing or validation,
+// to inspect it with BufferedBody, while the handler still reads the body from the request.
+// Bodies larger than maxBytes, e.g. streaming uploads, are not buffered and are passed to the handler intact.
+// A body which cannot be read is rejected with 400 Bad Request.
+func NewBufferBodyMiddleware(maxBytes int64) MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Body == nil || r.Body == http.NoBody || maxBytes <= 0 || r.ContentLength > maxBytes {
+				next.ServeHTTP(w, r)
+				return
+			}
+			// bodies of unknown length are read up to one byte over the limit, in order to tell whether they exceed it
+			b, err := ioutil.ReadAll(io.LimitReader(r.Body, maxBytes+1))
+			if err != nil {
+				http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+				return
+			}
+			if int64(len(b)) > maxBytes {
+				r.Body = &replayBody{Reader: io.MultiReader(bytes.NewReader(b), r.Body), Closer: r.Body}
+				next.ServeHTTP(w, r)
+				return
+			}
+			r.Body = &replayBody{Reader: bytes.NewReader(b), Closer: r.Body}
+			next.ServeHTTP(w, r.WithContext(SetValue(r.Context(), BodyKey, b)))
+		})
+	}
+}
+
+// BufferedBody returns the request body buffered by the buffer body middleware, if it exists.
+// The returned slice is shared and must not be modified.
+func BufferedBody(r *http.Request) ([]byte, bool) {
+	b, ok := r.Context().Value(BodyKey).([]byte)
+	return b, ok
+}
+
+// replayBody reads the buffered body, while closing the original body.
+type replayBody struct {
+	io.Reader
+	io.Closer
+}
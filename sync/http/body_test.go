@@ -0,0 +1,72 @@
+package http
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewBufferBodyMiddleware(t *testing.T) {
+	tests := map[string]struct {
+		body          string
+		contentLength int64
+		buffered      bool
+	}{
+		"buffered":                       {body: "payload", contentLength: 7, buffered: true},
+		"unknown length buffered":        {body: "payload", contentLength: -1, buffered: true},
+		"exceeding limit":                {body: "large payload", contentLength: 13},
+		"unknown length exceeding limit": {body: "large payload", contentLength: -1},
+		"empty body":                     {body: "", contentLength: 0, buffered: true},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			var inspected []byte
+			var buffered bool
+			inspect := func(next http.Handler) http.Handler {
+				return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					inspected, buffered = BufferedBody(r)
+					next.ServeHTTP(w, r)
+				})
+			}
+			var handled string
+			h := MiddlewareChain(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				b, err := ioutil.ReadAll(r.Body)
+				assert.NoError(t, err)
+				handled = string(b)
+				assert.NoError(t, r.Body.Close())
+			}), NewBufferBodyMiddleware(10), inspect)
+
+			req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tt.body))
+			req.ContentLength = tt.contentLength
+			rsp := httptest.NewRecorder()
+			h.ServeHTTP(rsp, req)
+
+			assert.Equal(t, http.StatusOK, rsp.Code)
+			assert.Equal(t, tt.body, handled)
+			assert.Equal(t, tt.buffered, buffered)
+			if tt.buffered {
+				assert.Equal(t, tt.body, string(inspected))
+			}
+		})
+	}
+}
+
+type failingReader struct{}
+
+func (failingReader) Read([]byte) (int, error) {
+	return 0, http.ErrBodyReadAfterClose
+}
+
+func TestNewBufferBodyMiddleware_ReadFailure(t *testing.T) {
+	h := NewBufferBodyMiddleware(10)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Fatal("handler should not be called")
+	}))
+	req := httptest.NewRequest(http.MethodPost, "/", failingReader{})
+	rsp := httptest.NewRecorder()
+	h.ServeHTTP(rsp, req)
+	assert.Equal(t, http.StatusBadRequest, rsp.Code)
+}
@@ -0,0 +1,43 @@
+package http
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/beatlabs/patron/encoding"
+	"github.com/beatlabs/patron/encoding/json"
+)
+
+// maxPooledBufferSize bounds the capacity of the buffers returned to the pool,
+// in order for a few large responses not to keep their buffers allocated.
+const maxPooledBufferSize = 64 << 10
+
+var responseBuffers = sync.Pool{New: func() interface{} { return new(bytes.Buffer) }}
+
+// pooledResponse reports whether the payload of the response is encoded into a pooled buffer,
+// i.e. pooling is enabled for the component serving the request and the response is JSON.
+func pooledResponse(r *http.Request, w http.ResponseWriter) bool {
+	return requestSettings(r).bufferPool &&
+		strings.HasPrefix(w.Header().Get(encoding.ContentTypeHeader), json.Type)
+}
+
+// writePooledJSON encodes the payload into a pooled buffer and writes it, after which the buffer is returned to the pool.
+// Writers do not retain the written data, e.g. the compression middleware compresses it while writing, so the buffer is reused safely.
+func writePooledJSON(w http.ResponseWriter, status int, payload interface{}) error {
+	buf := responseBuffers.Get().(*bytes.Buffer)
+	defer func() {
+		if buf.Cap() <= maxPooledBufferSize {
+			buf.Reset()
+			responseBuffers.Put(buf)
+		}
+	}()
+	err := json.EncodeTo(buf, payload)
+	if err != nil {
+		return err
+	}
+	w.WriteHeader(status)
+	_, err = w.Write(buf.Bytes())
+	return err
+}
@@ -0,0 +1,114 @@
+package http
+
+import (
+	"compress/gzip"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/beatlabs/patron/encoding"
+	"github.com/beatlabs/patron/encoding/json"
+	"github.com/beatlabs/patron/encoding/protobuf"
+	"github.com/beatlabs/patron/sync"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type pooledPayload struct {
+	Name  string   `json:"name"`
+	Items []string `json:"items"`
+}
+
+func TestHandler_ResponseBufferPool(t *testing.T) {
+	small := pooledPayload{Name: "<small>", Items: []string{"a", "b"}}
+	large := pooledPayload{Name: "large", Items: []string{strings.Repeat("x", 2*maxPooledBufferSize)}}
+	compression, err := NewCompressionMiddleware(gzip.BestSpeed)
+	require.NoError(t, err)
+
+	serve := func(payload interface{}, gz bool, ct string, pooling bool) *httptest.ResponseRecorder {
+		h := http.Handler(handler(func(context.Context, *sync.Request) (*sync.Response, error) {
+			return sync.NewResponse(payload), nil
+		}))
+		if gz {
+			h = compression(h)
+		}
+		h = settingsMiddleware(&settings{bufferPool: pooling})(h)
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set(encoding.AcceptHeader, ct)
+		if gz {
+			req.Header.Set(headerAcceptEncoding, gzipEncoding)
+		}
+		rc := httptest.NewRecorder()
+		h.ServeHTTP(rc, req)
+		return rc
+	}
+	body := func(rc *httptest.ResponseRecorder, gz bool) string {
+		if !gz {
+			return rc.Body.String()
+		}
+		r, err := gzip.NewReader(rc.Body)
+		require.NoError(t, err)
+		b, err := ioutil.ReadAll(r)
+		require.NoError(t, err)
+		return string(b)
+	}
+
+	for _, gz := range []bool{false, true} {
+		for _, payload := range []interface{}{small, large, small, large, small} {
+			expected := serve(payload, gz, json.Type, false)
+			rc := serve(payload, gz, json.Type, true)
+			assert.Equal(t, http.StatusOK, rc.Code)
+			assert.Equal(t, expected.Header(), rc.Header())
+			assert.Equal(t, body(expected, gz), body(rc, gz))
+		}
+	}
+
+	// the responses which are not JSON, or of components without pooling, are not pooled
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	pooled := req.WithContext(context.WithValue(req.Context(), settingsKey{}, &settings{bufferPool: true}))
+	rc := httptest.NewRecorder()
+	prepareResponse(rc, protobuf.Type)
+	assert.False(t, pooledResponse(pooled, rc))
+	prepareResponse(rc, json.TypeCharset)
+	assert.True(t, pooledResponse(pooled, rc))
+	assert.False(t, pooledResponse(req, rc))
+}
+
+// discardResponseWriter discards the responses, in order for the benchmarks to measure only the allocations of the handler.
+type discardResponseWriter struct {
+	header http.Header
+}
+
+func (w *discardResponseWriter) Header() http.Header { return w.header }
+
+func (w *discardResponseWriter) Write(b []byte) (int, error) { return len(b), nil }
+
+func (w *discardResponseWriter) WriteHeader(int) {}
+
+func benchmarkHandleSuccess(b *testing.B, pooling bool) {
+	items := make([]string, 100)
+	for i := range items {
+		items[i] = strings.Repeat("item", 10)
+	}
+	rsp := sync.NewResponse(pooledPayload{Name: "name", Items: items})
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req = req.WithContext(context.WithValue(req.Context(), settingsKey{}, &settings{bufferPool: pooling}))
+	w := &discardResponseWriter{header: http.Header{}}
+	prepareResponse(w, json.TypeCharset)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = handleSuccess(w, req, rsp, json.Encode)
+	}
+}
+
+func BenchmarkHandleSuccess(b *testing.B) {
+	benchmarkHandleSuccess(b, false)
+}
+
+func BenchmarkHandleSuccess_ResponseBufferPool(b *testing.B) {
+	benchmarkHandleSuccess(b, true)
+}
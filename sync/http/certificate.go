@@ -0,0 +1,67 @@
+package http
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync/atomic"
+	"syscall"
+
+	"github.com/beatlabs/patron/log"
+)
+
+// certReloader holds the TLS certificate of the server, which is reloaded from disk on SIGHUP,
+// allowing certificates to be rotated without a restart.
+type certReloader struct {
+	certFile string
+	keyFile  string
+	cert     atomic.Value
+}
+
+func newCertReloader(certFile, keyFile string) (*certReloader, error) {
+	cr := &certReloader{certFile: certFile, keyFile: keyFile}
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load certificate: %w", err)
+	}
+	cr.cert.Store(&cert)
+	return cr, nil
+}
+
+// reload loads the certificate from disk. On failure the previous certificate is kept.
+func (cr *certReloader) reload() error {
+	cert, err := tls.LoadX509KeyPair(cr.certFile, cr.keyFile)
+	if err != nil {
+		return fmt.Errorf("failed to reload certificate: %w", err)
+	}
+	cr.cert.Store(&cert)
+	return nil
+}
+
+func (cr *certReloader) getCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	return cr.cert.Load().(*tls.Certificate), nil
+}
+
+// watch reloads the certificate every time the process receives a SIGHUP, until the context is done.
+func (cr *certReloader) watch(ctx context.Context) {
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, syscall.SIGHUP)
+	go func() {
+		defer signal.Stop(ch)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ch:
+				err := cr.reload()
+				if err != nil {
+					log.Errorf("keeping the current certificate: %v", err)
+					continue
+				}
+				log.Infof("reloaded certificate from %s", cr.certFile)
+			}
+		}
+	}()
+}
@@ -0,0 +1,121 @@
+package http
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewCertReloader(t *testing.T) {
+	cr, err := newCertReloader("testdata/server.pem", "testdata/server.key")
+	assert.NoError(t, err)
+	assert.NotNil(t, cr)
+
+	cr, err = newCertReloader("testdata/server.pem", "testdata/server.pem")
+	assert.Error(t, err)
+	assert.Nil(t, cr)
+}
+
+func TestCertReloader_Reload(t *testing.T) {
+	dir, err := ioutil.TempDir("", "certs")
+	require.NoError(t, err)
+	defer func() { _ = os.RemoveAll(dir) }()
+	certFile := filepath.Join(dir, "server.pem")
+	keyFile := filepath.Join(dir, "server.key")
+	writeCert(t, certFile, keyFile, "first")
+
+	cr, err := newCertReloader(certFile, keyFile)
+	require.NoError(t, err)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	srv := &http.Server{TLSConfig: &tls.Config{GetCertificate: cr.getCertificate}}
+	go func() { _ = srv.ServeTLS(ln, "", "") }()
+	defer func() { _ = srv.Close() }()
+	addr := ln.Addr().String()
+
+	assert.Equal(t, "first", peerCommonName(t, addr))
+
+	writeCert(t, certFile, keyFile, "second")
+	assert.NoError(t, cr.reload())
+	assert.Equal(t, "second", peerCommonName(t, addr))
+
+	// a bad reload keeps serving the current certificate
+	require.NoError(t, ioutil.WriteFile(keyFile, []byte("invalid"), 0600))
+	assert.Error(t, cr.reload())
+	assert.Equal(t, "second", peerCommonName(t, addr))
+}
+
+func TestCertReloader_Watch(t *testing.T) {
+	dir, err := ioutil.TempDir("", "certs")
+	require.NoError(t, err)
+	defer func() { _ = os.RemoveAll(dir) }()
+	certFile := filepath.Join(dir, "server.pem")
+	keyFile := filepath.Join(dir, "server.key")
+	writeCert(t, certFile, keyFile, "first")
+
+	cr, err := newCertReloader(certFile, keyFile)
+	require.NoError(t, err)
+	ctx, cnl := context.WithCancel(context.Background())
+	defer cnl()
+	cr.watch(ctx)
+
+	writeCert(t, certFile, keyFile, "second")
+	require.NoError(t, syscall.Kill(os.Getpid(), syscall.SIGHUP))
+	for i := 0; i < 100; i++ {
+		cert, err := cr.getCertificate(nil)
+		require.NoError(t, err)
+		if commonName(t, cert) == "second" {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("certificate not reloaded on SIGHUP")
+}
+
+func peerCommonName(t *testing.T, addr string) string {
+	conn, err := tls.Dial("tcp", addr, &tls.Config{InsecureSkipVerify: true}) // nolint:gosec
+	require.NoError(t, err)
+	defer func() { _ = conn.Close() }()
+	return conn.ConnectionState().PeerCertificates[0].Subject.CommonName
+}
+
+func commonName(t *testing.T, cert *tls.Certificate) string {
+	c, err := x509.ParseCertificate(cert.Certificate[0])
+	require.NoError(t, err)
+	return c.Subject.CommonName
+}
+
+func writeCert(t *testing.T, certFile, keyFile, cn string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+	require.NoError(t, ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600))
+	require.NoError(t, ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600))
+}
@@ -0,0 +1,83 @@
+package http
+
+import (
+	"net"
+	"net/http"
+	"strings"
+)
+
+const (
+	// HeaderForwardedFor is the de-facto standard header for identifying the originating IP of a client behind proxies.
+	HeaderForwardedFor = "X-Forwarded-For"
+	// HeaderRealIP is the header set by some proxies with the IP of the originating client.
+	HeaderRealIP = "X-Real-IP"
+)
+
+// NewClientIPMiddleware creates a MiddlewareFunc that determines the real client IP of a request and stores it in the request context.
+// The X-Forwarded-For and X-Real-IP headers are only taken into account when the immediate peer is one of the trusted proxies,
+// otherwise the headers could be spoofed by the client.
+func NewClientIPMiddleware(trustedProxies []net.IPNet) MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ip := clientIP(r, trustedProxies)
+			next.ServeHTTP(w, r.WithContext(SetValue(r.Context(), clientIPKey, ip)))
+		})
+	}
+}
+
+// ClientIP returns the client IP of the request as determined by the client IP middleware.
+// If the middleware has not been applied the IP of the immediate peer is returned.
+func ClientIP(r *http.Request) string {
+	if ip, ok := StringValue(r.Context(), clientIPKey); ok {
+		return ip
+	}
+	return remoteIP(r)
+}
+
+func clientIP(r *http.Request, trustedProxies []net.IPNet) string {
+	peer := remoteIP(r)
+	if !isTrusted(peer, trustedProxies) {
+		return peer
+	}
+
+	if xff := r.Header.Get(HeaderForwardedFor); xff != "" {
+		hops := strings.Split(xff, ",")
+		// Walk the chain from the closest hop backwards, the first untrusted hop is the client.
+		for i := len(hops) - 1; i >= 0; i-- {
+			hop := strings.TrimSpace(hops[i])
+			if net.ParseIP(hop) == nil {
+				return peer
+			}
+			if i == 0 || !isTrusted(hop, trustedProxies) {
+				return hop
+			}
+		}
+	}
+
+	if rip := strings.TrimSpace(r.Header.Get(HeaderRealIP)); net.ParseIP(rip) != nil {
+		return rip
+	}
+
+	return peer
+}
+
+func remoteIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+func isTrusted(ip string, trustedProxies []net.IPNet) bool {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return false
+	}
+	for _, n := range trustedProxies {
+		if n.Contains(parsed) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,61 @@
+package http
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewClientIPMiddleware(t *testing.T) {
+	_, proxies, err := net.ParseCIDR("10.0.0.0/8")
+	assert.NoError(t, err)
+	trusted := []net.IPNet{*proxies}
+
+	tests := map[string]struct {
+		trusted    []net.IPNet
+		remoteAddr string
+		xff        string
+		realIP     string
+		want       string
+	}{
+		"no headers":                           {trusted: trusted, remoteAddr: "1.2.3.4:1000", want: "1.2.3.4"},
+		"spoofed header without trusted proxy": {trusted: nil, remoteAddr: "1.2.3.4:1000", xff: "5.6.7.8", want: "1.2.3.4"},
+		"spoofed header from untrusted peer":   {trusted: trusted, remoteAddr: "1.2.3.4:1000", xff: "5.6.7.8", want: "1.2.3.4"},
+		"forwarded from trusted proxy":         {trusted: trusted, remoteAddr: "10.0.0.1:1000", xff: "5.6.7.8", want: "5.6.7.8"},
+		"forwarded through trusted chain":      {trusted: trusted, remoteAddr: "10.0.0.1:1000", xff: "5.6.7.8, 10.0.0.2", want: "5.6.7.8"},
+		"spoofed hop before client":            {trusted: trusted, remoteAddr: "10.0.0.1:1000", xff: "9.9.9.9, 5.6.7.8, 10.0.0.2", want: "5.6.7.8"},
+		"invalid forwarded hop":                {trusted: trusted, remoteAddr: "10.0.0.1:1000", xff: "garbage", want: "10.0.0.1"},
+		"real ip from trusted proxy":           {trusted: trusted, remoteAddr: "10.0.0.1:1000", realIP: "5.6.7.8", want: "5.6.7.8"},
+		"real ip from untrusted peer":          {trusted: trusted, remoteAddr: "1.2.3.4:1000", realIP: "5.6.7.8", want: "1.2.3.4"},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			var got string
+			h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = ClientIP(r)
+			})
+			req, err := http.NewRequest(http.MethodGet, "/", nil)
+			assert.NoError(t, err)
+			req.RemoteAddr = tt.remoteAddr
+			if tt.xff != "" {
+				req.Header.Set(HeaderForwardedFor, tt.xff)
+			}
+			if tt.realIP != "" {
+				req.Header.Set(HeaderRealIP, tt.realIP)
+			}
+			NewClientIPMiddleware(tt.trusted)(h).ServeHTTP(httptest.NewRecorder(), req)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestClientIP_WithoutMiddleware(t *testing.T) {
+	req, err := http.NewRequest(http.MethodGet, "/", nil)
+	assert.NoError(t, err)
+	req.RemoteAddr = "1.2.3.4:1000"
+	req.Header.Set(HeaderForwardedFor, "5.6.7.8")
+	assert.Equal(t, "1.2.3.4", ClientIP(req))
+}
@@ -2,14 +2,21 @@ package http
 
 import (
 	"context"
+	"crypto/tls"
 	"errors"
 	"fmt"
+	"net"
 	"net/http"
+	"os"
 	"sync"
 	"time"
 
+	"github.com/beatlabs/patron/correlation"
 	patronErrors "github.com/beatlabs/patron/errors"
+	"github.com/beatlabs/patron/info"
 	"github.com/beatlabs/patron/log"
+	"github.com/beatlabs/patron/reliability/budget"
+	"github.com/beatlabs/patron/sync/http/auth"
 	"github.com/julienschmidt/httprouter"
 )
 
@@ -18,6 +25,7 @@ const (
 	httpReadTimeout  = 5 * time.Second
 	httpWriteTimeout = 10 * time.Second
 	httpIdleTimeout  = 120 * time.Second
+	httpDrainTimeout = 10 * time.Second
 )
 
 var (
@@ -25,66 +33,188 @@ var (
 	DefaultAliveCheck = func() AliveStatus { return Alive }
 	// DefaultReadyCheck return always ready.
 	DefaultReadyCheck = func() ReadyStatus { return Ready }
+	// DefaultStartupCheck return always started.
+	DefaultStartupCheck = func() bool { return true }
 )
 
 // Component implementation of HTTP.
 type Component struct {
 	ac               AliveCheckFunc
 	rc               ReadyCheckFunc
+	sc               StartupCheckFunc
 	httpPort         int
 	httpReadTimeout  time.Duration
 	httpWriteTimeout time.Duration
+	drainTimeout     time.Duration
+	degradedStatus   int
 	sync.Mutex
-	routes      []Route
+	routes []Route
+	// userRoutes is the number of the routes provided to the builder, which precede the system routes.
+	userRoutes  int
+	pool        *workerPool
 	middlewares []MiddlewareFunc
 	certFile    string
 	keyFile     string
+	// noMethodHandling disables the 405 Method Not Allowed and the automatic OPTIONS responses.
+	noMethodHandling bool
+	poolSize         int
+	poolQueue        int
+	maintenance      *Maintenance
+	authPolicy       *AuthPolicy
+	dependencies     []Dependency
+	dependencyWait   time.Duration
+	settings         *settings
+	// started is closed once the component listens for the first time.
+	started   chan struct{}
+	startOnce sync.Once
+}
+
+// Started returns a channel which is closed once the component listens, i.e. after its dependencies are reachable,
+// which the service waits for before reporting that it has started.
+func (c *Component) Started() <-chan struct{} {
+	return c.started
+}
+
+// Maintenance returns the switch of the read-only maintenance mode, or nil if the component is built without it.
+func (c *Component) Maintenance() *Maintenance {
+	return c.maintenance
+}
+
+// Info returns information about the component, which is exposed in the /info endpoint.
+func (c *Component) Info() map[string]interface{} {
+	c.Lock()
+	defer c.Unlock()
+	return map[string]interface{}{
+		"port":   c.httpPort,
+		"ssl":    c.certFile != "" && c.keyFile != "",
+		"routes": len(c.routes),
+	}
 }
 
 // Run starts the HTTP server.
 func (c *Component) Run(ctx context.Context) error {
+	if len(c.dependencies) > 0 {
+		log.Infof("waiting up to %v for %d dependencies", c.dependencyWait, len(c.dependencies))
+		err := waitForDependencies(ctx, c.dependencies, c.dependencyWait)
+		if err != nil {
+			if ctx.Err() != nil {
+				log.Info("component stopped while waiting for dependencies")
+				return nil
+			}
+			return err
+		}
+	}
 	c.Lock()
 	log.Debug("applying tracing to routes")
 	chFail := make(chan error)
+	if c.poolSize > 0 {
+		// the goroutine budget of the service is shared with its other components
+		c.pool = newWorkerPool(c.poolSize, c.poolQueue, budget.FromContext(ctx))
+		defer c.pool.stop()
+	}
 	srv := c.createHTTPServer()
+	ct := &connTracker{conns: make(map[net.Conn]struct{})}
+	srv.ConnState = ct.track
+	if c.certFile != "" && c.keyFile != "" {
+		cr, err := newCertReloader(c.certFile, c.keyFile)
+		if err != nil {
+			c.Unlock()
+			return err
+		}
+		cr.watch(ctx)
+		srv.TLSConfig = &tls.Config{GetCertificate: cr.getCertificate}
+	}
 	go c.listenAndServe(srv, chFail)
 	c.Unlock()
 
 	select {
 	case <-ctx.Done():
 		log.Info("shutting down component")
-		return srv.Shutdown(ctx)
+		return c.shutdown(srv, ct)
 	case err := <-chFail:
 		return err
 	}
 }
 
+// shutdown drains the server's connections for the duration of the drain timeout,
+// after which the remaining connections are force-closed.
+func (c *Component) shutdown(srv *http.Server, ct *connTracker) error {
+	ctx, cnl := context.WithTimeout(context.Background(), c.drainTimeout)
+	defer cnl()
+	err := srv.Shutdown(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		return err
+	}
+	log.Warnf("drain timeout of %v expired, force closing %d connections", c.drainTimeout, ct.count())
+	return srv.Close()
+}
+
+// connTracker keeps track of the open connections of a server.
+type connTracker struct {
+	sync.Mutex
+	conns map[net.Conn]struct{}
+}
+
+func (ct *connTracker) track(conn net.Conn, state http.ConnState) {
+	ct.Lock()
+	defer ct.Unlock()
+	switch state {
+	case http.StateClosed, http.StateHijacked:
+		delete(ct.conns, conn)
+	default:
+		ct.conns[conn] = struct{}{}
+	}
+}
+
+func (ct *connTracker) count() int {
+	ct.Lock()
+	defer ct.Unlock()
+	return len(ct.conns)
+}
+
 func (c *Component) listenAndServe(srv *http.Server, ch chan<- error) {
-	if c.certFile != "" && c.keyFile != "" {
+	ln, err := net.Listen("tcp", srv.Addr)
+	if err != nil {
+		ch <- err
+		return
+	}
+	c.startOnce.Do(func() { close(c.started) })
+
+	if srv.TLSConfig != nil {
 		log.Infof("HTTPS component listening on port %d", c.httpPort)
-		ch <- srv.ListenAndServeTLS(c.certFile, c.keyFile)
+		// the certificate is provided by the TLS config, in order to be reloadable
+		ch <- srv.ServeTLS(ln, "", "")
+		return
 	}
 
 	log.Infof("HTTP component listening on port %d", c.httpPort)
-	ch <- srv.ListenAndServe()
+	ch <- srv.Serve(ln)
 }
 
 func (c *Component) createHTTPServer() *http.Server {
 	log.Debugf("adding %d routes", len(c.routes))
 	router := httprouter.New()
-	for _, route := range c.routes {
+	router.HandleMethodNotAllowed = !c.noMethodHandling
+	router.HandleOPTIONS = !c.noMethodHandling
+	for i, route := range c.routes {
+		var h http.Handler = route.Handler
 		if len(route.Middlewares) > 0 {
-			h := MiddlewareChain(route.Handler, route.Middlewares...)
-			router.Handler(route.Method, route.Pattern, h)
-		} else {
-			router.HandlerFunc(route.Method, route.Pattern, route.Handler)
+			h = MiddlewareChain(route.Handler, route.Middlewares...)
+		}
+		// the system routes, e.g. the health checks and the metrics, are not subject to the load of the user routes
+		if c.pool != nil && i < c.userRoutes {
+			h = c.pool.handler(h)
 		}
+		router.Handler(route.Method, route.Pattern, h)
 
 		log.Debugf("added route %s %s", route.Method, route.Pattern)
 	}
 	// Add first the recovery middleware to ensure that no panic occur.
 	routerAfterMiddleware := MiddlewareChain(router, NewRecoveryMiddleware())
 	routerAfterMiddleware = MiddlewareChain(routerAfterMiddleware, c.middlewares...)
+	routerAfterMiddleware = disconnectMiddleware(routerAfterMiddleware)
+	// the settings of the component are set first, in order to apply to all the middlewares
+	routerAfterMiddleware = MiddlewareChain(routerAfterMiddleware, settingsMiddleware(c.settings))
 
 	return &http.Server{
 		Addr:         fmt.Sprintf(":%d", c.httpPort),
@@ -102,13 +232,31 @@ const fieldSetMsg = "Setting property '%v' for '%v'"
 type Builder struct {
 	ac               AliveCheckFunc
 	rc               ReadyCheckFunc
+	sc               StartupCheckFunc
 	httpPort         int
 	httpReadTimeout  time.Duration
 	httpWriteTimeout time.Duration
+	drainTimeout     time.Duration
+	degradedStatus   int
 	routes           []Route
 	middlewares      []MiddlewareFunc
 	certFile         string
 	keyFile          string
+	noMethodHandling bool
+	poolSize         int
+	poolQueue        int
+	runtimeInfo      bool
+	bufferPool       bool
+	logSampleRate    int
+	logSlowThreshold time.Duration
+	maintenance      *Maintenance
+	authPolicy       *AuthPolicy
+	authPolicyAdmin  auth.Authenticator
+	maintenanceAdmin auth.Authenticator
+	dependencies     []Dependency
+	dependencyWait   time.Duration
+	noMetrics        bool
+	idStrategy       correlation.Strategy
 	errors           []error
 }
 
@@ -120,31 +268,48 @@ func NewBuilder() *Builder {
 	return &Builder{
 		ac:               DefaultAliveCheck,
 		rc:               DefaultReadyCheck,
+		sc:               DefaultStartupCheck,
 		httpPort:         httpPort,
 		httpReadTimeout:  httpReadTimeout,
 		httpWriteTimeout: httpWriteTimeout,
+		drainTimeout:     httpDrainTimeout,
+		degradedStatus:   http.StatusOK,
 		errors:           errs,
 	}
 }
 
 // WithSSL sets the filenames for the Certificate and Keyfile, in order to enable SSL.
+// It will append an error to the builder if either of them is missing or does not exist.
 func (cb *Builder) WithSSL(c, k string) *Builder {
 	if c == "" || k == "" {
 		cb.errors = append(cb.errors, errors.New("Invalid cert or key provided"))
-	} else {
-		log.Info(fieldSetMsg, "Cert, Key", c+","+k)
-		cb.certFile = c
-		cb.keyFile = k
+		return cb
 	}
+	for _, f := range []string{c, k} {
+		if _, err := os.Stat(f); err != nil {
+			cb.errors = append(cb.errors, fmt.Errorf("Invalid cert or key file provided: %w", err))
+			return cb
+		}
+	}
+	log.Info(fieldSetMsg, "Cert, Key", c+","+k)
+	cb.certFile = c
+	cb.keyFile = k
 
 	return cb
 }
 
 // WithRoutes adds routes to the HTTP component.
+// It will append an error to the builder for every route with an empty path, an invalid method or a nil handler.
 func (cb *Builder) WithRoutes(rr []Route) *Builder {
 	if len(rr) == 0 {
 		cb.errors = append(cb.errors, errors.New("Empty Routes slice provided"))
 	} else {
+		for _, r := range rr {
+			err := r.validate()
+			if err != nil {
+				cb.errors = append(cb.errors, err)
+			}
+		}
 		log.Info(fieldSetMsg, "Routes", rr)
 		cb.routes = append(cb.routes, rr...)
 	}
@@ -188,6 +353,19 @@ func (cb *Builder) WithWriteTimeout(wt time.Duration) *Builder {
 	return cb
 }
 
+// WithDrainTimeout sets the duration the HTTP component waits for in-flight requests to finish
+// on shutdown, before force-closing the remaining connections.
+func (cb *Builder) WithDrainTimeout(dt time.Duration) *Builder {
+	if dt <= 0*time.Second {
+		cb.errors = append(cb.errors, errors.New("Negative or zero drain timeout provided"))
+	} else {
+		log.Infof(fieldSetMsg, "Drain Timeout", dt)
+		cb.drainTimeout = dt
+	}
+
+	return cb
+}
+
 // WithPort sets the port used by the HTTP component.
 func (cb *Builder) WithPort(p int) *Builder {
 	if p <= 0 || p > 65535 {
@@ -224,8 +402,173 @@ func (cb *Builder) WithReadyCheckFunc(rcf ReadyCheckFunc) *Builder {
 	return cb
 }
 
+// WithStartupCheckFunc sets the StartupCheckFunc used by the HTTP component.
+func (cb *Builder) WithStartupCheckFunc(scf StartupCheckFunc) *Builder {
+	if scf == nil {
+		cb.errors = append(cb.errors, errors.New("Nil StartupCheckFunc provided"))
+	} else {
+		log.Infof(fieldSetMsg, "StartupCheckFunc", scf)
+		cb.sc = scf
+	}
+
+	return cb
+}
+
+// WithUnavailableWhenDegraded sets the readiness route to respond with 503 Service Unavailable,
+// instead of 200 OK, when the ReadyCheckFunc reports a Degraded status.
+func (cb *Builder) WithUnavailableWhenDegraded() *Builder {
+	log.Infof(fieldSetMsg, "Degraded Status", http.StatusServiceUnavailable)
+	cb.degradedStatus = http.StatusServiceUnavailable
+	return cb
+}
+
+// WithoutMethodHandling disables responding with 405 Method Not Allowed and an Allow header to requests of
+// a path served only for other methods, which are then responded with 404 Not Found, as well as the automatic
+// responses to OPTIONS requests listing the allowed methods of a path in the Allow header.
+func (cb *Builder) WithoutMethodHandling() *Builder {
+	log.Infof(fieldSetMsg, "Method Handling", false)
+	cb.noMethodHandling = true
+	return cb
+}
+
+// WithWorkerPool sets the HTTP component to execute requests on a fixed number of workers, instead of
+// the goroutine of each connection. Requests wait in a queue of the provided size for a worker to be available,
+// while requests exceeding the queue are rejected with 503 Service Unavailable.
+func (cb *Builder) WithWorkerPool(size, queue int) *Builder {
+	if size <= 0 {
+		cb.errors = append(cb.errors, errors.New("Negative or zero worker pool size provided"))
+	} else if queue < 0 {
+		cb.errors = append(cb.errors, errors.New("Negative worker pool queue provided"))
+	} else {
+		log.Infof(fieldSetMsg, "Worker Pool", fmt.Sprintf("%d workers, %d queue", size, queue))
+		cb.poolSize = size
+		cb.poolQueue = queue
+	}
+
+	return cb
+}
+
+// WithoutMetrics disables the /metrics route serving the prometheus metrics, e.g. for services which serve the metrics themselves.
+func (cb *Builder) WithoutMetrics() *Builder {
+	log.Infof(fieldSetMsg, "Metrics", false)
+	cb.noMetrics = true
+	return cb
+}
+
+// WithRuntimeInfo sets the /info route to include a snapshot of the runtime statistics, e.g. the number of goroutines
+// and the allocated heap. The snapshot is taken on every request and briefly stops the world.
+func (cb *Builder) WithRuntimeInfo() *Builder {
+	log.Infof(fieldSetMsg, "Runtime Info", true)
+	cb.runtimeInfo = true
+	return cb
+}
+
+// WithResponseBufferPool sets the JSON responses of the processor routes to be encoded into pooled buffers, which are reused
+// across requests in order to reduce the allocations of hot endpoints. Raw routes, e.g. streaming ones, are not affected.
+// The pooling applies to the routes of the component.
+func (cb *Builder) WithResponseBufferPool() *Builder {
+	log.Infof(fieldSetMsg, "Response Buffer Pool", true)
+	cb.bufferPool = true
+	return cb
+}
+
+// WithRequestIDStrategy sets the strategy of the correlation IDs generated for the requests without an X-Correlation-Id header,
+// e.g. correlation.ULID for IDs which sort in the order of the requests. The strategy applies to the requests of the component,
+// and defaults to correlation.UUIDv4.
+func (cb *Builder) WithRequestIDStrategy(s correlation.Strategy) *Builder {
+	if s == nil {
+		cb.errors = append(cb.errors, errors.New("Nil request ID strategy provided"))
+	} else {
+		log.Infof(fieldSetMsg, "Request ID Strategy", true)
+		cb.idStrategy = s
+	}
+
+	return cb
+}
+
+// WithRequestLogSampling sets the logging middleware to log one in n successful requests at info level,
+// while server errors and requests slower than the threshold, unless it is zero, are always logged.
+// The sampling applies to the routes of the component.
+func (cb *Builder) WithRequestLogSampling(n int, slowThreshold time.Duration) *Builder {
+	if n <= 0 {
+		cb.errors = append(cb.errors, errors.New("Negative or zero request log sample rate provided"))
+	} else if slowThreshold < 0 {
+		cb.errors = append(cb.errors, errors.New("Negative slow request threshold provided"))
+	} else {
+		log.Infof(fieldSetMsg, "Request Log Sampling", fmt.Sprintf("1 in %d, slow above %v", n, slowThreshold))
+		cb.logSampleRate = n
+		cb.logSlowThreshold = slowThreshold
+	}
+
+	return cb
+}
+
+// WithMaintenanceToggle adds a runtime switch of the read-only maintenance mode, which is returned by the Maintenance
+// of the component and toggled with a PUT or a DELETE to the /maintenance route, whose requests are authenticated with
+// the admin authenticator. While the maintenance mode is on, the requests with methods which are not safe, e.g. POST,
+// are rejected with 503 Service Unavailable, while the reads, including the health and the metrics routes, are served.
+func (cb *Builder) WithMaintenanceToggle(admin auth.Authenticator) *Builder {
+	if admin == nil {
+		cb.errors = append(cb.errors, errors.New("Nil admin authenticator of the maintenance toggle provided"))
+	} else {
+		log.Infof(fieldSetMsg, "Maintenance Toggle", MaintenancePath)
+		cb.maintenance = &Maintenance{}
+		cb.maintenanceAdmin = admin
+	}
+	return cb
+}
+
+// WithAuthPolicy sets a policy overriding at runtime whether the authentication of the routes with an authenticator is enforced,
+// e.g. from a SIGHUP hook, which is also changed with a PUT or a DELETE to the /auth-policy route.
+// The requests of the /auth-policy route are authenticated with the admin authenticator, which the policy does not override.
+// Routes without an override enforce the authentication they were created with.
+func (cb *Builder) WithAuthPolicy(p *AuthPolicy, admin auth.Authenticator) *Builder {
+	if p == nil {
+		cb.errors = append(cb.errors, errors.New("Nil AuthPolicy provided"))
+	} else if admin == nil {
+		cb.errors = append(cb.errors, errors.New("Nil admin authenticator of the auth policy provided"))
+	} else {
+		log.Infof(fieldSetMsg, "Auth Policy", AuthPolicyPath)
+		cb.authPolicy = p
+		cb.authPolicyAdmin = admin
+	}
+	return cb
+}
+
+// WithDependencyWait sets the downstream dependencies, e.g. a database, which have to be reachable before the HTTP component
+// starts listening. Their checks are polled until all of them pass, or the component fails to start after the timeout.
+func (cb *Builder) WithDependencyWait(timeout time.Duration, dd ...Dependency) *Builder {
+	if timeout <= 0 {
+		cb.errors = append(cb.errors, errors.New("Negative or zero dependency wait timeout provided"))
+		return cb
+	}
+	if len(dd) == 0 {
+		cb.errors = append(cb.errors, errors.New("Empty list of dependencies provided"))
+		return cb
+	}
+	for _, d := range dd {
+		if d.Name == "" || d.Check == nil {
+			cb.errors = append(cb.errors, errors.New("Dependency without a name or a check provided"))
+			return cb
+		}
+	}
+	log.Infof(fieldSetMsg, "Dependency Wait", timeout)
+	cb.dependencies = append(cb.dependencies, dd...)
+	cb.dependencyWait = timeout
+	return cb
+}
+
 // Create constructs the HTTP component by applying the gathered properties.
 func (cb *Builder) Create() (*Component, error) {
+	if !cb.noMetrics {
+		mr := metricRoute()
+		for _, r := range cb.routes {
+			if r.Method == mr.Method && r.Pattern == mr.Pattern {
+				cb.errors = append(cb.errors, fmt.Errorf("Route %s %s collides with the metrics route", r.Method, r.Pattern))
+			}
+		}
+	}
+
 	if len(cb.errors) > 0 {
 		return nil, patronErrors.Aggregate(cb.errors...)
 	}
@@ -233,19 +576,62 @@ func (cb *Builder) Create() (*Component, error) {
 	c := &Component{
 		ac:               cb.ac,
 		rc:               cb.rc,
+		sc:               startedOnce(cb.sc),
 		httpPort:         cb.httpPort,
 		httpReadTimeout:  cb.httpReadTimeout,
 		httpWriteTimeout: cb.httpWriteTimeout,
+		drainTimeout:     cb.drainTimeout,
+		degradedStatus:   cb.degradedStatus,
 		routes:           cb.routes,
 		middlewares:      cb.middlewares,
 		certFile:         cb.certFile,
 		keyFile:          cb.keyFile,
+		noMethodHandling: cb.noMethodHandling,
+		poolSize:         cb.poolSize,
+		poolQueue:        cb.poolQueue,
+		maintenance:      cb.maintenance,
+		authPolicy:       cb.authPolicy,
+		dependencies:     cb.dependencies,
+		dependencyWait:   cb.dependencyWait,
+		settings:         &settings{idStrategy: cb.idStrategy, bufferPool: cb.bufferPool},
+		started:          make(chan struct{}),
+	}
+
+	if cb.logSampleRate > 0 {
+		c.settings.logSampler = &requestLogSampler{n: uint64(cb.logSampleRate), slow: cb.logSlowThreshold}
 	}
 
+	if c.maintenance != nil {
+		// the writes are rejected before reaching the other middlewares
+		c.middlewares = append([]MiddlewareFunc{NewMaintenanceMiddleware(c.maintenance)}, c.middlewares...)
+	}
+
+	for _, r := range c.routes {
+		if r.policyAuth != nil {
+			r.policyAuth.policy = c.authPolicy
+		}
+		if r.Deprecation != nil {
+			info.AddDeprecatedRoute(r.Method, r.Pattern, r.Deprecation.Since, r.Deprecation.Sunset)
+		}
+	}
+
+	c.userRoutes = len(c.routes)
 	c.routes = append(c.routes, aliveCheckRoute(c.ac))
-	c.routes = append(c.routes, readyCheckRoute(c.rc))
+	c.routes = append(c.routes, readyCheckRoute(c.rc, c.sc, c.degradedStatus))
+	c.routes = append(c.routes, startupCheckRoute(c.sc))
 	c.routes = append(c.routes, profilingRoutes()...)
-	c.routes = append(c.routes, metricRoute())
+	c.routes = append(c.routes, consumersDumpRoute())
+	c.routes = append(c.routes, buffersRoute())
+	if !cb.noMetrics {
+		c.routes = append(c.routes, metricRoute())
+	}
+	c.routes = append(c.routes, infoRoute(cb.runtimeInfo))
+	if c.maintenance != nil {
+		c.routes = append(c.routes, maintenanceRoutes(c.maintenance, cb.maintenanceAdmin)...)
+	}
+	if c.authPolicy != nil {
+		c.routes = append(c.routes, authPolicyRoutes(c.authPolicy, cb.authPolicyAdmin)...)
+	}
 
 	return c, nil
 }
@@ -3,9 +3,13 @@ package http
 import (
 	"context"
 	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/beatlabs/patron/correlation"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -15,8 +19,81 @@ func TestBuilderWithoutOptions(t *testing.T) {
 	assert.NoError(t, err)
 }
 
+func TestBuilder_WithResponseBufferPool(t *testing.T) {
+	c, err := NewBuilder().Create()
+	assert.NoError(t, err)
+	assert.False(t, c.settings.bufferPool)
+	c, err = NewBuilder().WithResponseBufferPool().Create()
+	assert.NoError(t, err)
+	assert.True(t, c.settings.bufferPool)
+}
+
+func TestBuilder_WithRequestIDStrategy(t *testing.T) {
+	_, err := NewBuilder().WithRequestIDStrategy(nil).Create()
+	assert.EqualError(t, err, "Nil request ID strategy provided\n")
+
+	var id string
+	routes := []Route{NewRouteRaw("/test", http.MethodGet, func(w http.ResponseWriter, r *http.Request) {
+		id = r.Header.Get(correlation.HeaderID)
+	}, true)}
+	c, err := NewBuilder().WithRoutes(routes).WithRequestIDStrategy(func() string { return "id" }).Create()
+	assert.NoError(t, err)
+	c.createHTTPServer().Handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/test", nil))
+	assert.Equal(t, "id", id)
+
+	// the strategy applies only to the component it is set on
+	c, err = NewBuilder().WithRoutes(routes).Create()
+	assert.NoError(t, err)
+	c.createHTTPServer().Handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/test", nil))
+	assert.Regexp(t, `^[0-9a-f-]{36}$`, id)
+}
+
+func TestBuilder_WithRoutes_Validation(t *testing.T) {
+	h := func(w http.ResponseWriter, r *http.Request) {}
+	tests := map[string]struct {
+		route   Route
+		wantErr string
+	}{
+		"valid":                  {route: NewRouteRaw("/test", http.MethodGet, h, false)},
+		"valid custom processor": {route: NewGetRoute("/test", testHandler{}.Process, false)},
+		"empty path":             {route: NewRouteRaw("", http.MethodGet, h, false), wantErr: "route GET has an empty path\n"},
+		"relative path":          {route: NewRouteRaw("test", http.MethodGet, h, false), wantErr: "route GET test has a path not starting with '/'\n"},
+		"missing method":         {route: NewRouteRaw("/test", "", h, false), wantErr: "route /test has an invalid method ''\n"},
+		"invalid method":         {route: NewRouteRaw("/test", "GETS", h, false), wantErr: "route /test has an invalid method 'GETS'\n"},
+		"nil handler":            {route: NewRouteRaw("/test", http.MethodGet, nil, false), wantErr: "route GET /test has a nil handler\n"},
+		"nil processor":          {route: NewGetRoute("/test", nil, false), wantErr: "route GET /test has a nil handler\n"},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := NewBuilder().WithRoutes([]Route{tt.route}).Create()
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestBuilder_Metrics(t *testing.T) {
+	h := func(w http.ResponseWriter, r *http.Request) {}
+	rr := []Route{NewRouteRaw("/metrics", http.MethodGet, h, false)}
+	_, err := NewBuilder().WithRoutes(rr).Create()
+	assert.EqualError(t, err, "Route GET /metrics collides with the metrics route\n")
+
+	c, err := NewBuilder().WithRoutes(rr).WithoutMetrics().Create()
+	assert.NoError(t, err)
+	n := 0
+	for _, r := range c.routes {
+		if r.Pattern == "/metrics" {
+			n++
+		}
+	}
+	assert.Equal(t, 1, n)
+}
+
 func TestComponent_ListenAndServe_DefaultRoutes_Shutdown(t *testing.T) {
-	rr := []Route{NewRoute("/", "GET", nil, true, nil)}
+	rr := []Route{NewRoute("/", "GET", testHandler{}.Process, true, nil)}
 	s, err := NewBuilder().WithRoutes(rr).WithPort(50003).Create()
 	assert.NoError(t, err)
 	done := make(chan bool)
@@ -26,13 +103,13 @@ func TestComponent_ListenAndServe_DefaultRoutes_Shutdown(t *testing.T) {
 		done <- true
 	}()
 	time.Sleep(100 * time.Millisecond)
-	assert.Len(t, s.routes, 15)
+	assert.Len(t, s.routes, 19)
 	cnl()
 	assert.True(t, <-done)
 }
 
 func TestComponent_ListenAndServeTLS_DefaultRoutes_Shutdown(t *testing.T) {
-	rr := []Route{NewRoute("/", "GET", nil, true, nil)}
+	rr := []Route{NewRoute("/", "GET", testHandler{}.Process, true, nil)}
 	s, err := NewBuilder().WithRoutes(rr).WithSSL("testdata/server.pem", "testdata/server.key").WithPort(50003).Create()
 	assert.NoError(t, err)
 	done := make(chan bool)
@@ -42,13 +119,97 @@ func TestComponent_ListenAndServeTLS_DefaultRoutes_Shutdown(t *testing.T) {
 		done <- true
 	}()
 	time.Sleep(100 * time.Millisecond)
-	assert.Len(t, s.routes, 15)
+	assert.Len(t, s.routes, 19)
 	cnl()
 	assert.True(t, <-done)
 }
 
+func TestComponent_DrainTimeout_HungRequest(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	defer close(release)
+	h := func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+	}
+	rr := []Route{NewRouteRaw("/hung", http.MethodGet, h, false)}
+	s, err := NewBuilder().WithRoutes(rr).WithPort(50004).WithDrainTimeout(100 * time.Millisecond).Create()
+	assert.NoError(t, err)
+	done := make(chan error)
+	ctx, cnl := context.WithCancel(context.Background())
+	go func() {
+		done <- s.Run(ctx)
+	}()
+	time.Sleep(100 * time.Millisecond)
+
+	chRsp := make(chan error)
+	go func() {
+		rsp, err := http.Get("http://localhost:50004/hung")
+		if err == nil {
+			_ = rsp.Body.Close()
+		}
+		chRsp <- err
+	}()
+	<-started
+	cnl()
+
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(2 * time.Second):
+		t.Fatal("shutdown blocked by hung request")
+	}
+	assert.Error(t, <-chRsp)
+}
+
+func TestComponent_WorkerPool(t *testing.T) {
+	h := func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+	}
+	release := make(chan struct{})
+	blocking := func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}
+	rr := []Route{NewRouteRaw("/test", http.MethodGet, h, false), NewRouteRaw("/blocking", http.MethodGet, blocking, false)}
+	s, err := NewBuilder().WithRoutes(rr).WithPort(50005).WithWorkerPool(1, 0).Create()
+	assert.NoError(t, err)
+	done := make(chan error)
+	ctx, cnl := context.WithCancel(context.Background())
+	go func() {
+		done <- s.Run(ctx)
+	}()
+	time.Sleep(100 * time.Millisecond)
+
+	rsp, err := http.Get("http://localhost:50005/test")
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusAccepted, rsp.StatusCode)
+	assert.NoError(t, rsp.Body.Close())
+
+	// while the worker is busy the user routes are shed, but not the system routes
+	go func() {
+		rsp, err := http.Get("http://localhost:50005/blocking")
+		if assert.NoError(t, err) {
+			assert.NoError(t, rsp.Body.Close())
+		}
+	}()
+	time.Sleep(100 * time.Millisecond)
+	rsp, err = http.Get("http://localhost:50005/test")
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusServiceUnavailable, rsp.StatusCode)
+	assert.NoError(t, rsp.Body.Close())
+	for _, path := range []string{"/alive", "/ready", "/metrics"} {
+		rsp, err = http.Get("http://localhost:50005" + path)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, rsp.StatusCode, path)
+		assert.NoError(t, rsp.Body.Close())
+	}
+	close(release)
+	cnl()
+	assert.NoError(t, <-done)
+}
+
 func TestComponent_ListenAndServeTLS_FailsInvalidCerts(t *testing.T) {
-	rr := []Route{NewRoute("/", "GET", nil, true, nil)}
+	rr := []Route{NewRoute("/", "GET", testHandler{}.Process, true, nil)}
 	s, err := NewBuilder().WithRoutes(rr).WithSSL("testdata/server.pem", "testdata/server.pem").Create()
 	assert.NoError(t, err)
 	assert.Error(t, s.Run(context.Background()))
@@ -67,6 +228,78 @@ func Test_createHTTPServer(t *testing.T) {
 	assert.Equal(t, 10*time.Second, s.WriteTimeout)
 }
 
+func TestComponent_AliveAndReadyChecks(t *testing.T) {
+	tests := map[string]struct {
+		acf       AliveCheckFunc
+		rcf       ReadyCheckFunc
+		wantAlive int
+		wantReady int
+	}{
+		"default checks":      {wantAlive: http.StatusOK, wantReady: http.StatusOK},
+		"alive and ready":     {acf: func() AliveStatus { return Alive }, rcf: func() ReadyStatus { return Ready }, wantAlive: http.StatusOK, wantReady: http.StatusOK},
+		"alive and not ready": {acf: func() AliveStatus { return Alive }, rcf: func() ReadyStatus { return NotReady }, wantAlive: http.StatusOK, wantReady: http.StatusServiceUnavailable},
+		"unresponsive":        {acf: func() AliveStatus { return Unresponsive }, wantAlive: http.StatusServiceUnavailable, wantReady: http.StatusOK},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			b := NewBuilder()
+			if tt.acf != nil {
+				b.WithAliveCheckFunc(tt.acf)
+			}
+			if tt.rcf != nil {
+				b.WithReadyCheckFunc(tt.rcf)
+			}
+			cmp, err := b.Create()
+			assert.NoError(t, err)
+			s := cmp.createHTTPServer()
+			for path, want := range map[string]int{"/alive": tt.wantAlive, "/ready": tt.wantReady} {
+				req, err := http.NewRequest(http.MethodGet, path, nil)
+				assert.NoError(t, err)
+				rc := httptest.NewRecorder()
+				s.Handler.ServeHTTP(rc, req)
+				assert.Equal(t, want, rc.Code, path)
+			}
+		})
+	}
+}
+
+func Test_createHTTPServer_MethodHandling(t *testing.T) {
+	h := func(w http.ResponseWriter, r *http.Request) {}
+	rr := []Route{NewRouteRaw("/test", http.MethodGet, h, false), NewRouteRaw("/test", http.MethodPost, h, false)}
+	tests := map[string]struct {
+		noMethodHandling bool
+		method           string
+		wantStatus       int
+		wantAllow        []string
+	}{
+		"method not allowed": {method: http.MethodPut, wantStatus: http.StatusMethodNotAllowed,
+			wantAllow: []string{http.MethodGet, http.MethodPost, http.MethodOptions}},
+		"options": {method: http.MethodOptions, wantStatus: http.StatusOK,
+			wantAllow: []string{http.MethodGet, http.MethodPost, http.MethodOptions}},
+		"allowed method":                     {method: http.MethodGet, wantStatus: http.StatusOK},
+		"method not allowed opted out":       {noMethodHandling: true, method: http.MethodPut, wantStatus: http.StatusNotFound},
+		"options opted out":                  {noMethodHandling: true, method: http.MethodOptions, wantStatus: http.StatusNotFound},
+		"allowed method with method opt out": {noMethodHandling: true, method: http.MethodGet, wantStatus: http.StatusOK},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			cmp := Component{routes: rr, noMethodHandling: tt.noMethodHandling}
+			s := cmp.createHTTPServer()
+			req, err := http.NewRequest(tt.method, "/test", nil)
+			assert.NoError(t, err)
+			rc := httptest.NewRecorder()
+			s.Handler.ServeHTTP(rc, req)
+			assert.Equal(t, tt.wantStatus, rc.Code)
+			allow := rc.Header().Get("Allow")
+			if len(tt.wantAllow) == 0 {
+				assert.Empty(t, allow)
+				return
+			}
+			assert.ElementsMatch(t, tt.wantAllow, strings.Split(allow, ", "))
+		})
+	}
+}
+
 func Test_createHTTPServerUsingBuilder(t *testing.T) {
 
 	var httpBuilderNoErrors = []error{}
@@ -79,6 +312,8 @@ func Test_createHTTPServerUsingBuilder(t *testing.T) {
 		errors.New("Empty Routes slice provided"),
 		errors.New("Empty list of middlewares provided"),
 		errors.New("Invalid cert or key provided"),
+		errors.New("Negative or zero drain timeout provided"),
+		errors.New("Negative or zero worker pool size provided"),
 	}
 
 	tests := map[string]struct {
@@ -87,6 +322,8 @@ func Test_createHTTPServerUsingBuilder(t *testing.T) {
 		p        int
 		rt       time.Duration
 		wt       time.Duration
+		dt       time.Duration
+		ps       int
 		rr       []Route
 		mm       []MiddlewareFunc
 		c        string
@@ -99,17 +336,18 @@ func Test_createHTTPServerUsingBuilder(t *testing.T) {
 			p:   httpPort,
 			rt:  httpReadTimeout,
 			wt:  httpIdleTimeout,
+			dt:  httpDrainTimeout,
+			ps:  10,
 			rr: []Route{
 				aliveCheckRoute(DefaultAliveCheck),
-				readyCheckRoute(DefaultReadyCheck),
-				metricRoute(),
+				readyCheckRoute(DefaultReadyCheck, DefaultStartupCheck, http.StatusOK),
 			},
 			mm: []MiddlewareFunc{
 				NewRecoveryMiddleware(),
 				panicMiddleware("error"),
 			},
-			c:        "cert.file",
-			k:        "key.file",
+			c:        "testdata/server.pem",
+			k:        "testdata/server.key",
 			wantErrs: httpBuilderNoErrors,
 		},
 		"error in all builder steps": {
@@ -118,6 +356,8 @@ func Test_createHTTPServerUsingBuilder(t *testing.T) {
 			p:        -1,
 			rt:       -10 * time.Second,
 			wt:       -20 * time.Second,
+			dt:       -30 * time.Second,
+			ps:       0,
 			rr:       []Route{},
 			mm:       []MiddlewareFunc{},
 			c:        "",
@@ -134,6 +374,8 @@ func Test_createHTTPServerUsingBuilder(t *testing.T) {
 				WithPort(tc.p).
 				WithReadTimeout(tc.rt).
 				WithWriteTimeout(tc.wt).
+				WithDrainTimeout(tc.dt).
+				WithWorkerPool(tc.ps, 10).
 				WithRoutes(tc.rr).
 				WithMiddlewares(tc.mm...).
 				WithSSL(tc.c, tc.k).
@@ -0,0 +1,145 @@
+package http
+
+import (
+	"compress/gzip"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/beatlabs/patron/encoding"
+)
+
+const (
+	headerAcceptEncoding  = "Accept-Encoding"
+	headerContentEncoding = "Content-Encoding"
+	headerContentLength   = "Content-Length"
+	headerVary            = "Vary"
+	gzipEncoding          = "gzip"
+)
+
+// NewCompressionMiddleware creates a MiddlewareFunc which compresses the response bodies with gzip at the given level,
+// from gzip.HuffmanOnly to gzip.BestCompression, for the requests accepting the gzip encoding.
+// Empty bodies, e.g. of 204 No Content, and responses which set their own Content-Encoding, e.g. already compressed ones,
+// are not compressed.
+func NewCompressionMiddleware(level int) (MiddlewareFunc, error) {
+	if level < gzip.HuffmanOnly || level > gzip.BestCompression {
+		return nil, fmt.Errorf("invalid gzip compression level %d provided", level)
+	}
+	pool := sync.Pool{New: func() interface{} {
+		// the level is validated above
+		gz, _ := gzip.NewWriterLevel(nil, level)
+		return gz
+	}}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add(headerVary, headerAcceptEncoding)
+			if !acceptsGzip(r.Header.Get(headerAcceptEncoding)) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			cw := &compressWriter{ResponseWriter: w, pool: &pool}
+			defer cw.close()
+			next.ServeHTTP(cw, r)
+		})
+	}, nil
+}
+
+// acceptsGzip returns true if the Accept-Encoding header contains gzip, without a zero quality.
+func acceptsGzip(accept string) bool {
+	for _, enc := range strings.Split(accept, ",") {
+		parts := strings.Split(enc, ";")
+		if !strings.EqualFold(strings.TrimSpace(parts[0]), gzipEncoding) {
+			continue
+		}
+		for _, p := range parts[1:] {
+			kv := strings.SplitN(strings.TrimSpace(p), "=", 2)
+			if len(kv) != 2 || kv[0] != "q" {
+				continue
+			}
+			if q, err := strconv.ParseFloat(kv[1], 64); err == nil && q == 0 {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// compressWriter defers the writing of the header until the first byte of the body, in order to decide
+// whether to compress the response, since empty bodies are not compressed.
+type compressWriter struct {
+	http.ResponseWriter
+	pool        *sync.Pool
+	gz          *gzip.Writer
+	status      int
+	wroteHeader bool
+}
+
+// WriteHeader records the status, which is written along with the first byte of the body.
+func (w *compressWriter) WriteHeader(code int) {
+	if w.wroteHeader || w.status != 0 {
+		return
+	}
+	w.status = code
+}
+
+// Write compresses the body, unless the response sets its own Content-Encoding.
+func (w *compressWriter) Write(b []byte) (int, error) {
+	if len(b) == 0 {
+		return 0, nil
+	}
+	if !w.wroteHeader {
+		w.writeHeader(b)
+	}
+	if w.gz == nil {
+		return w.ResponseWriter.Write(b)
+	}
+	return w.gz.Write(b)
+}
+
+// Flush flushes the compressed data written so far, e.g. for streaming responses.
+func (w *compressWriter) Flush() {
+	if w.gz != nil {
+		_ = w.gz.Flush()
+	}
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// writeHeader writes the header, compressing the response if it has a body, whose first bytes are provided.
+func (w *compressWriter) writeHeader(b []byte) {
+	w.wroteHeader = true
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	h := w.Header()
+	if len(b) > 0 && h.Get(headerContentEncoding) == "" {
+		// the content type would otherwise be detected from the compressed body
+		if h.Get(encoding.ContentTypeHeader) == "" {
+			h.Set(encoding.ContentTypeHeader, http.DetectContentType(b))
+		}
+		h.Set(headerContentEncoding, gzipEncoding)
+		h.Del(headerContentLength)
+		w.gz = w.pool.Get().(*gzip.Writer)
+		w.gz.Reset(w.ResponseWriter)
+	}
+	w.ResponseWriter.WriteHeader(w.status)
+}
+
+// close writes the header of a response without a body and completes the compressed body.
+func (w *compressWriter) close() {
+	if !w.wroteHeader {
+		if w.status == 0 {
+			return
+		}
+		w.writeHeader(nil)
+	}
+	if w.gz == nil {
+		return
+	}
+	_ = w.gz.Close()
+	w.pool.Put(w.gz)
+}
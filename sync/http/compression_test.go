@@ -0,0 +1,79 @@
+package http
+
+import (
+	"compress/gzip"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewCompressionMiddleware_InvalidLevel(t *testing.T) {
+	_, err := NewCompressionMiddleware(gzip.BestCompression + 1)
+	assert.EqualError(t, err, "invalid gzip compression level 10 provided")
+	_, err = NewCompressionMiddleware(gzip.HuffmanOnly - 1)
+	assert.Error(t, err)
+}
+
+func TestNewCompressionMiddleware(t *testing.T) {
+	body := strings.Repeat(`{"key":"value"}`, 100)
+	tests := map[string]struct {
+		acceptEncoding  string
+		contentEncoding string
+		status          int
+		body            string
+		compressed      bool
+	}{
+		"gzip accepted":     {acceptEncoding: "gzip, deflate", status: http.StatusOK, body: body, compressed: true},
+		"gzip not accepted": {acceptEncoding: "", status: http.StatusOK, body: body},
+		"gzip refused":      {acceptEncoding: "deflate, gzip;q=0", status: http.StatusOK, body: body},
+		"already encoded":   {acceptEncoding: "gzip", contentEncoding: "br", status: http.StatusOK, body: body},
+		"empty body":        {acceptEncoding: "gzip", status: http.StatusNoContent},
+		"status with body":  {acceptEncoding: "gzip", status: http.StatusCreated, body: body, compressed: true},
+		"gzip with quality": {acceptEncoding: "gzip;q=0.8", status: http.StatusOK, body: body, compressed: true},
+		"implicit status":   {acceptEncoding: "GZIP", status: 0, body: body, compressed: true},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			mw, err := NewCompressionMiddleware(gzip.BestSpeed)
+			require.NoError(t, err)
+			h := mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if tt.contentEncoding != "" {
+					w.Header().Set(headerContentEncoding, tt.contentEncoding)
+				}
+				if tt.status != 0 {
+					w.WriteHeader(tt.status)
+				}
+				_, _ = w.Write([]byte(tt.body))
+			}))
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.acceptEncoding != "" {
+				req.Header.Set(headerAcceptEncoding, tt.acceptEncoding)
+			}
+			rc := httptest.NewRecorder()
+			h.ServeHTTP(rc, req)
+
+			if tt.status != 0 {
+				assert.Equal(t, tt.status, rc.Code)
+			}
+			assert.Equal(t, headerAcceptEncoding, rc.Header().Get(headerVary))
+			if !tt.compressed {
+				assert.Equal(t, tt.contentEncoding, rc.Header().Get(headerContentEncoding))
+				assert.Equal(t, tt.body, rc.Body.String())
+				return
+			}
+			assert.Equal(t, gzipEncoding, rc.Header().Get(headerContentEncoding))
+			assert.Equal(t, "text/plain; charset=utf-8", rc.Header().Get("Content-Type"))
+			assert.True(t, rc.Body.Len() < len(tt.body))
+			gz, err := gzip.NewReader(rc.Body)
+			require.NoError(t, err)
+			got, err := ioutil.ReadAll(gz)
+			require.NoError(t, err)
+			assert.Equal(t, tt.body, string(got))
+		})
+	}
+}
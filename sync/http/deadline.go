@@ -0,0 +1,90 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/beatlabs/patron/log"
+)
+
+// DeadlineHeader is the default header of the request deadline.
+const DeadlineHeader = "X-Request-Deadline"
+
+// maxDeadline caps the deadline of a request relative to its arrival, which also prevents the timeouts
+// of the largest values and units, e.g. 99999999H, from overflowing a time.Duration.
+const maxDeadline = 24 * time.Hour
+
+var timeoutUnits = map[byte]time.Duration{
+	'H': time.Hour,
+	'M': time.Minute,
+	'S': time.Second,
+	'm': time.Millisecond,
+	'u': time.Microsecond,
+	'n': time.Nanosecond,
+}
+
+// NewDeadlinePropagationMiddleware creates a MiddlewareFunc which sets the deadline of the request context
+// according to the deadline sent by the client in the header, in order for the downstream calls of the handler,
+// e.g. via the traced clients, to inherit it. The header holds either an absolute deadline in RFC3339 format
+// or a timeout in the grpc-timeout format, i.e. a positive integer followed by a unit (H, M, S, m, u or n), e.g. 250m.
+// Deadlines later than a day from the arrival of the request are capped to a day.
+// Requests whose deadline has already passed are rejected with 504 Gateway Timeout, since the client is no longer waiting,
+// while requests with an invalid header are served without a deadline.
+func NewDeadlinePropagationMiddleware(headerName string) MiddlewareFunc {
+	if headerName == "" {
+		headerName = DeadlineHeader
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			v := r.Header.Get(headerName)
+			if v == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			deadline, err := parseDeadline(v, time.Now())
+			if err != nil {
+				log.FromContext(r.Context()).Debugf("ignoring request deadline header %s: %v", headerName, err)
+				next.ServeHTTP(w, r)
+				return
+			}
+			if !deadline.After(time.Now()) {
+				http.Error(w, http.StatusText(http.StatusGatewayTimeout), http.StatusGatewayTimeout)
+				return
+			}
+			ctx, cnl := context.WithDeadline(r.Context(), deadline)
+			defer cnl()
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// parseDeadline parses an RFC3339 deadline or a grpc-timeout relative to now, capped to the max deadline.
+func parseDeadline(v string, now time.Time) (time.Time, error) {
+	deadline, err := time.Parse(time.RFC3339Nano, v)
+	if err == nil {
+		if deadline.Sub(now) > maxDeadline {
+			return now.Add(maxDeadline), nil
+		}
+		return deadline, nil
+	}
+	if len(v) < 2 {
+		return time.Time{}, errors.New("invalid deadline")
+	}
+	unit, ok := timeoutUnits[v[len(v)-1]]
+	if !ok {
+		return time.Time{}, errors.New("invalid deadline")
+	}
+	// grpc-timeout values have at most 8 digits
+	n, err := strconv.ParseUint(v[:len(v)-1], 10, 32)
+	if err != nil || len(v) > 9 {
+		return time.Time{}, errors.New("invalid timeout")
+	}
+	// the timeout is compared in units, since multiplying it could overflow
+	if n > uint64(maxDeadline/unit) {
+		return now.Add(maxDeadline), nil
+	}
+	return now.Add(time.Duration(n) * unit), nil
+}
@@ -0,0 +1,85 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewDeadlinePropagationMiddleware(t *testing.T) {
+	future := time.Now().Add(time.Minute).UTC().Truncate(time.Second)
+	tests := map[string]struct {
+		headerName  string
+		header      string
+		value       string
+		status      int
+		hasDeadline bool
+		deadline    time.Time
+	}{
+		"no header":            {header: DeadlineHeader, status: http.StatusOK},
+		"future deadline":      {header: DeadlineHeader, value: future.Format(time.RFC3339), status: http.StatusOK, hasDeadline: true, deadline: future},
+		"past deadline":        {header: DeadlineHeader, value: time.Now().Add(-time.Second).Format(time.RFC3339), status: http.StatusGatewayTimeout},
+		"timeout":              {headerName: "grpc-timeout", header: "grpc-timeout", value: "1M", status: http.StatusOK, hasDeadline: true, deadline: time.Now().Add(time.Minute)},
+		"invalid header":       {header: DeadlineHeader, value: "tomorrow", status: http.StatusOK},
+		"custom header absent": {headerName: "grpc-timeout", header: DeadlineHeader, value: "1M", status: http.StatusOK},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			var called, hasDeadline bool
+			var deadline time.Time
+			h := NewDeadlinePropagationMiddleware(tt.headerName)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				called = true
+				deadline, hasDeadline = r.Context().Deadline()
+			}))
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.value != "" {
+				req.Header.Set(tt.header, tt.value)
+			}
+			rsp := httptest.NewRecorder()
+			h.ServeHTTP(rsp, req)
+			assert.Equal(t, tt.status, rsp.Code)
+			assert.Equal(t, tt.status == http.StatusOK, called)
+			assert.Equal(t, tt.hasDeadline, hasDeadline)
+			if tt.hasDeadline {
+				assert.WithinDuration(t, tt.deadline, deadline, time.Second)
+			}
+		})
+	}
+}
+
+func TestParseDeadline(t *testing.T) {
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := map[string]struct {
+		value    string
+		deadline time.Time
+		wantErr  bool
+	}{
+		"rfc3339":       {value: "2020-01-01T00:00:05Z", deadline: now.Add(5 * time.Second)},
+		"hours":         {value: "2H", deadline: now.Add(2 * time.Hour)},
+		"milliseconds":  {value: "250m", deadline: now.Add(250 * time.Millisecond)},
+		"nanoseconds":   {value: "100n", deadline: now.Add(100)},
+		"missing unit":  {value: "250", wantErr: true},
+		"unknown unit":  {value: "250x", wantErr: true},
+		"missing value": {value: "m", wantErr: true},
+		"negative":      {value: "-1S", wantErr: true},
+		"too long":      {value: "123456789S", wantErr: true},
+		"capped":        {value: "25H", deadline: now.Add(maxDeadline)},
+		"overflow":      {value: "99999999H", deadline: now.Add(maxDeadline)},
+		"max":           {value: "86400000m", deadline: now.Add(maxDeadline)},
+		"capped rfc":    {value: "2030-01-01T00:00:00Z", deadline: now.Add(maxDeadline)},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := parseDeadline(tt.value, now)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.deadline, got)
+		})
+	}
+}
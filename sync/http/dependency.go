@@ -0,0 +1,151 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/beatlabs/patron/log"
+	"github.com/beatlabs/patron/trace"
+	"github.com/opentracing/opentracing-go"
+)
+
+// dependencyPollInterval is the interval between the checks of the dependencies which are not yet reachable.
+var dependencyPollInterval = 500 * time.Millisecond
+
+// HealthCheckFunc definition of a function which checks whether a dependency, e.g. a database, a cache or a broker, is reachable.
+type HealthCheckFunc func(ctx context.Context) error
+
+// Dependency is a downstream dependency of the service, which has to be reachable before the service accepts traffic.
+type Dependency struct {
+	Name  string
+	Check HealthCheckFunc
+}
+
+// DependenciesReadyCheck returns a readiness check which checks the dependencies concurrently and reports NotReady
+// if any of them is not reachable within the limit, which caps the duration of the whole check.
+// Every traceEvery-th execution, starting with the first, is traced with a health-check span, which is the parent of a span per dependency
+// tagged with the name and the status of the dependency, in order to find which dependency slows down the readiness check.
+// Tracing is disabled with a zero traceEvery, which avoids tracing the frequent probes of the orchestrator.
+func DependenciesReadyCheck(limit time.Duration, traceEvery int, dd ...Dependency) ReadyCheckFunc {
+	var executions uint64
+	return func() ReadyStatus {
+		ctx, cnl := context.WithTimeout(context.Background(), limit)
+		defer cnl()
+		traced := traceEvery > 0 && (atomic.AddUint64(&executions, 1)-1)%uint64(traceEvery) == 0
+		var sp opentracing.Span
+		if traced {
+			sp, ctx = trace.ChildSpan(ctx, trace.HealthCheckComponent, trace.HealthCheckComponent)
+		}
+
+		ch := make(chan error, len(dd))
+		for _, d := range dd {
+			go func(d Dependency) {
+				ch <- checkDependency(ctx, d, traced)
+			}(d)
+		}
+		st := Ready
+	wait:
+		for range dd {
+			select {
+			case err := <-ch:
+				if err != nil {
+					st = NotReady
+				}
+			case <-ctx.Done():
+				log.Warnf("dependency checks did not complete within %v", limit)
+				st = NotReady
+				break wait
+			}
+		}
+		if sp != nil {
+			sp.SetTag("status", readyStatusName(st))
+			if st == Ready {
+				trace.SpanSuccess(sp)
+			} else {
+				trace.SpanError(sp)
+			}
+		}
+		return st
+	}
+}
+
+// checkDependency checks the dependency, in a child span of the health-check span when traced.
+func checkDependency(ctx context.Context, d Dependency, traced bool) error {
+	if !traced {
+		return d.Check(ctx)
+	}
+	sp, ctx := trace.ChildSpan(ctx, trace.ComponentOpName(trace.HealthCheckComponent, d.Name), trace.HealthCheckComponent,
+		opentracing.Tag{Key: "dependency", Value: d.Name})
+	err := d.Check(ctx)
+	if err != nil {
+		sp.SetTag("status", "unreachable")
+		sp.LogKV("error", err.Error())
+		trace.SpanError(sp)
+		return err
+	}
+	sp.SetTag("status", "reachable")
+	trace.SpanSuccess(sp)
+	return nil
+}
+
+func readyStatusName(st ReadyStatus) string {
+	if st == Ready {
+		return "ready"
+	}
+	return "not-ready"
+}
+
+// waitForDependencies polls the checks of the dependencies until all of them pass or the timeout elapses,
+// in which case it returns an error with the last error of every dependency which is not reachable.
+func waitForDependencies(ctx context.Context, dd []Dependency, timeout time.Duration) error {
+	start := time.Now()
+	ctx, cnl := context.WithTimeout(ctx, timeout)
+	defer cnl()
+
+	pending := make(map[string]Dependency, len(dd))
+	for _, d := range dd {
+		pending[d.Name] = d
+	}
+	failures := make(map[string]error, len(dd))
+	for {
+		for name, d := range pending {
+			err := d.Check(ctx)
+			if err == nil {
+				log.Infof("dependency %s is reachable after %v", name, time.Since(start))
+				delete(pending, name)
+				delete(failures, name)
+				continue
+			}
+			if _, ok := failures[name]; !ok {
+				log.Warnf("waiting for dependency %s: %v", name, err)
+			}
+			failures[name] = err
+		}
+		if len(pending) == 0 {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return dependenciesError(failures, timeout)
+		case <-time.After(dependencyPollInterval):
+		}
+	}
+}
+
+func dependenciesError(failures map[string]error, timeout time.Duration) error {
+	names := make([]string, 0, len(failures))
+	for name := range failures {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	ee := make([]string, 0, len(names))
+	for _, name := range names {
+		ee = append(ee, fmt.Sprintf("%s: %v", name, failures[name]))
+	}
+	return errors.New("dependencies not reachable after " + timeout.String() + ": " + strings.Join(ee, ", "))
+}
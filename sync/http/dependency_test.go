@@ -0,0 +1,146 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/mocktracer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuilder_WithDependencyWait(t *testing.T) {
+	check := func(context.Context) error { return nil }
+	_, err := NewBuilder().WithDependencyWait(0, Dependency{Name: "db", Check: check}).Create()
+	assert.EqualError(t, err, "Negative or zero dependency wait timeout provided\n")
+	_, err = NewBuilder().WithDependencyWait(time.Second).Create()
+	assert.EqualError(t, err, "Empty list of dependencies provided\n")
+	_, err = NewBuilder().WithDependencyWait(time.Second, Dependency{Name: "db"}).Create()
+	assert.EqualError(t, err, "Dependency without a name or a check provided\n")
+	cmp, err := NewBuilder().WithDependencyWait(time.Second, Dependency{Name: "db", Check: check}).Create()
+	require.NoError(t, err)
+	assert.Len(t, cmp.dependencies, 1)
+	assert.Equal(t, time.Second, cmp.dependencyWait)
+}
+
+// setDependencyPollInterval sets the poll interval, returning a function which restores it.
+func setDependencyPollInterval(interval time.Duration) func() {
+	prev := dependencyPollInterval
+	dependencyPollInterval = interval
+	return func() { dependencyPollInterval = prev }
+}
+
+func TestComponent_DependencyWait(t *testing.T) {
+	defer setDependencyPollInterval(10 * time.Millisecond)()
+	healthyAt := time.Now().Add(100 * time.Millisecond)
+	var checks int32
+	db := Dependency{Name: "db", Check: func(context.Context) error {
+		atomic.AddInt32(&checks, 1)
+		if time.Now().Before(healthyAt) {
+			return errors.New("connection refused")
+		}
+		return nil
+	}}
+	cache := Dependency{Name: "cache", Check: func(context.Context) error { return nil }}
+	cmp, err := NewBuilder().WithPort(50006).WithDependencyWait(5*time.Second, db, cache).Create()
+	require.NoError(t, err)
+
+	ctx, cnl := context.WithCancel(context.Background())
+	chDone := make(chan error)
+	go func() { chDone <- cmp.Run(ctx) }()
+
+	// the listener starts only after the dependency becomes healthy
+	var rsp *http.Response
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(5 * time.Millisecond) {
+		rsp, err = http.Get("http://localhost:50006/alive")
+		if err == nil {
+			break
+		}
+	}
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, rsp.StatusCode)
+	assert.NoError(t, rsp.Body.Close())
+	assert.False(t, time.Now().Before(healthyAt))
+	assert.True(t, atomic.LoadInt32(&checks) > 1)
+
+	cnl()
+	assert.NoError(t, <-chDone)
+}
+
+func TestComponent_DependencyWaitTimeout(t *testing.T) {
+	defer setDependencyPollInterval(10 * time.Millisecond)()
+	db := Dependency{Name: "db", Check: func(context.Context) error { return errors.New("connection refused") }}
+	cache := Dependency{Name: "cache", Check: func(context.Context) error { return nil }}
+	cmp, err := NewBuilder().WithPort(50007).WithDependencyWait(50*time.Millisecond, db, cache).Create()
+	require.NoError(t, err)
+	assert.EqualError(t, cmp.Run(context.Background()), "dependencies not reachable after 50ms: db: connection refused")
+
+	// stopping the component while waiting is not a failure
+	cmp, err = NewBuilder().WithPort(50007).WithDependencyWait(time.Minute, db).Create()
+	require.NoError(t, err)
+	ctx, cnl := context.WithCancel(context.Background())
+	cnl()
+	assert.NoError(t, cmp.Run(ctx))
+}
+
+func TestDependenciesReadyCheck(t *testing.T) {
+	db := Dependency{Name: "db", Check: func(context.Context) error { return nil }}
+	cache := Dependency{Name: "cache", Check: func(context.Context) error { return errors.New("connection refused") }}
+	slow := Dependency{Name: "slow", Check: func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}}
+	assert.Equal(t, Ready, DependenciesReadyCheck(time.Second, 0, db)())
+	assert.Equal(t, NotReady, DependenciesReadyCheck(time.Second, 0, db, cache)())
+	assert.Equal(t, NotReady, DependenciesReadyCheck(10*time.Millisecond, 0, db, slow)())
+}
+
+func TestDependenciesReadyCheck_Tracing(t *testing.T) {
+	mtr := mocktracer.New()
+	opentracing.SetGlobalTracer(mtr)
+	defer opentracing.SetGlobalTracer(opentracing.NoopTracer{})
+
+	db := Dependency{Name: "db", Check: func(context.Context) error { return nil }}
+	cache := Dependency{Name: "cache", Check: func(context.Context) error { return errors.New("connection refused") }}
+	rcf := DependenciesReadyCheck(time.Second, 2, db, cache)
+
+	assert.Equal(t, NotReady, rcf())
+	spans := mtr.FinishedSpans()
+	require.Len(t, spans, 3)
+	var root *mocktracer.MockSpan
+	children := map[string]*mocktracer.MockSpan{}
+	for _, sp := range spans {
+		if sp.OperationName == "health-check" {
+			root = sp
+			continue
+		}
+		children[sp.Tag("dependency").(string)] = sp
+	}
+	require.NotNil(t, root)
+	assert.Equal(t, "not-ready", root.Tag("status"))
+	assert.Equal(t, true, root.Tag("error"))
+	require.Len(t, children, 2)
+	for name, status := range map[string]string{"db": "reachable", "cache": "unreachable"} {
+		sp := children[name]
+		assert.Equal(t, "health-check "+name, sp.OperationName)
+		assert.Equal(t, status, sp.Tag("status"))
+		assert.Equal(t, root.SpanContext.SpanID, sp.ParentID)
+	}
+
+	// the executions are sampled
+	mtr.Reset()
+	assert.Equal(t, NotReady, rcf())
+	assert.Empty(t, mtr.FinishedSpans())
+	assert.Equal(t, NotReady, rcf())
+	assert.Len(t, mtr.FinishedSpans(), 3)
+
+	// tracing is disabled by default
+	mtr.Reset()
+	assert.Equal(t, Ready, DependenciesReadyCheck(time.Second, 0, db)())
+	assert.Empty(t, mtr.FinishedSpans())
+}
@@ -0,0 +1,64 @@
+package http
+
+import (
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/beatlabs/patron/log"
+)
+
+const (
+	// HeaderDeprecation is the header signaling that a route is deprecated, along with the time it was deprecated.
+	HeaderDeprecation = "Deprecation"
+	// HeaderSunset is the header signaling the time a deprecated route will be removed.
+	HeaderSunset = "Sunset"
+	// deprecationLogSample is the number of hits of a deprecated route per logged warning.
+	deprecationLogSample = 100
+)
+
+// Deprecation describes the deprecation of a route.
+type Deprecation struct {
+	// Since is the time the route was deprecated.
+	Since time.Time
+	// Sunset is the optional time the route will be removed.
+	Sunset time.Time
+}
+
+// Deprecate marks the route as deprecated. Responses of the route carry the Deprecation and, if set, Sunset headers,
+// while hits of the route are logged as warnings, sampled, along with the identification of the caller.
+// Deprecated routes are listed in the /info endpoint.
+func (r Route) Deprecate(d Deprecation) Route {
+	r.Deprecation = &d
+	r.Middlewares = append([]MiddlewareFunc{newDeprecationMiddleware(r.Method, r.Pattern, d)}, r.Middlewares...)
+	return r
+}
+
+func newDeprecationMiddleware(method, path string, d Deprecation) MiddlewareFunc {
+	deprecation := fmt.Sprintf("@%d", d.Since.Unix())
+	var sunset string
+	if !d.Sunset.IsZero() {
+		sunset = d.Sunset.UTC().Format(http.TimeFormat)
+	}
+	var hits uint64
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set(HeaderDeprecation, deprecation)
+			if sunset != "" {
+				w.Header().Set(HeaderSunset, sunset)
+			}
+			n := atomic.AddUint64(&hits, 1)
+			if n%deprecationLogSample == 1 {
+				log.FromContext(r.Context()).Sub(map[string]interface{}{
+					"method":    method,
+					"path":      path,
+					"userAgent": r.UserAgent(),
+					"clientIP":  ClientIP(r),
+					"hits":      n,
+				}).Warn("deprecated route called")
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
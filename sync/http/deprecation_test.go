@@ -0,0 +1,63 @@
+package http
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/beatlabs/patron/log"
+	plog "github.com/beatlabs/patron/log/zerolog"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRoute_Deprecate(t *testing.T) {
+	since := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	sunset := time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC)
+	h := func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+	}
+	route := NewRouteRaw("/test", http.MethodGet, h, false).Deprecate(Deprecation{Since: since, Sunset: sunset})
+	assert.Equal(t, &Deprecation{Since: since, Sunset: sunset}, route.Deprecation)
+	assert.Len(t, route.Middlewares, 1)
+
+	var b bytes.Buffer
+	zl := zerolog.New(&b)
+	lg := plog.NewLogger(&zl, log.InfoLevel, nil)
+
+	for i := 0; i < deprecationLogSample+1; i++ {
+		req, err := http.NewRequest(http.MethodGet, "/test", nil)
+		assert.NoError(t, err)
+		req.Header.Set("User-Agent", "client/1.0")
+		req.RemoteAddr = "1.2.3.4:1000"
+		req = req.WithContext(log.WithContext(req.Context(), lg))
+		rc := httptest.NewRecorder()
+		MiddlewareChain(route.Handler, route.Middlewares...).ServeHTTP(rc, req)
+		assert.Equal(t, http.StatusAccepted, rc.Code)
+		assert.Equal(t, "@1577836800", rc.Header().Get(HeaderDeprecation))
+		assert.Equal(t, "Mon, 01 Jun 2020 00:00:00 GMT", rc.Header().Get(HeaderSunset))
+	}
+
+	// the warning is sampled
+	lines := strings.Split(strings.TrimSpace(b.String()), "\n")
+	assert.Len(t, lines, 2)
+	assert.Contains(t, lines[0], "deprecated route called")
+	assert.Contains(t, lines[0], `"path":"/test"`)
+	assert.Contains(t, lines[0], `"userAgent":"client/1.0"`)
+	assert.Contains(t, lines[0], `"clientIP":"1.2.3.4"`)
+	assert.Contains(t, lines[1], `"hits":101`)
+}
+
+func TestRoute_Deprecate_WithoutSunset(t *testing.T) {
+	h := func(w http.ResponseWriter, r *http.Request) {}
+	route := NewRouteRaw("/test", http.MethodGet, h, false).Deprecate(Deprecation{Since: time.Unix(100, 0)})
+	req, err := http.NewRequest(http.MethodGet, "/test", nil)
+	assert.NoError(t, err)
+	rc := httptest.NewRecorder()
+	MiddlewareChain(route.Handler, route.Middlewares...).ServeHTTP(rc, req)
+	assert.Equal(t, "@100", rc.Header().Get(HeaderDeprecation))
+	assert.Empty(t, rc.Header().Get(HeaderSunset))
+}
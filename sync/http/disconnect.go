@@ -0,0 +1,36 @@
+package http
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/beatlabs/patron/log"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var clientDisconnects prometheus.Counter
+
+func init() {
+	clientDisconnects = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: "component",
+			Subsystem: "http",
+			Name:      "client_disconnected_total",
+			Help:      "Requests whose client disconnected before the response was completed",
+		},
+	)
+	prometheus.MustRegister(clientDisconnects)
+}
+
+// disconnectMiddleware counts the requests whose client disconnected while they were handled.
+// The server cancels the context of a request when its client disconnects, so the work of the handler which uses it,
+// e.g. with the traced HTTP client or the Kafka producer, is aborted.
+func disconnectMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r)
+		if r.Context().Err() == context.Canceled {
+			clientDisconnects.Inc()
+			log.FromContext(r.Context()).Debugf("client disconnected during %s %s", r.Method, r.URL.Path)
+		}
+	})
+}
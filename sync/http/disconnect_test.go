@@ -0,0 +1,53 @@
+package http
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func clientDisconnectsCount(t *testing.T) float64 {
+	m := &dto.Metric{}
+	require.NoError(t, clientDisconnects.Write(m))
+	return m.GetCounter().GetValue()
+}
+
+func TestComponent_ClientDisconnect(t *testing.T) {
+	started := make(chan struct{})
+	canceled := make(chan struct{})
+	h := func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		select {
+		case <-r.Context().Done():
+			close(canceled)
+		case <-time.After(5 * time.Second):
+		}
+	}
+	cmp := Component{routes: []Route{NewRouteRaw("/slow", http.MethodGet, h, false)}}
+	srv := httptest.NewServer(cmp.createHTTPServer().Handler)
+	defer srv.Close()
+	before := clientDisconnectsCount(t)
+
+	conn, err := net.Dial("tcp", srv.Listener.Addr().String())
+	require.NoError(t, err)
+	_, err = conn.Write([]byte("GET /slow HTTP/1.1\r\nHost: localhost\r\n\r\n"))
+	require.NoError(t, err)
+	<-started
+	require.NoError(t, conn.Close())
+
+	select {
+	case <-canceled:
+	case <-time.After(time.Second):
+		t.Fatal("context of the handler not canceled")
+	}
+	for i := 0; i < 50 && clientDisconnectsCount(t) == before; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.Equal(t, before+1, clientDisconnectsCount(t))
+}
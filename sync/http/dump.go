@@ -0,0 +1,38 @@
+package http
+
+import (
+	encjson "encoding/json"
+	"net/http"
+
+	"github.com/beatlabs/patron/async"
+	"github.com/beatlabs/patron/encoding/json"
+)
+
+const (
+	// ConsumersDumpPath is the path of the dump of the state of the async consumers, e.g. the positions of their partitions.
+	ConsumersDumpPath = "/debug/consumers"
+	// BuffersPath is the path of the usage of the buffers of the async components built with buffer inspection.
+	BuffersPath = "/debug/buffers"
+)
+
+func consumersDumpRoute() Route {
+	return debugRoute(ConsumersDumpPath, func() interface{} { return async.Dumps() })
+}
+
+func buffersRoute() Route {
+	return debugRoute(BuffersPath, func() interface{} { return async.Buffers() })
+}
+
+// debugRoute returns a route responding with the JSON of the state returned by the function.
+func debugRoute(path string, state func() interface{}) Route {
+	f := func(w http.ResponseWriter, r *http.Request) {
+		body, err := encjson.Marshal(state())
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", json.TypeCharset)
+		_, _ = w.Write(body)
+	}
+	return NewRouteRaw(path, http.MethodGet, f, false)
+}
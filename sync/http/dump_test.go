@@ -0,0 +1,42 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/beatlabs/patron/async"
+	"github.com/beatlabs/patron/encoding/json"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_consumersDumpRoute(t *testing.T) {
+	async.RegisterDump("consumer", func() interface{} {
+		return map[string]interface{}{"partitions": []int{0, 1}}
+	})
+	defer async.UnregisterDump("consumer")
+	route := consumersDumpRoute()
+	assert.Equal(t, http.MethodGet, route.Method)
+	assert.Equal(t, ConsumersDumpPath, route.Pattern)
+
+	req, err := http.NewRequest(http.MethodGet, ConsumersDumpPath, nil)
+	assert.NoError(t, err)
+	rsp := httptest.NewRecorder()
+	route.Handler(rsp, req)
+	assert.Equal(t, http.StatusOK, rsp.Code)
+	assert.Equal(t, json.TypeCharset, rsp.Header().Get("Content-Type"))
+	assert.JSONEq(t, `{"consumer":{"partitions":[0,1]}}`, rsp.Body.String())
+}
+
+func Test_buffersRoute(t *testing.T) {
+	route := buffersRoute()
+	assert.Equal(t, http.MethodGet, route.Method)
+	assert.Equal(t, BuffersPath, route.Pattern)
+
+	req, err := http.NewRequest(http.MethodGet, BuffersPath, nil)
+	assert.NoError(t, err)
+	rsp := httptest.NewRecorder()
+	route.Handler(rsp, req)
+	assert.Equal(t, http.StatusOK, rsp.Code)
+	assert.JSONEq(t, `{}`, rsp.Body.String())
+}
@@ -0,0 +1,32 @@
+package http
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/beatlabs/patron/trace"
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var requestDuration *prometheus.HistogramVec
+
+func init() {
+	requestDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: "component",
+			Subsystem: "http",
+			Name:      "request_duration_seconds",
+			Help:      "Duration of the handled requests, classified by path, method and status code",
+			Buckets:   prometheus.DefBuckets,
+		},
+		[]string{"path", "method", "status_code"},
+	)
+	prometheus.MustRegister(requestDuration)
+}
+
+// observeRequestDuration records the duration of a request of the route path, along with an exemplar holding
+// the trace ID of the span of the request, if it is sampled.
+func observeRequestDuration(path, method string, status int, d time.Duration, sp opentracing.Span) {
+	trace.ObserveWithExemplar(requestDuration.WithLabelValues(path, method, strconv.Itoa(status)), d.Seconds(), sp)
+}
@@ -19,6 +19,16 @@ func (e *Error) Error() string {
 	return fmt.Sprintf("HTTP error with code: %d payload: %v", e.code, e.payload)
 }
 
+// Code returns the HTTP status code of the error.
+func (e *Error) Code() int {
+	return e.code
+}
+
+// Payload returns the payload of the error.
+func (e *Error) Payload() interface{} {
+	return e.payload
+}
+
 // NewValidationError creates a new validation error with default payload.
 func NewValidationError() *Error {
 	return &Error{http.StatusBadRequest, http.StatusText(http.StatusBadRequest)}
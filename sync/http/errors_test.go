@@ -82,3 +82,9 @@ func TestNewErrorWithCodeAndNoPayload(t *testing.T) {
 	assert.EqualError(t, err, "HTTP error with code: 409")
 	assert.Equal(t, 409, err.code)
 }
+
+func TestError_CodeAndPayload(t *testing.T) {
+	err := NewErrorWithCodeAndPayload(409, "conflict")
+	assert.Equal(t, 409, err.Code())
+	assert.Equal(t, "conflict", err.Payload())
+}
@@ -5,7 +5,6 @@ import (
 	"net/http"
 	"strings"
 
-	"github.com/beatlabs/patron/correlation"
 	"github.com/beatlabs/patron/encoding"
 	"github.com/beatlabs/patron/encoding/json"
 	"github.com/beatlabs/patron/encoding/protobuf"
@@ -30,8 +29,8 @@ func handler(hnd sync.ProcessorFunc) http.HandlerFunc {
 			f[k] = v
 		}
 
-		corID := getOrSetCorrelationID(r.Header)
-		ctx := correlation.ContextWithID(r.Context(), corID)
+		corID := getOrSetCorrelationID(r)
+		ctx := withRequestID(r.Context(), corID)
 		logger := log.Sub(map[string]interface{}{"correlationID": corID})
 		ctx = log.WithContext(ctx, logger)
 
@@ -125,21 +124,57 @@ func extractHeaders(r *http.Request) map[string]string {
 
 func handleSuccess(w http.ResponseWriter, r *http.Request, rsp *sync.Response, enc encoding.EncodeFunc) error {
 	if rsp == nil {
-		w.WriteHeader(http.StatusNoContent)
+		writeNoContent(w)
 		return nil
 	}
 
+	for k, v := range rsp.Headers {
+		w.Header().Set(k, v)
+	}
+
+	status := responseStatus(r, rsp)
+	if status == http.StatusNoContent {
+		writeNoContent(w)
+		return nil
+	}
+
+	if rsp.Payload == nil {
+		w.WriteHeader(status)
+		return nil
+	}
+
+	if pooledResponse(r, w) {
+		return writePooledJSON(w, status, rsp.Payload)
+	}
+
 	p, err := enc(rsp.Payload)
 	if err != nil {
 		return err
 	}
 
+	w.WriteHeader(status)
+	_, err = w.Write(p)
+	return err
+}
+
+// responseStatus returns the explicitly set status of the response, or defaults to
+// 204 for a missing payload, 201 for POST requests and 200 for everything else.
+func responseStatus(r *http.Request, rsp *sync.Response) int {
+	if rsp.Status != 0 {
+		return rsp.Status
+	}
+	if rsp.Payload == nil {
+		return http.StatusNoContent
+	}
 	if r.Method == http.MethodPost {
-		w.WriteHeader(http.StatusCreated)
+		return http.StatusCreated
 	}
+	return http.StatusOK
+}
 
-	_, err = w.Write(p)
-	return err
+func writeNoContent(w http.ResponseWriter) {
+	w.Header().Del(encoding.ContentTypeHeader)
+	w.WriteHeader(http.StatusNoContent)
 }
 
 func handleError(logger log.Logger, w http.ResponseWriter, enc encoding.EncodeFunc, err error) {
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/beatlabs/patron/correlation"
@@ -114,7 +115,9 @@ func Test_getOrSetCorrelationID(t *testing.T) {
 	}
 	for name, tt := range tests {
 		t.Run(name, func(t *testing.T) {
-			assert.NotEmpty(t, getOrSetCorrelationID(tt.args.hdr))
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header = tt.args.hdr
+			assert.NotEmpty(t, getOrSetCorrelationID(req))
 			assert.NotEmpty(t, tt.args.hdr[correlation.HeaderID][0])
 		})
 	}
@@ -149,6 +152,8 @@ func Test_handleSuccess(t *testing.T) {
 		{"GET OK success", args{req: get, rsp: jsonRsp, enc: json.Encode}, http.StatusOK, false},
 		{"POST Created success", args{req: post, rsp: jsonRsp, enc: json.Encode}, http.StatusCreated, false},
 		{"Encode failure", args{req: post, rsp: jsonEncodeFailRsp, enc: json.Encode}, http.StatusCreated, true},
+		{"GET nil payload success", args{req: get, rsp: sync.NewResponse(nil), enc: json.Encode}, http.StatusNoContent, false},
+		{"GET explicit status success", args{req: get, rsp: sync.NewResponseWithStatus("test", http.StatusAccepted), enc: json.Encode}, http.StatusAccepted, false},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -167,6 +172,41 @@ func Test_handleSuccess(t *testing.T) {
 	}
 }
 
+func Test_handleSuccess_CustomStatus(t *testing.T) {
+	post, err := http.NewRequest(http.MethodPost, "/", nil)
+	assert.NoError(t, err)
+
+	t.Run("201 with body and headers", func(t *testing.T) {
+		rsp := httptest.NewRecorder()
+		prepareResponse(rsp, json.TypeCharset)
+		r := sync.NewResponseWithStatus("created", http.StatusCreated)
+		r.Headers = map[string]string{"Location": "/resource/1"}
+		assert.NoError(t, handleSuccess(rsp, post, r, json.Encode))
+		assert.Equal(t, http.StatusCreated, rsp.Code)
+		assert.Equal(t, `"created"`, strings.TrimSpace(rsp.Body.String()))
+		assert.Equal(t, json.TypeCharset, rsp.Header().Get(encoding.ContentTypeHeader))
+		assert.Equal(t, "/resource/1", rsp.Header().Get("Location"))
+	})
+
+	t.Run("204 with nil payload", func(t *testing.T) {
+		rsp := httptest.NewRecorder()
+		prepareResponse(rsp, json.TypeCharset)
+		assert.NoError(t, handleSuccess(rsp, post, sync.NewResponseWithStatus(nil, http.StatusNoContent), json.Encode))
+		assert.Equal(t, http.StatusNoContent, rsp.Code)
+		assert.Empty(t, rsp.Body.String())
+		assert.Empty(t, rsp.Header().Get(encoding.ContentTypeHeader))
+	})
+
+	t.Run("204 ignores payload", func(t *testing.T) {
+		rsp := httptest.NewRecorder()
+		prepareResponse(rsp, json.TypeCharset)
+		assert.NoError(t, handleSuccess(rsp, post, sync.NewResponseWithStatus("ignored", http.StatusNoContent), json.Encode))
+		assert.Equal(t, http.StatusNoContent, rsp.Code)
+		assert.Empty(t, rsp.Body.String())
+		assert.Empty(t, rsp.Header().Get(encoding.ContentTypeHeader))
+	})
+}
+
 func Test_handleError(t *testing.T) {
 	type args struct {
 		err error
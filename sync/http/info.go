@@ -0,0 +1,25 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/beatlabs/patron/encoding/json"
+	"github.com/beatlabs/patron/info"
+)
+
+func infoRoute(withRuntime bool) Route {
+	marshal := info.Marshal
+	if withRuntime {
+		marshal = info.MarshalWithRuntime
+	}
+	f := func(w http.ResponseWriter, r *http.Request) {
+		body, err := marshal()
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", json.TypeCharset)
+		_, _ = w.Write(body)
+	}
+	return NewRouteRaw("/info", http.MethodGet, f, false)
+}
@@ -0,0 +1,59 @@
+package http
+
+import (
+	encjson "encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/beatlabs/patron/encoding/json"
+	"github.com/beatlabs/patron/info"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_infoRoute(t *testing.T) {
+	info.UpdateName("test", "1.0.0")
+	route := infoRoute(false)
+	assert.Equal(t, http.MethodGet, route.Method)
+	assert.Equal(t, "/info", route.Pattern)
+	assert.False(t, route.Trace)
+
+	req, err := http.NewRequest(http.MethodGet, "/info", nil)
+	assert.NoError(t, err)
+	rsp := httptest.NewRecorder()
+	route.Handler(rsp, req)
+	assert.Equal(t, http.StatusOK, rsp.Code)
+	assert.Equal(t, json.TypeCharset, rsp.Header().Get("Content-Type"))
+	assert.Contains(t, rsp.Body.String(), `"name":"test"`)
+	assert.NotContains(t, rsp.Body.String(), `"runtime"`)
+}
+
+func Test_infoRoute_WithRuntime(t *testing.T) {
+	req, err := http.NewRequest(http.MethodGet, "/info", nil)
+	assert.NoError(t, err)
+	rsp := httptest.NewRecorder()
+	infoRoute(true).Handler(rsp, req)
+	assert.Equal(t, http.StatusOK, rsp.Code)
+	var got struct {
+		Runtime map[string]interface{} `json:"runtime"`
+	}
+	assert.NoError(t, encjson.Unmarshal(rsp.Body.Bytes(), &got))
+	for _, f := range []string{"goroutines", "heapAlloc", "numGC", "lastGCPause", "totalGCPause"} {
+		assert.Contains(t, got.Runtime, f)
+	}
+	assert.True(t, got.Runtime["goroutines"].(float64) > 0)
+}
+
+func TestComponent_DeprecatedRoutesInfo(t *testing.T) {
+	h := func(w http.ResponseWriter, r *http.Request) {}
+	rr := []Route{NewRouteRaw("/deprecated", http.MethodGet, h, false).Deprecate(Deprecation{Since: time.Unix(0, 0)})}
+	_, err := NewBuilder().WithRoutes(rr).Create()
+	assert.NoError(t, err)
+
+	req, err := http.NewRequest(http.MethodGet, "/info", nil)
+	assert.NoError(t, err)
+	rsp := httptest.NewRecorder()
+	infoRoute(false).Handler(rsp, req)
+	assert.Contains(t, rsp.Body.String(), `"deprecatedRoutes":[{"method":"GET","path":"/deprecated","since":"1970-01-01T00:00:00Z"}]`)
+}
@@ -0,0 +1,31 @@
+package http
+
+import (
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/beatlabs/patron/log"
+)
+
+// requestLogSampler decides which requests are logged by the logging middleware.
+// One in n successful requests is logged, while server errors and requests slower than the threshold are always logged.
+type requestLogSampler struct {
+	n     uint64
+	slow  time.Duration
+	count uint64
+}
+
+// level returns the level a request is logged with, or log.NoLevel if it is not logged.
+func (s *requestLogSampler) level(status int, elapsed time.Duration) log.Level {
+	if status >= http.StatusInternalServerError {
+		return log.ErrorLevel
+	}
+	if s.slow > 0 && elapsed > s.slow {
+		return log.WarnLevel
+	}
+	if (atomic.AddUint64(&s.count, 1)-1)%s.n == 0 {
+		return log.InfoLevel
+	}
+	return log.NoLevel
+}
@@ -0,0 +1,83 @@
+package http
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/beatlabs/patron/log"
+	plog "github.com/beatlabs/patron/log/zerolog"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequestLogSampler_Level(t *testing.T) {
+	s := &requestLogSampler{n: 3, slow: 100 * time.Millisecond}
+
+	var levels []log.Level
+	for i := 0; i < 6; i++ {
+		levels = append(levels, s.level(http.StatusOK, time.Millisecond))
+	}
+	assert.Equal(t, []log.Level{log.InfoLevel, log.NoLevel, log.NoLevel, log.InfoLevel, log.NoLevel, log.NoLevel}, levels)
+
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, log.ErrorLevel, s.level(http.StatusInternalServerError, time.Millisecond))
+		assert.Equal(t, log.WarnLevel, s.level(http.StatusOK, time.Second))
+	}
+
+	s = &requestLogSampler{n: 1}
+	assert.Equal(t, log.InfoLevel, s.level(http.StatusOK, time.Hour))
+}
+
+func TestNewLoggingTracingMiddleware_Sampling(t *testing.T) {
+	var b bytes.Buffer
+	zl := zerolog.New(&b)
+	lg := plog.NewLogger(&zl, log.InfoLevel, nil)
+
+	st := &settings{logSampler: &requestLogSampler{n: 10}}
+	serve := func(status int) {
+		h := MiddlewareChain(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(status)
+		}), settingsMiddleware(st), NewLoggingTracingMiddleware("/test"))
+		req, err := http.NewRequest(http.MethodGet, "/test", nil)
+		assert.NoError(t, err)
+		h.ServeHTTP(httptest.NewRecorder(), req.WithContext(log.WithContext(req.Context(), lg)))
+	}
+
+	for i := 0; i < 20; i++ {
+		serve(http.StatusOK)
+		serve(http.StatusBadGateway)
+	}
+
+	lines := strings.Split(strings.TrimSpace(b.String()), "\n")
+	var successes, errors int
+	for _, l := range lines {
+		switch {
+		case strings.Contains(l, `"status":200`):
+			successes++
+			assert.Contains(t, l, `"level":"info"`)
+		case strings.Contains(l, `"status":502`):
+			errors++
+			assert.Contains(t, l, `"level":"error"`)
+		}
+	}
+	assert.Equal(t, 2, successes)
+	assert.Equal(t, 20, errors)
+}
+
+func TestBuilder_WithRequestLogSampling(t *testing.T) {
+	_, err := NewBuilder().WithRequestLogSampling(0, 0).Create()
+	assert.EqualError(t, err, "Negative or zero request log sample rate provided\n")
+	_, err = NewBuilder().WithRequestLogSampling(1, -time.Second).Create()
+	assert.EqualError(t, err, "Negative slow request threshold provided\n")
+	c, err := NewBuilder().WithRequestLogSampling(100, time.Second).Create()
+	assert.NoError(t, err)
+	assert.Equal(t, &requestLogSampler{n: 100, slow: time.Second}, c.settings.logSampler)
+	// the sampling applies only to the component it is set on
+	c, err = NewBuilder().Create()
+	assert.NoError(t, err)
+	assert.Nil(t, c.settings.logSampler)
+}
@@ -0,0 +1,89 @@
+package http
+
+import (
+	"net/http"
+	"sync/atomic"
+
+	"github.com/beatlabs/patron/encoding/json"
+	"github.com/beatlabs/patron/log"
+	"github.com/beatlabs/patron/sync/http/auth"
+)
+
+// MaintenancePath is the path of the route which toggles the maintenance mode.
+const MaintenancePath = "/maintenance"
+
+// Maintenance is a runtime switch of the read-only maintenance mode, e.g. during a migration,
+// which makes the maintenance middleware reject the requests with methods which are not safe.
+type Maintenance struct {
+	on int32
+}
+
+// Enable turns the maintenance mode on.
+func (m *Maintenance) Enable() {
+	if atomic.CompareAndSwapInt32(&m.on, 0, 1) {
+		log.Info("read-only maintenance mode enabled")
+	}
+}
+
+// Disable turns the maintenance mode off.
+func (m *Maintenance) Disable() {
+	if atomic.CompareAndSwapInt32(&m.on, 1, 0) {
+		log.Info("read-only maintenance mode disabled")
+	}
+}
+
+// Enabled returns whether the maintenance mode is on.
+func (m *Maintenance) Enabled() bool {
+	return atomic.LoadInt32(&m.on) == 1
+}
+
+// NewMaintenanceMiddleware creates a MiddlewareFunc which rejects the requests with methods which are not safe,
+// i.e. other than GET, HEAD, OPTIONS and TRACE, with 503 Service Unavailable while the maintenance mode is on,
+// so the reads are still served. The requests toggling the maintenance mode are always served.
+func NewMaintenanceMiddleware(m *Maintenance) MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !m.Enabled() || safeMethod(r.Method) || r.URL.Path == MaintenancePath {
+				next.ServeHTTP(w, r)
+				return
+			}
+			writeError(w, r, http.StatusServiceUnavailable, "the service is in read-only maintenance mode")
+		})
+	}
+}
+
+func safeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	default:
+		return false
+	}
+}
+
+// maintenanceRoutes returns the routes which report the maintenance mode, turn it on with a PUT and off with a DELETE,
+// which are authenticated with the admin authenticator.
+func maintenanceRoutes(m *Maintenance, admin auth.Authenticator) []Route {
+	status := func(w http.ResponseWriter, r *http.Request) {
+		body, err := json.Encode(map[string]bool{"enabled": m.Enabled()})
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", json.TypeCharset)
+		_, _ = w.Write(body)
+	}
+	enable := func(w http.ResponseWriter, r *http.Request) {
+		m.Enable()
+		status(w, r)
+	}
+	disable := func(w http.ResponseWriter, r *http.Request) {
+		m.Disable()
+		status(w, r)
+	}
+	return []Route{
+		NewRouteRaw(MaintenancePath, http.MethodGet, status, false, NewAuthMiddleware(admin)),
+		NewRouteRaw(MaintenancePath, http.MethodPut, enable, false, NewAuthMiddleware(admin)),
+		NewRouteRaw(MaintenancePath, http.MethodDelete, disable, false, NewAuthMiddleware(admin)),
+	}
+}
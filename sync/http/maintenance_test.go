@@ -0,0 +1,87 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewMaintenanceMiddleware(t *testing.T) {
+	m := &Maintenance{}
+	mw := NewMaintenanceMiddleware(m)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	serve := func(method, path string) int {
+		rc := httptest.NewRecorder()
+		mw.ServeHTTP(rc, httptest.NewRequest(method, path, nil))
+		return rc.Code
+	}
+
+	assert.Equal(t, http.StatusAccepted, serve(http.MethodPost, "/orders"))
+	m.Enable()
+	assert.True(t, m.Enabled())
+	for _, method := range []string{http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete} {
+		assert.Equal(t, http.StatusServiceUnavailable, serve(method, "/orders"), method)
+	}
+	for _, method := range []string{http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace} {
+		assert.Equal(t, http.StatusAccepted, serve(method, "/orders"), method)
+	}
+	assert.Equal(t, http.StatusAccepted, serve(http.MethodDelete, MaintenancePath))
+	m.Disable()
+	assert.False(t, m.Enabled())
+	assert.Equal(t, http.StatusAccepted, serve(http.MethodPost, "/orders"))
+}
+
+func TestComponent_MaintenanceToggle(t *testing.T) {
+	rr := []Route{
+		NewRouteRaw("/orders", http.MethodGet, func(w http.ResponseWriter, r *http.Request) {}, false),
+		NewRouteRaw("/orders", http.MethodPost, func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusCreated) }, false),
+	}
+	_, err := NewBuilder().WithMaintenanceToggle(nil).Create()
+	assert.EqualError(t, err, "Nil admin authenticator of the maintenance toggle provided\n")
+
+	cmp, err := NewBuilder().WithRoutes(rr).WithMaintenanceToggle(adminAuthenticator{}).Create()
+	require.NoError(t, err)
+	require.NotNil(t, cmp.Maintenance())
+	h := cmp.createHTTPServer().Handler
+	serve := func(method, path string) *httptest.ResponseRecorder {
+		rc := httptest.NewRecorder()
+		req := httptest.NewRequest(method, path, nil)
+		if path == MaintenancePath {
+			req.Header.Set("Authorization", "admin")
+		}
+		h.ServeHTTP(rc, req)
+		return rc
+	}
+
+	// the toggle requires the admin authentication
+	for _, method := range []string{http.MethodGet, http.MethodPut, http.MethodDelete} {
+		rc := httptest.NewRecorder()
+		h.ServeHTTP(rc, httptest.NewRequest(method, MaintenancePath, nil))
+		assert.Equal(t, http.StatusUnauthorized, rc.Code, method)
+	}
+	assert.False(t, cmp.Maintenance().Enabled())
+
+	assert.Equal(t, http.StatusCreated, serve(http.MethodPost, "/orders").Code)
+	assert.JSONEq(t, `{"enabled":true}`, serve(http.MethodPut, MaintenancePath).Body.String())
+	assert.True(t, cmp.Maintenance().Enabled())
+
+	// writes are blocked, while reads and health checks pass
+	rc := serve(http.MethodPost, "/orders")
+	assert.Equal(t, http.StatusServiceUnavailable, rc.Code)
+	assert.Equal(t, "application/problem+json", rc.Header().Get("Content-Type"))
+	assert.Equal(t, http.StatusOK, serve(http.MethodGet, "/orders").Code)
+	assert.Equal(t, http.StatusOK, serve(http.MethodGet, "/alive").Code)
+	assert.Equal(t, http.StatusOK, serve(http.MethodGet, "/metrics").Code)
+	assert.JSONEq(t, `{"enabled":true}`, serve(http.MethodGet, MaintenancePath).Body.String())
+
+	assert.JSONEq(t, `{"enabled":false}`, serve(http.MethodDelete, MaintenancePath).Body.String())
+	assert.Equal(t, http.StatusCreated, serve(http.MethodPost, "/orders").Code)
+
+	cmp, err = NewBuilder().Create()
+	require.NoError(t, err)
+	assert.Nil(t, cmp.Maintenance())
+}
@@ -3,9 +3,14 @@ package http
 import (
 	"net/http"
 
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
+// metricRoute serves the metrics in the OpenMetrics format, which carries the exemplars, to the scrapers requesting it
+// with the Accept header, and in the Prometheus text format otherwise.
 func metricRoute() Route {
-	return NewRouteRaw("/metrics", http.MethodGet, promhttp.Handler().ServeHTTP, false)
+	h := promhttp.InstrumentMetricHandler(prometheus.DefaultRegisterer,
+		promhttp.HandlerFor(prometheus.DefaultGatherer, promhttp.HandlerOpts{EnableOpenMetrics: true}))
+	return NewRouteRaw("/metrics", http.MethodGet, h.ServeHTTP, false)
 }
@@ -2,9 +2,13 @@ package http
 
 import (
 	"net/http"
+	"net/http/httptest"
 	"testing"
 
+	opentracing "github.com/opentracing/opentracing-go"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	jaeger "github.com/uber/jaeger-client-go"
 )
 
 func Test_metricRoute(t *testing.T) {
@@ -14,3 +18,33 @@ func Test_metricRoute(t *testing.T) {
 	assert.NotNil(t, route.Handler)
 	assert.False(t, route.Trace)
 }
+
+func Test_metricRoute_Exemplars(t *testing.T) {
+	tr, cls := jaeger.NewTracer("test", jaeger.NewConstSampler(true), jaeger.NewInMemoryReporter())
+	defer cls.Close()
+	opentracing.SetGlobalTracer(tr)
+	defer opentracing.SetGlobalTracer(opentracing.NoopTracer{})
+
+	var traceID string
+	h := NewLoggingTracingMiddleware("/exemplar")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceID = opentracing.SpanFromContext(r.Context()).Context().(jaeger.SpanContext).TraceID().String()
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/exemplar", nil))
+	require.NotEmpty(t, traceID)
+
+	route := metricRoute()
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	req.Header.Set("Accept", "application/openmetrics-text; version=0.0.1")
+	rsp := httptest.NewRecorder()
+	route.Handler(rsp, req)
+	assert.Contains(t, rsp.Header().Get("Content-Type"), "application/openmetrics-text")
+	assert.Regexp(t, `component_http_request_duration_seconds_bucket\{method="POST",path="/exemplar",status_code="202",le="[^"]+"\} 1 # \{trace_id="`+
+		traceID+`"\}`, rsp.Body.String())
+
+	// the text format is served without the exemplars
+	rsp = httptest.NewRecorder()
+	route.Handler(rsp, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Contains(t, rsp.Body.String(), `component_http_request_duration_seconds_count{method="POST",path="/exemplar",status_code="202"} 1`)
+	assert.NotContains(t, rsp.Body.String(), "trace_id")
+}
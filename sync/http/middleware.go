@@ -3,13 +3,13 @@ package http
 import (
 	"errors"
 	"net/http"
-	"strings"
+	"time"
 
 	"github.com/beatlabs/patron/correlation"
 	"github.com/beatlabs/patron/log"
+	"github.com/beatlabs/patron/reliability/errorrate"
 	"github.com/beatlabs/patron/sync/http/auth"
 	"github.com/beatlabs/patron/trace"
-	"github.com/google/uuid"
 )
 
 type responseWriter struct {
@@ -103,16 +103,60 @@ func NewAuthMiddleware(auth auth.Authenticator) MiddlewareFunc {
 }
 
 // NewLoggingTracingMiddleware creates a MiddlewareFunc that continues a tracing span and finishes it.
-// It also logs the HTTP request on debug logging level
+// The span and the correlation ID are set in the request context, in order to be propagated by the clients
+// and producers used in the handler, e.g. to the headers of the messages sent with the Kafka producer.
+// It also logs the HTTP request on debug logging level, or according to the request log sampling
+// when it is set with WithRequestLogSampling of the builder of the HTTP component serving the request,
+// and records its duration with an exemplar linking to its trace.
 func NewLoggingTracingMiddleware(path string) MiddlewareFunc {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			corID := getOrSetCorrelationID(r.Header)
+			start := time.Now()
+			corID := getOrSetCorrelationID(r)
 			sp, r := trace.HTTPSpan(path, corID, r)
+			r = r.WithContext(withRequestID(r.Context(), corID))
 			lw := newResponseWriter(w)
 			next.ServeHTTP(lw, r)
 			trace.FinishHTTPSpan(sp, lw.Status())
-			logRequestResponse(lw, r)
+			observeRequestDuration(path, r.Method, lw.Status(), time.Since(start), sp)
+			logRequestResponse(lw, r, time.Since(start))
+		})
+	}
+}
+
+// NewRequestIDMiddleware creates a MiddlewareFunc that propagates the request ID of the X-Request-ID header,
+// or of the X-Correlation-Id header if missing, as the correlation ID of the request, generating a new one if both are missing.
+// The ID is set on the context of the request along with a logger carrying it as the correlationID field, which is returned by log.FromContext,
+// and is echoed in the X-Request-ID header of the response. The producers of the trace package propagate it downstream, e.g. as a Kafka message header.
+func NewRequestIDMiddleware() MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id := r.Header.Get(correlation.RequestHeaderID)
+			if id == "" {
+				id = getOrSetCorrelationID(r)
+			} else {
+				r.Header.Set(correlation.HeaderID, id)
+			}
+			ctx := withRequestID(r.Context(), id)
+			ctx = log.WithContext(ctx, log.Sub(map[string]interface{}{correlation.ID: id}))
+			w.Header().Set(correlation.RequestHeaderID, id)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// NewErrorRateMiddleware creates a MiddlewareFunc that records server errors (5xx) as failures
+// and every other response as success in the error rate tracker.
+func NewErrorRateMiddleware(t *errorrate.Tracker) MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			lw := newResponseWriter(w)
+			next.ServeHTTP(lw, r)
+			if lw.Status() >= http.StatusInternalServerError {
+				t.Failure()
+			} else {
+				t.Success()
+			}
 		})
 	}
 }
@@ -125,45 +169,48 @@ func MiddlewareChain(f http.Handler, mm ...MiddlewareFunc) http.Handler {
 	return f
 }
 
-func logRequestResponse(w *responseWriter, r *http.Request) {
-	if !log.Enabled(log.DebugLevel) {
+func logRequestResponse(w *responseWriter, r *http.Request, elapsed time.Duration) {
+	lvl := log.DebugLevel
+	lg := log.FromContext(r.Context())
+	if s := requestSettings(r).logSampler; s != nil {
+		lvl = s.level(w.Status(), elapsed)
+		if lvl == log.NoLevel {
+			return
+		}
+	} else if !log.Enabled(log.DebugLevel) {
 		return
 	}
 
-	remoteAddr := r.RemoteAddr
-	if i := strings.LastIndex(remoteAddr, ":"); i != -1 {
-		remoteAddr = remoteAddr[:i]
-	}
-
 	info := map[string]interface{}{
 		"request": map[string]interface{}{
-			"remote-address": remoteAddr,
+			"remote-address": ClientIP(r),
 			"method":         r.Method,
 			"url":            r.URL,
 			"proto":          r.Proto,
 			"status":         w.Status(),
 			"referer":        r.Referer(),
 			"user-agent":     r.UserAgent(),
+			"duration":       elapsed.String(),
 		},
 	}
-	log.Sub(info).Debug()
+	sub := lg.Sub(info)
+	switch lvl {
+	case log.ErrorLevel:
+		sub.Error()
+	case log.WarnLevel:
+		sub.Warn()
+	case log.InfoLevel:
+		sub.Info()
+	default:
+		sub.Debug()
+	}
 }
 
-func getOrSetCorrelationID(h http.Header) string {
-	cor, ok := h[correlation.HeaderID]
-	if !ok {
-		corID := uuid.New().String()
-		h.Set(correlation.HeaderID, corID)
-		return corID
-	}
-	if len(cor) == 0 {
-		corID := uuid.New().String()
-		h.Set(correlation.HeaderID, corID)
-		return corID
-	}
-	if cor[0] == "" {
-		corID := uuid.New().String()
-		h.Set(correlation.HeaderID, corID)
+func getOrSetCorrelationID(r *http.Request) string {
+	cor, ok := r.Header[correlation.HeaderID]
+	if !ok || len(cor) == 0 || cor[0] == "" {
+		corID := requestSettings(r).newID()
+		r.Header.Set(correlation.HeaderID, corID)
 		return corID
 	}
 	return cor[0]
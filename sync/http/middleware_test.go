@@ -1,11 +1,16 @@
 package http
 
 import (
+	"context"
 	"errors"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
+	"github.com/beatlabs/patron/correlation"
+	"github.com/beatlabs/patron/log"
+	"github.com/beatlabs/patron/reliability/errorrate"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -118,3 +123,97 @@ func TestResponseWriter(t *testing.T) {
 	assert.True(t, rw.statusHeaderWritten, "expected to be true")
 	assert.Equal(t, "test", rc.Body.String(), "body expected to be test but was %s", rc.Body.String())
 }
+
+func TestNewErrorRateMiddleware(t *testing.T) {
+	tr, err := errorrate.New(errorrate.Setting{Window: time.Minute, Threshold: 0.5, MinEvents: 4})
+	assert.NoError(t, err)
+	status := http.StatusOK
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+	})
+	mw := NewErrorRateMiddleware(tr)(h)
+	ready := readyCheckRoute(ErrorRateReadyCheck(DefaultReadyCheck, tr), DefaultStartupCheck, http.StatusServiceUnavailable)
+
+	serve := func(hnd http.HandlerFunc) *httptest.ResponseRecorder {
+		req, err := http.NewRequest(http.MethodGet, "/", nil)
+		assert.NoError(t, err)
+		rc := httptest.NewRecorder()
+		hnd(rc, req)
+		return rc
+	}
+
+	serve(mw.ServeHTTP)
+	status = http.StatusBadRequest
+	serve(mw.ServeHTTP)
+	assert.Equal(t, http.StatusOK, serve(ready.Handler).Code)
+
+	status = http.StatusInternalServerError
+	serve(mw.ServeHTTP)
+	serve(mw.ServeHTTP)
+	assert.Equal(t, http.StatusOK, serve(ready.Handler).Code)
+	serve(mw.ServeHTTP)
+	assert.Equal(t, 0.6, tr.Rate())
+	rc := serve(ready.Handler)
+	assert.Equal(t, http.StatusServiceUnavailable, rc.Code)
+	assert.Equal(t, "degraded", rc.Body.String())
+}
+
+// fieldsLogger records the fields of the sub loggers.
+type fieldsLogger struct {
+	log.Logger
+	fields map[string]interface{}
+}
+
+func (fl *fieldsLogger) Sub(ff map[string]interface{}) log.Logger {
+	return &fieldsLogger{Logger: fl.Logger, fields: ff}
+}
+
+func TestNewRequestIDMiddleware(t *testing.T) {
+	defer func(l log.Logger) {
+		_ = log.Setup(func(map[string]interface{}) log.Logger { return l }, nil)
+	}(log.FromContext(context.Background()))
+	assert.NoError(t, log.Setup(func(ff map[string]interface{}) log.Logger { return &fieldsLogger{fields: ff} }, nil))
+
+	var corID, reqID string
+	var fields map[string]interface{}
+	mw := NewRequestIDMiddleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		corID = correlation.IDFromContext(r.Context())
+		reqID, _ = RequestID(r.Context())
+		fields = log.FromContext(r.Context()).(*fieldsLogger).fields
+	}))
+
+	tests := map[string]struct {
+		requestID     string
+		correlationID string
+		want          string
+	}{
+		"request ID":                     {requestID: "123", want: "123"},
+		"correlation ID":                 {correlationID: "456", want: "456"},
+		"request ID over correlation ID": {requestID: "123", correlationID: "456", want: "123"},
+		"generated":                      {},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.requestID != "" {
+				req.Header.Set(correlation.RequestHeaderID, tt.requestID)
+			}
+			if tt.correlationID != "" {
+				req.Header.Set(correlation.HeaderID, tt.correlationID)
+			}
+			rc := httptest.NewRecorder()
+			mw.ServeHTTP(rc, req)
+
+			id := rc.Header().Get(correlation.RequestHeaderID)
+			if tt.want == "" {
+				assert.NotEmpty(t, id)
+			} else {
+				assert.Equal(t, tt.want, id)
+			}
+			assert.Equal(t, id, corID)
+			assert.Equal(t, id, reqID)
+			assert.Equal(t, id, req.Header.Get(correlation.HeaderID))
+			assert.Equal(t, map[string]interface{}{correlation.ID: id}, fields)
+		})
+	}
+}
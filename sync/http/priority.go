@@ -0,0 +1,102 @@
+package http
+
+import (
+	"math"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Priority of a request, which determines the order in which requests are shed under overload.
+type Priority int
+
+const (
+	// PriorityLow is the priority of requests which are shed first, e.g. bulk or batch traffic.
+	PriorityLow Priority = iota
+	// PriorityNormal is the default priority of requests.
+	PriorityNormal
+	// PriorityHigh is the priority of requests which are shed only after the low and normal priority ones.
+	PriorityHigh
+	// PriorityCritical is the priority of requests which are shed last, e.g. health checks.
+	PriorityCritical
+)
+
+// PriorityHeader is the default header of the priority hint of a request.
+const PriorityHeader = "X-Request-Priority"
+
+const (
+	shedSourcePriority = "priority"
+	priorityRetryAfter = time.Second
+)
+
+// priorityShares are the shares of the concurrency limit, which the requests of each priority may use.
+var priorityShares = map[Priority]float64{
+	PriorityLow:      0.5,
+	PriorityNormal:   0.75,
+	PriorityHigh:     0.9,
+	PriorityCritical: 1,
+}
+
+var priorityNames = map[string]Priority{
+	"low":      PriorityLow,
+	"normal":   PriorityNormal,
+	"high":     PriorityHigh,
+	"critical": PriorityCritical,
+}
+
+// PriorityFunc defines a function which determines the priority of a request.
+type PriorityFunc func(*http.Request) Priority
+
+// HeaderPriority returns a PriorityFunc which reads the priority from the header, i.e. low, normal, high or critical,
+// while the health checks are critical and requests without a valid hint are normal.
+func HeaderPriority(header string) PriorityFunc {
+	if header == "" {
+		header = PriorityHeader
+	}
+	return func(r *http.Request) Priority {
+		switch r.URL.Path {
+		case "/alive", "/ready", "/startup":
+			return PriorityCritical
+		}
+		if p, ok := priorityNames[strings.ToLower(r.Header.Get(header))]; ok {
+			return p
+		}
+		return PriorityNormal
+	}
+}
+
+// NewPriorityShedMiddleware creates a MiddlewareFunc which limits the requests in flight to the limit and, when the capacity
+// is constrained, sheds the requests of the lowest priority first, since the requests of each priority may use only a share
+// of the limit: a half for low, three quarters for normal, nine tenths for high and the whole limit for critical priority.
+// Shed requests are responded with 503 Service Unavailable and a Retry-After header.
+// The priority is determined by HeaderPriority with the default header if no PriorityFunc is provided.
+func NewPriorityShedMiddleware(limit int, pf PriorityFunc) MiddlewareFunc {
+	if pf == nil {
+		pf = HeaderPriority(PriorityHeader)
+	}
+	thresholds := make(map[Priority]int64, len(priorityShares))
+	for p, share := range priorityShares {
+		thresholds[p] = int64(math.Max(1, math.Ceil(float64(limit)*share)))
+	}
+	var inFlight int64
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if limit <= 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+			threshold, ok := thresholds[pf(r)]
+			if !ok {
+				threshold = thresholds[PriorityNormal]
+			}
+			if atomic.AddInt64(&inFlight, 1) > threshold {
+				atomic.AddInt64(&inFlight, -1)
+				shed(w, priorityRetryAfter, shedSourcePriority, false)
+				return
+			}
+			defer atomic.AddInt64(&inFlight, -1)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,93 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHeaderPriority(t *testing.T) {
+	pf := HeaderPriority("")
+	tests := map[string]struct {
+		path, hint string
+		want       Priority
+	}{
+		"low":          {path: "/orders", hint: "low", want: PriorityLow},
+		"high":         {path: "/orders", hint: "HIGH", want: PriorityHigh},
+		"critical":     {path: "/orders", hint: "critical", want: PriorityCritical},
+		"missing":      {path: "/orders", want: PriorityNormal},
+		"invalid":      {path: "/orders", hint: "urgent", want: PriorityNormal},
+		"health check": {path: "/alive", hint: "low", want: PriorityCritical},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.hint != "" {
+				r.Header.Set(PriorityHeader, tt.hint)
+			}
+			assert.Equal(t, tt.want, pf(r))
+		})
+	}
+}
+
+func TestNewPriorityShedMiddleware(t *testing.T) {
+	release := make(chan struct{})
+	var started sync.WaitGroup
+	mw := NewPriorityShedMiddleware(4, nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			started.Done()
+			<-release
+		}
+	}))
+	serve := func(path, priority string) int {
+		r := httptest.NewRequest(http.MethodGet, path, nil)
+		r.Header.Set(PriorityHeader, priority)
+		rc := httptest.NewRecorder()
+		mw.ServeHTTP(rc, r)
+		return rc.Code
+	}
+
+	// without load every priority is served
+	assert.Equal(t, http.StatusOK, serve("/", "low"))
+
+	// two slow requests exhaust the share of the low priority
+	var done sync.WaitGroup
+	occupy := func(n int) {
+		started.Add(n)
+		done.Add(n)
+		for i := 0; i < n; i++ {
+			go func() {
+				defer done.Done()
+				assert.Equal(t, http.StatusOK, serve("/slow", "critical"))
+			}()
+		}
+		started.Wait()
+	}
+	occupy(2)
+	rc := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set(PriorityHeader, "low")
+	mw.ServeHTTP(rc, r)
+	assert.Equal(t, http.StatusServiceUnavailable, rc.Code)
+	assert.Equal(t, "1", rc.Header().Get("Retry-After"))
+	assert.Equal(t, http.StatusOK, serve("/", "normal"))
+
+	// a third slow request exhausts the share of the normal priority, while high and critical requests are served
+	occupy(1)
+	assert.Equal(t, http.StatusServiceUnavailable, serve("/", "low"))
+	assert.Equal(t, http.StatusServiceUnavailable, serve("/", "normal"))
+	assert.Equal(t, http.StatusOK, serve("/", "high"))
+	assert.Equal(t, http.StatusOK, serve("/alive", "low"))
+
+	// at the limit every request is shed
+	occupy(1)
+	assert.Equal(t, http.StatusServiceUnavailable, serve("/", "high"))
+	assert.Equal(t, http.StatusServiceUnavailable, serve("/", "critical"))
+
+	close(release)
+	done.Wait()
+	assert.Equal(t, http.StatusOK, serve("/", "low"))
+}
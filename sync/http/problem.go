@@ -0,0 +1,75 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/beatlabs/patron/encoding"
+	"github.com/beatlabs/patron/trace"
+)
+
+const problemContentType = "application/problem+json"
+
+// ErrorEncoderFunc defines a function which writes the response of an error, whose status code and payload
+// are available, if it is an *Error, via its Code and Payload methods.
+type ErrorEncoderFunc func(w http.ResponseWriter, r *http.Request, err error)
+
+var (
+	errorEncoderMu sync.RWMutex
+	errorEncoder   ErrorEncoderFunc = ProblemErrorEncoder
+)
+
+// SetErrorEncoder sets the encoder of the error responses written by the middlewares, e.g. the request size limit middleware.
+// The default encoder is ProblemErrorEncoder.
+func SetErrorEncoder(enc ErrorEncoderFunc) error {
+	if enc == nil {
+		return errors.New("error encoder is nil")
+	}
+	errorEncoderMu.Lock()
+	defer errorEncoderMu.Unlock()
+	errorEncoder = enc
+	return nil
+}
+
+func writeError(w http.ResponseWriter, r *http.Request, status int, detail string) {
+	errorEncoderMu.RLock()
+	enc := errorEncoder
+	errorEncoderMu.RUnlock()
+	enc(w, r, &Error{code: status, payload: detail})
+}
+
+// problem is the RFC 7807 problem details representation of an error.
+type problem struct {
+	Type    string `json:"type"`
+	Title   string `json:"title"`
+	Status  int    `json:"status"`
+	Detail  string `json:"detail,omitempty"`
+	TraceID string `json:"trace_id,omitempty"`
+}
+
+// ProblemErrorEncoder writes the error as RFC 7807 problem details in JSON format, with the trace id of the span
+// of the request, if any, as the trace_id extension member, in order for the errors to be traceable.
+// The status of errors other than *Error is 500 Internal Server Error, and their message is not exposed.
+func ProblemErrorEncoder(w http.ResponseWriter, r *http.Request, err error) {
+	status := http.StatusInternalServerError
+	detail := ""
+	var httpErr *Error
+	if errors.As(err, &httpErr) {
+		status = httpErr.code
+		if httpErr.payload != nil {
+			detail = fmt.Sprint(httpErr.payload)
+		}
+	}
+	w.Header().Set(encoding.ContentTypeHeader, problemContentType)
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(problem{
+		Type:    "about:blank",
+		Title:   http.StatusText(status),
+		Status:  status,
+		Detail:  detail,
+		TraceID: trace.TraceID(r.Context()),
+	})
+}
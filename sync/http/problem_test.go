@@ -0,0 +1,64 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	jaeger "github.com/uber/jaeger-client-go"
+)
+
+func TestProblemErrorEncoder(t *testing.T) {
+	tr, cls := jaeger.NewTracer("test", jaeger.NewConstSampler(true), jaeger.NewNullReporter())
+	defer func() { assert.NoError(t, cls.Close()) }()
+	sp := tr.StartSpan("op")
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req = req.WithContext(opentracing.ContextWithSpan(req.Context(), sp))
+
+	rc := httptest.NewRecorder()
+	ProblemErrorEncoder(rc, req, NewValidationErrorWithPayload("invalid id"))
+	assert.Equal(t, http.StatusBadRequest, rc.Code)
+	assert.Equal(t, "application/problem+json", rc.Header().Get("Content-Type"))
+	var got problem
+	require.NoError(t, json.NewDecoder(rc.Body).Decode(&got))
+	assert.Equal(t, problem{
+		Type:    "about:blank",
+		Title:   "Bad Request",
+		Status:  http.StatusBadRequest,
+		Detail:  "invalid id",
+		TraceID: sp.Context().(jaeger.SpanContext).TraceID().String(),
+	}, got)
+
+	// errors other than *Error are internal server errors, without exposing their message
+	rc = httptest.NewRecorder()
+	ProblemErrorEncoder(rc, httptest.NewRequest(http.MethodGet, "/", nil), errors.New("connection refused"))
+	assert.Equal(t, http.StatusInternalServerError, rc.Code)
+	got = problem{}
+	require.NoError(t, json.NewDecoder(rc.Body).Decode(&got))
+	assert.Equal(t, problem{Type: "about:blank", Title: "Internal Server Error", Status: http.StatusInternalServerError}, got)
+}
+
+func TestSetErrorEncoder(t *testing.T) {
+	defer func() { require.NoError(t, SetErrorEncoder(ProblemErrorEncoder)) }()
+	assert.EqualError(t, SetErrorEncoder(nil), "error encoder is nil")
+
+	var code int
+	require.NoError(t, SetErrorEncoder(func(w http.ResponseWriter, r *http.Request, err error) {
+		var httpErr *Error
+		require.True(t, errors.As(err, &httpErr))
+		code = httpErr.Code()
+		w.WriteHeader(httpErr.Code())
+		_, _ = w.Write([]byte(`{"error_code":"url_too_long"}`))
+	}))
+	mw := NewRequestSizeLimitMiddleware(5, 0)
+	rc := httptest.NewRecorder()
+	mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})).ServeHTTP(rc, httptest.NewRequest(http.MethodGet, "/too-long", nil))
+	assert.Equal(t, http.StatusRequestURITooLong, code)
+	assert.Equal(t, http.StatusRequestURITooLong, rc.Code)
+	assert.Equal(t, `{"error_code":"url_too_long"}`, rc.Body.String())
+}
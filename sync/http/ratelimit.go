@@ -0,0 +1,125 @@
+package http
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// RateLimiterStore defines the storage of the rate limits, which decides if a request identified by a key is allowed.
+// Implementations backed by a shared store (e.g. Redis) enforce a global limit across all replicas of a service.
+type RateLimiterStore interface {
+	// Allow returns true if the request is allowed, otherwise false and the duration after which to retry.
+	Allow(key string) (bool, time.Duration)
+}
+
+// RateLimitKeyFunc definition of a function which returns the key a request is rate limited by.
+type RateLimitKeyFunc func(*http.Request) string
+
+// NewRateLimitMiddleware creates a MiddlewareFunc which rejects requests exceeding the limits of the store
+// with a 429 Too Many Requests status and a Retry-After header.
+// Requests are rate limited per key, which by default is the client IP of the request.
+func NewRateLimitMiddleware(store RateLimiterStore, keyFunc RateLimitKeyFunc) MiddlewareFunc {
+	if keyFunc == nil {
+		keyFunc = ClientIP
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			allowed, retryAfter := store.Allow(keyFunc(r))
+			if !allowed {
+				w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+				http.Error(w, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// NewRateLimitingMiddleware creates a MiddlewareFunc which limits the requests to limit requests per second,
+// with bursts of at most burst requests, regardless of the client, e.g. in order to cap the requests of an expensive route.
+// Rejected requests are responded with 429 Too Many Requests and a Retry-After header, without calling the handler.
+// A middleware limits the requests of all the routes it is applied to together, so a limit per route requires a middleware per route.
+func NewRateLimitingMiddleware(limit float64, burst int) (MiddlewareFunc, error) {
+	store, err := NewMemoryRateLimiterStore(limit, burst)
+	if err != nil {
+		return nil, err
+	}
+	return NewRateLimitMiddleware(store, func(*http.Request) string { return "" }), nil
+}
+
+const memoryStoreSweepInterval = 1024
+
+// MemoryRateLimiterStore is an in-process token bucket RateLimiterStore, which limits requests per key.
+type MemoryRateLimiterStore struct {
+	sync.Mutex
+	limit   float64
+	burst   float64
+	buckets map[string]*tokenBucket
+	calls   int
+	now     func() time.Time
+}
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// NewMemoryRateLimiterStore creates an in-process store allowing limit requests per second per key,
+// with bursts of at most burst requests.
+func NewMemoryRateLimiterStore(limit float64, burst int) (*MemoryRateLimiterStore, error) {
+	if limit <= 0 {
+		return nil, errors.New("limit must be greater than 0")
+	}
+	if burst <= 0 {
+		return nil, errors.New("burst must be greater than 0")
+	}
+	return &MemoryRateLimiterStore{
+		limit:   limit,
+		burst:   float64(burst),
+		buckets: make(map[string]*tokenBucket),
+		now:     time.Now,
+	}, nil
+}
+
+// Allow takes a token from the bucket of the key, if available.
+func (s *MemoryRateLimiterStore) Allow(key string) (bool, time.Duration) {
+	s.Lock()
+	defer s.Unlock()
+	now := s.now()
+
+	s.calls++
+	if s.calls%memoryStoreSweepInterval == 0 {
+		s.sweep(now)
+	}
+
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &tokenBucket{tokens: s.burst, last: now}
+		s.buckets[key] = b
+	}
+	s.refill(b, now)
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	return false, time.Duration((1 - b.tokens) / s.limit * float64(time.Second))
+}
+
+func (s *MemoryRateLimiterStore) refill(b *tokenBucket, now time.Time) {
+	b.tokens = math.Min(s.burst, b.tokens+now.Sub(b.last).Seconds()*s.limit)
+	b.last = now
+}
+
+// sweep removes the full buckets, which are equivalent to missing ones, in order to bound memory usage.
+func (s *MemoryRateLimiterStore) sweep(now time.Time) {
+	for k, b := range s.buckets {
+		s.refill(b, now)
+		if b.tokens >= s.burst {
+			delete(s.buckets, k)
+		}
+	}
+}
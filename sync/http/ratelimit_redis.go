@@ -0,0 +1,94 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"time"
+
+	"github.com/beatlabs/patron/log"
+)
+
+// RedisScripter is the subset of a Redis client required by the RedisRateLimiterStore, which evaluates a Lua script
+// with the provided keys and arguments and returns its reply, e.g. an adapter of the Eval method of a Redis client.
+type RedisScripter interface {
+	Eval(script string, keys []string, args ...interface{}) (interface{}, error)
+}
+
+// slidingWindowScript keeps the requests of the window in a sorted set scored by their time in milliseconds.
+// It returns {1, 0} if the request is allowed, otherwise {0, retryAfter} with the milliseconds until the oldest request
+// of the window expires.
+const slidingWindowScript = `
+local now = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local limit = tonumber(ARGV[3])
+redis.call('ZREMRANGEBYSCORE', KEYS[1], '-inf', now - window)
+if redis.call('ZCARD', KEYS[1]) < limit then
+	redis.call('ZADD', KEYS[1], now, ARGV[4])
+	redis.call('PEXPIRE', KEYS[1], window)
+	return {1, 0}
+end
+local oldest = redis.call('ZRANGE', KEYS[1], 0, 0, 'WITHSCORES')
+return {0, tonumber(oldest[2]) + window - now}
+`
+
+const redisRateLimitKeyPrefix = "patron:ratelimit:"
+
+// RedisRateLimiterStore is a sliding window RateLimiterStore backed by Redis, which enforces a global limit per key
+// across all the replicas of a service sharing the Redis instance.
+type RedisRateLimiterStore struct {
+	client RedisScripter
+	limit  int
+	window time.Duration
+	now    func() time.Time
+}
+
+// NewRedisRateLimiterStore creates a store backed by Redis allowing limit requests per key within a sliding window.
+func NewRedisRateLimiterStore(client RedisScripter, limit int, window time.Duration) (*RedisRateLimiterStore, error) {
+	if client == nil {
+		return nil, errors.New("redis client is nil")
+	}
+	if limit <= 0 {
+		return nil, errors.New("limit must be greater than 0")
+	}
+	if window < time.Millisecond {
+		return nil, errors.New("window must be at least a millisecond")
+	}
+	return &RedisRateLimiterStore{client: client, limit: limit, window: window, now: time.Now}, nil
+}
+
+// Allow records the request in the window of the key, if the limit of the window has not been reached.
+// Requests are allowed when Redis is unavailable, so that an outage of Redis does not take down the service.
+func (s *RedisRateLimiterStore) Allow(key string) (bool, time.Duration) {
+	now := s.now().UnixNano() / int64(time.Millisecond)
+	member := strconv.FormatInt(now, 10) + "-" + strconv.FormatInt(rand.Int63(), 36)
+	reply, err := s.client.Eval(slidingWindowScript, []string{redisRateLimitKeyPrefix + key},
+		now, s.window.Milliseconds(), s.limit, member)
+	if err != nil {
+		log.Warnf("failed to evaluate the rate limit of key %s, allowing the request: %v", key, err)
+		return true, 0
+	}
+	allowed, retryAfter, err := parseSlidingWindowReply(reply)
+	if err != nil {
+		log.Warnf("failed to parse the rate limit of key %s, allowing the request: %v", key, err)
+		return true, 0
+	}
+	return allowed, retryAfter
+}
+
+func parseSlidingWindowReply(reply interface{}) (bool, time.Duration, error) {
+	rr, ok := reply.([]interface{})
+	if !ok || len(rr) != 2 {
+		return false, 0, fmt.Errorf("unexpected reply %v", reply)
+	}
+	allowed, ok := rr[0].(int64)
+	if !ok {
+		return false, 0, fmt.Errorf("unexpected reply %v", reply)
+	}
+	retryAfter, ok := rr[1].(int64)
+	if !ok {
+		return false, 0, fmt.Errorf("unexpected reply %v", reply)
+	}
+	return allowed == 1, time.Duration(retryAfter) * time.Millisecond, nil
+}
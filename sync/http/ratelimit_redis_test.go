@@ -0,0 +1,111 @@
+package http
+
+import (
+	"errors"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// windowRedis emulates the evaluation of the sliding window script on the sorted sets of Redis.
+type windowRedis struct {
+	sets  map[string][]int64
+	err   error
+	reply interface{}
+}
+
+func (r *windowRedis) Eval(script string, keys []string, args ...interface{}) (interface{}, error) {
+	if r.err != nil || r.reply != nil {
+		return r.reply, r.err
+	}
+	now, window, limit := args[0].(int64), args[1].(int64), args[2].(int)
+	var set []int64
+	for _, score := range r.sets[keys[0]] {
+		if score > now-window {
+			set = append(set, score)
+		}
+	}
+	r.sets[keys[0]] = set
+	if len(set) < limit {
+		r.sets[keys[0]] = append(set, now)
+		return []interface{}{int64(1), int64(0)}, nil
+	}
+	sort.Slice(set, func(i, j int) bool { return set[i] < set[j] })
+	return []interface{}{int64(0), set[0] + window - now}, nil
+}
+
+func TestNewRedisRateLimiterStore(t *testing.T) {
+	tests := map[string]struct {
+		client  RedisScripter
+		limit   int
+		window  time.Duration
+		wantErr bool
+	}{
+		"success":        {client: &windowRedis{}, limit: 1, window: time.Second},
+		"missing client": {limit: 1, window: time.Second, wantErr: true},
+		"invalid limit":  {client: &windowRedis{}, limit: 0, window: time.Second, wantErr: true},
+		"invalid window": {client: &windowRedis{}, limit: 1, window: time.Microsecond, wantErr: true},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := NewRedisRateLimiterStore(tt.client, tt.limit, tt.window)
+			if tt.wantErr {
+				assert.Error(t, err)
+				assert.Nil(t, got)
+			} else {
+				assert.NoError(t, err)
+				assert.NotNil(t, got)
+			}
+		})
+	}
+}
+
+func TestRedisRateLimiterStore_Allow(t *testing.T) {
+	r := &windowRedis{sets: make(map[string][]int64)}
+	s, err := NewRedisRateLimiterStore(r, 2, time.Second)
+	require.NoError(t, err)
+	now := time.Now()
+	s.now = func() time.Time { return now }
+
+	allowed, _ := s.Allow("a")
+	assert.True(t, allowed)
+	now = now.Add(400 * time.Millisecond)
+	allowed, _ = s.Allow("a")
+	assert.True(t, allowed)
+	allowed, retryAfter := s.Allow("a")
+	assert.False(t, allowed)
+	assert.Equal(t, 600*time.Millisecond, retryAfter)
+
+	// other keys have their own window
+	allowed, _ = s.Allow("b")
+	assert.True(t, allowed)
+
+	// the window slides past the first request
+	now = now.Add(600 * time.Millisecond)
+	allowed, _ = s.Allow("a")
+	assert.True(t, allowed)
+	allowed, retryAfter = s.Allow("a")
+	assert.False(t, allowed)
+	assert.Equal(t, 400*time.Millisecond, retryAfter)
+	assert.Len(t, r.sets[redisRateLimitKeyPrefix+"a"], 2)
+}
+
+func TestRedisRateLimiterStore_Allow_Unavailable(t *testing.T) {
+	tests := map[string]*windowRedis{
+		"eval error":       {err: errors.New("connection refused")},
+		"unexpected reply": {reply: "OK"},
+		"malformed reply":  {reply: []interface{}{"1", int64(0)}},
+	}
+	for name, r := range tests {
+		t.Run(name, func(t *testing.T) {
+			s, err := NewRedisRateLimiterStore(r, 1, time.Second)
+			require.NoError(t, err)
+			allowed, retryAfter := s.Allow("a")
+			assert.True(t, allowed)
+			assert.Zero(t, retryAfter)
+		})
+	}
+}
@@ -0,0 +1,136 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewMemoryRateLimiterStore(t *testing.T) {
+	tests := map[string]struct {
+		limit   float64
+		burst   int
+		wantErr bool
+	}{
+		"success":       {limit: 1, burst: 1},
+		"invalid limit": {limit: 0, burst: 1, wantErr: true},
+		"invalid burst": {limit: 1, burst: 0, wantErr: true},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := NewMemoryRateLimiterStore(tt.limit, tt.burst)
+			if tt.wantErr {
+				assert.Error(t, err)
+				assert.Nil(t, got)
+			} else {
+				assert.NoError(t, err)
+				assert.NotNil(t, got)
+			}
+		})
+	}
+}
+
+func TestMemoryRateLimiterStore_Allow(t *testing.T) {
+	s, err := NewMemoryRateLimiterStore(2, 3)
+	assert.NoError(t, err)
+	now := time.Now()
+	s.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		allowed, _ := s.Allow("a")
+		assert.True(t, allowed)
+	}
+	allowed, retryAfter := s.Allow("a")
+	assert.False(t, allowed)
+	assert.Equal(t, 500*time.Millisecond, retryAfter)
+
+	// other keys have their own bucket
+	allowed, _ = s.Allow("b")
+	assert.True(t, allowed)
+
+	now = now.Add(500 * time.Millisecond)
+	allowed, _ = s.Allow("a")
+	assert.True(t, allowed)
+	allowed, _ = s.Allow("a")
+	assert.False(t, allowed)
+
+	// full buckets are swept
+	now = now.Add(time.Hour)
+	s.sweep(now)
+	assert.Empty(t, s.buckets)
+}
+
+type mockRateLimiterStore struct {
+	allowed bool
+	keys    []string
+}
+
+func (m *mockRateLimiterStore) Allow(key string) (bool, time.Duration) {
+	m.keys = append(m.keys, key)
+	return m.allowed, 1500 * time.Millisecond
+}
+
+func TestNewRateLimitMiddleware(t *testing.T) {
+	tests := map[string]struct {
+		allowed    bool
+		keyFunc    RateLimitKeyFunc
+		wantKey    string
+		wantStatus int
+		wantRetry  string
+	}{
+		"allowed by client ip": {allowed: true, wantKey: "1.2.3.4", wantStatus: http.StatusAccepted},
+		"rejected":             {allowed: false, wantKey: "1.2.3.4", wantStatus: http.StatusTooManyRequests, wantRetry: "2"},
+		"custom key": {allowed: true, keyFunc: func(r *http.Request) string { return r.Header.Get("X-Api-Key") },
+			wantKey: "key", wantStatus: http.StatusAccepted},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			store := &mockRateLimiterStore{allowed: tt.allowed}
+			h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusAccepted)
+			})
+			req, err := http.NewRequest(http.MethodGet, "/", nil)
+			assert.NoError(t, err)
+			req.RemoteAddr = "1.2.3.4:1000"
+			req.Header.Set("X-Api-Key", "key")
+			rc := httptest.NewRecorder()
+			NewRateLimitMiddleware(store, tt.keyFunc)(h).ServeHTTP(rc, req)
+			assert.Equal(t, tt.wantStatus, rc.Code)
+			assert.Equal(t, tt.wantRetry, rc.Header().Get("Retry-After"))
+			assert.Equal(t, []string{tt.wantKey}, store.keys)
+		})
+	}
+}
+
+func TestNewRateLimitingMiddleware(t *testing.T) {
+	_, err := NewRateLimitingMiddleware(1, 0)
+	assert.EqualError(t, err, "burst must be greater than 0")
+	_, err = NewRateLimitingMiddleware(0, 1)
+	assert.EqualError(t, err, "limit must be greater than 0")
+
+	// the limit is low enough for no token to be refilled during the test
+	mw, err := NewRateLimitingMiddleware(0.0001, 3)
+	assert.NoError(t, err)
+	calls := 0
+	route := NewRouteRaw("/expensive", http.MethodGet, func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusOK)
+	}, false, mw)
+
+	h := MiddlewareChain(route.Handler, route.Middlewares...)
+	codes := make(map[int]int)
+	for i := 0; i < 10; i++ {
+		req := httptest.NewRequest(http.MethodGet, "/expensive", nil)
+		// the limit applies regardless of the client
+		req.RemoteAddr = "1.2.3." + strconv.Itoa(i) + ":1000"
+		rc := httptest.NewRecorder()
+		h.ServeHTTP(rc, req)
+		codes[rc.Code]++
+	}
+	assert.Equal(t, map[int]int{http.StatusOK: 3, http.StatusTooManyRequests: 7}, codes)
+	assert.Equal(t, 3, calls)
+}
@@ -2,6 +2,10 @@ package http
 
 import (
 	"net/http"
+	"time"
+
+	"github.com/beatlabs/patron/log"
+	"github.com/beatlabs/patron/reliability/errorrate"
 )
 
 // ReadyStatus type.
@@ -12,19 +16,136 @@ const (
 	Ready ReadyStatus = 1
 	// NotReady represents a state defining a NotReady state.
 	NotReady ReadyStatus = 2
+	// Degraded represents a state defining a Ready state with a degraded quality of service, e.g. due to a high error rate.
+	Degraded ReadyStatus = 3
+)
+
+const (
+	degradedBody = "degraded"
+	startingBody = "starting"
 )
 
 // ReadyCheckFunc defines a function type for implementing a readiness check.
 type ReadyCheckFunc func() ReadyStatus
 
-func readyCheckRoute(rcf ReadyCheckFunc) Route {
+// ErrorRateReadyCheck wraps a readiness check and reports Degraded when the service is ready
+// but the error rate of any of the trackers exceeds its threshold.
+func ErrorRateReadyCheck(rcf ReadyCheckFunc, trackers ...*errorrate.Tracker) ReadyCheckFunc {
+	return func() ReadyStatus {
+		st := rcf()
+		if st != Ready {
+			return st
+		}
+		for _, t := range trackers {
+			if t.Exceeded() {
+				return Degraded
+			}
+		}
+		return st
+	}
+}
+
+// ConsumersReadyCheck wraps a readiness check and reports NotReady when the service is ready
+// but any of the consumers is not, e.g. a Kafka group consumer which was not assigned partitions yet.
+func ConsumersReadyCheck(rcf ReadyCheckFunc, rr ...func() bool) ReadyCheckFunc {
+	return func() ReadyStatus {
+		st := rcf()
+		if st == NotReady {
+			return st
+		}
+		for _, ready := range rr {
+			if !ready() {
+				return NotReady
+			}
+		}
+		return st
+	}
+}
+
+// TimeoutReadyCheck wraps a readiness check, which reports the onTimeout status when the check does not complete
+// within the timeout, so a slow dependency does not hang the readiness probe.
+// A check which hangs keeps running in the background, since checks cannot be canceled.
+func TimeoutReadyCheck(rcf ReadyCheckFunc, timeout time.Duration, onTimeout ReadyStatus) ReadyCheckFunc {
+	return func() ReadyStatus {
+		ch := make(chan ReadyStatus, 1)
+		go func() {
+			ch <- rcf()
+		}()
+		tm := time.NewTimer(timeout)
+		defer tm.Stop()
+		select {
+		case st := <-ch:
+			return st
+		case <-tm.C:
+			log.Warnf("readiness check did not complete within %v, reporting status %d", timeout, onTimeout)
+			return onTimeout
+		}
+	}
+}
+
+// CompositeReadyCheck runs the readiness checks concurrently and reports the worst of their statuses,
+// where NotReady is worse than Degraded, which is worse than Ready.
+// The checks which do not complete within the limit, which caps the duration of the whole check, are reported as NotReady.
+// Individual deadlines are set by wrapping the checks with TimeoutReadyCheck.
+func CompositeReadyCheck(limit time.Duration, checks ...ReadyCheckFunc) ReadyCheckFunc {
+	return func() ReadyStatus {
+		ch := make(chan ReadyStatus, len(checks))
+		for _, rcf := range checks {
+			go func(rcf ReadyCheckFunc) {
+				ch <- rcf()
+			}(rcf)
+		}
+		tm := time.NewTimer(limit)
+		defer tm.Stop()
+		st := Ready
+		for range checks {
+			select {
+			case s := <-ch:
+				st = worse(st, s)
+			case <-tm.C:
+				log.Warnf("readiness checks did not complete within %v", limit)
+				return NotReady
+			}
+		}
+		return st
+	}
+}
+
+func worse(a, b ReadyStatus) ReadyStatus {
+	rank := func(st ReadyStatus) int {
+		switch st {
+		case NotReady:
+			return 2
+		case Degraded:
+			return 1
+		default:
+			return 0
+		}
+	}
+	if rank(b) > rank(a) {
+		return b
+	}
+	return a
+}
+
+// readyCheckRoute reports the status of the readiness check, after the startup check reports that the service has started,
+// while before that it responds with 503 Service Unavailable and a "starting" body.
+func readyCheckRoute(rcf ReadyCheckFunc, scf StartupCheckFunc, degradedStatusCode int) Route {
 
 	f := func(w http.ResponseWriter, r *http.Request) {
+		if !scf() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			_, _ = w.Write([]byte(startingBody))
+			return
+		}
 		switch rcf() {
 		case Ready:
 			w.WriteHeader(http.StatusOK)
 		case NotReady:
 			w.WriteHeader(http.StatusServiceUnavailable)
+		case Degraded:
+			w.WriteHeader(degradedStatusCode)
+			_, _ = w.Write([]byte(degradedBody))
 		default:
 			w.WriteHeader(http.StatusOK)
 		}
@@ -4,28 +4,113 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
+	"github.com/beatlabs/patron/reliability/errorrate"
 	"github.com/stretchr/testify/assert"
 )
 
 func Test_readyCheckRoute(t *testing.T) {
+	notStarted := func() bool { return false }
 	tests := []struct {
-		name string
-		rcf  ReadyCheckFunc
-		want int
+		name     string
+		rcf      ReadyCheckFunc
+		scf      StartupCheckFunc
+		degraded int
+		want     int
+		wantBody string
 	}{
-		{"ready", func() ReadyStatus { return Ready }, http.StatusOK},
-		{"notReady", func() ReadyStatus { return NotReady }, http.StatusServiceUnavailable},
-		{"default", func() ReadyStatus { return 10 }, http.StatusOK},
+		{"ready", func() ReadyStatus { return Ready }, DefaultStartupCheck, http.StatusOK, http.StatusOK, ""},
+		{"notReady", func() ReadyStatus { return NotReady }, DefaultStartupCheck, http.StatusOK, http.StatusServiceUnavailable, ""},
+		{"degraded", func() ReadyStatus { return Degraded }, DefaultStartupCheck, http.StatusOK, http.StatusOK, "degraded"},
+		{"degraded unavailable", func() ReadyStatus { return Degraded }, DefaultStartupCheck, http.StatusServiceUnavailable, http.StatusServiceUnavailable, "degraded"},
+		{"default", func() ReadyStatus { return 10 }, DefaultStartupCheck, http.StatusOK, http.StatusOK, ""},
+		{"starting", func() ReadyStatus { return Ready }, notStarted, http.StatusOK, http.StatusServiceUnavailable, "starting"},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			r := readyCheckRoute(tt.rcf)
+			r := readyCheckRoute(tt.rcf, tt.scf, tt.degraded)
 			resp := httptest.NewRecorder()
 			req, err := http.NewRequest("GET", "/alive", nil)
 			assert.NoError(t, err)
 			r.Handler(resp, req)
 			assert.Equal(t, tt.want, resp.Code)
+			assert.Equal(t, tt.wantBody, resp.Body.String())
+		})
+	}
+}
+
+func TestErrorRateReadyCheck(t *testing.T) {
+	tr, err := errorrate.New(errorrate.Setting{Window: time.Minute, Threshold: 0.5, MinEvents: 2})
+	assert.NoError(t, err)
+
+	rcf := ErrorRateReadyCheck(DefaultReadyCheck, tr)
+	assert.Equal(t, Ready, rcf())
+	tr.Failure()
+	assert.Equal(t, Ready, rcf())
+	tr.Failure()
+	assert.Equal(t, Degraded, rcf())
+	assert.Equal(t, NotReady, ErrorRateReadyCheck(func() ReadyStatus { return NotReady }, tr)())
+}
+
+func TestConsumersReadyCheck(t *testing.T) {
+	ready := false
+	rcf := ConsumersReadyCheck(DefaultReadyCheck, func() bool { return true }, func() bool { return ready })
+	assert.Equal(t, NotReady, rcf())
+	ready = true
+	assert.Equal(t, Ready, rcf())
+	assert.Equal(t, Degraded, ConsumersReadyCheck(readyCheck(Degraded), func() bool { return true })())
+	assert.Equal(t, NotReady, ConsumersReadyCheck(readyCheck(NotReady), func() bool { return true })())
+}
+
+func readyCheck(st ReadyStatus) ReadyCheckFunc {
+	return func() ReadyStatus { return st }
+}
+
+func hangingReadyCheck(release <-chan struct{}) ReadyCheckFunc {
+	return func() ReadyStatus {
+		<-release
+		return Ready
+	}
+}
+
+func TestTimeoutReadyCheck(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+
+	assert.Equal(t, Degraded, TimeoutReadyCheck(readyCheck(Degraded), time.Second, NotReady)())
+
+	start := time.Now()
+	assert.Equal(t, Degraded, TimeoutReadyCheck(hangingReadyCheck(release), 20*time.Millisecond, Degraded)())
+	assert.True(t, time.Since(start) < time.Second)
+}
+
+func TestCompositeReadyCheck(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+
+	tests := map[string]struct {
+		checks []ReadyCheckFunc
+		want   ReadyStatus
+	}{
+		"no checks":     {want: Ready},
+		"all ready":     {checks: []ReadyCheckFunc{readyCheck(Ready), readyCheck(Ready)}, want: Ready},
+		"one degraded":  {checks: []ReadyCheckFunc{readyCheck(Ready), readyCheck(Degraded)}, want: Degraded},
+		"one not ready": {checks: []ReadyCheckFunc{readyCheck(NotReady), readyCheck(Degraded)}, want: NotReady},
+		"hanging check with timeout": {
+			checks: []ReadyCheckFunc{readyCheck(Ready), TimeoutReadyCheck(hangingReadyCheck(release), 20*time.Millisecond, Degraded)},
+			want:   Degraded,
+		},
+		"hanging check exceeding the limit": {
+			checks: []ReadyCheckFunc{readyCheck(Ready), hangingReadyCheck(release)},
+			want:   NotReady,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			start := time.Now()
+			assert.Equal(t, tt.want, CompositeReadyCheck(100*time.Millisecond, tt.checks...)())
+			assert.True(t, time.Since(start) < time.Second)
 		})
 	}
 }
@@ -0,0 +1,44 @@
+package http
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// NewRequestSizeLimitMiddleware creates a MiddlewareFunc which rejects requests with an URL, including the query string,
+// longer than maxURLLen with a 414 URI Too Long status and requests with headers larger than maxHeaderBytes,
+// counting the names and the values, with a 431 Request Header Fields Too Large status.
+// The responses are written by the error encoder, which defaults to problem details in JSON format.
+// A limit of zero or less is not enforced.
+func NewRequestSizeLimitMiddleware(maxURLLen, maxHeaderBytes int) MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if maxURLLen > 0 {
+				l := len(r.URL.RequestURI())
+				if l > maxURLLen {
+					writeError(w, r, http.StatusRequestURITooLong, fmt.Sprintf("URL length %d exceeds the limit of %d", l, maxURLLen))
+					return
+				}
+			}
+			if maxHeaderBytes > 0 {
+				l := headerSize(r.Header)
+				if l > maxHeaderBytes {
+					writeError(w, r, http.StatusRequestHeaderFieldsTooLarge,
+						fmt.Sprintf("header size %d exceeds the limit of %d", l, maxHeaderBytes))
+					return
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func headerSize(h http.Header) int {
+	size := 0
+	for k, vv := range h {
+		for _, v := range vv {
+			size += len(k) + len(v)
+		}
+	}
+	return size
+}
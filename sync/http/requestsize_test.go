@@ -0,0 +1,48 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewRequestSizeLimitMiddleware(t *testing.T) {
+	tests := map[string]struct {
+		url            string
+		header         string
+		maxURLLen      int
+		maxHeaderBytes int
+		wantStatus     int
+	}{
+		"within limits":       {url: "/test?q=1", header: "v", maxURLLen: 20, maxHeaderBytes: 20, wantStatus: http.StatusAccepted},
+		"long query string":   {url: "/test?q=" + strings.Repeat("a", 100), maxURLLen: 20, wantStatus: http.StatusRequestURITooLong},
+		"large headers":       {url: "/test", header: strings.Repeat("a", 100), maxHeaderBytes: 20, wantStatus: http.StatusRequestHeaderFieldsTooLarge},
+		"limits not enforced": {url: "/test?q=" + strings.Repeat("a", 100), header: strings.Repeat("a", 100), wantStatus: http.StatusAccepted},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusAccepted)
+			})
+			req, err := http.NewRequest(http.MethodGet, tt.url, nil)
+			assert.NoError(t, err)
+			req.Header.Set("X-Test", tt.header)
+			rc := httptest.NewRecorder()
+			NewRequestSizeLimitMiddleware(tt.maxURLLen, tt.maxHeaderBytes)(h).ServeHTTP(rc, req)
+			assert.Equal(t, tt.wantStatus, rc.Code)
+			if tt.wantStatus == http.StatusAccepted {
+				return
+			}
+			assert.Equal(t, "application/problem+json", rc.Header().Get("Content-Type"))
+			var got problem
+			assert.NoError(t, json.Unmarshal(rc.Body.Bytes(), &got))
+			assert.Equal(t, tt.wantStatus, got.Status)
+			assert.Equal(t, http.StatusText(tt.wantStatus), got.Title)
+			assert.NotEmpty(t, got.Detail)
+		})
+	}
+}
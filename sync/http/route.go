@@ -1,7 +1,9 @@
 package http
 
 import (
+	"fmt"
 	"net/http"
+	"strings"
 
 	"github.com/beatlabs/patron/sync"
 	"github.com/beatlabs/patron/sync/http/auth"
@@ -15,6 +17,38 @@ type Route struct {
 	Trace       bool
 	Auth        auth.Authenticator
 	Middlewares []MiddlewareFunc
+	Deprecation *Deprecation
+	// policyAuth is the authenticator of the route, which is subject to the auth policy of the component.
+	policyAuth *policyAuthenticator
+}
+
+var methods = map[string]struct{}{
+	http.MethodGet:     {},
+	http.MethodHead:    {},
+	http.MethodPost:    {},
+	http.MethodPut:     {},
+	http.MethodPatch:   {},
+	http.MethodDelete:  {},
+	http.MethodConnect: {},
+	http.MethodOptions: {},
+	http.MethodTrace:   {},
+}
+
+// validate checks the route for the mistakes which would otherwise surface only when the route is served.
+func (r Route) validate() error {
+	if r.Pattern == "" {
+		return fmt.Errorf("route %s has an empty path", r.Method)
+	}
+	if !strings.HasPrefix(r.Pattern, "/") {
+		return fmt.Errorf("route %s %s has a path not starting with '/'", r.Method, r.Pattern)
+	}
+	if _, ok := methods[r.Method]; !ok {
+		return fmt.Errorf("route %s has an invalid method '%s'", r.Pattern, r.Method)
+	}
+	if r.Handler == nil {
+		return fmt.Errorf("route %s %s has a nil handler", r.Method, r.Pattern)
+	}
+	return nil
 }
 
 // NewGetRoute creates a new GET route from a generic handler.
@@ -58,13 +92,19 @@ func NewRoute(p string, m string, pr sync.ProcessorFunc, trace bool, auth auth.A
 	if trace {
 		middlewares = append(middlewares, NewLoggingTracingMiddleware(p))
 	}
-	if auth != nil {
-		middlewares = append(middlewares, NewAuthMiddleware(auth))
+	pa := newPolicyAuthenticator(m, p, auth)
+	if pa != nil {
+		middlewares = append(middlewares, NewAuthMiddleware(pa))
 	}
 	if len(mm) > 0 {
 		middlewares = append(middlewares, mm...)
 	}
-	return Route{Pattern: p, Method: m, Handler: handler(pr), Trace: trace, Auth: auth, Middlewares: middlewares}
+	var h http.HandlerFunc
+	// a nil processor is rejected when the route is registered
+	if pr != nil {
+		h = handler(pr)
+	}
+	return Route{Pattern: p, Method: m, Handler: h, Trace: trace, Auth: auth, Middlewares: middlewares, policyAuth: pa}
 }
 
 // NewRouteRaw creates a new route from a HTTP handler.
@@ -120,11 +160,12 @@ func NewAuthRouteRaw(p string, m string, h http.HandlerFunc, trace bool, auth au
 	if trace {
 		middlewares = append(middlewares, NewLoggingTracingMiddleware(p))
 	}
-	if auth != nil {
-		middlewares = append(middlewares, NewAuthMiddleware(auth))
+	pa := newPolicyAuthenticator(m, p, auth)
+	if pa != nil {
+		middlewares = append(middlewares, NewAuthMiddleware(pa))
 	}
 	if len(mm) > 0 {
 		middlewares = append(middlewares, mm...)
 	}
-	return Route{Pattern: p, Method: m, Handler: h, Trace: trace, Auth: auth, Middlewares: middlewares}
+	return Route{Pattern: p, Method: m, Handler: h, Trace: trace, Auth: auth, Middlewares: middlewares, policyAuth: pa}
 }
@@ -0,0 +1,67 @@
+package http
+
+import (
+	"net/http"
+)
+
+const (
+	headerContentTypeOptions      = "X-Content-Type-Options"
+	headerFrameOptions            = "X-Frame-Options"
+	headerStrictTransportSecurity = "Strict-Transport-Security"
+	headerContentSecurityPolicy   = "Content-Security-Policy"
+
+	// SecurityHeaderOmitted can be set as the value of a security header in order for the header not to be sent.
+	SecurityHeaderOmitted = "-"
+)
+
+// SecurityHeadersConfig holds the values of the security headers sent with every response.
+// Empty values are replaced by secure defaults, while SecurityHeaderOmitted omits the header.
+type SecurityHeadersConfig struct {
+	// ContentTypeOptions defaults to "nosniff".
+	ContentTypeOptions string
+	// FrameOptions defaults to "DENY".
+	FrameOptions string
+	// StrictTransportSecurity defaults to "max-age=63072000; includeSubDomains" and is only sent over TLS.
+	StrictTransportSecurity string
+	// ContentSecurityPolicy defaults to "default-src 'self'".
+	ContentSecurityPolicy string
+}
+
+// NewSecurityHeadersMiddleware creates a MiddlewareFunc which sets the security headers of the config on every response.
+// The headers are set before calling the next handler, which can therefore override them.
+func NewSecurityHeadersMiddleware(cfg SecurityHeadersConfig) MiddlewareFunc {
+	headers := securityHeaders(map[string]string{
+		headerContentTypeOptions:    withDefault(cfg.ContentTypeOptions, "nosniff"),
+		headerFrameOptions:          withDefault(cfg.FrameOptions, "DENY"),
+		headerContentSecurityPolicy: withDefault(cfg.ContentSecurityPolicy, "default-src 'self'"),
+	})
+	hsts := withDefault(cfg.StrictTransportSecurity, "max-age=63072000; includeSubDomains")
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			for k, v := range headers {
+				w.Header().Set(k, v)
+			}
+			// HSTS is ignored by browsers over plaintext and is only meaningful over TLS
+			if r.TLS != nil && hsts != SecurityHeaderOmitted {
+				w.Header().Set(headerStrictTransportSecurity, hsts)
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func securityHeaders(hh map[string]string) map[string]string {
+	for k, v := range hh {
+		if v == SecurityHeaderOmitted {
+			delete(hh, k)
+		}
+	}
+	return hh
+}
+
+func withDefault(v, def string) string {
+	if v == "" {
+		return def
+	}
+	return v
+}
@@ -0,0 +1,75 @@
+package http
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewSecurityHeadersMiddleware(t *testing.T) {
+	tests := map[string]struct {
+		cfg  SecurityHeadersConfig
+		tls  bool
+		want map[string]string
+	}{
+		"defaults over plaintext": {
+			want: map[string]string{
+				"X-Content-Type-Options":    "nosniff",
+				"X-Frame-Options":           "DENY",
+				"Content-Security-Policy":   "default-src 'self'",
+				"Strict-Transport-Security": "",
+			},
+		},
+		"defaults over TLS": {
+			tls: true,
+			want: map[string]string{
+				"X-Content-Type-Options":    "nosniff",
+				"X-Frame-Options":           "DENY",
+				"Content-Security-Policy":   "default-src 'self'",
+				"Strict-Transport-Security": "max-age=63072000; includeSubDomains",
+			},
+		},
+		"overrides": {
+			cfg: SecurityHeadersConfig{
+				FrameOptions:            "SAMEORIGIN",
+				ContentSecurityPolicy:   SecurityHeaderOmitted,
+				StrictTransportSecurity: "max-age=60",
+			},
+			tls: true,
+			want: map[string]string{
+				"X-Content-Type-Options":    "nosniff",
+				"X-Frame-Options":           "SAMEORIGIN",
+				"Content-Security-Policy":   "",
+				"Strict-Transport-Security": "max-age=60",
+			},
+		},
+		"HSTS omitted over TLS": {
+			cfg: SecurityHeadersConfig{StrictTransportSecurity: SecurityHeaderOmitted},
+			tls: true,
+			want: map[string]string{
+				"Strict-Transport-Security": "",
+			},
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusAccepted)
+			})
+			req, err := http.NewRequest(http.MethodGet, "/test", nil)
+			assert.NoError(t, err)
+			if tt.tls {
+				req.TLS = &tls.ConnectionState{}
+			}
+			rc := httptest.NewRecorder()
+			NewSecurityHeadersMiddleware(tt.cfg)(h).ServeHTTP(rc, req)
+			assert.Equal(t, http.StatusAccepted, rc.Code)
+			for k, v := range tt.want {
+				assert.Equal(t, v, rc.Header().Get(k), k)
+			}
+		})
+	}
+}
@@ -0,0 +1,47 @@
+package http
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/beatlabs/patron/correlation"
+)
+
+// settings are the settings of a component which apply to the handling of its requests, e.g. the request log sampling.
+// They are set on the context of the requests, so that the middlewares and the handlers of the routes apply the settings
+// of the component serving the request, instead of settings shared by the process.
+type settings struct {
+	logSampler *requestLogSampler
+	idStrategy correlation.Strategy
+	bufferPool bool
+}
+
+type settingsKey struct{}
+
+// defaultSettings apply to the requests which are not served by a component, e.g. of a middleware used on its own.
+var defaultSettings = &settings{}
+
+// settingsMiddleware sets the settings of the component on the context of its requests.
+func settingsMiddleware(s *settings) MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), settingsKey{}, s)))
+		})
+	}
+}
+
+// requestSettings returns the settings of the component serving the request.
+func requestSettings(r *http.Request) *settings {
+	if s, ok := r.Context().Value(settingsKey{}).(*settings); ok && s != nil {
+		return s
+	}
+	return defaultSettings
+}
+
+// newID generates a correlation ID with the strategy of the component, which defaults to correlation.NewID.
+func (s *settings) newID() string {
+	if s.idStrategy == nil {
+		return correlation.NewID()
+	}
+	return s.idStrategy()
+}
@@ -0,0 +1,57 @@
+package http
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	shedSourceHandler    = "handler"
+	shedSourceWorkerPool = "worker_pool"
+
+	workerPoolRetryAfter = time.Second
+)
+
+var shedRequests *prometheus.CounterVec
+
+func init() {
+	shedRequests = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "component",
+			Subsystem: "http",
+			Name:      "shed_requests",
+			Help:      "Requests rejected in order to shed load, classified by the source of the rejection",
+		},
+		[]string{"source"},
+	)
+	prometheus.MustRegister(shedRequests)
+}
+
+// Shed rejects a request in order to shed load, e.g. when the queue of a handler is full or a circuit is open,
+// by responding with 503 Service Unavailable and a Retry-After header of the provided duration, rounded up to seconds.
+// The framework sheds load, e.g. in the worker pool, with the same response and every rejection is counted in
+// the component_http_shed_requests metric.
+func Shed(w http.ResponseWriter, retryAfter time.Duration) {
+	shed(w, retryAfter, shedSourceHandler, false)
+}
+
+// ShedAndClose sheds load like Shed and additionally closes the connection after the response,
+// in order for persistent connections to be dropped and the clients to reconnect, e.g. to other instances.
+func ShedAndClose(w http.ResponseWriter, retryAfter time.Duration) {
+	shed(w, retryAfter, shedSourceHandler, true)
+}
+
+func shed(w http.ResponseWriter, retryAfter time.Duration, source string, closeConn bool) {
+	shedRequests.WithLabelValues(source).Inc()
+	if retryAfter > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+	}
+	if closeConn {
+		w.Header().Set("Connection", "close")
+	}
+	http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+}
@@ -0,0 +1,43 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShed(t *testing.T) {
+	tests := map[string]struct {
+		shed           func(http.ResponseWriter, time.Duration)
+		retryAfter     time.Duration
+		wantRetryAfter string
+		wantConnection string
+	}{
+		"shed":                   {shed: Shed, retryAfter: 1500 * time.Millisecond, wantRetryAfter: "2"},
+		"shed without retry":     {shed: Shed},
+		"shed and close":         {shed: ShedAndClose, retryAfter: time.Second, wantRetryAfter: "1", wantConnection: "close"},
+		"shed and close no wait": {shed: ShedAndClose, wantConnection: "close"},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			before := shedCount(t)
+			rc := httptest.NewRecorder()
+			tt.shed(rc, tt.retryAfter)
+			assert.Equal(t, http.StatusServiceUnavailable, rc.Code)
+			assert.Equal(t, "Service Unavailable\n", rc.Body.String())
+			assert.Equal(t, tt.wantRetryAfter, rc.Header().Get("Retry-After"))
+			assert.Equal(t, tt.wantConnection, rc.Header().Get("Connection"))
+			assert.Equal(t, before+1, shedCount(t))
+		})
+	}
+}
+
+func shedCount(t *testing.T) float64 {
+	m := &dto.Metric{}
+	assert.NoError(t, shedRequests.WithLabelValues(shedSourceHandler).Write(m))
+	return m.GetCounter().GetValue()
+}
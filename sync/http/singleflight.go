@@ -0,0 +1,121 @@
+package http
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// SingleflightKeyFunc definition of a function which returns the key used to coalesce identical requests.
+type SingleflightKeyFunc func(*http.Request) string
+
+// DefaultSingleflightKey returns a key consisting of the method, the URL and the authentication headers of the request,
+// which ensures that requests of different authentication contexts are never coalesced.
+func DefaultSingleflightKey(r *http.Request) string {
+	return strings.Join([]string{r.Method, r.URL.String(), r.Header.Get("Authorization"), r.Header.Get("Cookie")}, "\n")
+}
+
+// NewSingleflightMiddleware creates a MiddlewareFunc which coalesces identical in-flight requests,
+// executing the handler once and replaying the buffered response to all waiting requests.
+// Only GET and HEAD requests are coalesced. If the key func is nil the DefaultSingleflightKey is used.
+// The key func is responsible to include everything that differentiates responses, e.g. authentication headers.
+func NewSingleflightMiddleware(keyFunc SingleflightKeyFunc) MiddlewareFunc {
+	if keyFunc == nil {
+		keyFunc = DefaultSingleflightKey
+	}
+	g := &singleflightGroup{calls: make(map[string]*singleflightCall)}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodGet && r.Method != http.MethodHead {
+				next.ServeHTTP(w, r)
+				return
+			}
+			g.do(keyFunc(r), func(bw *bufferedResponseWriter) {
+				next.ServeHTTP(bw, r)
+			}).replay(w)
+		})
+	}
+}
+
+type singleflightCall struct {
+	wg  sync.WaitGroup
+	rsp *bufferedResponseWriter
+}
+
+type singleflightGroup struct {
+	sync.Mutex
+	calls map[string]*singleflightCall
+}
+
+func (g *singleflightGroup) do(key string, fn func(*bufferedResponseWriter)) *bufferedResponseWriter {
+	g.Lock()
+	if c, ok := g.calls[key]; ok {
+		g.Unlock()
+		c.wg.Wait()
+		return c.rsp
+	}
+	c := &singleflightCall{rsp: newBufferedResponseWriter()}
+	c.wg.Add(1)
+	g.calls[key] = c
+	g.Unlock()
+
+	defer func() {
+		g.Lock()
+		delete(g.calls, key)
+		g.Unlock()
+		c.wg.Done()
+	}()
+	fn(c.rsp)
+	c.rsp.completed = true
+	return c.rsp
+}
+
+// bufferedResponseWriter buffers a response in order to be replayed to many clients.
+type bufferedResponseWriter struct {
+	header    http.Header
+	status    int
+	body      bytes.Buffer
+	completed bool
+}
+
+func newBufferedResponseWriter() *bufferedResponseWriter {
+	return &bufferedResponseWriter{header: make(http.Header)}
+}
+
+// Header returns the header.
+func (w *bufferedResponseWriter) Header() http.Header {
+	return w.header
+}
+
+// Write writes to the buffer and sets the status if not set already.
+func (w *bufferedResponseWriter) Write(d []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.body.Write(d)
+}
+
+// WriteHeader saves the status if not set already.
+func (w *bufferedResponseWriter) WriteHeader(code int) {
+	if w.status == 0 {
+		w.status = code
+	}
+}
+
+// replay writes the buffered response. If the handler did not complete, e.g. due to a panic, an internal server error is written.
+func (w *bufferedResponseWriter) replay(rw http.ResponseWriter) {
+	if !w.completed {
+		http.Error(rw, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	status := w.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	for k, vv := range w.header {
+		rw.Header()[k] = append([]string(nil), vv...)
+	}
+	rw.WriteHeader(status)
+	_, _ = rw.Write(w.body.Bytes())
+}
@@ -0,0 +1,117 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewSingleflightMiddleware_Coalesce(t *testing.T) {
+	var execs int32
+	release := make(chan struct{})
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&execs, 1)
+		<-release
+		w.Header().Set("X-Test", "test")
+		w.WriteHeader(http.StatusAccepted)
+		_, _ = w.Write([]byte("payload"))
+	})
+	mw := NewSingleflightMiddleware(nil)(h)
+
+	const requests = 10
+	wg := sync.WaitGroup{}
+	wg.Add(requests)
+	rr := make([]*httptest.ResponseRecorder, requests)
+	for i := 0; i < requests; i++ {
+		rr[i] = httptest.NewRecorder()
+		go func(rc *httptest.ResponseRecorder) {
+			defer wg.Done()
+			req, err := http.NewRequest(http.MethodGet, "/test?a=1", nil)
+			assert.NoError(t, err)
+			mw.ServeHTTP(rc, req)
+		}(rr[i])
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&execs))
+	for _, rc := range rr {
+		assert.Equal(t, http.StatusAccepted, rc.Code)
+		assert.Equal(t, "test", rc.Header().Get("X-Test"))
+		assert.Equal(t, "payload", rc.Body.String())
+	}
+}
+
+func TestNewSingleflightMiddleware_NotCoalesced(t *testing.T) {
+	var execs int32
+	release := make(chan struct{})
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&execs, 1)
+		<-release
+		_, _ = w.Write([]byte("payload"))
+	})
+	mw := NewSingleflightMiddleware(nil)(h)
+
+	reqs := []struct {
+		method string
+		auth   string
+	}{
+		{method: http.MethodGet, auth: "user1"},
+		{method: http.MethodGet, auth: "user2"},
+		{method: http.MethodPost},
+		{method: http.MethodPost},
+	}
+	wg := sync.WaitGroup{}
+	wg.Add(len(reqs))
+	for _, r := range reqs {
+		req, err := http.NewRequest(r.method, "/test", nil)
+		assert.NoError(t, err)
+		req.Header.Set("Authorization", r.auth)
+		go func() {
+			defer wg.Done()
+			rc := httptest.NewRecorder()
+			mw.ServeHTTP(rc, req)
+			assert.Equal(t, http.StatusOK, rc.Code)
+			assert.Equal(t, "payload", rc.Body.String())
+		}()
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, int32(len(reqs)), atomic.LoadInt32(&execs))
+}
+
+func TestNewSingleflightMiddleware_Panic(t *testing.T) {
+	release := make(chan struct{})
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		panic("error")
+	})
+	mw := MiddlewareChain(h, NewRecoveryMiddleware(), NewSingleflightMiddleware(func(*http.Request) string { return "key" }))
+
+	rr := []*httptest.ResponseRecorder{httptest.NewRecorder(), httptest.NewRecorder()}
+	wg := sync.WaitGroup{}
+	wg.Add(len(rr))
+	for _, rc := range rr {
+		go func(rc *httptest.ResponseRecorder) {
+			defer wg.Done()
+			req, err := http.NewRequest(http.MethodGet, "/test", nil)
+			assert.NoError(t, err)
+			mw.ServeHTTP(rc, req)
+		}(rc)
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	for _, rc := range rr {
+		assert.Equal(t, http.StatusInternalServerError, rc.Code)
+	}
+}
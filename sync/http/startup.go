@@ -0,0 +1,35 @@
+package http
+
+import (
+	"net/http"
+	"sync/atomic"
+)
+
+// StartupCheckFunc defines a function type for implementing a startup check, which reports whether the service has started.
+type StartupCheckFunc func() bool
+
+// startedOnce returns a check which reports that the service has started from the first time the check does,
+// without calling it again, since a service starts only once.
+func startedOnce(scf StartupCheckFunc) StartupCheckFunc {
+	var started int32
+	return func() bool {
+		if atomic.LoadInt32(&started) == 1 || scf() {
+			atomic.StoreInt32(&started, 1)
+			return true
+		}
+		return false
+	}
+}
+
+// startupCheckRoute responds with 503 Service Unavailable until the check reports that the service has started,
+// and with 200 OK from then on.
+func startupCheckRoute(scf StartupCheckFunc) Route {
+	f := func(w http.ResponseWriter, r *http.Request) {
+		if scf() {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	return NewRouteRaw("/startup", http.MethodGet, f, false)
+}
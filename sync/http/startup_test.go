@@ -0,0 +1,41 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_startupCheckRoute(t *testing.T) {
+	started := false
+	calls := 0
+	r := startupCheckRoute(startedOnce(func() bool {
+		calls++
+		return started
+	}))
+	status := func() int {
+		resp := httptest.NewRecorder()
+		req, err := http.NewRequest("GET", "/startup", nil)
+		assert.NoError(t, err)
+		r.Handler(resp, req)
+		return resp.Code
+	}
+
+	assert.Equal(t, http.StatusServiceUnavailable, status())
+	started = true
+	assert.Equal(t, http.StatusOK, status())
+	// the service remains started without checking again
+	started = false
+	assert.Equal(t, http.StatusOK, status())
+	assert.Equal(t, 2, calls)
+}
+
+func TestBuilder_WithStartupCheckFunc(t *testing.T) {
+	_, err := NewBuilder().WithStartupCheckFunc(nil).Create()
+	assert.EqualError(t, err, "Nil StartupCheckFunc provided\n")
+	cmp, err := NewBuilder().WithStartupCheckFunc(func() bool { return false }).Create()
+	assert.NoError(t, err)
+	assert.False(t, cmp.sc())
+}
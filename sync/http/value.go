@@ -0,0 +1,75 @@
+package http
+
+import (
+	"context"
+
+	"github.com/beatlabs/patron/correlation"
+)
+
+// Key identifies a request scoped value, which middlewares store in the request context for handlers to retrieve.
+// Keys are compared by identity, so values stored with different keys never collide, even when the keys have
+// the same name, nor do they collide with values stored with raw context keys.
+type Key struct {
+	name string
+}
+
+// NewKey creates a key with a name, which is used only for debugging.
+func NewKey(name string) *Key {
+	return &Key{name: name}
+}
+
+// String returns the name of the key.
+func (k *Key) String() string {
+	return "http.Key(" + k.name + ")"
+}
+
+// The keys of the values provided by the framework are not exported, in order not to be replaced or overwritten by other packages,
+// and their values are retrieved with their accessors, i.e. ClientIP, RequestID and Claims.
+var (
+	// clientIPKey is the key of the client IP, which is stored as a string by the client IP middleware.
+	clientIPKey = NewKey("client-ip")
+	// requestIDKey is the key of the request ID, which is stored as a string along with the correlation ID of the request.
+	requestIDKey = NewKey("request-id")
+	// claimsKey is the key of the claims of the authenticated subject, which are stored by an authenticating middleware.
+	claimsKey = NewKey("claims")
+)
+
+// SetValue returns a copy of the context holding the value of the key.
+func SetValue(ctx context.Context, key *Key, v interface{}) context.Context {
+	return context.WithValue(ctx, key, v)
+}
+
+// Value returns the value of the key held by the context and whether it exists.
+func Value(ctx context.Context, key *Key) (interface{}, bool) {
+	v := ctx.Value(key)
+	return v, v != nil
+}
+
+// StringValue returns the value of the key held by the context, if it exists and is a string.
+func StringValue(ctx context.Context, key *Key) (string, bool) {
+	v, ok := ctx.Value(key).(string)
+	return v, ok
+}
+
+// withRequestID returns a copy of the context holding the ID of the request, which is the correlation ID as well.
+func withRequestID(ctx context.Context, id string) context.Context {
+	return SetValue(correlation.ContextWithID(ctx, id), requestIDKey, id)
+}
+
+// RequestID returns the ID of the request held by the context, which is set by the tracing and the request ID middlewares
+// and by the handlers of the processor routes, and whether it exists.
+func RequestID(ctx context.Context) (string, bool) {
+	return StringValue(ctx, requestIDKey)
+}
+
+// SetClaims returns a copy of the context holding the claims of the authenticated subject of the request,
+// e.g. of a JWT, in order for an authenticating middleware to pass them to the handler.
+func SetClaims(ctx context.Context, claims map[string]interface{}) context.Context {
+	return SetValue(ctx, claimsKey, claims)
+}
+
+// Claims returns the claims of the authenticated subject of the request held by the context and whether they exist.
+func Claims(ctx context.Context) (map[string]interface{}, bool) {
+	claims, ok := ctx.Value(claimsKey).(map[string]interface{})
+	return claims, ok
+}
@@ -0,0 +1,75 @@
+package http
+
+import (
+	"context"
+	"testing"
+
+	"github.com/beatlabs/patron/correlation"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetValue(t *testing.T) {
+	key := NewKey("subject")
+	ctx := SetValue(context.Background(), key, "user")
+
+	v, ok := Value(ctx, key)
+	assert.True(t, ok)
+	assert.Equal(t, "user", v)
+	s, ok := StringValue(ctx, key)
+	assert.True(t, ok)
+	assert.Equal(t, "user", s)
+
+	_, ok = Value(context.Background(), key)
+	assert.False(t, ok)
+	_, ok = StringValue(SetValue(context.Background(), key, 1), key)
+	assert.False(t, ok)
+	assert.Equal(t, "http.Key(subject)", key.String())
+}
+
+func TestSetValue_Collisions(t *testing.T) {
+	key := NewKey("subject")
+	other := NewKey("subject")
+	type rawKey string
+	ctx := context.WithValue(context.Background(), "subject", "raw") // nolint:golint,staticcheck
+	ctx = context.WithValue(ctx, rawKey("subject"), "typed raw")
+	ctx = SetValue(ctx, key, "user")
+	ctx = SetValue(ctx, other, "other")
+
+	s, ok := StringValue(ctx, key)
+	assert.True(t, ok)
+	assert.Equal(t, "user", s)
+	s, ok = StringValue(ctx, other)
+	assert.True(t, ok)
+	assert.Equal(t, "other", s)
+	assert.Equal(t, "raw", ctx.Value("subject"))
+	assert.Equal(t, "typed raw", ctx.Value(rawKey("subject")))
+}
+
+func TestRequestID(t *testing.T) {
+	_, ok := RequestID(context.Background())
+	assert.False(t, ok)
+
+	ctx := withRequestID(context.Background(), "123")
+	id, ok := RequestID(ctx)
+	assert.True(t, ok)
+	assert.Equal(t, "123", id)
+	assert.Equal(t, "123", correlation.IDFromContext(ctx))
+
+	// a key with the same name does not collide with the key of the framework
+	id, ok = RequestID(SetValue(ctx, NewKey("request-id"), "other"))
+	assert.True(t, ok)
+	assert.Equal(t, "123", id)
+}
+
+func TestClaims(t *testing.T) {
+	_, ok := Claims(context.Background())
+	assert.False(t, ok)
+
+	claims := map[string]interface{}{"sub": "user", "admin": true}
+	got, ok := Claims(SetClaims(context.Background(), claims))
+	assert.True(t, ok)
+	assert.Equal(t, claims, got)
+
+	_, ok = Claims(SetValue(context.Background(), NewKey("claims"), claims))
+	assert.False(t, ok)
+}
@@ -0,0 +1,113 @@
+package http
+
+import (
+	"net/http"
+	"runtime/debug"
+	"sync"
+
+	"github.com/beatlabs/patron/log"
+	"github.com/beatlabs/patron/reliability/budget"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var workerPoolQueueDepth prometheus.Gauge
+
+func init() {
+	workerPoolQueueDepth = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: "component",
+			Subsystem: "http",
+			Name:      "worker_pool_queue_depth",
+			Help:      "Number of requests queued for execution by the worker pool",
+		},
+	)
+	prometheus.MustRegister(workerPoolQueueDepth)
+}
+
+type job struct {
+	h    http.Handler
+	w    http.ResponseWriter
+	r    *http.Request
+	done chan struct{}
+}
+
+// workerPool executes the requests of the handlers it wraps on a fixed number of workers.
+// Requests are queued until a worker is available, while requests exceeding the queue
+// are shed with a 503 Service Unavailable status.
+type workerPool struct {
+	budget  *budget.Budget
+	jobs    chan job
+	mu      sync.RWMutex
+	stopped bool
+}
+
+func newWorkerPool(size, queue int, b *budget.Budget) *workerPool {
+	wp := &workerPool{budget: b, jobs: make(chan job, queue)}
+	for i := 0; i < size; i++ {
+		go wp.work()
+	}
+	return wp
+}
+
+func (wp *workerPool) work() {
+	for j := range wp.jobs {
+		workerPoolQueueDepth.Dec()
+		// the client may have gone away while the request was queued
+		// or while waiting for the goroutine budget shared with the other components
+		if wp.budget.Acquire(j.r.Context()) == nil {
+			wp.serve(j)
+			wp.budget.Release()
+		}
+		close(j.done)
+	}
+}
+
+func (wp *workerPool) serve(j job) {
+	// a panic would otherwise kill the worker and with it the process,
+	// since the request is not served on the connection's goroutine
+	defer func() {
+		if r := recover(); r != nil {
+			log.FromContext(j.r.Context()).Errorf("recovering from a panic of the worker pool %v: %s", r, debug.Stack())
+			http.Error(j.w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		}
+	}()
+	j.h.ServeHTTP(j.w, j.r)
+}
+
+// handler returns a handler which queues the requests of the next handler and waits for a worker to execute them.
+func (wp *workerPool) handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		j := job{h: next, w: w, r: r, done: make(chan struct{})}
+		if !wp.enqueue(j) {
+			shed(w, workerPoolRetryAfter, shedSourceWorkerPool, false)
+			return
+		}
+		<-j.done
+	})
+}
+
+func (wp *workerPool) enqueue(j job) bool {
+	wp.mu.RLock()
+	defer wp.mu.RUnlock()
+	if wp.stopped {
+		return false
+	}
+	workerPoolQueueDepth.Inc()
+	select {
+	case wp.jobs <- j:
+		return true
+	default:
+		workerPoolQueueDepth.Dec()
+		return false
+	}
+}
+
+// stop rejects new requests. The workers stop after executing the queued requests.
+func (wp *workerPool) stop() {
+	wp.mu.Lock()
+	defer wp.mu.Unlock()
+	if !wp.stopped {
+		wp.stopped = true
+		close(wp.jobs)
+	}
+}
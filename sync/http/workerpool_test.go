@@ -0,0 +1,93 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWorkerPool_Bounded(t *testing.T) {
+	var running, maxRunning int32
+	release := make(chan struct{})
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&running, 1)
+		for {
+			m := atomic.LoadInt32(&maxRunning)
+			if n <= m || atomic.CompareAndSwapInt32(&maxRunning, m, n) {
+				break
+			}
+		}
+		<-release
+		atomic.AddInt32(&running, -1)
+		w.WriteHeader(http.StatusAccepted)
+	})
+	wp := newWorkerPool(2, 3, nil)
+	defer wp.stop()
+	ph := wp.handler(h)
+
+	const requests = 50
+	codes := make(chan int, requests)
+	wg := sync.WaitGroup{}
+	wg.Add(requests)
+	for i := 0; i < requests; i++ {
+		go func() {
+			defer wg.Done()
+			req, err := http.NewRequest(http.MethodGet, "/", nil)
+			assert.NoError(t, err)
+			rc := httptest.NewRecorder()
+			ph.ServeHTTP(rc, req)
+			codes <- rc.Code
+		}()
+	}
+
+	// all requests besides the running and the queued ones are rejected
+	rejected := 0
+	for rejected < requests-5 {
+		select {
+		case code := <-codes:
+			assert.Equal(t, http.StatusServiceUnavailable, code)
+			rejected++
+		case <-time.After(5 * time.Second):
+			t.Fatalf("only %d requests rejected", rejected)
+		}
+	}
+	close(release)
+	wg.Wait()
+	close(codes)
+	for code := range codes {
+		assert.Equal(t, http.StatusAccepted, code)
+	}
+	assert.Equal(t, int32(2), atomic.LoadInt32(&maxRunning))
+}
+
+func TestWorkerPool_Panic(t *testing.T) {
+	wp := newWorkerPool(1, 1, nil)
+	defer wp.stop()
+	h := wp.handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("error")
+	}))
+	req, err := http.NewRequest(http.MethodGet, "/", nil)
+	assert.NoError(t, err)
+	for i := 0; i < 2; i++ {
+		rc := httptest.NewRecorder()
+		h.ServeHTTP(rc, req)
+		assert.Equal(t, http.StatusInternalServerError, rc.Code)
+	}
+}
+
+func TestWorkerPool_Stopped(t *testing.T) {
+	wp := newWorkerPool(1, 1, nil)
+	wp.stop()
+	wp.stop()
+	req, err := http.NewRequest(http.MethodGet, "/", nil)
+	assert.NoError(t, err)
+	rc := httptest.NewRecorder()
+	wp.handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})).ServeHTTP(rc, req)
+	assert.Equal(t, http.StatusServiceUnavailable, rc.Code)
+	assert.Equal(t, "1", rc.Header().Get("Retry-After"))
+}
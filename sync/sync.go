@@ -26,8 +26,11 @@ func (r *Request) Decode(v interface{}) error {
 }
 
 // Response definition of the sync response model.
+// Status and Headers are optional and are written by the component if set.
 type Response struct {
 	Payload interface{}
+	Status  int
+	Headers map[string]string
 }
 
 // NewResponse creates a new response.
@@ -35,5 +38,10 @@ func NewResponse(p interface{}) *Response {
 	return &Response{Payload: p}
 }
 
+// NewResponseWithStatus creates a new response with an explicit status code.
+func NewResponseWithStatus(p interface{}, status int) *Response {
+	return &Response{Payload: p, Status: status}
+}
+
 // ProcessorFunc definition of a function type for processing sync requests.
 type ProcessorFunc func(context.Context, *Request) (*Response, error)
@@ -30,3 +30,10 @@ func TestNewResponse(t *testing.T) {
 	assert.NotNil(t, rsp)
 	assert.IsType(t, "test", rsp.Payload)
 }
+
+func TestNewResponseWithStatus(t *testing.T) {
+	rsp := NewResponseWithStatus("test", 202)
+	assert.NotNil(t, rsp)
+	assert.IsType(t, "test", rsp.Payload)
+	assert.Equal(t, 202, rsp.Status)
+}
@@ -11,6 +11,7 @@ import (
 	"github.com/beatlabs/patron/encoding/json"
 	"github.com/beatlabs/patron/encoding/protobuf"
 	patronErrors "github.com/beatlabs/patron/errors"
+	"github.com/beatlabs/patron/info"
 	"github.com/beatlabs/patron/trace"
 	opentracing "github.com/opentracing/opentracing-go"
 	"github.com/opentracing/opentracing-go/ext"
@@ -112,6 +113,7 @@ func NewPublisher(url, exc string, oo ...OptionFunc) (*TracedPublisher, error) {
 		return nil, fmt.Errorf("failed to declare exchange: %w", err)
 	}
 
+	info.AddDependency(info.Dependency{Type: "amqp", Name: exc, Direction: info.Outbound})
 	return &p, nil
 }
 
@@ -0,0 +1,54 @@
+package trace
+
+import (
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/prometheus/client_golang/prometheus"
+	jaeger "github.com/uber/jaeger-client-go"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+// ExemplarTraceIDLabel is the label of the exemplars holding the trace ID, which links a metric observation to its trace.
+const ExemplarTraceIDLabel = "trace_id"
+
+// otelSpanContext is the span context of the spans of an OpenTelemetry tracer bridged to OpenTracing, e.g. of OTLPProvider.
+type otelSpanContext interface {
+	IsValid() bool
+	IsSampled() bool
+	TraceID() oteltrace.TraceID
+}
+
+// sampledTraceID returns the trace ID of the span and true, if the span is sampled and created by a Jaeger tracer
+// or an OpenTelemetry tracer bridged to OpenTracing.
+func sampledTraceID(sp opentracing.Span) (string, bool) {
+	if sp == nil {
+		return "", false
+	}
+	switch sc := sp.Context().(type) {
+	case jaeger.SpanContext:
+		if sc.IsValid() && sc.IsSampled() {
+			return sc.TraceID().String(), true
+		}
+	case otelSpanContext:
+		if sc.IsValid() && sc.IsSampled() {
+			return sc.TraceID().String(), true
+		}
+	}
+	return "", false
+}
+
+// ObserveWithExemplar records the value along with an exemplar holding the trace ID of the span, if the span is sampled,
+// so the metric links to the trace, e.g. a latency histogram bucket to a slow request. Exemplars are exposed in the
+// OpenMetrics format of the metrics route. Otherwise the value is recorded without an exemplar.
+func ObserveWithExemplar(o prometheus.Observer, v float64, sp opentracing.Span) {
+	id, ok := sampledTraceID(sp)
+	if !ok {
+		o.Observe(v)
+		return
+	}
+	eo, ok := o.(prometheus.ExemplarObserver)
+	if !ok {
+		o.Observe(v)
+		return
+	}
+	eo.ObserveWithExemplar(v, prometheus.Labels{ExemplarTraceIDLabel: id})
+}
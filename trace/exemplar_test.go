@@ -0,0 +1,73 @@
+package trace
+
+import (
+	"testing"
+
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/mocktracer"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	jaeger "github.com/uber/jaeger-client-go"
+	otelbridge "go.opentelemetry.io/otel/bridge/opentracing"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+func TestSampledTraceID(t *testing.T) {
+	sampled, cls := jaeger.NewTracer("test", jaeger.NewConstSampler(true), jaeger.NewInMemoryReporter())
+	defer cls.Close()
+	unsampled, cls := jaeger.NewTracer("test", jaeger.NewConstSampler(false), jaeger.NewInMemoryReporter())
+	defer cls.Close()
+
+	sp := sampled.StartSpan("test")
+	id, ok := sampledTraceID(sp)
+	assert.True(t, ok)
+	assert.Equal(t, sp.Context().(jaeger.SpanContext).TraceID().String(), id)
+
+	// the spans of an OpenTelemetry tracer bridged to OpenTracing
+	bt, _ := otelbridge.NewTracerPair(sdktrace.NewTracerProvider().Tracer("test"))
+	sp = bt.StartSpan("test")
+	id, ok = sampledTraceID(sp)
+	assert.True(t, ok)
+	assert.Len(t, id, 32)
+	bt, _ = otelbridge.NewTracerPair(sdktrace.NewTracerProvider(sdktrace.WithSampler(sdktrace.NeverSample())).Tracer("test"))
+
+	tests := map[string]opentracing.Span{
+		"nil span":            nil,
+		"unsampled span":      unsampled.StartSpan("test"),
+		"unsampled otel span": bt.StartSpan("test"),
+		"other tracer":        mocktracer.New().StartSpan("test"),
+	}
+	for name, sp := range tests {
+		t.Run(name, func(t *testing.T) {
+			id, ok := sampledTraceID(sp)
+			assert.False(t, ok)
+			assert.Empty(t, id)
+		})
+	}
+}
+
+func TestObserveWithExemplar(t *testing.T) {
+	tr, cls := jaeger.NewTracer("test", jaeger.NewConstSampler(true), jaeger.NewInMemoryReporter())
+	defer cls.Close()
+	sp := tr.StartSpan("test")
+	id, _ := sampledTraceID(sp)
+
+	h := prometheus.NewHistogram(prometheus.HistogramOpts{Name: "test_seconds", Buckets: []float64{1, 10}})
+	ObserveWithExemplar(h, 5, sp)
+	ObserveWithExemplar(h, 0.5, nil)
+
+	m := &dto.Metric{}
+	require.NoError(t, h.Write(m))
+	assert.Equal(t, uint64(2), m.Histogram.GetSampleCount())
+	bb := m.Histogram.GetBucket()
+	require.Len(t, bb, 2)
+	assert.Nil(t, bb[0].GetExemplar())
+	require.NotNil(t, bb[1].GetExemplar())
+	assert.Equal(t, 5.0, bb[1].GetExemplar().GetValue())
+	lbl := bb[1].GetExemplar().GetLabel()
+	require.Len(t, lbl, 1)
+	assert.Equal(t, ExemplarTraceIDLabel, lbl[0].GetName())
+	assert.Equal(t, id, lbl[0].GetValue())
+}
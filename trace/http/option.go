@@ -1,11 +1,16 @@
 package http
 
 import (
+	"crypto/tls"
 	"errors"
 	"fmt"
+	"net/http"
+	"os"
 	"time"
 
+	"github.com/beatlabs/patron/log"
 	"github.com/beatlabs/patron/reliability/circuitbreaker"
+	"github.com/opentracing-contrib/go-stdlib/nethttp"
 )
 
 // OptionFunc definition for configuring the client in a functional way.
@@ -33,3 +38,38 @@ func CircuitBreaker(name string, set circuitbreaker.Setting) OptionFunc {
 		return nil
 	}
 }
+
+// insecureWarnf logs the warning of skipping the TLS verification, which tests replace.
+var insecureWarnf = log.Warnf
+
+// InsecureSkipVerify option for connecting to HTTPS servers without verifying their certificate chain and host name,
+// e.g. servers with self-signed certificates in development setups. The option is rejected when the PATRON_ENV
+// environment variable is set to production, since it allows man-in-the-middle attacks.
+func InsecureSkipVerify() OptionFunc {
+	return func(tc *TracedClient) error {
+		if os.Getenv("PATRON_ENV") == "production" {
+			return errors.New("skipping the TLS verification is not allowed in production")
+		}
+		insecureWarnf("INSECURE: TLS verification of the HTTP client is skipped, which must never be used in production")
+		tr, ok := tc.cl.Transport.(*nethttp.Transport)
+		if !ok {
+			return errors.New("unexpected transport of the HTTP client")
+		}
+		var base *http.Transport
+		if tr.RoundTripper != nil {
+			base, ok = tr.RoundTripper.(*http.Transport)
+			if !ok {
+				return errors.New("unexpected round tripper of the HTTP client")
+			}
+			base = base.Clone()
+		} else {
+			base = http.DefaultTransport.(*http.Transport).Clone()
+		}
+		if base.TLSClientConfig == nil {
+			base.TLSClientConfig = &tls.Config{}
+		}
+		base.TLSClientConfig.InsecureSkipVerify = true // nolint:gosec
+		tr.RoundTripper = base
+		return nil
+	}
+}
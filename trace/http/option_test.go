@@ -0,0 +1,34 @@
+package http
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"testing"
+
+	"github.com/opentracing-contrib/go-stdlib/nethttp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInsecureSkipVerify(t *testing.T) {
+	var warnings []string
+	defer func(f func(string, ...interface{})) { insecureWarnf = f }(insecureWarnf)
+	insecureWarnf = func(msg string, args ...interface{}) { warnings = append(warnings, fmt.Sprintf(msg, args...)) }
+
+	tc, err := New(InsecureSkipVerify())
+	require.NoError(t, err)
+	tr := tc.cl.Transport.(*nethttp.Transport).RoundTripper.(*http.Transport)
+	assert.True(t, tr.TLSClientConfig.InsecureSkipVerify)
+	// the default transport shared by the process is not modified
+	assert.True(t, http.DefaultTransport != tr)
+	assert.Len(t, warnings, 1)
+	assert.Contains(t, warnings[0], "INSECURE")
+
+	require.NoError(t, os.Setenv("PATRON_ENV", "production"))
+	defer func() { require.NoError(t, os.Unsetenv("PATRON_ENV")) }()
+	tc, err = New(InsecureSkipVerify())
+	assert.EqualError(t, err, "skipping the TLS verification is not allowed in production")
+	assert.Nil(t, tc)
+	assert.Len(t, warnings, 1)
+}
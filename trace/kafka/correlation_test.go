@@ -0,0 +1,73 @@
+package kafka
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Shopify/sarama"
+	asynckafka "github.com/beatlabs/patron/async/kafka"
+	"github.com/beatlabs/patron/correlation"
+	"github.com/beatlabs/patron/encoding/json"
+	phttp "github.com/beatlabs/patron/sync/http"
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/mocktracer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// chanProducer is a sarama async producer delivering the produced messages to its input channel only.
+type chanProducer struct {
+	sarama.AsyncProducer
+	input chan *sarama.ProducerMessage
+}
+
+func (cp *chanProducer) Input() chan<- *sarama.ProducerMessage {
+	return cp.input
+}
+
+func TestHTTPRequestToKafkaCorrelation(t *testing.T) {
+	mtr := mocktracer.New()
+	opentracing.SetGlobalTracer(mtr)
+	defer opentracing.SetGlobalTracer(opentracing.NoopTracer{})
+
+	prod := &chanProducer{input: make(chan *sarama.ProducerMessage, 1)}
+	ap := &AsyncProducer{prod: prod, enc: json.Encode, contentType: json.Type}
+
+	h := phttp.MiddlewareChain(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		err := ap.Send(r.Context(), NewMessage("orders", "order"))
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}), phttp.NewLoggingTracingMiddleware("/orders"))
+
+	req := httptest.NewRequest(http.MethodPost, "/orders", nil)
+	req.Header.Set(correlation.HeaderID, "request-1")
+	rsp := httptest.NewRecorder()
+	h.ServeHTTP(rsp, req)
+	require.Equal(t, http.StatusAccepted, rsp.Code)
+
+	pm := <-prod.input
+	hh := make([]*sarama.RecordHeader, 0, len(pm.Headers))
+	for i := range pm.Headers {
+		hh = append(hh, &pm.Headers[i])
+	}
+	value, err := pm.Value.Encode()
+	require.NoError(t, err)
+	msg, err := asynckafka.ClaimMessage(context.Background(), &sarama.ConsumerMessage{Topic: pm.Topic, Value: value, Headers: hh}, nil, nil, asynckafka.ClaimOptions{})
+	require.NoError(t, err)
+	opentracing.SpanFromContext(msg.Context()).Finish()
+
+	assert.Equal(t, "request-1", correlation.IDFromContext(msg.Context()))
+
+	spans := mtr.FinishedSpans()
+	require.Len(t, spans, 3)
+	httpSpan, producerSpan, consumerSpan := spans[1], spans[0], spans[2]
+	assert.Equal(t, "POST /orders", httpSpan.OperationName)
+	assert.Equal(t, httpSpan.SpanContext.TraceID, producerSpan.SpanContext.TraceID)
+	assert.Equal(t, httpSpan.SpanContext.SpanID, producerSpan.ParentID)
+	assert.Equal(t, httpSpan.SpanContext.TraceID, consumerSpan.SpanContext.TraceID)
+}
@@ -4,11 +4,17 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"sync"
+	"sync/atomic"
 
 	"github.com/Shopify/sarama"
 	"github.com/beatlabs/patron/correlation"
 	"github.com/beatlabs/patron/encoding"
+	"github.com/beatlabs/patron/encoding/compression"
 	"github.com/beatlabs/patron/encoding/json"
+	patronErrors "github.com/beatlabs/patron/errors"
+	"github.com/beatlabs/patron/info"
+	"github.com/beatlabs/patron/log"
 	"github.com/beatlabs/patron/trace"
 	"github.com/opentracing/opentracing-go"
 	"github.com/opentracing/opentracing-go/ext"
@@ -18,7 +24,8 @@ import (
 type Message struct {
 	topic string
 	body  interface{}
-	key   *string
+	// key is nil for messages without a key.
+	key interface{}
 }
 
 // NewMessage creates a new message.
@@ -31,7 +38,14 @@ func NewMessageWithKey(t string, b interface{}, k string) (*Message, error) {
 	if k == "" {
 		return nil, errors.New("key string can not be null")
 	}
-	return &Message{topic: t, body: b, key: &k}, nil
+	return &Message{topic: t, body: b, key: k}, nil
+}
+
+// NewMessageWithEncodedKey creates a new message with a key, which is encoded with the KeyEncoder of the producer,
+// e.g. an Avro key of a schema registry setup. Without a key encoder, the key has to be a string or a byte slice.
+// A nil key creates a message without a key.
+func NewMessageWithEncodedKey(t string, b interface{}, k interface{}) *Message {
+	return &Message{topic: t, body: b, key: k}
 }
 
 // NewJSONMessage creates a new message with a JSON encoded body.
@@ -52,7 +66,7 @@ func NewJSONMessageWithKey(t string, d interface{}, k string) (*Message, error)
 	if err != nil {
 		return nil, fmt.Errorf("failed to JSON encode: %w", err)
 	}
-	return &Message{topic: t, body: b, key: &k}, nil
+	return &Message{topic: t, body: b, key: k}, nil
 }
 
 // Producer interface for Kafka.
@@ -64,12 +78,24 @@ type Producer interface {
 
 // AsyncProducer defines a async Kafka producer.
 type AsyncProducer struct {
-	cfg         *sarama.Config
-	prod        sarama.AsyncProducer
-	chErr       chan error
-	tag         opentracing.Tag
-	enc         encoding.EncodeFunc
-	contentType string
+	cfg           *sarama.Config
+	client        sarama.Client
+	prod          sarama.AsyncProducer
+	chErr         chan error
+	tag           opentracing.Tag
+	enc           encoding.EncodeFunc
+	contentType   string
+	baggagePrefix string
+	compression   compression.Codec
+	keyEnc        encoding.EncodeFunc
+	// closing stops the propagation of the errors, which are returned by Close instead.
+	closing chan struct{}
+	wg      sync.WaitGroup
+	sent    uint64
+	failed  uint64
+	mu      sync.Mutex
+	// undelivered are the errors which occurred while closing.
+	undelivered []error
 }
 
 // NewAsyncProducer creates a new async producer with default configuration.
@@ -87,17 +113,34 @@ func NewAsyncProducer(brokers []string, oo ...OptionFunc) (*AsyncProducer, error
 		}
 	}
 
-	prod, err := sarama.NewAsyncProducer(brokers, ap.cfg)
+	var prod sarama.AsyncProducer
+	var err error
+	if ap.client != nil {
+		prod, err = sarama.NewAsyncProducerFromClient(ap.client)
+	} else {
+		ap.cfg.Producer.Partitioner = explicitPartitioner(ap.cfg.Producer.Partitioner)
+		prod, err = sarama.NewAsyncProducer(brokers, ap.cfg)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to create async producer: %w", err)
 	}
 	ap.prod = prod
-	go ap.propagateError()
+	ap.start()
 	return &ap, nil
 }
 
-// Send a message to a topic.
+// Send a message to a topic. A message is not sent if the context is canceled, e.g. when the client of an HTTP request disconnects,
+// before it is queued by the producer.
 func (ap *AsyncProducer) Send(ctx context.Context, msg *Message) error {
+	return ap.send(ctx, msg, nil)
+}
+
+// send a message to a topic, after the producer message is adjusted, e.g. with an explicit partition.
+func (ap *AsyncProducer) send(ctx context.Context, msg *Message, adjust func(*sarama.ProducerMessage)) error {
+	// the work of an abandoned request, e.g. of a disconnected HTTP client, is not sent
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("failed to send message: %w", err)
+	}
 	sp, _ := trace.ChildSpan(ctx, trace.ComponentOpName(trace.KafkaAsyncProducerComponent, msg.topic),
 		trace.KafkaAsyncProducerComponent, ext.SpanKindProducer, ap.tag,
 		opentracing.Tag{Key: "topic", Value: msg.topic})
@@ -106,7 +149,18 @@ func (ap *AsyncProducer) Send(ctx context.Context, msg *Message) error {
 		trace.SpanError(sp)
 		return err
 	}
-	ap.prod.Input() <- pm
+	if adjust != nil {
+		adjust(pm)
+	}
+	select {
+	case ap.prod.Input() <- pm:
+	case <-ctx.Done():
+		// the producer's queue is full and the context is canceled while waiting
+		trace.SpanError(sp)
+		return fmt.Errorf("failed to send message: %w", ctx.Err())
+	}
+	atomic.AddUint64(&ap.sent, 1)
+	info.AddDependency(info.Dependency{Type: "kafka", Name: msg.topic, Direction: info.Outbound})
 	trace.SpanSuccess(sp)
 	return nil
 }
@@ -116,18 +170,54 @@ func (ap *AsyncProducer) Error() <-chan error {
 	return ap.chErr
 }
 
-// Close gracefully the producer.
+// Close gracefully the producer, after flushing the messages which have been sent.
+// The messages which failed while closing, since they cannot be received from the Error channel anymore,
+// are returned as an aggregated error. The number of sent, succeeded and failed messages is logged.
 func (ap *AsyncProducer) Close() error {
-	err := ap.prod.Close()
+	close(ap.closing)
+	ap.prod.AsyncClose()
+	ap.wg.Wait()
+
+	sent, failed := atomic.LoadUint64(&ap.sent), atomic.LoadUint64(&ap.failed)
+	log.Infof("kafka async producer closed: %d messages sent, %d succeeded, %d failed", sent, sent-failed, failed)
+
+	ap.mu.Lock()
+	defer ap.mu.Unlock()
+	err := patronErrors.Aggregate(ap.undelivered...)
 	if err != nil {
-		return fmt.Errorf("failed to close sync producer: %w", err)
+		return fmt.Errorf("failed to close async producer: %w", err)
 	}
 	return nil
 }
 
+// start accounts for the results of the sent messages, until the producer is closed.
+func (ap *AsyncProducer) start() {
+	ap.closing = make(chan struct{})
+	ap.wg.Add(2)
+	go ap.propagateError()
+	go ap.drainSuccesses()
+}
+
 func (ap *AsyncProducer) propagateError() {
+	defer ap.wg.Done()
 	for pe := range ap.prod.Errors() {
-		ap.chErr <- fmt.Errorf("failed to send message: %w", pe)
+		atomic.AddUint64(&ap.failed, 1)
+		err := fmt.Errorf("failed to send message: %w", pe)
+		select {
+		case ap.chErr <- err:
+		case <-ap.closing:
+			ap.mu.Lock()
+			ap.undelivered = append(ap.undelivered, err)
+			ap.mu.Unlock()
+		}
+	}
+}
+
+// drainSuccesses drains the successes of the messages, which are returned only if enabled in the config of a shared client,
+// so that the producer does not block on them.
+func (ap *AsyncProducer) drainSuccesses() {
+	defer ap.wg.Done()
+	for range ap.prod.Successes() {
 	}
 }
 
@@ -138,16 +228,29 @@ func (ap *AsyncProducer) createProducerMessage(ctx context.Context, msg *Message
 		return nil, fmt.Errorf("failed to inject tracing headers: %w", err)
 	}
 	c.Set(encoding.ContentTypeHeader, ap.contentType)
+	if ap.baggagePrefix != "" {
+		sp.Context().ForeachBaggageItem(func(k, v string) bool {
+			c.Set(ap.baggagePrefix+k, v)
+			return true
+		})
+	}
 
-	var saramaKey sarama.Encoder
-	if msg.key != nil {
-		saramaKey = sarama.StringEncoder(*msg.key)
+	saramaKey, err := ap.encodeKey(msg.key)
+	if err != nil {
+		return nil, err
 	}
 
 	b, err := ap.enc(msg.body)
 	if err != nil {
 		return nil, fmt.Errorf("failed to encode message body")
 	}
+	if ap.compression != "" {
+		b, err = compression.Compress(ap.compression, b)
+		if err != nil {
+			return nil, fmt.Errorf("failed to compress message body: %w", err)
+		}
+		c.Set(encoding.ContentEncodingHeader, string(ap.compression))
+	}
 
 	c.Set(correlation.HeaderID, correlation.IDFromContext(ctx))
 	return &sarama.ProducerMessage{
@@ -158,6 +261,28 @@ func (ap *AsyncProducer) createProducerMessage(ctx context.Context, msg *Message
 	}, nil
 }
 
+// encodeKey encodes the key with the key encoder, or as is if it is a string or a byte slice.
+func (ap *AsyncProducer) encodeKey(k interface{}) (sarama.Encoder, error) {
+	if k == nil {
+		return nil, nil
+	}
+	if ap.keyEnc != nil {
+		b, err := ap.keyEnc(k)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode message key: %w", err)
+		}
+		return sarama.ByteEncoder(b), nil
+	}
+	switch key := k.(type) {
+	case string:
+		return sarama.StringEncoder(key), nil
+	case []byte:
+		return sarama.ByteEncoder(key), nil
+	default:
+		return nil, fmt.Errorf("message key of type %T requires a key encoder", k)
+	}
+}
+
 type kafkaHeadersCarrier []sarama.RecordHeader
 
 // Set implements Set() of opentracing.TextMapWriter.
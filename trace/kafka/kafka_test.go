@@ -2,15 +2,23 @@ package kafka
 
 import (
 	"context"
+	"errors"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/Shopify/sarama"
+	asynckafka "github.com/beatlabs/patron/async/kafka"
 	"github.com/beatlabs/patron/encoding"
+	"github.com/beatlabs/patron/encoding/compression"
 	"github.com/beatlabs/patron/encoding/json"
 	"github.com/beatlabs/patron/encoding/protobuf"
 	"github.com/beatlabs/patron/examples"
 	"github.com/beatlabs/patron/trace"
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/mocktracer"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"github.com/uber/jaeger-client-go"
 )
 
@@ -109,6 +117,27 @@ func TestNewSyncProducer_Success(t *testing.T) {
 	assert.NotNil(t, got)
 }
 
+func TestNewAsyncProducer_SharedClient(t *testing.T) {
+	seed := sarama.NewMockBroker(t, 1)
+	defer seed.Close()
+	seed.SetHandlerByMap(map[string]sarama.MockResponse{
+		"MetadataRequest": sarama.NewMockMetadataResponse(t).
+			SetBroker(seed.Addr(), seed.BrokerID()).
+			SetLeader("TOPIC", 0, seed.BrokerID()),
+	})
+	cfg := sarama.NewConfig()
+	cfg.Version = sarama.V0_11_0_0
+	client, err := sarama.NewClient([]string{seed.Addr()}, cfg)
+	assert.NoError(t, err)
+	ap, err := NewAsyncProducer(nil, Client(client))
+	assert.NoError(t, err)
+	assert.NotNil(t, ap)
+	assert.NoError(t, ap.Close())
+	// the shared client is owned by the caller
+	assert.False(t, client.Closed())
+	assert.NoError(t, client.Close())
+}
+
 func TestAsyncProducer_SendMessage_Close(t *testing.T) {
 	msg, err := NewJSONMessage("TOPIC", "TEST")
 	assert.NoError(t, err)
@@ -128,7 +157,7 @@ func TestAsyncProducer_SendMessage_Close(t *testing.T) {
 func TestAsyncProducer_SendMessage_WithKey(t *testing.T) {
 	testKey := "TEST"
 	msg, err := NewJSONMessageWithKey("TOPIC", "TEST", testKey)
-	assert.Equal(t, testKey, *msg.key)
+	assert.Equal(t, testKey, msg.key)
 	assert.NoError(t, err)
 	seed := createKafkaBroker(t, true)
 	ap, err := NewAsyncProducer([]string{seed.Addr()}, Version(sarama.V0_8_2_0.String()))
@@ -212,3 +241,213 @@ func TestSendWithCustomEncoder(t *testing.T) {
 		})
 	}
 }
+
+func TestAsyncProducer_PropagateBaggage(t *testing.T) {
+	mtr := mocktracer.New()
+	opentracing.SetGlobalTracer(mtr)
+	defer opentracing.SetGlobalTracer(opentracing.NoopTracer{})
+
+	ap := &AsyncProducer{enc: json.Encode, contentType: json.Type}
+	assert.NoError(t, PropagateBaggage("baggage-")(ap))
+
+	sp := mtr.StartSpan("producer")
+	sp.SetBaggageItem("tenant", "acme")
+	pm, err := ap.createProducerMessage(context.Background(), NewMessage("TOPIC", "TEST"), sp)
+	assert.NoError(t, err)
+
+	hh := make([]*sarama.RecordHeader, 0, len(pm.Headers))
+	found := false
+	for i := range pm.Headers {
+		if string(pm.Headers[i].Key) == "baggage-tenant" {
+			found = true
+			assert.Equal(t, "acme", string(pm.Headers[i].Value))
+		}
+		// drop the tracer's own propagation, in order to assert the baggage headers alone
+		if strings.HasPrefix(string(pm.Headers[i].Key), "mockpfx-") {
+			continue
+		}
+		hh = append(hh, &pm.Headers[i])
+	}
+	assert.True(t, found)
+
+	value, err := pm.Value.Encode()
+	assert.NoError(t, err)
+	cm := &sarama.ConsumerMessage{Topic: "TOPIC", Value: value, Headers: hh}
+	msg, err := asynckafka.ClaimMessage(context.Background(), cm, nil, nil, asynckafka.ClaimOptions{BaggagePrefix: "baggage-"})
+	assert.NoError(t, err)
+	assert.Equal(t, "acme", opentracing.SpanFromContext(msg.Context()).BaggageItem("tenant"))
+
+	// without the option, no baggage is propagated
+	msg, err = asynckafka.ClaimMessage(context.Background(), cm, nil, nil, asynckafka.ClaimOptions{})
+	assert.NoError(t, err)
+	assert.Empty(t, opentracing.SpanFromContext(msg.Context()).BaggageItem("tenant"))
+}
+
+func TestAsyncProducer_ValueCompression(t *testing.T) {
+	type order struct {
+		ID    string `json:"id"`
+		Items []string
+	}
+	sent := order{ID: "order-1", Items: []string{strings.Repeat("item", 100), strings.Repeat("item", 100)}}
+	plain, err := json.Encode(sent)
+	require.NoError(t, err)
+
+	ap := &AsyncProducer{enc: json.Encode, contentType: json.Type}
+	require.NoError(t, ValueCompression(compression.Gzip)(ap))
+	sp := mocktracer.New().StartSpan("producer")
+	pm, err := ap.createProducerMessage(context.Background(), NewMessage("TOPIC", sent), sp)
+	require.NoError(t, err)
+
+	value, err := pm.Value.Encode()
+	require.NoError(t, err)
+	assert.True(t, len(value) < len(plain))
+	hh := make([]*sarama.RecordHeader, 0, len(pm.Headers))
+	for i := range pm.Headers {
+		if string(pm.Headers[i].Key) == encoding.ContentEncodingHeader {
+			assert.Equal(t, "gzip", string(pm.Headers[i].Value))
+		}
+		hh = append(hh, &pm.Headers[i])
+	}
+
+	// the consumer decompresses the value transparently
+	cm := &sarama.ConsumerMessage{Topic: "TOPIC", Value: value, Headers: hh}
+	msg, err := asynckafka.ClaimMessage(context.Background(), cm, nil, nil, asynckafka.ClaimOptions{})
+	require.NoError(t, err)
+	var got order
+	require.NoError(t, msg.Decode(&got))
+	assert.Equal(t, sent, got)
+
+	// a message with an unsupported content encoding is not claimed
+	cm.Headers = []*sarama.RecordHeader{
+		{Key: []byte(encoding.ContentTypeHeader), Value: []byte(json.Type)},
+		{Key: []byte(encoding.ContentEncodingHeader), Value: []byte("lz4")},
+	}
+	_, err = asynckafka.ClaimMessage(context.Background(), cm, nil, nil, asynckafka.ClaimOptions{})
+	assert.EqualError(t, err, `failed to determine the decompression of the message: compression codec "lz4" is unsupported`)
+}
+
+func TestAsyncProducer_KeyEncoder(t *testing.T) {
+	type orderKey struct {
+		ID string `json:"id"`
+	}
+	sp := mocktracer.New().StartSpan("producer")
+	encodedKey := func(ap *AsyncProducer, msg *Message) []byte {
+		pm, err := ap.createProducerMessage(context.Background(), msg, sp)
+		require.NoError(t, err)
+		if pm.Key == nil {
+			return nil
+		}
+		key, err := pm.Key.Encode()
+		require.NoError(t, err)
+		return key
+	}
+
+	// the key is encoded with the key encoder, independently of the value encoder
+	ap := &AsyncProducer{enc: protobuf.Encode, contentType: protobuf.Type}
+	require.NoError(t, KeyEncoder(json.Encode)(ap))
+	firstname := "John"
+	msg := NewMessageWithEncodedKey("TOPIC", &examples.User{Firstname: &firstname}, orderKey{ID: "order-1"})
+	assert.Equal(t, `{"id":"order-1"}`, string(encodedKey(ap, msg)))
+	pm, err := ap.createProducerMessage(context.Background(), msg, sp)
+	require.NoError(t, err)
+	value, err := pm.Value.Encode()
+	require.NoError(t, err)
+	var user examples.User
+	require.NoError(t, protobuf.DecodeRaw(value, &user))
+	assert.Equal(t, "John", user.GetFirstname())
+
+	// a nil key produces a message without a key
+	assert.Nil(t, encodedKey(ap, NewMessageWithEncodedKey("TOPIC", &user, nil)))
+	assert.Nil(t, encodedKey(ap, NewMessage("TOPIC", &user)))
+
+	// without a key encoder, string and byte slice keys are produced as is
+	ap = &AsyncProducer{enc: json.Encode, contentType: json.Type}
+	msg, err = NewMessageWithKey("TOPIC", "TEST", "order-1")
+	require.NoError(t, err)
+	assert.Equal(t, "order-1", string(encodedKey(ap, msg)))
+	assert.Equal(t, "order-2", string(encodedKey(ap, NewMessageWithEncodedKey("TOPIC", "TEST", []byte("order-2")))))
+	_, err = ap.createProducerMessage(context.Background(), NewMessageWithEncodedKey("TOPIC", "TEST", orderKey{ID: "order-1"}), sp)
+	assert.EqualError(t, err, "message key of type kafka.orderKey requires a key encoder")
+}
+
+// fakeAsyncProducer fails the messages of the failing topic and succeeds the rest, until it is closed.
+type fakeAsyncProducer struct {
+	input     chan *sarama.ProducerMessage
+	successes chan *sarama.ProducerMessage
+	errors    chan *sarama.ProducerError
+}
+
+func newFakeAsyncProducer() *fakeAsyncProducer {
+	p := &fakeAsyncProducer{
+		input:     make(chan *sarama.ProducerMessage, 10),
+		successes: make(chan *sarama.ProducerMessage),
+		errors:    make(chan *sarama.ProducerError),
+	}
+	go func() {
+		for msg := range p.input {
+			if msg.Topic == "failing" {
+				p.errors <- &sarama.ProducerError{Msg: msg, Err: sarama.ErrNotLeaderForPartition}
+				continue
+			}
+			p.successes <- msg
+		}
+		close(p.errors)
+		close(p.successes)
+	}()
+	return p
+}
+
+func (p *fakeAsyncProducer) AsyncClose()                               { close(p.input) }
+func (p *fakeAsyncProducer) Close() error                              { p.AsyncClose(); return nil }
+func (p *fakeAsyncProducer) Input() chan<- *sarama.ProducerMessage     { return p.input }
+func (p *fakeAsyncProducer) Successes() <-chan *sarama.ProducerMessage { return p.successes }
+func (p *fakeAsyncProducer) Errors() <-chan *sarama.ProducerError      { return p.errors }
+
+func newTestAsyncProducer() *AsyncProducer {
+	ap := &AsyncProducer{prod: newFakeAsyncProducer(), chErr: make(chan error), enc: json.Encode, contentType: json.Type}
+	ap.start()
+	return ap
+}
+
+func TestAsyncProducer_Send_Canceled(t *testing.T) {
+	ctx, cnl := context.WithCancel(context.Background())
+	cnl()
+	ap := newTestAsyncProducer()
+	err := ap.Send(ctx, NewMessage("topic", "value"))
+	assert.True(t, errors.Is(err, context.Canceled))
+
+	// the context is canceled while the queue of the producer is full
+	prod := &capturingAsyncProducer{input: make(chan *sarama.ProducerMessage), successes: make(chan *sarama.ProducerMessage), errors: make(chan *sarama.ProducerError)}
+	ap = &AsyncProducer{prod: prod, chErr: make(chan error), enc: json.Encode, contentType: json.Type}
+	ap.start()
+	ctx, cnl = context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cnl()
+	err = ap.Send(ctx, NewMessage("topic", "value"))
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+	assert.NoError(t, ap.Close())
+}
+
+func TestAsyncProducer_Close_FlushAccounting(t *testing.T) {
+	ap := newTestAsyncProducer()
+	for _, topic := range []string{"topic", "failing", "topic"} {
+		require.NoError(t, ap.Send(context.Background(), NewMessage(topic, "value")))
+	}
+
+	// the failure is not received from the error channel, so it is returned on close
+	err := ap.Close()
+	assert.EqualError(t, err, "failed to close async producer: failed to send message: kafka: Failed to produce message to topic failing: "+
+		sarama.ErrNotLeaderForPartition.Error()+"\n")
+	assert.Equal(t, uint64(3), ap.sent)
+	assert.Equal(t, uint64(1), ap.failed)
+}
+
+func TestAsyncProducer_Close_ErrorReceived(t *testing.T) {
+	ap := newTestAsyncProducer()
+	require.NoError(t, ap.Send(context.Background(), NewMessage("failing", "value")))
+	require.NoError(t, ap.Send(context.Background(), NewMessage("topic", "value")))
+	assert.Error(t, <-ap.Error())
+
+	assert.NoError(t, ap.Close())
+	assert.Equal(t, uint64(2), ap.sent)
+	assert.Equal(t, uint64(1), ap.failed)
+}
@@ -7,6 +7,7 @@ import (
 
 	"github.com/Shopify/sarama"
 	"github.com/beatlabs/patron/encoding"
+	"github.com/beatlabs/patron/encoding/compression"
 	"github.com/beatlabs/patron/log"
 )
 
@@ -77,3 +78,75 @@ func Encoder(enc encoding.EncodeFunc, contentType string) OptionFunc {
 		return nil
 	}
 }
+
+// Client option for injecting a shared sarama client, which is reused instead of opening new broker connections.
+// The client's config takes precedence over the producer's config.
+// The client is owned by the caller, who is responsible for closing it after the producer is closed.
+func Client(client sarama.Client) OptionFunc {
+	return func(ap *AsyncProducer) error {
+		if client == nil {
+			return errors.New("client is nil")
+		}
+		if client.Closed() {
+			return errors.New("client is closed")
+		}
+		if !client.Config().Version.IsAtLeast(sarama.V0_11_0_0) {
+			return errors.New("client config version has to be at least 0.11 in order to support message headers")
+		}
+		ap.client = client
+		log.Info("shared client set")
+		return nil
+	}
+}
+
+// PropagateBaggage option for injecting the baggage items of the producer span as message headers,
+// with their key prefixed by the header prefix. It complements the PropagateBaggage option of the async/kafka consumers.
+func PropagateBaggage(headerPrefix string) OptionFunc {
+	return func(ap *AsyncProducer) error {
+		if headerPrefix == "" {
+			return errors.New("baggage header prefix is required")
+		}
+		ap.baggagePrefix = headerPrefix
+		return nil
+	}
+}
+
+// ValueCompression option for compressing the encoded value of the messages with the codec, e.g. for large JSON payloads,
+// which is set in the content encoding header in order for the async/kafka consumers to decompress it transparently.
+// It saves bandwidth and storage beyond the compression of the batches by the producer.
+func ValueCompression(codec compression.Codec) OptionFunc {
+	return func(ap *AsyncProducer) error {
+		err := codec.Validate()
+		if err != nil {
+			return err
+		}
+		ap.compression = codec
+		return nil
+	}
+}
+
+// KeyEncoder option for encoding the message keys with an encoder other than the one of the values, e.g. Avro keys of a schema registry setup,
+// since the keys of compacted topics have to be encoded deterministically. Without it, the keys are produced as is, as a string or a byte slice.
+func KeyEncoder(enc encoding.EncodeFunc) OptionFunc {
+	return func(ap *AsyncProducer) error {
+		if enc == nil {
+			return errors.New("key encoder is nil")
+		}
+		ap.keyEnc = enc
+		return nil
+	}
+}
+
+// Partitioner option for setting the partitioner of the messages, e.g. HashPartitioner, ManualPartitioner,
+// RoundRobinPartitioner or a custom one, e.g. consistent hashing on a business key. It is ignored with a shared client,
+// whose config provides the partitioner.
+func Partitioner(p sarama.PartitionerConstructor) OptionFunc {
+	return func(ap *AsyncProducer) error {
+		if p == nil {
+			return errors.New("partitioner is required")
+		}
+		ap.cfg.Producer.Partitioner = p
+		log.Info("partitioner set")
+		return nil
+	}
+}
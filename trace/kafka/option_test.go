@@ -6,6 +6,7 @@ import (
 
 	"github.com/Shopify/sarama"
 	"github.com/beatlabs/patron/encoding"
+	"github.com/beatlabs/patron/encoding/compression"
 	"github.com/beatlabs/patron/encoding/json"
 	"github.com/beatlabs/patron/encoding/protobuf"
 	"github.com/stretchr/testify/assert"
@@ -126,3 +127,69 @@ func TestEncoder(t *testing.T) {
 		})
 	}
 }
+
+type mockClient struct {
+	sarama.Client
+	cfg    *sarama.Config
+	closed bool
+}
+
+func (m *mockClient) Config() *sarama.Config { return m.cfg }
+func (m *mockClient) Closed() bool           { return m.closed }
+
+func TestClient(t *testing.T) {
+	oldVersion := sarama.NewConfig()
+	oldVersion.Version = sarama.V0_10_2_0
+	newVersion := sarama.NewConfig()
+	newVersion.Version = sarama.V2_1_0_0
+	tests := map[string]struct {
+		client  sarama.Client
+		wantErr bool
+	}{
+		"success":             {client: &mockClient{cfg: newVersion}},
+		"nil client":          {client: nil, wantErr: true},
+		"closed client":       {client: &mockClient{cfg: newVersion, closed: true}, wantErr: true},
+		"unsupported version": {client: &mockClient{cfg: oldVersion}, wantErr: true},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			ap := &AsyncProducer{}
+			err := Client(tt.client)(ap)
+			if tt.wantErr {
+				assert.Error(t, err)
+				assert.Nil(t, ap.client)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.client, ap.client)
+			}
+		})
+	}
+}
+
+func TestPropagateBaggage(t *testing.T) {
+	ap := &AsyncProducer{}
+	assert.Error(t, PropagateBaggage("")(ap))
+	assert.NoError(t, PropagateBaggage("baggage-")(ap))
+	assert.Equal(t, "baggage-", ap.baggagePrefix)
+}
+
+func TestValueCompression(t *testing.T) {
+	ap := &AsyncProducer{}
+	assert.EqualError(t, ValueCompression("lz4")(ap), `compression codec "lz4" is unsupported`)
+	assert.NoError(t, ValueCompression(compression.Gzip)(ap))
+	assert.Equal(t, compression.Gzip, ap.compression)
+}
+
+func TestKeyEncoder(t *testing.T) {
+	ap := &AsyncProducer{}
+	assert.EqualError(t, KeyEncoder(nil)(ap), "key encoder is nil")
+	assert.NoError(t, KeyEncoder(json.Encode)(ap))
+	assert.NotNil(t, ap.keyEnc)
+}
+
+func TestPartitioner(t *testing.T) {
+	ap := AsyncProducer{cfg: sarama.NewConfig()}
+	assert.EqualError(t, Partitioner(nil)(&ap), "partitioner is required")
+	assert.NoError(t, Partitioner(RoundRobinPartitioner)(&ap))
+	assert.NotNil(t, ap.cfg.Producer.Partitioner)
+}
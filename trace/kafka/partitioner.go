@@ -0,0 +1,60 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/Shopify/sarama"
+)
+
+var (
+	// HashPartitioner routes the messages by the hash of their key, which is the default of the producer.
+	HashPartitioner sarama.PartitionerConstructor = sarama.NewHashPartitioner
+	// ManualPartitioner routes the messages to the partition they were sent to, which is the first partition by default.
+	ManualPartitioner sarama.PartitionerConstructor = sarama.NewManualPartitioner
+	// RoundRobinPartitioner distributes the messages to the partitions in turn.
+	RoundRobinPartitioner sarama.PartitionerConstructor = sarama.NewRoundRobinPartitioner
+)
+
+// explicitPartition marks the messages which are sent to an explicit partition with SendToPartition.
+type explicitPartition struct{}
+
+// partitioner routes the messages sent with SendToPartition to their partition, and the rest with the configured partitioner.
+type partitioner struct {
+	sarama.Partitioner
+}
+
+// explicitPartitioner wraps the partitioner of the producer, in order for SendToPartition to work with any partitioner.
+func explicitPartitioner(pc sarama.PartitionerConstructor) sarama.PartitionerConstructor {
+	if pc == nil {
+		pc = HashPartitioner
+	}
+	return func(topic string) sarama.Partitioner {
+		return &partitioner{Partitioner: pc(topic)}
+	}
+}
+
+// Partition returns the explicit partition of a message, which has to exist, or the partition of the configured partitioner.
+func (p *partitioner) Partition(msg *sarama.ProducerMessage, numPartitions int32) (int32, error) {
+	if _, ok := msg.Metadata.(explicitPartition); !ok {
+		return p.Partitioner.Partition(msg, numPartitions)
+	}
+	if msg.Partition >= numPartitions {
+		return -1, fmt.Errorf("partition %d of topic %s out of range, the topic has %d partitions", msg.Partition, msg.Topic, numPartitions)
+	}
+	return msg.Partition, nil
+}
+
+// SendToPartition sends a message to an explicit partition of its topic, regardless of the partitioner of the producer.
+// A partition which does not exist fails asynchronously, with an error received from the Error channel.
+// The config of a shared client is not modified, so with a shared client the partition is honored only by the ManualPartitioner.
+func (ap *AsyncProducer) SendToPartition(ctx context.Context, msg *Message, partition int32) error {
+	if partition < 0 {
+		return errors.New("partition must not be negative")
+	}
+	return ap.send(ctx, msg, func(pm *sarama.ProducerMessage) {
+		pm.Partition = partition
+		pm.Metadata = explicitPartition{}
+	})
+}
@@ -0,0 +1,77 @@
+package kafka
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Shopify/sarama"
+	"github.com/beatlabs/patron/encoding/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExplicitPartitioner(t *testing.T) {
+	p := explicitPartitioner(RoundRobinPartitioner)("topic")
+	for _, want := range []int32{0, 1, 2, 0} {
+		got, err := p.Partition(&sarama.ProducerMessage{Topic: "topic"}, 3)
+		require.NoError(t, err)
+		assert.Equal(t, want, got)
+	}
+
+	got, err := p.Partition(&sarama.ProducerMessage{Topic: "topic", Partition: 2, Metadata: explicitPartition{}}, 3)
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), got)
+
+	_, err = p.Partition(&sarama.ProducerMessage{Topic: "topic", Partition: 3, Metadata: explicitPartition{}}, 3)
+	assert.EqualError(t, err, "partition 3 of topic topic out of range, the topic has 3 partitions")
+
+	// the messages of a key are routed to the same partition by the default partitioner
+	p = explicitPartitioner(nil)("topic")
+	msg := &sarama.ProducerMessage{Topic: "topic", Key: sarama.StringEncoder("key")}
+	first, err := p.Partition(msg, 10)
+	require.NoError(t, err)
+	for i := 0; i < 5; i++ {
+		got, err = p.Partition(msg, 10)
+		require.NoError(t, err)
+		assert.Equal(t, first, got)
+	}
+}
+
+// capturingAsyncProducer captures the messages sent to the producer.
+type capturingAsyncProducer struct {
+	input     chan *sarama.ProducerMessage
+	successes chan *sarama.ProducerMessage
+	errors    chan *sarama.ProducerError
+}
+
+func (p *capturingAsyncProducer) AsyncClose() {
+	close(p.errors)
+	close(p.successes)
+}
+func (p *capturingAsyncProducer) Close() error                              { p.AsyncClose(); return nil }
+func (p *capturingAsyncProducer) Input() chan<- *sarama.ProducerMessage     { return p.input }
+func (p *capturingAsyncProducer) Successes() <-chan *sarama.ProducerMessage { return p.successes }
+func (p *capturingAsyncProducer) Errors() <-chan *sarama.ProducerError      { return p.errors }
+
+func TestAsyncProducer_SendToPartition(t *testing.T) {
+	cp := &capturingAsyncProducer{
+		input:     make(chan *sarama.ProducerMessage, 2),
+		successes: make(chan *sarama.ProducerMessage),
+		errors:    make(chan *sarama.ProducerError),
+	}
+	ap := &AsyncProducer{prod: cp, chErr: make(chan error), enc: json.Encode, contentType: json.Type}
+	ap.start()
+
+	assert.EqualError(t, ap.SendToPartition(context.Background(), NewMessage("topic", "value"), -1), "partition must not be negative")
+	require.NoError(t, ap.SendToPartition(context.Background(), NewMessage("topic", "value"), 2))
+	require.NoError(t, ap.Send(context.Background(), NewMessage("topic", "value")))
+
+	p := explicitPartitioner(ManualPartitioner)("topic")
+	got, err := p.Partition(<-cp.input, 3)
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), got)
+	got, err = p.Partition(<-cp.input, 3)
+	require.NoError(t, err)
+	assert.Equal(t, int32(0), got)
+	assert.NoError(t, ap.Close())
+}
@@ -0,0 +1,70 @@
+package trace
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/beatlabs/patron/log"
+	opentracing "github.com/opentracing/opentracing-go"
+	otelbridge "go.opentelemetry.io/otel/bridge/opentracing"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+)
+
+// otlpShutdownTimeout bounds the flush of the spans, and the shutdown of the exporter, when the tracer closes.
+const otlpShutdownTimeout = 5 * time.Second
+
+// OTLPProvider is a tracing backend, which exports the spans over OTLP/gRPC to an OpenTelemetry collector,
+// with an OpenTelemetry tracer provider bridged to OpenTracing.
+type OTLPProvider struct {
+	// Endpoint is the host and the port of the collector, e.g. localhost:4317.
+	Endpoint string
+	// Insecure disables the transport security of the connection to the collector.
+	Insecure bool
+	// SampleRatio is the ratio of the traces sampled, unless the parent span is sampled, e.g. 0.1.
+	SampleRatio float64
+}
+
+// Tracer creates a tracer exporting the spans to the collector, which propagates the span contexts in the W3C trace context format.
+func (p OTLPProvider) Tracer(name, version string) (opentracing.Tracer, io.Closer, error) {
+	if p.Endpoint == "" {
+		return nil, nil, errors.New("OTLP endpoint is required")
+	}
+	oo := []otlptracegrpc.Option{otlptracegrpc.WithEndpoint(p.Endpoint)}
+	if p.Insecure {
+		oo = append(oo, otlptracegrpc.WithInsecure())
+	}
+	exp, err := otlptracegrpc.New(context.Background(), oo...)
+	if err != nil {
+		return nil, nil, fmt.Errorf("cannot initialize OTLP exporter: %w", err)
+	}
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exp),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(p.SampleRatio))),
+		sdktrace.WithResource(resource.NewWithAttributes(semconv.SchemaURL,
+			semconv.ServiceName(name),
+			semconv.ServiceVersion(version),
+		)),
+	)
+	bt, _ := otelbridge.NewTracerPair(tp.Tracer(name))
+	bt.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	bt.SetWarningHandler(func(msg string) { log.Debugf("OpenTracing bridge: %s", msg) })
+	return bt, otlpCloser{tp: tp}, nil
+}
+
+// otlpCloser flushes the spans and shuts down the tracer provider along with its exporter.
+type otlpCloser struct {
+	tp *sdktrace.TracerProvider
+}
+
+func (c otlpCloser) Close() error {
+	ctx, cnl := context.WithTimeout(context.Background(), otlpShutdownTimeout)
+	defer cnl()
+	return c.tp.Shutdown(ctx)
+}
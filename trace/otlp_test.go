@@ -0,0 +1,78 @@
+package trace
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	"google.golang.org/grpc"
+)
+
+// collector records the spans exported to it.
+type collector struct {
+	coltracepb.UnimplementedTraceServiceServer
+	mu       sync.Mutex
+	spans    map[string][]byte
+	services []string
+}
+
+func (c *collector) Export(_ context.Context, req *coltracepb.ExportTraceServiceRequest) (*coltracepb.ExportTraceServiceResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, rs := range req.GetResourceSpans() {
+		for _, a := range rs.GetResource().GetAttributes() {
+			if a.GetKey() == "service.name" {
+				c.services = append(c.services, a.GetValue().GetStringValue())
+			}
+		}
+		for _, ss := range rs.GetScopeSpans() {
+			for _, sp := range ss.GetSpans() {
+				c.spans[sp.GetName()] = sp.GetTraceId()
+			}
+		}
+	}
+	return &coltracepb.ExportTraceServiceResponse{}, nil
+}
+
+func TestOTLPProvider_Tracer(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	c := &collector{spans: map[string][]byte{}}
+	srv := grpc.NewServer()
+	coltracepb.RegisterTraceServiceServer(srv, c)
+	go func() { _ = srv.Serve(lis) }()
+	defer srv.Stop()
+
+	tr, cls, err := OTLPProvider{Endpoint: lis.Addr().String(), Insecure: true, SampleRatio: 1}.Tracer("test", "1.0.0")
+	require.NoError(t, err)
+
+	sp := tr.StartSpan("parent")
+	// the span context is propagated in text maps, e.g. the headers of the messages, as well as in HTTP headers
+	hdr := opentracing.TextMapCarrier{}
+	require.NoError(t, tr.Inject(sp.Context(), opentracing.TextMap, hdr))
+	assert.Contains(t, hdr, "traceparent")
+	sc, err := tr.Extract(opentracing.HTTPHeaders, hdr)
+	require.NoError(t, err)
+	child := tr.StartSpan("child", opentracing.ChildOf(sc))
+	child.Finish()
+	sp.Finish()
+
+	// closing the tracer flushes the spans to the collector of the endpoint
+	require.NoError(t, cls.Close())
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	require.Len(t, c.spans, 2)
+	assert.NotEmpty(t, c.spans["parent"])
+	assert.Equal(t, c.spans["parent"], c.spans["child"])
+	assert.Contains(t, c.services, "test")
+}
+
+func TestOTLPProvider_Tracer_NoEndpoint(t *testing.T) {
+	_, _, err := OTLPProvider{}.Tracer("test", "1.0.0")
+	assert.EqualError(t, err, "OTLP endpoint is required")
+}
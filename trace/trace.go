@@ -2,6 +2,7 @@ package trace
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -12,6 +13,7 @@ import (
 	"github.com/beatlabs/patron/log"
 	opentracing "github.com/opentracing/opentracing-go"
 	"github.com/opentracing/opentracing-go/ext"
+	jaeger "github.com/uber/jaeger-client-go"
 	"github.com/uber/jaeger-client-go/config"
 	"github.com/uber/jaeger-client-go/rpcmetrics"
 	"github.com/uber/jaeger-lib/metrics/prometheus"
@@ -34,8 +36,10 @@ const (
 	SQSConsumerComponent = "sqs-consumer"
 	// SNSPublisherComponent definition.
 	SNSPublisherComponent = "sns-publisher"
-	versionTag            = "version"
-	hostsTag              = "hosts"
+	// HealthCheckComponent definition.
+	HealthCheckComponent = "health-check"
+	versionTag           = "version"
+	hostsTag             = "hosts"
 )
 
 var (
@@ -43,21 +47,35 @@ var (
 	version = "dev"
 )
 
-// Setup tracing by providing all necessary parameters.
-func Setup(name, ver, agent, typ string, prm float64) error {
-	if ver != "" {
-		version = ver
-	}
+// Provider is a tracing backend, which creates the tracer of a service along with a closer flushing its spans and shutting it down.
+// The tracer is set as the global OpenTracing tracer, so the spans of the HTTP middlewares and of the consumers and producers,
+// e.g. of ClaimMessage, are created the same way regardless of the backend, e.g. an OpenTelemetry tracer provider bridged to OpenTracing.
+type Provider interface {
+	Tracer(name, version string) (opentracing.Tracer, io.Closer, error)
+}
+
+// JaegerProvider is the default tracing backend, which reports the spans to a Jaeger agent.
+type JaegerProvider struct {
+	// Agent is the host and the port of the agent, e.g. 0.0.0.0:6831.
+	Agent string
+	// SamplerType is the type of the sampler, e.g. probabilistic.
+	SamplerType string
+	// SamplerParam is the param of the sampler, e.g. the sampling probability.
+	SamplerParam float64
+}
+
+// Tracer creates a Jaeger tracer reporting to the agent.
+func (p JaegerProvider) Tracer(name, _ string) (opentracing.Tracer, io.Closer, error) {
 	cfg := config.Configuration{
 		ServiceName: name,
 		Sampler: &config.SamplerConfig{
-			Type:  typ,
-			Param: prm,
+			Type:  p.SamplerType,
+			Param: p.SamplerParam,
 		},
 		Reporter: &config.ReporterConfig{
 			LogSpans:            false,
 			BufferFlushInterval: 1 * time.Second,
-			LocalAgentHostPort:  agent,
+			LocalAgentHostPort:  p.Agent,
 		},
 	}
 	time.Sleep(100 * time.Millisecond)
@@ -67,7 +85,27 @@ func Setup(name, ver, agent, typ string, prm float64) error {
 		config.Observer(rpcmetrics.NewObserver(metricsFactory.Namespace(name, nil), rpcmetrics.DefaultNameNormalizer)),
 	)
 	if err != nil {
-		return fmt.Errorf("cannot initialize jaeger tracer: %w", err)
+		return nil, nil, fmt.Errorf("cannot initialize jaeger tracer: %w", err)
+	}
+	return tr, clsTemp, nil
+}
+
+// Setup tracing by providing all necessary parameters.
+func Setup(name, ver, agent, typ string, prm float64) error {
+	return SetupProvider(name, ver, JaegerProvider{Agent: agent, SamplerType: typ, SamplerParam: prm})
+}
+
+// SetupProvider sets up tracing with the tracer of the provider, which is closed by Close.
+func SetupProvider(name, ver string, p Provider) error {
+	if p == nil {
+		return errors.New("tracing provider is nil")
+	}
+	if ver != "" {
+		version = ver
+	}
+	tr, clsTemp, err := p.Tracer(name, ver)
+	if err != nil {
+		return err
 	}
 	cls = clsTemp
 	opentracing.SetGlobalTracer(tr)
@@ -75,9 +113,12 @@ func Setup(name, ver, agent, typ string, prm float64) error {
 	return nil
 }
 
-// Close the tracer.
+// Close the tracer of the active provider, which flushes its spans.
 func Close() error {
 	log.Debug("closing tracer")
+	if cls == nil {
+		return nil
+	}
 	return cls.Close()
 }
 
@@ -120,6 +161,33 @@ func ConsumerSpan(ctx context.Context, opName, cmp, corID string, hdr map[string
 	return sp, opentracing.ContextWithSpan(ctx, sp)
 }
 
+// UnsampledConsumerSpan returns a span which is not recorded, for the messages of consumers which do not trace every message.
+// The span context extracted from the headers is propagated with the returned context, so that the spans started from it,
+// e.g. of the downstream requests, are children of the upstream trace and follow its sampling decision.
+func UnsampledConsumerSpan(ctx context.Context, hdr map[string]string) (opentracing.Span, context.Context) {
+	sp := opentracing.NoopTracer{}.StartSpan("")
+	spCtx, err := opentracing.GlobalTracer().Extract(opentracing.HTTPHeaders, opentracing.TextMapCarrier(hdr))
+	if err != nil {
+		if err != opentracing.ErrSpanContextNotFound {
+			log.Errorf("failed to extract consumer span: %v", err)
+		}
+		return sp, ctx
+	}
+	sp = unsampledSpan{Span: sp, ctx: spCtx}
+	return sp, opentracing.ContextWithSpan(ctx, sp)
+}
+
+// unsampledSpan is a span which is not recorded, with the span context of the upstream trace.
+type unsampledSpan struct {
+	opentracing.Span
+	ctx opentracing.SpanContext
+}
+
+// Context returns the span context of the upstream trace.
+func (s unsampledSpan) Context() opentracing.SpanContext {
+	return s.ctx
+}
+
 // SpanSuccess finishes a span with a success indicator.
 func SpanSuccess(sp opentracing.Span) {
 	ext.Error.Set(sp, false)
@@ -143,6 +211,29 @@ func ChildSpan(ctx context.Context, opName, cmp string, tags ...opentracing.Tag)
 	return sp, ctx
 }
 
+// BatchSpan starts a new span for processing a batch of messages, which is a child of the span of the context, if any,
+// and has a FollowsFrom reference to the span of every message context, so the trace shows the fan-in of the messages.
+// Message contexts without a span are skipped.
+func BatchSpan(ctx context.Context, opName, cmp string, msgCtxs []context.Context,
+	tags ...opentracing.Tag) (opentracing.Span, context.Context) {
+	opts := make([]opentracing.StartSpanOption, 0, len(msgCtxs)+1)
+	if parent := opentracing.SpanFromContext(ctx); parent != nil {
+		opts = append(opts, opentracing.ChildOf(parent.Context()))
+	}
+	for _, msgCtx := range msgCtxs {
+		if msgSp := opentracing.SpanFromContext(msgCtx); msgSp != nil {
+			opts = append(opts, opentracing.FollowsFrom(msgSp.Context()))
+		}
+	}
+	sp := opentracing.StartSpan(opName, opts...)
+	ext.Component.Set(sp, cmp)
+	for _, t := range tags {
+		sp.SetTag(t.Key, t.Value)
+	}
+	sp.SetTag(versionTag, version)
+	return sp, opentracing.ContextWithSpan(ctx, sp)
+}
+
 // SQLSpan starts a new SQL child span with specified tags.
 func SQLSpan(ctx context.Context, opName, cmp, sqlType, instance, user, stmt string,
 	tags ...opentracing.Tag) (opentracing.Span, context.Context) {
@@ -179,6 +270,26 @@ func EsSpan(ctx context.Context, opName, cmp, user, uri, method, body string, ho
 	return sp
 }
 
+// TraceID returns the trace id of the span of the context,
+// or an empty string if the context has no span or the span is not created by the jaeger tracer.
+func TraceID(ctx context.Context) string {
+	sp := opentracing.SpanFromContext(ctx)
+	if sp == nil {
+		return ""
+	}
+	switch sc := sp.Context().(type) {
+	case jaeger.SpanContext:
+		return sc.TraceID().String()
+	case otelSpanContext:
+		if !sc.IsValid() {
+			return ""
+		}
+		return sc.TraceID().String()
+	default:
+		return ""
+	}
+}
+
 // HTTPOpName return a string representation of the HTTP request operation.
 func HTTPOpName(method, path string) string {
 	return method + " " + path
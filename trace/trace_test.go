@@ -2,6 +2,8 @@ package trace
 
 import (
 	"context"
+	"errors"
+	"io"
 	"net/http"
 	"testing"
 
@@ -9,6 +11,9 @@ import (
 	"github.com/opentracing/opentracing-go/ext"
 	"github.com/opentracing/opentracing-go/mocktracer"
 	"github.com/stretchr/testify/assert"
+	jaeger "github.com/uber/jaeger-client-go"
+	otelbridge "go.opentelemetry.io/otel/bridge/opentracing"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 )
 
 func TestSetup_Tracer_Close(t *testing.T) {
@@ -19,6 +24,49 @@ func TestSetup_Tracer_Close(t *testing.T) {
 	version = "dev"
 }
 
+// mockProvider creates a mock tracer, whose closer counts the closes.
+type mockProvider struct {
+	tracer *mocktracer.MockTracer
+	closes int
+	err    error
+}
+
+func (p *mockProvider) Tracer(_, _ string) (opentracing.Tracer, io.Closer, error) {
+	if p.err != nil {
+		return nil, nil, p.err
+	}
+	return p.tracer, p, nil
+}
+
+func (p *mockProvider) Close() error {
+	p.closes++
+	return nil
+}
+
+func TestSetupProvider(t *testing.T) {
+	defer func() { cls, version = nil, "dev" }()
+	assert.EqualError(t, SetupProvider("TEST", "1.0.0", nil), "tracing provider is nil")
+	assert.EqualError(t, SetupProvider("TEST", "1.0.0", &mockProvider{err: errors.New("unreachable")}), "unreachable")
+
+	p := &mockProvider{tracer: mocktracer.New()}
+	assert.NoError(t, SetupProvider("TEST", "1.0.0", p))
+	assert.Equal(t, p.tracer, opentracing.GlobalTracer())
+
+	// the spans are created with the tracer of the provider
+	sp, _ := ConsumerSpan(context.Background(), "123", "custom-consumer", "corID", nil)
+	SpanSuccess(sp)
+	assert.Len(t, p.tracer.FinishedSpans(), 1)
+
+	assert.NoError(t, Close())
+	assert.Equal(t, 1, p.closes)
+}
+
+func TestClose_WithoutSetup(t *testing.T) {
+	defer func(c io.Closer) { cls = c }(cls)
+	cls = nil
+	assert.NoError(t, Close())
+}
+
 func TestStartFinishConsumerSpan(t *testing.T) {
 	mtr := mocktracer.New()
 	opentracing.SetGlobalTracer(mtr)
@@ -78,6 +126,57 @@ func TestStartFinishChildSpan(t *testing.T) {
 	}, rawSpan.Tags())
 }
 
+type referenceTracer struct {
+	*mocktracer.MockTracer
+	refs []opentracing.SpanReference
+}
+
+func (rt *referenceTracer) StartSpan(opName string, opts ...opentracing.StartSpanOption) opentracing.Span {
+	sso := opentracing.StartSpanOptions{}
+	for _, o := range opts {
+		o.Apply(&sso)
+	}
+	rt.refs = sso.References
+	return rt.MockTracer.StartSpan(opName, opts...)
+}
+
+func TestBatchSpan(t *testing.T) {
+	mtr := mocktracer.New()
+	opentracing.SetGlobalTracer(mtr)
+	var msgCtxs []context.Context
+	for i := 0; i < 3; i++ {
+		_, ctx := ConsumerSpan(context.Background(), "consume", KafkaConsumerComponent, "corID", nil)
+		msgCtxs = append(msgCtxs, ctx)
+	}
+	msgCtxs = append(msgCtxs, context.Background())
+	parent, ctx := ChildSpan(context.Background(), "parent", "cmp")
+
+	rt := &referenceTracer{MockTracer: mtr}
+	opentracing.SetGlobalTracer(rt)
+	defer opentracing.SetGlobalTracer(mtr)
+	tag := opentracing.Tag{Key: "key", Value: "value"}
+	sp, batchCtx := BatchSpan(ctx, "batch", "cmp", msgCtxs, tag)
+	assert.Equal(t, sp, opentracing.SpanFromContext(batchCtx))
+
+	assert.Len(t, rt.refs, 4)
+	assert.Equal(t, opentracing.ChildOfRef, rt.refs[0].Type)
+	assert.Equal(t, parent.Context(), rt.refs[0].ReferencedContext)
+	for i, ref := range rt.refs[1:] {
+		assert.Equal(t, opentracing.FollowsFromRef, ref.Type)
+		assert.Equal(t, opentracing.SpanFromContext(msgCtxs[i]).Context(), ref.ReferencedContext)
+	}
+
+	SpanSuccess(sp)
+	rawSpan := mtr.FinishedSpans()[0]
+	assert.Equal(t, "batch", rawSpan.OperationName)
+	assert.Equal(t, map[string]interface{}{
+		"component": "cmp",
+		"error":     false,
+		"key":       "value",
+		"version":   "dev",
+	}, rawSpan.Tags())
+}
+
 func TestHTTPStartFinishSpan(t *testing.T) {
 	mtr := mocktracer.New()
 	opentracing.SetGlobalTracer(mtr)
@@ -156,3 +255,22 @@ func TestEsSpan(t *testing.T) {
 func TestComponentOpName(t *testing.T) {
 	assert.Equal(t, "cmp target", ComponentOpName("cmp", "target"))
 }
+
+func TestTraceID(t *testing.T) {
+	tr, cls := jaeger.NewTracer("test", jaeger.NewConstSampler(true), jaeger.NewNullReporter())
+	defer func() { assert.NoError(t, cls.Close()) }()
+	sp := tr.StartSpan("op")
+	ctx := opentracing.ContextWithSpan(context.Background(), sp)
+	assert.Equal(t, sp.Context().(jaeger.SpanContext).TraceID().String(), TraceID(ctx))
+	assert.NotEmpty(t, TraceID(ctx))
+
+	assert.Empty(t, TraceID(context.Background()))
+	ctx = opentracing.ContextWithSpan(context.Background(), mocktracer.New().StartSpan("op"))
+	assert.Empty(t, TraceID(ctx))
+
+	// the spans of an OpenTelemetry tracer bridged to OpenTracing
+	bt, _ := otelbridge.NewTracerPair(sdktrace.NewTracerProvider().Tracer("test"))
+	sp = bt.StartSpan("op")
+	ctx = opentracing.ContextWithSpan(context.Background(), sp)
+	assert.Len(t, TraceID(ctx), 32)
+}